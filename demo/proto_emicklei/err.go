@@ -2,13 +2,11 @@ package proto_emicklei
 
 import (
 	"github.com/oldbai555/lbtool/log"
-	"os"
 )
 
 func Exit(err error) {
 	if err != nil {
-		log.Errorf("err:%v", err)
-		os.Exit(1)
+		log.Fatalf("err:%v", err)
 	}
 }
 
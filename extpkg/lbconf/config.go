@@ -27,7 +27,7 @@ func NewConfig(opts ...Option) (bconf.Config, error) {
 func (c *config) Load() error {
 	kvs, err := c.opts.dataSource.Load()
 	if err != nil {
-		return nil
+		return err
 	}
 	for _, v := range kvs {
 		c.viper.Set(v.Key, v.Val)
@@ -0,0 +1,98 @@
+package env
+
+import (
+	"context"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var _ bconf.DataSource = (*envSource)(nil)
+
+// envSource 读取带前缀的环境变量, LB_DB_HOST -> db.host, 双下划线表示 key 中的下划线 LB_MAX__CONNS -> max_conns
+// 值默认保持字符串, 需要类型转换的 key 通过 WithCoerce 指定
+type envSource struct {
+	prefix string
+	opts   *options
+}
+
+// NewEnvSource prefix 例如 LB, 和 file 一起使用时通过 lbconf.NewMultiSource 覆盖文件中的配置
+func NewEnvSource(prefix string, opts ...Option) bconf.DataSource {
+	if prefix != "" && !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	return &envSource{prefix: prefix, opts: newOptions(opts...)}
+}
+
+func (s *envSource) Load() ([]*bconf.Data, error) {
+	var list []*bconf.Data
+	for _, kv := range os.Environ() {
+		name, val, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, s.prefix) || len(name) == len(s.prefix) {
+			continue
+		}
+		key := s.opts.keyMapper(name[len(s.prefix):])
+		var v interface{} = val
+		if _, ok := s.opts.coerce[key]; ok {
+			v = coerce(val)
+		}
+		list = append(list, &bconf.Data{Key: key, Val: v})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list, nil
+}
+
+// Watch 环境变量在进程运行期间不会变化, Change 阻塞到 Close
+func (s *envSource) Watch() (bconf.DataWatcher, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &watcher{ctx: ctx, cancel: cancel}, nil
+}
+
+// KeyMapper 默认的 key 转换, DB_HOST -> db.host, MAX__CONNS -> max_conns
+func KeyMapper(name string) string {
+	parts := strings.Split(strings.ToLower(name), "__")
+	for i, p := range parts {
+		parts[i] = strings.ReplaceAll(p, "_", ".")
+	}
+	return strings.Join(parts, "_")
+}
+
+// coerce 只用于 WithCoerce 指定的 key, true / false 转 bool, 整数转 int64, 小数转 float64, 其他保持字符串
+func coerce(val string) interface{} {
+	switch val {
+	case "true", "TRUE", "True":
+		return true
+	case "false", "FALSE", "False":
+		return false
+	}
+	if i, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return i
+	}
+	if strings.Contains(val, ".") {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return val
+}
+
+var _ bconf.DataWatcher = (*watcher)(nil)
+
+type watcher struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (w *watcher) Change() ([]*bconf.Data, error) {
+	<-w.ctx.Done()
+	return nil, context.Canceled
+}
+
+func (w *watcher) Close() error {
+	w.cancel()
+	return nil
+}
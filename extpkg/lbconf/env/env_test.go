@@ -0,0 +1,62 @@
+package env
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("LB_DB_HOST", "127.0.0.1")
+	t.Setenv("LB_DB_PORT", "3306")
+	t.Setenv("LB_DEBUG", "true")
+	t.Setenv("LB_MAX__CONNS", "10")
+	t.Setenv("LB_RATIO", "0.5")
+	t.Setenv("LB_PHONE__PREFIX", "0086")
+	t.Setenv("LB_VERSION", "1.10")
+	t.Setenv("LBX_NAME", "other")
+
+	list, err := NewEnvSource("LB", WithCoerce("db.port", "debug", "max_conns", "ratio")).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := make(map[string]interface{})
+	for _, d := range list {
+		m[d.Key] = d.Val
+	}
+	want := map[string]interface{}{
+		"db.host":      "127.0.0.1",
+		"db.port":      int64(3306),
+		"debug":        true,
+		"max_conns":    int64(10),
+		"ratio":        0.5,
+		"phone_prefix": "0086",
+		"version":      "1.10",
+	}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s got %v(%T), want %v", k, m[k], m[k], v)
+		}
+	}
+	if _, ok := m["name"]; ok {
+		t.Errorf("LBX_ should not match prefix LB_")
+	}
+
+	list, _ = NewEnvSource("LB_").Load()
+	for _, d := range list {
+		if d.Key == "db.port" && d.Val != "3306" {
+			t.Errorf("got %v", d.Val)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	w, err := NewEnvSource("LB").Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	if _, err = w.Change(); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package env
+
+type Option func(*options)
+
+type options struct {
+	keyMapper func(name string) string
+	coerce    map[string]struct{}
+}
+
+// WithKeyMapper 自定义去掉前缀后的变量名到 key 的转换
+func WithKeyMapper(mapper func(name string) string) Option {
+	return func(o *options) {
+		o.keyMapper = mapper
+	}
+}
+
+// WithCoerce 这些 key 的值转换为 bool / int64 / float64, 其他 key 保持字符串, 读取后自行转换
+// 0086、邮编、版本号 1.10 这类值转成数字会丢失内容, 只对确定是数字的 key 使用
+func WithCoerce(keys ...string) Option {
+	return func(o *options) {
+		for _, key := range keys {
+			o.coerce[key] = struct{}{}
+		}
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		keyMapper: KeyMapper,
+		coerce:    make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
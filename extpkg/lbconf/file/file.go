@@ -0,0 +1,93 @@
+package file
+
+import (
+	"errors"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported config format")
+
+var supportFormats = map[string]string{
+	"yaml": "yaml",
+	"yml":  "yaml",
+	"json": "json",
+	"toml": "toml",
+}
+
+var _ bconf.DataSource = (*fileSource)(nil)
+
+// fileSource 本地配置文件, 按扩展名解析 yaml / json / toml, key 为 db.host 这样展开后的路径
+type fileSource struct {
+	path     string
+	format   string
+	debounce time.Duration
+}
+
+func NewFileSource(path string, opts ...Option) (bconf.DataSource, error) {
+	o := newOptions(opts...)
+	format := o.format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	format, ok := supportFormats[strings.ToLower(format)]
+	if !ok {
+		return nil, ErrUnsupportedFormat
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSource{path: absPath, format: format, debounce: o.debounce}, nil
+}
+
+func (s *fileSource) Load() ([]*bconf.Data, error) {
+	kvs, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return diff(nil, kvs), nil
+}
+
+func (s *fileSource) Watch() (bconf.DataWatcher, error) {
+	return newWatcher(s)
+}
+
+// read 使用 viper 解析, 只保留叶子节点
+func (s *fileSource) read() (map[string]interface{}, error) {
+	v := viper.New()
+	v.SetConfigFile(s.path)
+	v.SetConfigType(s.format)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	kvs := make(map[string]interface{})
+	for _, k := range v.AllKeys() {
+		kvs[k] = v.Get(k)
+	}
+	return kvs, nil
+}
+
+// diff 新增和修改的 key 返回新值, 删除的 key 返回 nil, 按 key 排序
+func diff(old, cur map[string]interface{}) []*bconf.Data {
+	var list []*bconf.Data
+	for k, v := range cur {
+		if ov, ok := old[k]; !ok || !reflect.DeepEqual(ov, v) {
+			list = append(list, &bconf.Data{Key: k, Val: v})
+		}
+	}
+	for k := range old {
+		if _, ok := cur[k]; !ok {
+			list = append(list, &bconf.Data{Key: k, Val: nil})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
@@ -0,0 +1,106 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func toMap(list []*bconf.Data) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, d := range list {
+		m[d.Key] = d.Val
+	}
+	return m
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.yaml": "db:\n  host: 127.0.0.1\n  port: 3306\nname: lb\n",
+		"app.json": `{"db":{"host":"127.0.0.1","port":3306},"name":"lb"}`,
+		"app.toml": "name = \"lb\"\n[db]\nhost = \"127.0.0.1\"\nport = 3306\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		s, err := NewFileSource(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list, err := s.Load()
+		if err != nil {
+			t.Fatalf("%s err:%v", name, err)
+		}
+		m := toMap(list)
+		if len(m) != 3 || m["db.host"] != "127.0.0.1" || m["name"] != "lb" {
+			t.Errorf("%s got %+v", name, m)
+		}
+	}
+
+	if _, err := NewFileSource(filepath.Join(dir, "app.ini")); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("db:\n  host: a\n  port: 1\nname: lb\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFileSource(path, WithDebounce(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		list []*bconf.Data
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		list, err := w.Change()
+		ch <- result{list, err}
+	}()
+
+	// 编辑器的保存方式: 写临时文件再 rename
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, []byte("db:\n  host: b\n  port: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		m := toMap(r.list)
+		if len(m) != 2 || m["db.host"] != "b" || m["name"] != nil {
+			t.Errorf("got %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change")
+	}
+
+	go func() {
+		list, err := w.Change()
+		ch <- result{list, err}
+	}()
+	_ = w.Close()
+	if r := <-ch; !errors.Is(r.err, context.Canceled) {
+		t.Errorf("got %+v", r)
+	}
+}
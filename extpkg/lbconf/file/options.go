@@ -0,0 +1,38 @@
+package file
+
+import (
+	"time"
+)
+
+const DefaultDebounce = 100 * time.Millisecond
+
+type Option func(*options)
+
+type options struct {
+	format   string
+	debounce time.Duration
+}
+
+// WithFormat 文件扩展名不能表示格式时指定, yaml / json / toml
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithDebounce 编辑器保存时会连续触发多次事件, 等待这段时间后再重新加载
+func WithDebounce(d time.Duration) Option {
+	return func(o *options) {
+		o.debounce = d
+	}
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{
+		debounce: DefaultDebounce,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
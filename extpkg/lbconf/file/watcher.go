@@ -0,0 +1,93 @@
+package file
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"path/filepath"
+	"time"
+)
+
+var _ bconf.DataWatcher = (*watcher)(nil)
+
+// watcher 监听文件所在的目录, 编辑器先写临时文件再 rename, k8s 的 configmap 通过软链切换, 只监听文件本身会丢事件
+type watcher struct {
+	s      *fileSource
+	fw     *fsnotify.Watcher
+	last   map[string]interface{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newWatcher(s *fileSource) (*watcher, error) {
+	last, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err = fw.Add(filepath.Dir(s.path)); err != nil {
+		_ = fw.Close()
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &watcher{
+		s:      s,
+		fw:     fw,
+		last:   last,
+		ctx:    ctx,
+		cancel: cancel,
+	}, nil
+}
+
+// Change 阻塞到配置有变化, 只返回变化的 key; 关闭后返回 context.Canceled
+func (w *watcher) Change() ([]*bconf.Data, error) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, context.Canceled
+		case err := <-w.fw.Errors:
+			if err == nil {
+				continue
+			}
+			return nil, err
+		case _, ok := <-w.fw.Events:
+			if !ok {
+				return nil, context.Canceled
+			}
+			w.drain()
+			cur, err := w.s.read()
+			if err != nil {
+				// 保存到一半读不到文件, 等下一次事件
+				return nil, err
+			}
+			changed := diff(w.last, cur)
+			w.last = cur
+			if len(changed) > 0 {
+				return changed, nil
+			}
+		}
+	}
+}
+
+// drain 合并 debounce 时间内的事件
+func (w *watcher) drain() {
+	timer := time.NewTimer(w.s.debounce)
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.fw.Events:
+		case <-timer.C:
+			return
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *watcher) Close() error {
+	w.cancel()
+	return w.fw.Close()
+}
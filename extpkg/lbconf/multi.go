@@ -0,0 +1,162 @@
+package lbconf
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"sync"
+)
+
+var _ bconf.DataSource = (*multiSource)(nil)
+
+// multiSource 多个数据源叠加, 后面的覆盖前面的, 例如环境变量覆盖配置文件
+type multiSource struct {
+	sources []bconf.DataSource
+	mu      sync.Mutex
+	datas   []map[string]interface{} // 每个数据源当前的值
+}
+
+// NewMultiSource 后面的数据源优先
+//
+//	lbconf.NewMultiSource(fileSource, env.NewEnvSource("LB"))
+func NewMultiSource(sources ...bconf.DataSource) bconf.DataSource {
+	return &multiSource{sources: sources}
+}
+
+// Load 按顺序返回所有数据源的值, 依次 Set 之后后面的覆盖前面的
+func (m *multiSource) Load() ([]*bconf.Data, error) {
+	datas := make([]map[string]interface{}, len(m.sources))
+	var list []*bconf.Data
+	for i, s := range m.sources {
+		kvs, err := s.Load()
+		if err != nil {
+			return nil, err
+		}
+		datas[i] = make(map[string]interface{}, len(kvs))
+		for _, v := range kvs {
+			datas[i][v.Key] = v.Val
+		}
+		list = append(list, kvs...)
+	}
+	m.mu.Lock()
+	m.datas = datas
+	m.mu.Unlock()
+	return list, nil
+}
+
+func (m *multiSource) Watch() (bconf.DataWatcher, error) {
+	m.mu.Lock()
+	loaded := m.datas != nil
+	m.mu.Unlock()
+	if !loaded {
+		if _, err := m.Load(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &multiWatcher{m: m, ch: make(chan multiChange), ctx: ctx, cancel: cancel}
+	for i, s := range m.sources {
+		sw, err := s.Watch()
+		if err != nil {
+			_ = w.Close()
+			return nil, err
+		}
+		w.watchers = append(w.watchers, sw)
+		go w.loop(i, sw)
+	}
+	return w, nil
+}
+
+// merge 被优先级更高的数据源覆盖的 key 不返回, 删除的 key 回落到优先级低的数据源
+func (m *multiSource) merge(idx int, kvs []*bconf.Data) []*bconf.Data {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*bconf.Data
+	for _, v := range kvs {
+		if v.Val == nil {
+			delete(m.datas[idx], v.Key)
+		} else {
+			m.datas[idx][v.Key] = v.Val
+		}
+		if m.overridden(idx, v.Key) {
+			continue
+		}
+		val := v.Val
+		if val == nil {
+			for i := idx - 1; i >= 0; i-- {
+				if lv, ok := m.datas[i][v.Key]; ok {
+					val = lv
+					break
+				}
+			}
+		}
+		list = append(list, &bconf.Data{Key: v.Key, Val: val})
+	}
+	return list
+}
+
+func (m *multiSource) overridden(idx int, key string) bool {
+	for i := idx + 1; i < len(m.datas); i++ {
+		if _, ok := m.datas[i][key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+type multiChange struct {
+	idx int
+	kvs []*bconf.Data
+	err error
+}
+
+var _ bconf.DataWatcher = (*multiWatcher)(nil)
+
+type multiWatcher struct {
+	m        *multiSource
+	watchers []bconf.DataWatcher
+	ch       chan multiChange
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func (w *multiWatcher) loop(idx int, sw bconf.DataWatcher) {
+	for {
+		kvs, err := sw.Change()
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		select {
+		case w.ch <- multiChange{idx: idx, kvs: kvs, err: err}:
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+// Change 任意一个数据源变化时返回, 关闭后返回 context.Canceled
+func (w *multiWatcher) Change() ([]*bconf.Data, error) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, context.Canceled
+		case c := <-w.ch:
+			if c.err != nil {
+				return nil, c.err
+			}
+			if list := w.m.merge(c.idx, c.kvs); len(list) > 0 {
+				return list, nil
+			}
+		}
+	}
+}
+
+func (w *multiWatcher) Close() error {
+	w.cancel()
+	var errs []error
+	for _, sw := range w.watchers {
+		errs = append(errs, sw.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,59 @@
+package lbconf
+
+import (
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/env"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/file"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMultiSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("db:\n  host: a\n  port: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LBTEST_DB_HOST", "b")
+
+	fs, err := file.NewFileSource(path, file.WithDebounce(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := NewConfig(WithDataSource(NewMultiSource(fs, env.NewEnvSource("LBTEST"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conf.Close()
+	if err = conf.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if host, _ := conf.Get("db.host"); host != "b" {
+		t.Errorf("env should override file, got %v", host)
+	}
+	if port, _ := conf.Get("db.port"); port != 1 {
+		t.Errorf("got %v", port)
+	}
+
+	changed := make(chan string, 10)
+	if err = conf.Watch(func(path string, v bconf.Val) {
+		changed <- path
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(path, []byte("db:\n  host: c\n  port: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case key := <-changed:
+		if key != "db.port" {
+			t.Errorf("db.host is overridden by env, got change of %s", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change")
+	}
+	if host, _ := conf.Get("db.host"); host != "b" {
+		t.Errorf("got %v", host)
+	}
+}
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/log/iface"
+	"sync/atomic"
+)
+
+// AsyncPolicy 异步缓冲满了之后的处理方式
+type AsyncPolicy int
+
+const (
+	AsyncBlock AsyncPolicy = iota // 阻塞等待, 不丢日志
+	AsyncDrop                     // 直接丢弃, 不影响业务耗时
+)
+
+const DefaultAsyncSize = 4096
+
+var _ iface.LogWriter = (*asyncWriter)(nil)
+var _ iface.EntryWriter = (*asyncWriter)(nil)
+
+type asyncItem struct {
+	entry *Entry
+	buf   []byte
+	flush chan error
+}
+
+// asyncWriter 日志先进入有界队列, 由后台协程写入底层 writer
+type asyncWriter struct {
+	w       iface.LogWriter
+	ch      chan asyncItem
+	policy  AsyncPolicy
+	dropped uint64
+}
+
+func newAsyncWriter(w iface.LogWriter, size int, policy AsyncPolicy) *asyncWriter {
+	if size <= 0 {
+		size = DefaultAsyncSize
+	}
+	a := &asyncWriter{
+		w:      w,
+		ch:     make(chan asyncItem, size),
+		policy: policy,
+	}
+	go a.loop()
+	return a
+}
+
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	return a.push(asyncItem{buf: append([]byte(nil), p...)}, len(p))
+}
+
+// WriteEntry 底层 writer 需要原始日志内容时一并放入队列
+func (a *asyncWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	item := asyncItem{buf: append([]byte(nil), p...)}
+	if _, ok := a.w.(iface.EntryWriter); ok {
+		c := *e
+		item.entry = &c
+	}
+	return a.push(item, len(p))
+}
+
+func (a *asyncWriter) push(item asyncItem, n int) (int, error) {
+	if a.policy == AsyncDrop {
+		select {
+		case a.ch <- item:
+		default:
+			atomic.AddUint64(&a.dropped, 1)
+		}
+		return n, nil
+	}
+	a.ch <- item
+	return n, nil
+}
+
+// Flush 等待队列中已有的日志写完, 丢弃策略下也会阻塞
+func (a *asyncWriter) Flush() error {
+	done := make(chan error, 1)
+	a.ch <- asyncItem{flush: done}
+	return <-done
+}
+
+func (a *asyncWriter) loop() {
+	for item := range a.ch {
+		if item.flush != nil {
+			if n := atomic.SwapUint64(&a.dropped, 0); n > 0 {
+				handleError(fmt.Errorf("dropped %d entries, async queue full", n))
+			}
+			item.flush <- a.w.Flush()
+			continue
+		}
+		var err error
+		if item.entry != nil {
+			_, err = a.w.(iface.EntryWriter).WriteEntry(item.entry, item.buf)
+		} else {
+			_, err = a.w.Write(item.buf)
+		}
+		if err != nil {
+			handleError(err)
+		}
+	}
+}
+
+// SetAsync 开启异步写日志, size 为队列长度
+// 需要在打日志之前调用, 进程退出前调用 Sync 保证日志落盘
+func SetAsync(size int, policy AsyncPolicy) {
+	log.SetAsync(size, policy)
+}
+
+func (l *Logger) SetAsync(size int, policy AsyncPolicy) {
+	if _, ok := l.logWriter.(*asyncWriter); ok {
+		return
+	}
+	l.logWriter = newAsyncWriter(l.logWriter, size, policy)
+}
+
+// Sync 把缓冲中的日志全部写入, 同 GetLogger().Flush()
+func Sync() error {
+	return log.Flush()
+}
+
+// Dropped 异步丢弃策略下, 上次 Sync 之后丢弃的条数
+func Dropped() uint64 {
+	return log.Dropped()
+}
+
+func (l *Logger) Dropped() uint64 {
+	if a, ok := l.logWriter.(*asyncWriter); ok {
+		return atomic.LoadUint64(&a.dropped)
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package log
+
+import (
+	"errors"
+	"github.com/oldbai555/lbtool/utils"
+	"io"
+	"testing"
+)
+
+func newDiscardLogger(opts ...Option) *Logger {
+	return New(append([]Option{WithWriter(NewStdWriter(io.Discard))}, opts...)...)
+}
+
+func TestInfofZeroAlloc(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items randomly under -race")
+	}
+	l := newDiscardLogger()
+	allocs := testing.AllocsPerRun(100, func() {
+		l.Infof("user login")
+	})
+	if allocs != 0 {
+		t.Errorf("Infof allocs %v, want 0", allocs)
+	}
+	l.SetFormat(FormatJSON)
+	allocs = testing.AllocsPerRun(100, func() {
+		l.Infof("user login")
+	})
+	if allocs != 0 {
+		t.Errorf("json Infof allocs %v, want 0", allocs)
+	}
+}
+
+func BenchmarkInfof(b *testing.B) {
+	l := newDiscardLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infof("user login")
+	}
+}
+
+func BenchmarkInfofArgs(b *testing.B) {
+	l := newDiscardLogger()
+	err := errors.New("timeout")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infof("user %s login err:%v", "lb", err)
+	}
+}
+
+func BenchmarkInfofParallel(b *testing.B) {
+	l := newDiscardLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			l.Infof("user login")
+		}
+	})
+}
+
+func BenchmarkInfofJSON(b *testing.B) {
+	l := newDiscardLogger(WithFormat(FormatJSON))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infof("user login")
+	}
+}
+
+func BenchmarkInfow(b *testing.B) {
+	l := newDiscardLogger()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infow("user login", "uid", 1, "name", "lb")
+	}
+}
+
+func BenchmarkDisabled(b *testing.B) {
+	l := newDiscardLogger(WithLevel(utils.LevelError))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Infof("user %d login", 1)
+	}
+}
@@ -0,0 +1,101 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SetCaller 是否记录调用位置, 关闭后可以省掉 runtime.Caller 的开销
+func SetCaller(enable bool) {
+	log.SetCaller(enable)
+}
+
+// SetCallerSkip 额外跳过的层数, 自己封装了一层日志函数时传 1, 调用位置显示为封装函数的调用方
+func SetCallerSkip(skip int) {
+	log.SetCallerSkip(skip)
+}
+
+func (l *Logger) SetCaller(enable bool) {
+	l.noCaller.Store(!enable)
+}
+
+func (l *Logger) SetCallerSkip(skip int) {
+	l.SetSkipCall(DefaultSkipCall + skip)
+}
+
+// callerInfo 调用位置解析一次后按 pc 缓存, 调用点的数量是有限的
+type callerInfo struct {
+	pkg    string
+	file   string
+	line   int
+	fn     string
+	caller string
+}
+
+var (
+	// callerCache 写时复制, 只在新的调用点第一次打日志时写入
+	callerCache   atomic.Pointer[map[uintptr]*callerInfo]
+	callerCacheMu sync.Mutex
+)
+
+// lookupCaller skip 的含义同 runtime.Caller, 从调用 lookupCaller 的函数算起
+func lookupCaller(skip int) *callerInfo {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+2, pcs[:]) == 0 {
+		return nil
+	}
+	return callerForPC(pcs[0])
+}
+
+// callerForPC pc 为 runtime.Callers 返回的值, slog.Record.PC 也是这样取的
+func callerForPC(pc uintptr) *callerInfo {
+	if m := callerCache.Load(); m != nil {
+		if c, ok := (*m)[pc]; ok {
+			return c
+		}
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	// 拿到调用方法
+	pkg, fn := getPackageName(frame.Function)
+	c := &callerInfo{
+		pkg:    pkg,
+		file:   frame.File,
+		line:   frame.Line,
+		fn:     fn,
+		caller: callerString(pkg, frame.File, frame.Line, fn),
+	}
+
+	callerCacheMu.Lock()
+	defer callerCacheMu.Unlock()
+	m := map[uintptr]*callerInfo{}
+	if old := callerCache.Load(); old != nil {
+		for k, v := range *old {
+			m[k] = v
+		}
+	}
+	m[pc] = c
+	callerCache.Store(&m)
+	return c
+}
+
+// fillCaller 需要直接在 write 中调用, 保证层数一致
+func (l *Logger) fillCaller(e *Entry) {
+	if l.noCaller.Load() {
+		return
+	}
+	c := lookupCaller(l.getSkipCall())
+	if c == nil {
+		return
+	}
+	e.File = c.file
+	e.Line = c.line
+	e.Func = c.fn
+	e.Caller = c.caller
+}
+
+func callerString(pkgPath, file string, line int, fn string) string {
+	return fmt.Sprintf("%s:%d:%s", path.Join(pkgPath, path.Base(file)), line, fn)
+}
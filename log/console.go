@@ -0,0 +1,55 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"path"
+	"strconv"
+)
+
+const consoleCallerWidth = 24
+
+// ConsoleEncoder 本地开发用的格式: 短时间, 带颜色的等级, 对齐的调用位置
+//
+//	15:04:05.000 INFO  <hint> log_test.go:12          msg k=v
+func ConsoleEncoder(e *Entry) string {
+	var b bytes.Buffer
+	b.WriteString(e.Time.Format("15:04:05.000"))
+	b.WriteString(" ")
+
+	levelStr := utils.LevelToStrMap[e.Level]
+	if color, err := utils.GetColorStdout(utils.LevelToStdoutColorMap[e.Level]); err == nil {
+		b.WriteString(color)
+		b.WriteString(fmt.Sprintf("%-5s", levelStr))
+		b.WriteString(utils.ColorEnd)
+	} else {
+		b.WriteString(fmt.Sprintf("%-5s", levelStr))
+	}
+	b.WriteString(" ")
+
+	if e.Hint != "" {
+		b.WriteString("<")
+		b.WriteString(e.Hint)
+		b.WriteString("> ")
+	}
+	if e.TraceId != "" {
+		b.WriteString("[")
+		b.WriteString(e.TraceId)
+		b.WriteString("] ")
+	}
+
+	caller := e.Caller
+	if e.File != "" {
+		caller = path.Base(e.File) + ":" + strconv.Itoa(e.Line)
+	}
+	b.WriteString(fmt.Sprintf("%-*s ", consoleCallerWidth, caller))
+
+	b.WriteString(e.Msg)
+	b.Write(appendTextFields(nil, e.Fields))
+	b.WriteString("\n")
+	if e.Stack != "" {
+		b.WriteString(e.Stack)
+	}
+	return b.String()
+}
@@ -0,0 +1,125 @@
+package log
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"sync"
+	"time"
+)
+
+// SetDedup 窗口内连续相同的日志只输出第一条, 窗口结束时补一条 "(repeated N times)", window 为 0 时关闭
+// 用于健康检查、重试循环这类刷屏的日志
+func SetDedup(window time.Duration) {
+	log.SetDedup(window)
+}
+
+func WithDedup(window time.Duration) Option {
+	return func(l *Logger) {
+		l.SetDedup(window)
+	}
+}
+
+func (l *Logger) SetDedup(window time.Duration) {
+	old := l.dedup.Swap(nil)
+	if old != nil {
+		old.flush(l)
+	}
+	if window > 0 {
+		l.dedup.Store(&deduper{window: window})
+	}
+}
+
+type dedupKey struct {
+	level utils.Level
+	msg   string
+}
+
+// deduper 只和上一条比较, 等级和内容都相同才算重复
+type deduper struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	last    Entry
+	key     dedupKey
+	start   time.Time
+	count   int
+	timer   *time.Timer
+	version int
+}
+
+// allow 返回 false 时这条日志被合并, 换了内容时先补上之前的重复次数
+func (d *deduper) allow(l *Logger, e *Entry) bool {
+	key := dedupKey{level: e.Level, msg: e.Msg}
+	d.mu.Lock()
+	if key == d.key && e.Time.Sub(d.start) < d.window {
+		d.count++
+		if d.timer == nil {
+			version := d.version
+			d.timer = time.AfterFunc(d.start.Add(d.window).Sub(e.Time), func() {
+				d.expire(l, version)
+			})
+		}
+		d.mu.Unlock()
+		return false
+	}
+	summary := d.takeSummary()
+	d.key = key
+	d.last = *e
+	d.start = e.Time
+	d.mu.Unlock()
+
+	if summary != nil {
+		d.output(l, summary)
+	}
+	return true
+}
+
+// expire 窗口结束, 之后相同的日志重新开始计数
+func (d *deduper) expire(l *Logger, version int) {
+	d.mu.Lock()
+	if version != d.version {
+		d.mu.Unlock()
+		return
+	}
+	summary := d.takeSummary()
+	d.key = dedupKey{}
+	d.mu.Unlock()
+
+	if summary != nil {
+		d.output(l, summary)
+	}
+}
+
+// flush 刷盘或者关闭时补上还没输出的重复次数
+func (d *deduper) flush(l *Logger) {
+	d.mu.Lock()
+	summary := d.takeSummary()
+	d.mu.Unlock()
+
+	if summary != nil {
+		d.output(l, summary)
+	}
+}
+
+// takeSummary 需要持有锁
+func (d *deduper) takeSummary() *Entry {
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+	d.version++
+	if d.count == 0 {
+		return nil
+	}
+	e := d.last
+	e.Time = time.Now()
+	e.Msg = fmt.Sprintf("%s (repeated %d times)", e.Msg, d.count)
+	d.count = 0
+	return &e
+}
+
+func (d *deduper) output(l *Logger, e *Entry) {
+	if err := l.output(e); err != nil {
+		handleError(err)
+	}
+}
@@ -0,0 +1,112 @@
+package log
+
+import (
+	"encoding/json"
+	"github.com/oldbai555/lbtool/log/iface"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Entry 一条日志的内容
+type Entry = iface.Entry
+
+const jsonTimeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// appendJSON 一行一个 json 对象, 方便 ELK / Loki 直接采集
+// 固定字段直接拼接, 只有 fields 走 json.Marshal
+func appendJSON(b []byte, e *Entry) ([]byte, error) {
+	levelStr, err := transferLevelToStr(e.Level)
+	if err != nil {
+		return b, err
+	}
+	b = append(b, `{"time":"`...)
+	b = e.Time.AppendFormat(b, jsonTimeLayout)
+	b = append(b, `","level":`...)
+	b = appendJSONString(b, levelStr)
+	b = append(b, `,"module":`...)
+	b = appendJSONString(b, e.Module)
+	b = append(b, `,"pid":`...)
+	b = strconv.AppendInt(b, int64(e.Pid), 10)
+	b = append(b, `,"gid":`...)
+	b = strconv.AppendInt(b, e.Gid, 10)
+	if e.Hint != "" {
+		b = append(b, `,"hint":`...)
+		b = appendJSONString(b, e.Hint)
+	}
+	if e.TraceId != "" {
+		b = append(b, `,"trace_id":`...)
+		b = appendJSONString(b, e.TraceId)
+	}
+	if e.SpanId != "" {
+		b = append(b, `,"span_id":`...)
+		b = appendJSONString(b, e.SpanId)
+	}
+	b = append(b, `,"caller":`...)
+	b = appendJSONString(b, e.Caller)
+	b = append(b, `,"msg":`...)
+	b = appendJSONString(b, e.Msg)
+	if len(e.Fields) > 0 {
+		fields, err := json.Marshal(e.Fields)
+		if err != nil {
+			return b, err
+		}
+		b = append(b, `,"fields":`...)
+		b = append(b, fields...)
+	}
+	if e.Stack != "" {
+		b = append(b, `,"stack":`...)
+		b = appendJSONString(b, e.Stack)
+	}
+	return append(b, "}\n"...), nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString 转义规则同 encoding/json, 非法的 utf8 替换为 �
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
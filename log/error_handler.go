@@ -0,0 +1,33 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+var errorHandler atomic.Value
+
+func defaultErrorHandler(err error) {
+	_, _ = fmt.Fprintf(os.Stderr, "log: %v\n", err)
+}
+
+// SetErrorHandler 写日志失败时的处理, 默认输出到标准错误, 打日志本身不会 panic
+// handler 中不要再调用本包打日志, 否则可能递归
+func SetErrorHandler(handler func(err error)) {
+	if handler == nil {
+		handler = defaultErrorHandler
+	}
+	errorHandler.Store(handler)
+}
+
+func handleError(err error) {
+	if err == nil {
+		return
+	}
+	handler, _ := errorHandler.Load().(func(err error))
+	if handler == nil {
+		handler = defaultErrorHandler
+	}
+	handler(err)
+}
@@ -0,0 +1,113 @@
+package log
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+)
+
+// Fields 结构化字段, 文本格式追加为 k=v, json 格式放在 fields 中
+type Fields = map[string]interface{}
+
+// FieldLogger 带字段的日志, 字段不会拼进格式化字符串
+type FieldLogger struct {
+	l      *Logger
+	fields Fields
+}
+
+// WithFields log.WithFields(log.Fields{"uid": 1}).Infof("login")
+func WithFields(fields Fields) *FieldLogger {
+	return log.WithFields(fields)
+}
+
+func WithField(key string, val interface{}) *FieldLogger {
+	return WithFields(Fields{key: val})
+}
+
+// WithFields 返回合并后的副本, 不修改原来的字段
+func (f *FieldLogger) WithFields(fields Fields) *FieldLogger {
+	merged := make(Fields, len(f.fields)+len(fields))
+	for k, v := range f.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &FieldLogger{l: f.l, fields: merged}
+}
+
+func (f *FieldLogger) WithField(key string, val interface{}) *FieldLogger {
+	return f.WithFields(Fields{key: val})
+}
+
+func (f *FieldLogger) Debugf(format string, args ...interface{}) {
+	if err := f.l.write(nil, f.fields, utils.LevelDebug, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (f *FieldLogger) Infof(format string, args ...interface{}) {
+	if err := f.l.write(nil, f.fields, utils.LevelInfo, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (f *FieldLogger) Warnf(format string, args ...interface{}) {
+	if err := f.l.write(nil, f.fields, utils.LevelWarn, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (f *FieldLogger) Errorf(format string, args ...interface{}) {
+	if err := f.l.write(nil, f.fields, utils.LevelError, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+// kvToFields 键值对转换为字段, 落单的值放在 !BADKEY 下
+func kvToFields(kv []interface{}) Fields {
+	if len(kv) == 0 {
+		return nil
+	}
+	fields := make(Fields, len(kv)/2+1)
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 >= len(kv) {
+			fields["!BADKEY"] = kv[i]
+			break
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		fields[key] = kv[i+1]
+	}
+	return fields
+}
+
+// Debugw log.Infow("login", "uid", 1, "ip", ip)
+func Debugw(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := log.write(nil, kvToFields(kv), utils.LevelDebug, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func Infow(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := log.write(nil, kvToFields(kv), utils.LevelInfo, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func Warnw(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := log.write(nil, kvToFields(kv), utils.LevelWarn, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func Errorw(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := log.write(nil, kvToFields(kv), utils.LevelError, format, args); err != nil {
+		handleError(err)
+	}
+}
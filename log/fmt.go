@@ -1,17 +1,14 @@
 package log
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"github.com/oldbai555/lbtool/log/iface"
 	"github.com/oldbai555/lbtool/utils"
-	"github.com/petermattis/goid"
-	"os"
-	"path"
-	"runtime"
+	"sort"
+	"strconv"
 	"strings"
-	"time"
+	"sync/atomic"
 )
 
 const (
@@ -22,13 +19,12 @@ var _ iface.Formatter = (*simpleFormatter)(nil)
 
 // SimpleFormatter 格式化日志
 type simpleFormatter struct {
-	skipCall   int          // 用于调用层数
-	formatType utils.Format // 格式化类型
+	formatType utils.Format           // 格式化类型
+	layout     atomic.Pointer[layout] // 文本格式的模板, 为空时使用默认格式
 }
 
 func newSimpleFormatter() *simpleFormatter {
 	return &simpleFormatter{
-		skipCall:   DefaultSkipCall,
 		formatType: utils.FormatText,
 	}
 }
@@ -45,66 +41,113 @@ func getPackageName(f string) (filePath string, fileFunc string) {
 	return
 }
 
-func (s *simpleFormatter) Sprintf(level utils.Level, color utils.Color, buf string) (string, error) {
-	// Go获取当前协程信息 第三方库
-	var b bytes.Buffer
-
-	routineId := goid.Get()
-	pid := os.Getpid()
-	// 进程、协程
-	b.WriteString(fmt.Sprintf("%s(%d,%d) ", moduleName, pid, routineId))
-
-	// req
-	b.WriteString(fmt.Sprintf("<%s> ", getLogHint()))
-
-	// 字体颜色
-	colorStdout, err := utils.GetColorStdout(color)
+func (s *simpleFormatter) Format(e *Entry) (string, error) {
+	b, err := s.AppendFormat(nil, e)
 	if err != nil {
 		return "", err
 	}
-	b.WriteString(colorStdout)
+	return string(b), nil
+}
 
-	// 时间
-	b.WriteString(time.Now().Format("2006-01-02T15:04:05"))
-	b.WriteString(fmt.Sprintf("%04d", time.Now().Nanosecond()/100000))
+func (s *simpleFormatter) AppendFormat(dst []byte, e *Entry) ([]byte, error) {
+	switch s.formatType {
+	case utils.FormatText:
+		if lay := s.layout.Load(); lay != nil {
+			return lay.appendTo(dst, e), nil
+		}
+		return appendText(dst, e)
+	case utils.FormatJSON:
+		return appendJSON(dst, e)
+	default:
+		return dst, errors.New("not support log format")
+	}
+}
 
+func appendText(b []byte, e *Entry) ([]byte, error) {
+	// 字体颜色
+	colorStdout, err := utils.GetColorStdout(utils.LevelToStdoutColorMap[e.Level])
+	if err != nil {
+		return b, err
+	}
 	// 日志等级
-	levelStr, err := transferLevelToStr(level)
+	levelStr, err := transferLevelToStr(e.Level)
 	if err != nil {
-		return "", err
+		return b, err
 	}
-	b.WriteString(" ")
-	b.WriteString(levelStr)
-	b.WriteString(" ")
-
-	// skip是层数，调用Caller函数外层的函数。1代表上次，2代表上上层，一般我们需要定位的也就是行数line跟file文件名
-	pc, callFile, callLine, ok := runtime.Caller(s.skipCall)
-	var callFuncName string
-	if ok {
-		// 拿到调用方法
-		callFuncName = runtime.FuncForPC(pc).Name()
+
+	// 进程、协程
+	b = append(b, e.Module...)
+	b = append(b, '(')
+	b = strconv.AppendInt(b, int64(e.Pid), 10)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, e.Gid, 10)
+	b = append(b, ") "...)
+
+	// req
+	b = append(b, '<')
+	b = append(b, e.Hint...)
+	b = append(b, "> "...)
+
+	// 同一个请求的日志可以按 trace id 串起来
+	if e.TraceId != "" {
+		b = append(b, '[')
+		b = append(b, e.TraceId...)
+		b = append(b, "] "...)
 	}
-	filePath, fileFunc := getPackageName(callFuncName)
-	b.WriteString(fmt.Sprintf("%s:%d:%s ", path.Join(filePath, path.Base(callFile)), callLine, fileFunc))
+
+	b = append(b, colorStdout...)
+
+	// 时间
+	b = e.Time.AppendFormat(b, "2006-01-02T15:04:05")
+	b = appendPadInt(b, e.Time.Nanosecond()/100000, 4)
+
+	b = append(b, ' ')
+	b = append(b, levelStr...)
+	b = append(b, ' ')
+
+	b = append(b, e.Caller...)
+	b = append(b, ' ')
 
 	// 颜色结尾
-	b.WriteString(utils.ColorEnd)
-	b.WriteString(" ")
+	b = append(b, utils.ColorEnd...)
+	b = append(b, ' ')
 
 	// 文本内容
-	b.WriteString(buf)
-	b.WriteString("\n")
+	b = append(b, e.Msg...)
+	b = appendTextFields(b, e.Fields)
+	b = append(b, '\n')
+	b = append(b, e.Stack...)
+	return b, nil
+}
 
-	switch s.formatType {
-	case utils.FormatText:
-		return b.String(), nil
-	default:
-		return "", errors.New("not support log format")
+// appendPadInt 左边补 0 到 width 位
+func appendPadInt(b []byte, n int, width int) []byte {
+	var tmp [20]byte
+	s := strconv.AppendInt(tmp[:0], int64(n), 10)
+	for i := len(s); i < width; i++ {
+		b = append(b, '0')
+	}
+	return append(b, s...)
+}
+
+// appendTextFields 按 key 排序追加 k=v
+func appendTextFields(b []byte, fields map[string]interface{}) []byte {
+	if len(fields) == 0 {
+		return b
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b = fmt.Appendf(b, " %s=%v", k, fields[k])
 	}
+	return b
 }
 
-func (s *simpleFormatter) SetSkipCall(skipCall int) {
-	s.skipCall = skipCall
+func (s *simpleFormatter) SetFormat(format utils.Format) {
+	s.formatType = format
 }
 
 func transferLevelToStr(level utils.Level) (string, error) {
@@ -1,6 +1,9 @@
 package log
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
 	"testing"
 	"time"
 )
@@ -15,3 +18,41 @@ func TestSimpleTraceFormatter_Sprintf(t *testing.T) {
 	}
 	time.Sleep(15 * time.Second)
 }
+
+func TestSimpleFormatter_JSON(t *testing.T) {
+	f := newSimpleFormatter()
+	f.SetFormat(FormatJSON)
+	out, err := f.Format(&Entry{
+		Time:   time.Now(),
+		Level:  utils.LevelInfo,
+		Caller: "log/fmt_test.go:30:TestSimpleFormatter_JSON",
+		Msg:    "hello \"lb\"",
+		Fields: map[string]interface{}{"uid": 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal([]byte(out), &m); err != nil {
+		t.Fatalf("invalid json %s, err:%v", out, err)
+	}
+	if m["level"] != "INFO" || m["msg"] != "hello \"lb\"" || m["fields"].(map[string]interface{})["uid"] != float64(1) {
+		t.Errorf("got %s", out)
+	}
+}
+
+func TestConsoleEncoder(t *testing.T) {
+	out := ConsoleEncoder(&Entry{
+		Time:   time.Date(2024, 1, 2, 15, 4, 5, 6e6, time.Local),
+		Level:  utils.LevelWarn,
+		Hint:   "req-1",
+		File:   "/src/lb/log/fmt_test.go",
+		Line:   12,
+		Msg:    "hello",
+		Fields: map[string]interface{}{"uid": 1},
+	})
+	want := "15:04:05.006 " + "\x1b[93m" + "WARN " + utils.ColorEnd + " <req-1> " + fmt.Sprintf("%-24s", "fmt_test.go:12") + " hello uid=1\n"
+	if out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
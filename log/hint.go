@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// RunWithHint 在当前协程设置 hint 执行 fn, 返回后恢复原来的 hint, 不会残留
+func RunWithHint(hint string, fn func()) {
+	log.RunWithHint(hint, fn)
+}
+
+// ClearLogHint 清除当前协程的 hint, 同 SetLogHint("")
+func ClearLogHint() {
+	log.ClearLogHint()
+}
+
+// SetDefaultHint 所有协程共用的 hint, ctx 和当前协程都没有 hint 时使用, 适合放服务名、实例名
+// SetLogHint 只对调用它的协程生效, 不要在初始化时用它设置全局的 hint
+func SetDefaultHint(hint string) {
+	log.SetDefaultHint(hint)
+}
+
+// StartHintSweeper 定期清理已经退出的协程残留的 hint, 返回停止函数
+func StartHintSweeper(interval time.Duration) (stop func()) {
+	return log.StartHintSweeper(interval)
+}
+
+func (l *Logger) RunWithHint(hint string, fn func()) {
+	prev := l.hints.get()
+	l.hints.set(hint)
+	defer l.hints.set(prev)
+	fn()
+}
+
+func (l *Logger) SetDefaultHint(hint string) {
+	l.defaultHint.Store(hint)
+}
+
+func (l *Logger) ClearLogHint() {
+	l.hints.set("")
+}
+
+func (l *Logger) StartHintSweeper(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				l.hints.sweep(liveGoroutines())
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+func (h *hintStore) sweep(live map[int64]struct{}) int {
+	var n int
+	h.m.Range(func(key, value interface{}) bool {
+		if _, ok := live[key.(int64)]; !ok {
+			h.m.Delete(key)
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+func (h *hintStore) size() int {
+	var n int
+	h.m.Range(func(key, value interface{}) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// liveGoroutines 从所有协程的调用栈中解析出协程 id, 开销较大, 只在定期清理时使用
+func liveGoroutines() map[int64]struct{} {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	prefix := []byte("goroutine ")
+	live := make(map[int64]struct{})
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		if !bytes.HasPrefix(line, prefix) {
+			continue
+		}
+		line = line[len(prefix):]
+		if i := bytes.IndexByte(line, ' '); i > 0 {
+			if id, err := strconv.ParseInt(string(line[:i]), 10, 64); err == nil {
+				live[id] = struct{}{}
+			}
+		}
+	}
+	return live
+}
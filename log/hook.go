@@ -0,0 +1,52 @@
+package log
+
+// Hook 日志写入前调用, 可以补充字段、改写内容, 返回 nil 时丢弃这条日志
+// 写完之后 entry 会被复用, hook 不能保留它的指针
+type Hook func(e *Entry) *Entry
+
+// AddHook 按添加顺序执行
+func AddHook(hook Hook) {
+	log.AddHook(hook)
+}
+
+// ResetHooks 移除所有 hook
+func ResetHooks() {
+	log.ResetHooks()
+}
+
+// hooks 写时复制, 写日志时不加锁
+func (l *Logger) AddHook(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var hooks []Hook
+	if old := l.hooks.Load(); old != nil {
+		hooks = append(hooks, *old...)
+	}
+	hooks = append(hooks, hook)
+	l.hooks.Store(&hooks)
+}
+
+func (l *Logger) ResetHooks() {
+	l.hooks.Store(nil)
+}
+
+func (l *Logger) runHooks(e *Entry) *Entry {
+	hooks := l.hooks.Load()
+	if hooks == nil {
+		return e
+	}
+	if len(e.Fields) > 0 {
+		// 字段可能来自共享的 FieldLogger, 复制后 hook 可以直接修改
+		fields := make(Fields, len(e.Fields))
+		for k, v := range e.Fields {
+			fields[k] = v
+		}
+		e.Fields = fields
+	}
+	for _, hook := range *hooks {
+		if e = hook(e); e == nil {
+			return nil
+		}
+	}
+	return e
+}
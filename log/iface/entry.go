@@ -0,0 +1,25 @@
+package iface
+
+import (
+	"github.com/oldbai555/lbtool/utils"
+	"time"
+)
+
+// Entry 一条日志, 经过 hook 处理后由 Formatter 编码
+type Entry struct {
+	Time    time.Time
+	Level   utils.Level
+	Module  string
+	Pid     int
+	Gid     int64
+	Hint    string
+	TraceId string // ctx 中带有 otel span 时填充, 用于和链路关联
+	SpanId  string
+	Caller  string // path/file.go:line:func
+	File    string
+	Line    int
+	Func    string
+	Msg     string
+	Fields  map[string]interface{}
+	Stack   string
+}
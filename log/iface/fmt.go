@@ -5,6 +5,11 @@ import (
 )
 
 type Formatter interface {
-	Sprintf(level utils.Level, color utils.Color, buf string) (string, error)
-	SetSkipCall(skipCall int)
+	Format(e *Entry) (string, error)
+	SetFormat(format utils.Format)
+}
+
+// AppendFormatter 可选实现, 编码追加到 dst, 避免每条日志分配字符串
+type AppendFormatter interface {
+	AppendFormat(dst []byte, e *Entry) ([]byte, error)
 }
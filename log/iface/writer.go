@@ -1,6 +1,13 @@
 package iface
 
+// LogWriter 每次 Write 的 p 都是单独的一份, 可以保留
 type LogWriter interface {
 	Write(p []byte) (n int, err error)
 	Flush() error
 }
+
+// EntryWriter 可选实现, 需要按自己的格式输出时使用原始日志内容, p 为 Formatter 编码后的结果
+// 返回后 e 和 p 都会被复用, 不能保留指针, 需要异步写入时自行复制
+type EntryWriter interface {
+	WriteEntry(e *Entry, p []byte) (n int, err error)
+}
@@ -0,0 +1,200 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+var strToLevelMap = map[string]utils.Level{
+	"debug": utils.LevelDebug,
+	"dbg":   utils.LevelDebug,
+	"info":  utils.LevelInfo,
+	"warn":  utils.LevelWarn,
+	"error": utils.LevelError,
+	"err":   utils.LevelError,
+	"panic": utils.LevelPanic,
+	"fatal": utils.LevelFatal,
+}
+
+// ParseLevel 解析 debug / info / warn / error / panic / fatal
+func ParseLevel(s string) (utils.Level, error) {
+	level, ok := strToLevelMap[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("unknow level %s", s)
+	}
+	return level, nil
+}
+
+// SetLevel 运行时修改全局日志等级, 同 SetLogLevel
+func SetLevel(level utils.Level) {
+	SetLogLevel(level)
+}
+
+func GetLevel() utils.Level {
+	return log.GetLevel()
+}
+
+func (l *Logger) SetLevel(level utils.Level) {
+	atomic.StoreInt32(&l.logLevel, int32(level))
+}
+
+func (l *Logger) GetLevel() utils.Level {
+	return utils.Level(atomic.LoadInt32(&l.logLevel))
+}
+
+// SetModuleLevel 单独设置某个包的日志等级, module 为包路径或者包名, 例如 orm
+func SetModuleLevel(module string, level utils.Level) {
+	log.SetModuleLevel(module, level)
+}
+
+// DelModuleLevel 删除包的日志等级, 恢复使用全局等级
+func DelModuleLevel(module string) {
+	log.DelModuleLevel(module)
+}
+
+// ModuleLevels 当前设置的包日志等级, 返回副本
+func ModuleLevels() map[string]utils.Level {
+	return log.ModuleLevels()
+}
+
+func (l *Logger) SetModuleLevel(module string, level utils.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.ModuleLevels()
+	m[module] = level
+	l.levels.Store(&m)
+}
+
+func (l *Logger) DelModuleLevel(module string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	m := l.ModuleLevels()
+	delete(m, module)
+	l.levels.Store(&m)
+}
+
+func (l *Logger) ModuleLevels() map[string]utils.Level {
+	m := make(map[string]utils.Level)
+	if old := l.levels.Load(); old != nil {
+		for k, v := range *old {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// enabled 没有设置包等级时只比较全局等级, 否则按调用方所在的包查找
+func (l *Logger) enabled(level utils.Level) bool {
+	if m := l.levels.Load(); m != nil && len(*m) > 0 {
+		if lv, ok := moduleLevel(*m, lookupCaller(l.getSkipCall())); ok {
+			return level >= lv
+		}
+	}
+	return level >= utils.Level(atomic.LoadInt32(&l.logLevel))
+}
+
+// enabledAt 调用位置已知时使用, 例如 slog.Record.PC
+func (l *Logger) enabledAt(level utils.Level, pc uintptr) bool {
+	if m := l.levels.Load(); m != nil && len(*m) > 0 && pc != 0 {
+		if lv, ok := moduleLevel(*m, callerForPC(pc)); ok {
+			return level >= lv
+		}
+	}
+	return level >= utils.Level(atomic.LoadInt32(&l.logLevel))
+}
+
+// minLevel 全局等级和包等级中最低的, 调用位置未知时只能按它判断
+func (l *Logger) minLevel() utils.Level {
+	min := utils.Level(atomic.LoadInt32(&l.logLevel))
+	if m := l.levels.Load(); m != nil {
+		for _, lv := range *m {
+			if lv < min {
+				min = lv
+			}
+		}
+	}
+	return min
+}
+
+// moduleLevel 按调用方所在的包查找, 包路径优先
+func moduleLevel(m map[string]utils.Level, c *callerInfo) (utils.Level, bool) {
+	if c == nil {
+		return 0, false
+	}
+	if lv, ok := m[c.pkg]; ok {
+		return lv, true
+	}
+	lv, ok := m[path.Base(c.pkg)]
+	return lv, ok
+}
+
+type levelResp struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// LevelHandler 查看和修改日志等级, 可以挂到 admin 端口上
+// GET 返回当前等级; PUT / POST 参数 level=debug[&module=orm], 带 module 且 level 为空时删除该包的等级
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			module, levelStr := r.FormValue("module"), r.FormValue("level")
+			if module != "" && levelStr == "" {
+				DelModuleLevel(module)
+				break
+			}
+			level, err := ParseLevel(levelStr)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if module != "" {
+				SetModuleLevel(module, level)
+			} else {
+				SetLevel(level)
+			}
+			Infof("log level changed, module:%s level:%s", module, levelStr)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		resp := levelResp{Level: utils.LevelToStrMap[GetLevel()], Modules: map[string]string{}}
+		for k, v := range ModuleLevels() {
+			resp.Modules[k] = utils.LevelToStrMap[v]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(&resp)
+	})
+}
+
+// WatchLevelSignal 收到信号时在 DEBUG 和原来的等级之间切换, 默认监听 SIGHUP
+func WatchLevelSignal(sigs ...os.Signal) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	go func() {
+		prev := GetLevel()
+		for range ch {
+			if cur := GetLevel(); cur != utils.LevelDebug {
+				prev = cur
+				SetLevel(utils.LevelDebug)
+			} else {
+				SetLevel(prev)
+			}
+			Warnf("log level switched to %s by signal", utils.LevelToStrMap[GetLevel()])
+		}
+	}()
+}
@@ -1,19 +1,22 @@
 package log
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/oldbai555/lbtool/log/iface"
 	"github.com/oldbai555/lbtool/utils"
 	"github.com/petermattis/goid"
 	"io"
+	"os"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 var (
-	log        *logger
-	logCtx     = map[int64]string{}
-	logCtxMu   sync.RWMutex
+	log        *Logger
 	moduleName = "UNKNOWN"
 )
 
@@ -25,132 +28,279 @@ func SetLogLevel(level utils.Level) {
 	if log == nil {
 		log = newLogger()
 	}
-	log.logLevel = level
+	log.SetLevel(level)
+}
+
+const (
+	FormatText = utils.FormatText
+	FormatJSON = utils.FormatJSON
+)
+
+// SetFormat 设置输出格式, FormatJSON 时每行一个 json 对象
+func SetFormat(format utils.Format) {
+	log.SetFormat(format)
 }
 
 func SetLogHint(hint string) {
-	i := goid.Get()
-	logCtxMu.Lock()
-	if hint == "" {
-		delete(logCtx, i)
-	} else {
-		logCtx[i] = hint
+	log.SetLogHint(hint)
+}
+
+type hintKey struct{}
+
+// NewCtxWithHint hint 跟随 ctx 传递, 跨协程也不会丢失
+func NewCtxWithHint(ctx context.Context, hint string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
 	}
-	logCtxMu.Unlock()
+	return context.WithValue(ctx, hintKey{}, hint)
 }
 
-func getLogHint() string {
-	i := goid.Get()
-	logCtxMu.RLock()
-	v := logCtx[i]
-	logCtxMu.RUnlock()
-	return v
+// GetHintFromCtx 获取 ctx 中的 hint
+func GetHintFromCtx(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	hint, _ := ctx.Value(hintKey{}).(string)
+	return hint
 }
 
 func SetModuleName(name string) {
 	moduleName = name
+	log.SetModuleName(name)
 }
 
 func GetWriter() io.Writer {
 	return log.logWriter
 }
 
-func GetLogger() *logger {
+func GetLogger() *Logger {
 	return log
 }
 
 func Debugf(format string, args ...interface{}) {
 
-	if err := log.write(utils.LevelDebug, append([]interface{}{format}, args...)...); err != nil {
-		panic(any(err))
+	if err := log.write(nil, nil, utils.LevelDebug, format, args); err != nil {
+		handleError(err)
 	}
 }
 
 func Infof(format string, args ...interface{}) {
-	if err := log.write(utils.LevelInfo, append([]interface{}{format}, args...)...); err != nil {
-		panic(any(err))
+	if err := log.write(nil, nil, utils.LevelInfo, format, args); err != nil {
+		handleError(err)
 	}
 }
 
 func Warnf(format string, args ...interface{}) {
-	if err := log.write(utils.LevelWarn, append([]interface{}{format}, args...)...); err != nil {
-		panic(any(err))
+	if err := log.write(nil, nil, utils.LevelWarn, format, args); err != nil {
+		handleError(err)
 	}
 
 }
 
 func Errorf(format string, args ...interface{}) {
-	if err := log.write(utils.LevelError, append([]interface{}{format}, args...)...); err != nil {
-		panic(any(err))
+	if err := log.write(nil, nil, utils.LevelError, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+// exitFunc 测试时替换
+var exitFunc = os.Exit
+
+// Panicf 写日志并刷盘后 panic
+func Panicf(format string, args ...interface{}) {
+	// format 和 args 分开传, 采样按 format 计数, 参数中的 error 可以提供调用栈
+	if err := log.write(nil, nil, utils.LevelPanic, format, args); err != nil {
+		handleError(err)
+	}
+	_ = log.Flush()
+	panic(any(fmt.Sprintf(format, args...)))
+}
+
+// Fatalf 写日志并刷盘后以状态码 1 退出
+func Fatalf(format string, args ...interface{}) {
+	if err := log.write(nil, nil, utils.LevelFatal, format, args); err != nil {
+		handleError(err)
+	}
+	_ = log.Flush()
+	exitFunc(1)
+}
+
+func CtxDebugf(ctx context.Context, format string, args ...interface{}) {
+	if err := log.write(ctx, nil, utils.LevelDebug, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func CtxInfof(ctx context.Context, format string, args ...interface{}) {
+	if err := log.write(ctx, nil, utils.LevelInfo, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func CtxWarnf(ctx context.Context, format string, args ...interface{}) {
+	if err := log.write(ctx, nil, utils.LevelWarn, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func CtxErrorf(ctx context.Context, format string, args ...interface{}) {
+	if err := log.write(ctx, nil, utils.LevelError, format, args); err != nil {
+		handleError(err)
 	}
 }
 
 //===================================logger===================================================
 
-// Logger 日志业务
-type logger struct {
-	logLevel  utils.Level
-	logWriter iface.LogWriter
-	fmt       iface.Formatter
-	mu        sync.RWMutex
+// Logger 日志, 包级别的函数使用默认实例
+type Logger struct {
+	logLevel    int32 // utils.Level, 运行时可修改
+	skipCall    atomic.Int32
+	noCaller    atomic.Bool
+	stackOn     atomic.Bool
+	stackLv     atomic.Int32                           // utils.Level
+	module      atomic.Value                           // string
+	levels      atomic.Pointer[map[string]utils.Level] // 包日志等级, 写时复制
+	hints       *hintStore
+	defaultHint atomic.Value // string, 所有协程共用
+	sampler     atomic.Pointer[sampler]
+	dedup       atomic.Pointer[deduper]
+	redactor    atomic.Pointer[Redactor]
+	logWriter   iface.LogWriter
+	sinks       atomic.Pointer[[]*sink]
+	hooks       atomic.Pointer[[]Hook]
+	fmt         iface.Formatter
+	mu          sync.Mutex // 只在修改 sinks / hooks / 包日志等级时使用
 }
 
-func newLogger() *logger {
-	return &logger{
+func newLogger() *Logger {
+	l := &Logger{
+		hints:     newHintStore(),
 		logWriter: newLogWriterImpl(),
 		fmt:       newSimpleFormatter(),
 	}
+	l.SetSkipCall(DefaultSkipCall)
+	l.SetModuleName(moduleName)
+	return l
+}
+
+func (l *Logger) SetSkipCall(skipCall int) {
+	l.skipCall.Store(int32(skipCall))
 }
 
-func (l *logger) SetSkipCall(skipCall int) {
-	l.fmt.SetSkipCall(skipCall)
+func (l *Logger) getSkipCall() int {
+	return int(l.skipCall.Load())
 }
 
-func (l *logger) write(level utils.Level, args ...interface{}) error {
-	if l.logLevel > level {
+// pid 不会变, 不用每次都走系统调用
+var pid = os.Getpid()
+
+// write format 中没有 % 时原样输出, 不经过 fmt.Sprintf
+func (l *Logger) write(ctx context.Context, fields Fields, level utils.Level, format string, args []interface{}) error {
+	if !l.enabled(level) {
+		return nil
+	}
+	if !l.sampled(level, format) {
 		return nil
 	}
 
-	argNum := len(args)
-	if argNum == 0 {
-		return errors.New("args num is 0")
+	msg := format
+	if len(args) > 0 || strings.IndexByte(format, '%') >= 0 {
+		msg = fmt.Sprintf(format, args...)
 	}
 
-	var realArgs []interface{}
-	if argNum > 1 {
-		realArgs = args[1:]
+	e := getEntry()
+	defer putEntry(e)
+	l.fillEntry(e, ctx, level, msg, fields)
+	l.fillCaller(e)
+	if l.stackOn.Load() && level >= utils.Level(l.stackLv.Load()) {
+		e.Stack = l.stack(args)
 	}
+	return l.dedupOutput(e)
+}
 
-	var (
-		format string
-		ok     bool
-	)
-	if format, ok = args[0].(string); !ok {
-		format = fmt.Sprint(format)
+// msgFormat Infow 等传入的是消息而不是 format, 带 % 时需要转义
+func msgFormat(msg string) (string, []interface{}) {
+	if strings.IndexByte(msg, '%') < 0 {
+		return msg, nil
 	}
+	return "%s", []interface{}{msg}
+}
+
+// sampled 按 format 采样, write 和 SlogHandler 共用
+func (l *Logger) sampled(level utils.Level, format string) bool {
+	s := l.sampler.Load()
+	return s == nil || s.check(level, format)
+}
 
-	stdoutColor := utils.LevelToStdoutColorMap[level]
-	logContent, err := l.fmt.Sprintf(level, stdoutColor, fmt.Sprintf(format, realArgs...))
+// dedupOutput 去重后输出, write 和 SlogHandler 共用
+func (l *Logger) dedupOutput(e *Entry) error {
+	if d := l.dedup.Load(); d != nil && !d.allow(l, e) {
+		return nil
+	}
+	return l.output(e)
+}
+
+func (l *Logger) fillEntry(e *Entry, ctx context.Context, level utils.Level, msg string, fields Fields) {
+	e.Time = time.Now()
+	e.Level = level
+	e.Module = l.getModuleName()
+	e.Pid = pid
+	// Go获取当前协程信息 第三方库
+	e.Gid = goid.Get()
+	e.Hint = l.getHint(ctx)
+	e.TraceId, e.SpanId = traceFromCtx(ctx)
+	e.Msg = msg
+	e.Fields = fields
+}
+
+// output 执行 hook, 编码后写入所有输出
+func (l *Logger) output(e *Entry) error {
+	if e = l.runHooks(e); e == nil {
+		return nil
+	}
+	if r := l.redactor.Load(); r != nil {
+		r.redact(e)
+	}
+
+	buf := getBuf()
+	defer putBuf(buf)
+	var err error
+	if af, ok := l.fmt.(iface.AppendFormatter); ok {
+		*buf, err = af.AppendFormat(*buf, e)
+	} else {
+		var s string
+		s, err = l.fmt.Format(e)
+		*buf = append(*buf, s...)
+	}
 	if err != nil {
 		return err
 	}
 
-	if _, err := l.logWriter.Write([]byte(logContent)); err != nil {
+	if _, err := writeTo(l.logWriter, e, *buf); err != nil {
 		return err
 	}
 
-	return nil
+	return l.writeSinks(e, *buf)
 }
 
-func (l *logger) Flush() error {
-	return l.logWriter.Flush()
+func (l *Logger) Flush() error {
+	if d := l.dedup.Load(); d != nil {
+		d.flush(l)
+	}
+	errs := []error{l.logWriter.Flush()}
+	if sinks := l.sinks.Load(); sinks != nil {
+		for _, s := range *sinks {
+			errs = append(errs, s.w.Flush())
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // Printf calls l.Output to print to the logger.
 // Arguments are handled in the manner of fmt.Printf.
-func (l *logger) Printf(format string, v ...any) {
-	if err := log.write(utils.LevelInfo, append([]interface{}{format}, v...)...); err != nil {
-		panic(any(err))
+func (l *Logger) Printf(format string, v ...any) {
+	if err := l.write(nil, nil, utils.LevelInfo, format, v); err != nil {
+		handleError(err)
 	}
 
 }
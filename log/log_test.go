@@ -0,0 +1,727 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memWriter struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (m *memWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buf.Write(p)
+}
+
+func (m *memWriter) Flush() error {
+	return nil
+}
+
+func (m *memWriter) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buf.String()
+}
+
+// useMemWriter 替换全局 logger 的输出, 测试结束后恢复
+func useMemWriter(t *testing.T) *memWriter {
+	w := &memWriter{}
+	old := log
+	log = New(WithWriter(w))
+	t.Cleanup(func() {
+		log = old
+	})
+	return w
+}
+
+func TestCtxHint(t *testing.T) {
+	w := useMemWriter(t)
+	ctx := NewCtxWithHint(context.Background(), "req-1")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		CtxInfof(ctx, "in goroutine")
+	}()
+	<-done
+	if !strings.Contains(w.String(), "<req-1>") {
+		t.Errorf("hint should follow ctx, got %s", w.String())
+	}
+
+	SetLogHint("goroutine-hint")
+	defer SetLogHint("")
+	CtxInfof(context.Background(), "fallback")
+	if !strings.Contains(w.String(), "<goroutine-hint>") {
+		t.Errorf("should fall back to goroutine hint, got %s", w.String())
+	}
+}
+
+type slowWriter struct {
+	memWriter
+	release chan struct{}
+}
+
+func (s *slowWriter) Write(p []byte) (int, error) {
+	<-s.release
+	return s.memWriter.Write(p)
+}
+
+func TestAsync(t *testing.T) {
+	w := useMemWriter(t)
+	SetAsync(8, AsyncBlock)
+	for i := 0; i < 100; i++ {
+		Infof("async %d", i)
+	}
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(w.String(), "async"); n != 100 {
+		t.Errorf("got %d entries, want 100", n)
+	}
+
+	s := &slowWriter{release: make(chan struct{})}
+	log.logWriter = newAsyncWriter(s, 2, AsyncDrop)
+	for i := 0; i < 10; i++ {
+		Infof("drop %d", i)
+	}
+	if Dropped() == 0 {
+		t.Errorf("should drop when queue is full")
+	}
+	close(s.release)
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(s.String(), "drop"); n == 0 || n == 10 {
+		t.Errorf("got %d entries", n)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	w := useMemWriter(t)
+	info, errSink := &memWriter{}, &memWriter{}
+	AddWriter(info, utils.LevelInfo)
+	AddWriter(NewStdWriter(errSink), utils.LevelError)
+
+	Debugf("debug msg")
+	Infof("info msg")
+	Errorf("error msg")
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := strings.Count(w.String(), "msg"); n != 3 {
+		t.Errorf("default writer got %d entries", n)
+	}
+	if strings.Contains(info.String(), "debug msg") || !strings.Contains(info.String(), "info msg") {
+		t.Errorf("info sink got %s", info.String())
+	}
+	if strings.Contains(errSink.String(), "info msg") || !strings.Contains(errSink.String(), "error msg") {
+		t.Errorf("error sink got %s", errSink.String())
+	}
+}
+
+// 运行时修改配置和写日志并发, 配合 -race 检查
+func TestSetterRace(t *testing.T) {
+	l := New(WithWriter(&memWriter{}))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.SetCaller(i%2 == 0)
+			l.EnableStack(utils.LevelError)
+			l.SetModuleName("lb")
+			l.SetSkipCall(DefaultSkipCall)
+			l.SetModuleLevel("log", utils.LevelDebug)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			l.Errorf("race %d", i)
+		}
+	}()
+	wg.Wait()
+}
+
+func TestModuleLevel(t *testing.T) {
+	w := useMemWriter(t)
+	SetLevel(utils.LevelInfo)
+	Debugf("hidden")
+	SetModuleLevel("log", utils.LevelDebug)
+	defer DelModuleLevel("log")
+	Debugf("shown")
+	SetModuleLevel("log", utils.LevelError)
+	Warnf("module warn hidden")
+	if strings.Contains(w.String(), "hidden") || !strings.Contains(w.String(), "shown") {
+		t.Errorf("got %s", w.String())
+	}
+
+	// 包等级属于各自的实例, 不影响其他实例
+	other := &memWriter{}
+	ol := New(WithWriter(other))
+	ol.SetLevel(utils.LevelInfo)
+	ol.Debugf("other hidden")
+	if other.String() != "" {
+		t.Errorf("other logger got %s", other.String())
+	}
+
+	rec := httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/?level=warn", nil))
+	if rec.Code != http.StatusOK || GetLevel() != utils.LevelWarn {
+		t.Errorf("got %d %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	LevelHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/?level=verbose", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got %d", rec.Code)
+	}
+}
+
+func TestHook(t *testing.T) {
+	w := useMemWriter(t)
+	AddHook(func(e *Entry) *Entry {
+		if strings.Contains(e.Msg, "health") {
+			return nil
+		}
+		e.Fields = map[string]interface{}{"host": "lb-1"}
+		e.Msg = strings.ReplaceAll(e.Msg, "secret", "***")
+		return e
+	})
+	Infof("health check")
+	Infof("token secret")
+	out := w.String()
+	if strings.Contains(out, "health") || strings.Contains(out, "secret") {
+		t.Errorf("got %s", out)
+	}
+	if !strings.Contains(out, "token *** host=lb-1") || !strings.Contains(out, "log_test.go") {
+		t.Errorf("got %s", out)
+	}
+}
+
+func logWrapper(format string, args ...interface{}) {
+	Infof(format, args...)
+}
+
+func TestCaller(t *testing.T) {
+	var last Entry
+	useMemWriter(t)
+	AddHook(func(e *Entry) *Entry {
+		// entry 会被复用, 需要复制
+		last = *e
+		return e
+	})
+
+	logWrapper("wrapped")
+	if last.Func != "logWrapper" {
+		t.Errorf("got %s", last.Caller)
+	}
+	SetCallerSkip(1)
+	logWrapper("wrapped")
+	if last.Func != "TestCaller" || !strings.HasSuffix(last.File, "log_test.go") || last.Line == 0 {
+		t.Errorf("got %s", last.Caller)
+	}
+	SetCaller(false)
+	logWrapper("no caller")
+	if last.Caller != "" {
+		t.Errorf("got %s", last.Caller)
+	}
+}
+
+func TestPanicFatal(t *testing.T) {
+	w := useMemWriter(t)
+	func() {
+		defer func() {
+			if r := recover(); r != "boom 1" {
+				t.Errorf("got %v", r)
+			}
+		}()
+		Panicf("boom %d", 1)
+	}()
+
+	// 参数中的 error 提供调用栈
+	log.EnableStack(utils.LevelPanic)
+	func() {
+		defer func() {
+			if r := recover(); r != "boom stack err" {
+				t.Errorf("got %v", r)
+			}
+		}()
+		Panicf("boom %v", stackErr{})
+	}()
+	if !strings.Contains(w.String(), "created.here") {
+		t.Errorf("should print error stack, got %s", w.String())
+	}
+
+	var code int
+	exitFunc = func(c int) { code = c }
+	defer func() { exitFunc = os.Exit }()
+	Fatalf("bye")
+	if code != 1 {
+		t.Errorf("got exit code %d", code)
+	}
+	if !strings.Contains(w.String(), "PANIC") || !strings.Contains(w.String(), "FATAL") {
+		t.Errorf("got %s", w.String())
+	}
+}
+
+// 没有参数时 %% 仍然按 format 处理, Infow 的消息原样输出
+func TestPercentNoArgs(t *testing.T) {
+	w := useMemWriter(t)
+	Infof("100%% done")
+	Infow("50% off", "uid", 1)
+	out := w.String()
+	if !strings.Contains(out, "100% done\n") || strings.Contains(out, "%%") {
+		t.Errorf("got %s", out)
+	}
+	if !strings.Contains(out, "50% off uid=1") {
+		t.Errorf("got %s", out)
+	}
+}
+
+func TestFields(t *testing.T) {
+	w := useMemWriter(t)
+	l := WithFields(Fields{"uid": 1})
+	l.WithField("order", "x").Infof("paid %d", 100)
+	l.Infof("base")
+	Infow("login", "ip", "127.0.0.1", "retry")
+	out := w.String()
+	if !strings.Contains(out, "paid 100 order=x uid=1") || !strings.Contains(out, "base uid=1\n") {
+		t.Errorf("got %s", out)
+	}
+	if !strings.Contains(out, "login !BADKEY=retry ip=127.0.0.1") {
+		t.Errorf("got %s", out)
+	}
+}
+
+type stackErr struct{}
+
+func (stackErr) Error() string { return "stack err" }
+func (stackErr) Stack() string { return "created.here\n\tfile.go:1\n" }
+
+func TestStack(t *testing.T) {
+	w := useMemWriter(t)
+	Errorf("no stack")
+	EnableStack(utils.LevelError)
+	Warnf("warn no stack")
+	Errorf("with stack")
+	Errorf("err:%v", stackErr{})
+	out := w.String()
+	if strings.Count(out, "log.TestStack\n") != 1 {
+		t.Errorf("got %s", out)
+	}
+	if !strings.Contains(out, "created.here") {
+		t.Errorf("should print error stack, got %s", out)
+	}
+}
+
+type failWriter struct{}
+
+func (failWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+func (failWriter) Flush() error                { return nil }
+
+func TestErrorHandler(t *testing.T) {
+	useMemWriter(t)
+	log.logWriter = failWriter{}
+	var got error
+	SetErrorHandler(func(err error) { got = err })
+	defer SetErrorHandler(nil)
+	Errorf("should not panic")
+	WithField("a", 1).Infof("should not panic")
+	if got == nil || got.Error() != "disk full" {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestNew(t *testing.T) {
+	def := useMemWriter(t)
+	w := &memWriter{}
+	l := New(WithWriter(w), WithLevel(utils.LevelWarn), WithModule("lib"), WithFormat(FormatJSON))
+	l.SetLogHint("lib-hint")
+	defer l.SetLogHint("")
+	l.Infof("hidden")
+	l.Warnf("lib warn")
+	l.WithField("k", "v").Errorf("lib err")
+	Infof("default info")
+
+	out := w.String()
+	if strings.Contains(out, "hidden") || strings.Count(out, "\"module\":\"lib\"") != 2 || !strings.Contains(out, "\"hint\":\"lib-hint\"") {
+		t.Errorf("got %s", out)
+	}
+	if strings.Contains(def.String(), "lib") || strings.Contains(def.String(), "lib-hint") || !strings.Contains(def.String(), "default info") {
+		t.Errorf("default logger got %s", def.String())
+	}
+}
+
+func TestSlog(t *testing.T) {
+	w := &memWriter{}
+	l := New(WithWriter(w), WithLevel(utils.LevelInfo))
+	sl := slog.New(NewSlogHandler(l)).With("svc", "lb").WithGroup("req")
+	sl.Info("from slog", "id", 1, slog.Group("user", "name", "x"))
+	sl.Debug("hidden")
+	out := w.String()
+	if !strings.Contains(out, "from slog req.id=1 req.user.name=x svc=lb") || !strings.Contains(out, "log_test.go") {
+		t.Errorf("got %s", out)
+	}
+	if strings.Contains(out, "hidden") {
+		t.Errorf("got %s", out)
+	}
+
+	var buf bytes.Buffer
+	l2 := New(WithWriter(NewSlogWriter(slog.New(slog.NewJSONHandler(&buf, nil)))))
+	l2.WithField("uid", 1).Warnf("to slog")
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("got %s err:%v", buf.String(), err)
+	}
+	if m["msg"] != "to slog" || m["level"] != "WARN" || m["uid"] != float64(1) {
+		t.Errorf("got %s", buf.String())
+	}
+}
+
+// slog 的日志和本包的日志经过相同的包等级、采样、去重
+func TestSlogPipeline(t *testing.T) {
+	w := &memWriter{}
+	l := New(WithWriter(w), WithLevel(utils.LevelInfo))
+	l.SetModuleLevel("log", utils.LevelDebug)
+	l.SetSampling(time.Hour, map[utils.Level]Sampling{utils.LevelError: {First: 2, Thereafter: 100}})
+	l.SetDedup(time.Hour)
+	sl := slog.New(NewSlogHandler(l))
+
+	sl.Debug("module debug")
+	for i := 0; i < 10; i++ {
+		sl.Error("sampled err")
+	}
+	for i := 0; i < 3; i++ {
+		sl.Warn("dup warn")
+	}
+	_ = l.Flush()
+	out := w.String()
+	if !strings.Contains(out, "module debug") {
+		t.Errorf("module level not used, got %s", out)
+	}
+	if n := strings.Count(out, "sampled err"); n != 2 {
+		t.Errorf("got %d sampled err, out %s", n, out)
+	}
+	if !strings.Contains(out, "dup warn (repeated 2 times)") {
+		t.Errorf("dedup not used, got %s", out)
+	}
+
+	l.DelModuleLevel("log")
+	if NewSlogHandler(l).Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("debug should be disabled")
+	}
+}
+
+func TestSQLLogger(t *testing.T) {
+	w := &memWriter{}
+	l := New(WithWriter(w), WithLevel(utils.LevelInfo))
+	s := NewSQLLogger(WithSQLLogger(l), WithSlowThreshold(50*time.Millisecond), WithIgnoreNoRows())
+
+	s.Trace(context.Background(), time.Now(), "select 1", nil, 1, nil)
+	s.Trace(context.Background(), time.Now(), "select * from user where id = ?", []interface{}{1}, 0, sql.ErrNoRows)
+	s.Trace(context.Background(), time.Now().Add(-time.Second), "select sleep(1)", nil, -1, nil)
+	s.Trace(context.Background(), time.Now(), "insert", []interface{}{1}, 0, errors.New("duplicate"))
+	out := w.String()
+	if strings.Contains(out, "select 1") || strings.Contains(out, "where id") {
+		t.Errorf("debug sql should be hidden, got %s", out)
+	}
+	if !strings.Contains(out, "WARN") || !strings.Contains(out, "slow sql") || !strings.Contains(out, "sql=select sleep(1)") {
+		t.Errorf("got %s", out)
+	}
+	if !strings.Contains(out, "sql error") || !strings.Contains(out, "err=duplicate") {
+		t.Errorf("got %s", out)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	w := &memWriter{}
+	l := New(WithWriter(w))
+	l.SetSampling(time.Hour, map[utils.Level]Sampling{utils.LevelError: {First: 3, Thereafter: 10}})
+	for i := 0; i < 100; i++ {
+		l.Errorf("loop err %d", i)
+		l.Infof("loop info %d", i)
+	}
+	out := w.String()
+	// 前 3 条, 之后第 13, 23 ... 93 条
+	if n := strings.Count(out, "loop err"); n != 3+9 {
+		t.Errorf("got %d error entries", n)
+	}
+	if n := strings.Count(out, "loop info"); n != 100 {
+		t.Errorf("got %d info entries", n)
+	}
+	if l.Sampled() != 100-12 {
+		t.Errorf("got %d sampled", l.Sampled())
+	}
+}
+
+func TestDefaultHint(t *testing.T) {
+	w := &memWriter{}
+	l := New(WithWriter(w))
+	l.SetDefaultHint("svc")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Infof("other goroutine")
+	}()
+	<-done
+	l.SetLogHint("req-1")
+	l.Infof("own hint")
+	l.ClearLogHint()
+	out := w.String()
+	if !regexp.MustCompile(`<svc> .*other goroutine`).MatchString(out) || !regexp.MustCompile(`<req-1> .*own hint`).MatchString(out) {
+		t.Errorf("got %s", out)
+	}
+}
+
+func TestRunWithHint(t *testing.T) {
+	w := &memWriter{}
+	l := New(WithWriter(w))
+	l.SetLogHint("outer")
+	l.RunWithHint("inner", func() {
+		l.Infof("in")
+	})
+	l.Infof("out")
+	l.ClearLogHint()
+	if !strings.Contains(w.String(), "<inner>") || !strings.Contains(w.String(), "<outer>") {
+		t.Errorf("got %s", w.String())
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.SetLogHint("leak")
+		}()
+	}
+	wg.Wait()
+	if l.hints.size() != 10 {
+		t.Fatalf("got %d", l.hints.size())
+	}
+	// 协程调用 Done 后可能还没完全退出, 多试几次
+	for i := 0; i < 100 && l.hints.size() > 0; i++ {
+		l.hints.sweep(liveGoroutines())
+		time.Sleep(10 * time.Millisecond)
+	}
+	if l.hints.size() != 0 {
+		t.Errorf("got %d after sweep", l.hints.size())
+	}
+}
+
+type requestIdKey struct{}
+
+func TestTraceId(t *testing.T) {
+	w := useMemWriter(t)
+	SetTraceKeys(requestIdKey{})
+	t.Cleanup(func() {
+		SetTraceKeys()
+	})
+
+	ctx := context.WithValue(context.Background(), requestIdKey{}, "req-9")
+	CtxInfof(ctx, "from key")
+	if !strings.Contains(w.String(), "[req-9] ") || GetTraceIdFromCtx(ctx) != "req-9" {
+		t.Errorf("got %s", w.String())
+	}
+
+	traceId, _ := oteltrace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanId, _ := oteltrace.SpanIDFromHex("0102030405060708")
+	ctx = oteltrace.ContextWithSpanContext(ctx, oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID: traceId,
+		SpanID:  spanId,
+	}))
+	SetFormat(FormatJSON)
+	CtxInfof(ctx, "from span")
+	lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["trace_id"] != traceId.String() || m["span_id"] != spanId.String() {
+		t.Errorf("got %s", lines[len(lines)-1])
+	}
+}
+
+func TestDedup(t *testing.T) {
+	w := useMemWriter(t)
+	SetDedup(time.Hour)
+	for i := 0; i < 5; i++ {
+		Warnf("health check failed")
+	}
+	Infof("other")
+	out := w.String()
+	if strings.Count(out, "health check failed") != 2 || !strings.Contains(out, "health check failed (repeated 4 times)") ||
+		strings.Index(out, "repeated") > strings.Index(out, "other") {
+		t.Errorf("got %s", out)
+	}
+
+	// 窗口结束时输出
+	log.SetDedup(50 * time.Millisecond)
+	Infof("retry")
+	Infof("retry")
+	Infof("retry")
+	for i := 0; i < 100 && !strings.Contains(w.String(), "retry (repeated 2 times)"); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(w.String(), "retry (repeated 2 times)") {
+		t.Errorf("got %s", w.String())
+	}
+
+	// 刷盘时补上
+	log.SetDedup(time.Hour)
+	Errorf("flush")
+	Errorf("flush")
+	_ = log.Flush()
+	if !strings.Contains(w.String(), "flush (repeated 1 times)") {
+		t.Errorf("got %s", w.String())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	w := useMemWriter(t)
+	SetRedactor(NewRedactor(
+		WithRedactFields("card"),
+		WithRedactRule(regexp.MustCompile(`sk-[a-z0-9]+`), func(string) string { return "sk-***" }),
+	))
+
+	fl := WithFields(Fields{"phone": "13812345678", "password": "hunter2", "Card": 62220088, "tags": []string{"a"}})
+	fl.Infof("user lb@example.com id 110101199003071234 phone 13812345678 key sk-abc123")
+	out := w.String()
+	for _, s := range []string{"l*@example.com", "110***********1234", "138****5678", "sk-***", "phone=138****5678", "password=******", "Card=******", "tags=[a]"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("want %s, got %s", s, out)
+		}
+	}
+	for _, s := range []string{"13812345678", "hunter2", "62220088", "sk-abc123"} {
+		if strings.Contains(out, s) {
+			t.Errorf("%s should be masked, got %s", s, out)
+		}
+	}
+	// 共享的字段不能被改掉
+	if fl.fields["phone"] != "13812345678" {
+		t.Errorf("fields of FieldLogger changed")
+	}
+}
+
+func TestRedactNested(t *testing.T) {
+	type user struct {
+		Name     string
+		Password string
+		Phone    int64
+		Tags     []string
+		secret   string
+	}
+	u := &user{Name: "lb", Password: "hunter2", Phone: 13912345678, Tags: []string{"a"}, secret: "s"}
+	args := []interface{}{"13712345678", int64(110101199003071234), 3}
+	e := &Entry{
+		Msg:   "ok",
+		Hint:  "13612345678",
+		Stack: "main.login(0x1, 13512345678)",
+		Fields: Fields{
+			"mobile": int64(13812345678),
+			"args":   args,
+			"user":   u,
+			"extra":  map[string]interface{}{"token": "abc", "inner": Fields{"email": "lb@example.com"}},
+			"count":  10,
+		},
+	}
+	NewRedactor().redact(e)
+
+	if e.Hint != "136****5678" || e.Stack != "main.login(0x1, 135****5678)" {
+		t.Errorf("hint %s stack %s", e.Hint, e.Stack)
+	}
+	if e.Fields["mobile"] != "138****5678" || e.Fields["count"] != 10 {
+		t.Errorf("mobile %v count %v", e.Fields["mobile"], e.Fields["count"])
+	}
+	got := fmt.Sprint(e.Fields["args"])
+	if got != "[137****5678 110***********1234 3]" {
+		t.Errorf("args got %s", got)
+	}
+	got = fmt.Sprint(e.Fields["user"])
+	if got != "map[Name:lb Password:****** Phone:139****5678 Tags:[a]]" {
+		t.Errorf("user got %s", got)
+	}
+	got = fmt.Sprint(e.Fields["extra"])
+	if got != "map[inner:map[email:l*@example.com] token:******]" {
+		t.Errorf("extra got %s", got)
+	}
+	// 原值不能被改掉
+	if args[0] != "13712345678" || u.Password != "hunter2" {
+		t.Errorf("origin value changed")
+	}
+}
+
+func TestPattern(t *testing.T) {
+	w := useMemWriter(t)
+	SetModuleName("lb")
+	t.Cleanup(func() {
+		SetModuleName("UNKNOWN")
+	})
+	if err := SetPattern("%time{15:04}% [%level%] [%module%] [%hint%] %file% - %msg%%fields% 100%%"); err != nil {
+		t.Fatal(err)
+	}
+	SetLogHint("req-1")
+	defer SetLogHint("")
+	WithField("uid", 1).Warnf("hello")
+	want := fmt.Sprintf(`^\d\d:\d\d \[WARN\] \[lb\] \[req-1\] log_test.go:%d - hello uid=1 100%%\n$`, callerLine()-1)
+	if out := w.String(); !regexp.MustCompile(want).MatchString(out) {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	for _, p := range []string{"%msg", "%unknown%"} {
+		if err := SetPattern(p); err == nil {
+			t.Errorf("%s should fail", p)
+		}
+	}
+	if err := SetPattern(""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func callerLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+// keepWriter 保留每次 Write 的 p, 后续日志不能改掉之前的内容
+type keepWriter struct {
+	got [][]byte
+}
+
+func (k *keepWriter) Write(p []byte) (int, error) {
+	k.got = append(k.got, p)
+	return len(p), nil
+}
+
+func (k *keepWriter) Flush() error {
+	return nil
+}
+
+func TestLogWriterKeepBuffer(t *testing.T) {
+	w := &keepWriter{}
+	l := New(WithWriter(w))
+	l.Infof("first")
+	l.Infof("second")
+	if len(w.got) != 2 || !strings.Contains(string(w.got[0]), "first") || !strings.Contains(string(w.got[1]), "second") {
+		t.Fatalf("got %q", w.got)
+	}
+}
@@ -0,0 +1,214 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"github.com/oldbai555/lbtool/log/iface"
+	"github.com/oldbai555/lbtool/utils"
+	"github.com/petermattis/goid"
+	"sync"
+)
+
+type Option func(*Logger)
+
+func WithLevel(level utils.Level) Option {
+	return func(l *Logger) {
+		l.SetLevel(level)
+	}
+}
+
+// WithWriter 默认输出到标准输出
+func WithWriter(w iface.LogWriter) Option {
+	return func(l *Logger) {
+		l.logWriter = w
+	}
+}
+
+func WithModule(name string) Option {
+	return func(l *Logger) {
+		l.SetModuleName(name)
+	}
+}
+
+func WithFormat(format utils.Format) Option {
+	return func(l *Logger) {
+		l.fmt.SetFormat(format)
+	}
+}
+
+func WithCallerSkip(skip int) Option {
+	return func(l *Logger) {
+		l.SetCallerSkip(skip)
+	}
+}
+
+// New 独立的日志实例, 等级、输出、模块名和 hint 都不和默认实例共享
+func New(opts ...Option) *Logger {
+	l := &Logger{
+		hints:     newHintStore(),
+		logWriter: NewStdWriter(stdout),
+		fmt:       newSimpleFormatter(),
+	}
+	l.SetSkipCall(DefaultSkipCall)
+	l.SetModuleName(moduleName)
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// hintStore 按协程保存 hint, 读多写少, 使用 sync.Map 避免写日志时加锁
+type hintStore struct {
+	m sync.Map // int64 -> string
+}
+
+func newHintStore() *hintStore {
+	return &hintStore{}
+}
+
+func (h *hintStore) set(hint string) {
+	i := goid.Get()
+	if hint == "" {
+		h.m.Delete(i)
+	} else {
+		h.m.Store(i, hint)
+	}
+}
+
+func (h *hintStore) get() string {
+	v, ok := h.m.Load(goid.Get())
+	if !ok {
+		return ""
+	}
+	return v.(string)
+}
+
+func (l *Logger) SetLogHint(hint string) {
+	l.hints.set(hint)
+}
+
+// getHint ctx 中没有时使用协程上的 hint
+func (l *Logger) getHint(ctx context.Context) string {
+	if hint := GetHintFromCtx(ctx); hint != "" {
+		return hint
+	}
+	if hint := l.hints.get(); hint != "" {
+		return hint
+	}
+	hint, _ := l.defaultHint.Load().(string)
+	return hint
+}
+
+func (l *Logger) SetModuleName(name string) {
+	l.module.Store(name)
+}
+
+func (l *Logger) getModuleName() string {
+	name, _ := l.module.Load().(string)
+	return name
+}
+
+func (l *Logger) SetFormat(format utils.Format) {
+	l.fmt.SetFormat(format)
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if err := l.write(nil, nil, utils.LevelDebug, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	if err := l.write(nil, nil, utils.LevelInfo, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	if err := l.write(nil, nil, utils.LevelWarn, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	if err := l.write(nil, nil, utils.LevelError, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Panicf(format string, args ...interface{}) {
+	// format 和 args 分开传, 采样按 format 计数, 参数中的 error 可以提供调用栈
+	if err := l.write(nil, nil, utils.LevelPanic, format, args); err != nil {
+		handleError(err)
+	}
+	_ = l.Flush()
+	panic(any(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	if err := l.write(nil, nil, utils.LevelFatal, format, args); err != nil {
+		handleError(err)
+	}
+	_ = l.Flush()
+	exitFunc(1)
+}
+
+func (l *Logger) CtxDebugf(ctx context.Context, format string, args ...interface{}) {
+	if err := l.write(ctx, nil, utils.LevelDebug, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) CtxInfof(ctx context.Context, format string, args ...interface{}) {
+	if err := l.write(ctx, nil, utils.LevelInfo, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) CtxWarnf(ctx context.Context, format string, args ...interface{}) {
+	if err := l.write(ctx, nil, utils.LevelWarn, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) CtxErrorf(ctx context.Context, format string, args ...interface{}) {
+	if err := l.write(ctx, nil, utils.LevelError, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Debugw(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := l.write(nil, kvToFields(kv), utils.LevelDebug, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Infow(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := l.write(nil, kvToFields(kv), utils.LevelInfo, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Warnw(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := l.write(nil, kvToFields(kv), utils.LevelWarn, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) Errorw(msg string, kv ...interface{}) {
+	format, args := msgFormat(msg)
+	if err := l.write(nil, kvToFields(kv), utils.LevelError, format, args); err != nil {
+		handleError(err)
+	}
+}
+
+func (l *Logger) WithFields(fields Fields) *FieldLogger {
+	return (&FieldLogger{l: l}).WithFields(fields)
+}
+
+func (l *Logger) WithField(key string, val interface{}) *FieldLogger {
+	return l.WithFields(Fields{key: val})
+}
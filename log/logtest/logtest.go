@@ -0,0 +1,84 @@
+package logtest
+
+import (
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/log/iface"
+	"github.com/oldbai555/lbtool/utils"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var _ iface.EntryWriter = (*Recorder)(nil)
+
+// Recorder 在内存中记录日志, 用于单测断言
+type Recorder struct {
+	mu      sync.Mutex
+	entries []log.Entry
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// Capture 替换默认 logger 的输出, 测试结束后恢复; 期间日志不再写文件和控制台
+func Capture(t testing.TB) *Recorder {
+	r := NewRecorder()
+	old, _ := log.GetWriter().(iface.LogWriter)
+	log.SetWriter(r)
+	t.Cleanup(func() {
+		if old != nil {
+			log.SetWriter(old)
+		}
+	})
+	return r
+}
+
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, log.Entry{Level: utils.LevelInfo, Msg: strings.TrimRight(string(p), "\n")})
+	return len(p), nil
+}
+
+func (r *Recorder) WriteEntry(e *log.Entry, p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, *e)
+	return len(p), nil
+}
+
+func (r *Recorder) Flush() error {
+	return nil
+}
+
+// Entries 已记录的日志, 返回副本
+func (r *Recorder) Entries() []log.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]log.Entry(nil), r.entries...)
+}
+
+// Contains 是否有该等级且内容包含 substr 的日志
+func (r *Recorder) Contains(level utils.Level, substr string) bool {
+	return r.Count(level, substr) > 0
+}
+
+// Count 该等级且内容包含 substr 的日志条数
+func (r *Recorder) Count(level utils.Level, substr string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for _, e := range r.entries {
+		if e.Level == level && strings.Contains(e.Msg, substr) {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
@@ -0,0 +1,35 @@
+package logtest
+
+import (
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/utils"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	r := Capture(t)
+	log.Infof("user %d login", 1)
+	log.WithField("order", "x").Errorf("pay failed")
+	if !r.Contains(utils.LevelInfo, "user 1 login") || !r.Contains(utils.LevelError, "pay failed") {
+		t.Fatalf("got %+v", r.Entries())
+	}
+	if r.Contains(utils.LevelError, "login") {
+		t.Errorf("level should match")
+	}
+	if r.Entries()[1].Fields["order"] != "x" {
+		t.Errorf("got %+v", r.Entries()[1])
+	}
+	r.Reset()
+	if len(r.Entries()) != 0 {
+		t.Errorf("should be empty after reset")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	l := log.New(log.WithWriter(r))
+	l.Warnf("disk %d%%", 90)
+	if r.Count(utils.LevelWarn, "disk 90%") != 1 {
+		t.Errorf("got %+v", r.Entries())
+	}
+}
@@ -0,0 +1,216 @@
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/log/iface"
+	"github.com/oldbai555/lbtool/pkg/trace"
+	"github.com/oldbai555/lbtool/utils"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// 和 pkg/trace 一样按 OTLP/HTTP 的 json 编码上报, 收集器默认监听 4318 端口
+
+const (
+	otlpLogsPath        = "/v1/logs"
+	InstrumentationName = "github.com/oldbai555/lbtool/log"
+)
+
+// Config 日志上报配置, Enabled 为 false 时 Setup 不做任何事
+type Config struct {
+	Enabled bool `json:"enabled"`
+	// Endpoint OTLP/HTTP 收集器地址, 例如 http://127.0.0.1:4318
+	Endpoint string `json:"endpoint"`
+	// Headers 上报时附带的请求头, 一般用于鉴权
+	Headers map[string]string `json:"headers"`
+	// ServiceName 服务名, 需要和链路追踪的一致才能关联
+	ServiceName string `json:"service_name"`
+	// Attributes 附加到 resource 上的属性, 例如 env, version
+	Attributes map[string]string `json:"attributes"`
+	// Level 上报的最低等级, 默认 info
+	Level string `json:"level"`
+}
+
+// Setup 按配置把日志上报到收集器, 返回的方法在服务退出时调用以上报剩余的日志
+func Setup(conf Config, opts ...Option) (shutdown func() error, err error) {
+	if !conf.Enabled {
+		return func() error { return nil }, nil
+	}
+	if conf.ServiceName == "" {
+		return nil, errors.New("service name is required")
+	}
+	level := utils.LevelInfo
+	if conf.Level != "" {
+		if level, err = log.ParseLevel(conf.Level); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Endpoint == "" {
+		conf.Endpoint = trace.DefaultEndpoint
+	}
+
+	opts = append([]Option{
+		WithHeaders(conf.Headers),
+		WithServiceName(conf.ServiceName),
+		WithResource(conf.Attributes),
+	}, opts...)
+	w := NewWriter(conf.Endpoint, opts...)
+	log.AddWriter(w, level)
+	return w.Close, nil
+}
+
+type Option func(*Writer)
+
+func WithHeaders(headers map[string]string) Option {
+	return func(w *Writer) {
+		w.headers = headers
+	}
+}
+
+func WithServiceName(name string) Option {
+	return func(w *Writer) {
+		w.serviceName = name
+	}
+}
+
+// WithResource 附加到 resource 上的属性
+func WithResource(attrs map[string]string) Option {
+	return func(w *Writer) {
+		w.attrs = attrs
+	}
+}
+
+// WithRemoteOptions 批量大小、重试、落盘等, 同 log.RemoteWriter
+func WithRemoteOptions(opts ...log.RemoteOption) Option {
+	return func(w *Writer) {
+		w.remoteOpts = append(w.remoteOpts, opts...)
+	}
+}
+
+var _ iface.LogWriter = (*Writer)(nil)
+var _ iface.EntryWriter = (*Writer)(nil)
+
+// Writer 把日志转换为 OTLP log record, 由 log.RemoteWriter 批量发送
+type Writer struct {
+	headers     map[string]string
+	serviceName string
+	attrs       map[string]string
+	remoteOpts  []log.RemoteOption
+
+	rw *log.RemoteWriter
+}
+
+// NewWriter 通过 log.AddWriter 加入, 或者在 Setup 中按配置开启
+func NewWriter(endpoint string, opts ...Option) *Writer {
+	w := &Writer{}
+	for _, opt := range opts {
+		opt(w)
+	}
+	t := &transport{
+		url:     strings.TrimRight(endpoint, "/") + otlpLogsPath,
+		headers: w.headers,
+		client:  &http.Client{Timeout: trace.DefaultExportTimeout},
+		prefix:  requestPrefix(w.serviceName, w.attrs),
+	}
+	w.rw = log.NewRemoteWriter(t, w.remoteOpts...)
+	return w
+}
+
+// Write 没有原始日志内容时整行作为 body
+func (w *Writer) Write(p []byte) (int, error) {
+	body := strings.TrimRight(string(p), "\n")
+	return w.write(&record{
+		TimeUnixNano:   trace.UnixNanoStr(time.Now()),
+		SeverityNumber: severityNumber(utils.LevelInfo),
+		SeverityText:   utils.LevelToStrMap[utils.LevelInfo],
+		Body:           trace.OTLPAnyValue(body),
+	}, len(p))
+}
+
+func (w *Writer) WriteEntry(e *log.Entry, p []byte) (int, error) {
+	return w.write(newRecord(e), len(p))
+}
+
+func (w *Writer) write(r *record, n int) (int, error) {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = w.rw.Write(append(buf, '\n')); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (w *Writer) Flush() error {
+	return w.rw.Flush()
+}
+
+// Close 发送剩余的日志后关闭
+func (w *Writer) Close() error {
+	return w.rw.Close()
+}
+
+// Dropped 队列满且落盘失败而丢弃的条数
+func (w *Writer) Dropped() uint64 {
+	return w.rw.Dropped()
+}
+
+var _ log.Transport = (*transport)(nil)
+
+// transport 一批 record 拼成一个 ExportLogsServiceRequest
+type transport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	prefix  []byte
+}
+
+var requestSuffix = []byte("]}]}]}")
+
+func (t *transport) Send(ctx context.Context, batch [][]byte) error {
+	var b bytes.Buffer
+	b.Write(t.prefix)
+	for i, r := range batch {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(bytes.TrimRight(r, "\n"))
+	}
+	b.Write(requestSuffix)
+	return trace.PostOTLP(ctx, t.client, t.url, t.headers, b.Bytes())
+}
+
+func (t *transport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+// requestPrefix resource 和 scope 不变, 只编码一次
+func requestPrefix(serviceName string, attrs map[string]string) []byte {
+	kvs := []trace.OTLPKeyValue{
+		{Key: "process.pid", Value: trace.OTLPAnyValue(os.Getpid())},
+	}
+	if serviceName != "" {
+		kvs = append(kvs, trace.OTLPKeyValue{Key: "service.name", Value: trace.OTLPAnyValue(serviceName)})
+	}
+	for k, v := range attrs {
+		kvs = append(kvs, trace.OTLPKeyValue{Key: k, Value: trace.OTLPAnyValue(v)})
+	}
+	resource, _ := json.Marshal(trace.OTLPResource{Attributes: kvs})
+	scope, _ := json.Marshal(trace.OTLPScope{Name: InstrumentationName})
+
+	var b bytes.Buffer
+	b.WriteString(`{"resourceLogs":[{"resource":`)
+	b.Write(resource)
+	b.WriteString(`,"scopeLogs":[{"scope":`)
+	b.Write(scope)
+	b.WriteString(`,"logRecords":[`)
+	return b.Bytes()
+}
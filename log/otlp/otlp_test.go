@@ -0,0 +1,113 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/utils"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type exportReq struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []struct {
+				Key   string                 `json:"key"`
+				Value map[string]interface{} `json:"value"`
+			} `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []map[string]interface{} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+func TestWriter(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		reqs []exportReq
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req exportReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode err:%v", err)
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	w := NewWriter(srv.URL, WithServiceName("lb"), WithHeaders(map[string]string{"Authorization": "token"}))
+	defer w.Close()
+	l := log.New(log.WithWriter(log.NewStdWriter(io.Discard)))
+	l.AddWriter(w, utils.LevelInfo)
+
+	traceId, _ := oteltrace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanId, _ := oteltrace.SpanIDFromHex("0102030405060708")
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: oteltrace.FlagsSampled,
+	}))
+	l.CtxErrorf(ctx, "pay failed")
+	l.WithField("uid", 1).Warnf("slow")
+	l.Debugf("ignored")
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests", len(reqs))
+	}
+	rl := reqs[0].ResourceLogs[0]
+	var service string
+	for _, kv := range rl.Resource.Attributes {
+		if kv.Key == "service.name" {
+			service, _ = kv.Value["stringValue"].(string)
+		}
+	}
+	if service != "lb" {
+		t.Errorf("got resource %+v", rl.Resource)
+	}
+	records := rl.ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("got %+v", records)
+	}
+	if records[0]["severityNumber"] != float64(17) || records[0]["traceId"] != traceId.String() || records[0]["spanId"] != spanId.String() {
+		t.Errorf("got %+v", records[0])
+	}
+	if body := records[0]["body"].(map[string]interface{}); body["stringValue"] != "pay failed" {
+		t.Errorf("got %+v", records[0])
+	}
+	var uid interface{}
+	for _, kv := range records[1]["attributes"].([]interface{}) {
+		if m := kv.(map[string]interface{}); m["key"] == "uid" {
+			uid = m["value"].(map[string]interface{})["intValue"]
+		}
+	}
+	if records[1]["severityText"] != "WARN" || uid != "1" {
+		t.Errorf("got %+v", records[1])
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(Config{})
+	if err != nil || shutdown() != nil {
+		t.Errorf("disabled setup should be noop")
+	}
+	if _, err = Setup(Config{Enabled: true}); err == nil {
+		t.Errorf("service name is required")
+	}
+}
@@ -0,0 +1,72 @@
+package otlp
+
+import (
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/trace"
+	"github.com/oldbai555/lbtool/utils"
+	"sort"
+	"time"
+)
+
+// record OTLP 的 LogRecord, 字段名按 json 编码的约定
+type record struct {
+	TimeUnixNano         string               `json:"timeUnixNano"`
+	ObservedTimeUnixNano string               `json:"observedTimeUnixNano,omitempty"`
+	SeverityNumber       int                  `json:"severityNumber"`
+	SeverityText         string               `json:"severityText"`
+	Body                 trace.OTLPValue      `json:"body"`
+	Attributes           []trace.OTLPKeyValue `json:"attributes,omitempty"`
+	TraceId              string               `json:"traceId,omitempty"`
+	SpanId               string               `json:"spanId,omitempty"`
+}
+
+// OTLP 的 SeverityNumber, 每个等级取该区间的第一个值
+var levelToSeverity = map[utils.Level]int{
+	utils.LevelDebug: 5,
+	utils.LevelInfo:  9,
+	utils.LevelWarn:  13,
+	utils.LevelError: 17,
+	utils.LevelPanic: 20,
+	utils.LevelFatal: 21,
+}
+
+func severityNumber(level utils.Level) int {
+	return levelToSeverity[level]
+}
+
+// newRecord 字段转换为 attributes, 调用位置使用 otel 语义约定的 code.* 属性
+func newRecord(e *log.Entry) *record {
+	r := &record{
+		TimeUnixNano:         trace.UnixNanoStr(e.Time),
+		ObservedTimeUnixNano: trace.UnixNanoStr(time.Now()),
+		SeverityNumber:       severityNumber(e.Level),
+		SeverityText:         utils.LevelToStrMap[e.Level],
+		Body:                 trace.OTLPAnyValue(e.Msg),
+		TraceId:              e.TraceId,
+		SpanId:               e.SpanId,
+	}
+
+	addStr := func(k, v string) {
+		if v != "" {
+			r.Attributes = append(r.Attributes, trace.OTLPKeyValue{Key: k, Value: trace.OTLPAnyValue(v)})
+		}
+	}
+	addStr("module", e.Module)
+	addStr("hint", e.Hint)
+	addStr("code.filepath", e.File)
+	if e.Line > 0 {
+		r.Attributes = append(r.Attributes, trace.OTLPKeyValue{Key: "code.lineno", Value: trace.OTLPAnyValue(e.Line)})
+	}
+	addStr("code.function", e.Func)
+	addStr("exception.stacktrace", e.Stack)
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		r.Attributes = append(r.Attributes, trace.OTLPKeyValue{Key: k, Value: trace.OTLPAnyValue(e.Fields[k])})
+	}
+	return r
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const defaultPatternTimeLayout = "2006-01-02 15:04:05.000"
+
+// SetPattern 文本格式按模板输出, 传空恢复默认格式, 对 FormatJSON 不生效
+//
+//	log.SetPattern("%time% [%level%] [%module%] [%hint%] %caller% - %msg%%fields%")
+//
+// 支持 %time% %time{15:04:05}% %level% %module% %pid% %gid% %hint% %trace% %span%
+// %caller% %file% %line% %func% %msg% %fields%, %% 输出 %; 调用栈总是追加在最后
+func SetPattern(pattern string) error {
+	return log.SetPattern(pattern)
+}
+
+func (l *Logger) SetPattern(pattern string) error {
+	s, ok := l.fmt.(*simpleFormatter)
+	if !ok {
+		return fmt.Errorf("formatter %T not support pattern", l.fmt)
+	}
+	if pattern == "" {
+		s.layout.Store(nil)
+		return nil
+	}
+	lay, err := parsePattern(pattern)
+	if err != nil {
+		return err
+	}
+	s.layout.Store(lay)
+	return nil
+}
+
+type patternAppender func(b []byte, e *Entry) []byte
+
+// layout 解析后的模板, 按顺序追加
+type layout struct {
+	appenders []patternAppender
+}
+
+func (lay *layout) appendTo(b []byte, e *Entry) []byte {
+	for _, a := range lay.appenders {
+		b = a(b, e)
+	}
+	b = append(b, '\n')
+	return append(b, e.Stack...)
+}
+
+func parsePattern(pattern string) (*layout, error) {
+	lay := &layout{}
+	for len(pattern) > 0 {
+		i := strings.IndexByte(pattern, '%')
+		if i < 0 {
+			lay.appenders = append(lay.appenders, literalAppender(pattern))
+			break
+		}
+		if i > 0 {
+			lay.appenders = append(lay.appenders, literalAppender(pattern[:i]))
+		}
+		pattern = pattern[i+1:]
+		if strings.HasPrefix(pattern, "%") {
+			lay.appenders = append(lay.appenders, literalAppender("%"))
+			pattern = pattern[1:]
+			continue
+		}
+		j := strings.IndexByte(pattern, '%')
+		if j < 0 {
+			return nil, fmt.Errorf("unclosed pattern %%%s", pattern)
+		}
+		a, err := newPatternAppender(pattern[:j])
+		if err != nil {
+			return nil, err
+		}
+		lay.appenders = append(lay.appenders, a)
+		pattern = pattern[j+1:]
+	}
+	return lay, nil
+}
+
+func literalAppender(s string) patternAppender {
+	return func(b []byte, e *Entry) []byte {
+		return append(b, s...)
+	}
+}
+
+func newPatternAppender(name string) (patternAppender, error) {
+	if strings.HasPrefix(name, "time{") && strings.HasSuffix(name, "}") {
+		timeLayout := name[len("time{") : len(name)-1]
+		return func(b []byte, e *Entry) []byte {
+			return e.Time.AppendFormat(b, timeLayout)
+		}, nil
+	}
+	switch name {
+	case "time":
+		return func(b []byte, e *Entry) []byte {
+			return e.Time.AppendFormat(b, defaultPatternTimeLayout)
+		}, nil
+	case "level":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, utils.LevelToStrMap[e.Level]...)
+		}, nil
+	case "module":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, e.Module...)
+		}, nil
+	case "pid":
+		return func(b []byte, e *Entry) []byte {
+			return strconv.AppendInt(b, int64(e.Pid), 10)
+		}, nil
+	case "gid":
+		return func(b []byte, e *Entry) []byte {
+			return strconv.AppendInt(b, e.Gid, 10)
+		}, nil
+	case "hint":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, e.Hint...)
+		}, nil
+	case "trace":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, e.TraceId...)
+		}, nil
+	case "span":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, e.SpanId...)
+		}, nil
+	case "caller":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, e.Caller...)
+		}, nil
+	case "file":
+		// 文件名:行号, 和 log4j 的 %F:%L 类似
+		return func(b []byte, e *Entry) []byte {
+			if e.File == "" {
+				return b
+			}
+			b = append(b, path.Base(e.File)...)
+			b = append(b, ':')
+			return strconv.AppendInt(b, int64(e.Line), 10)
+		}, nil
+	case "line":
+		return func(b []byte, e *Entry) []byte {
+			return strconv.AppendInt(b, int64(e.Line), 10)
+		}, nil
+	case "func":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, e.Func...)
+		}, nil
+	case "msg":
+		return func(b []byte, e *Entry) []byte {
+			return append(b, e.Msg...)
+		}, nil
+	case "fields":
+		return func(b []byte, e *Entry) []byte {
+			return appendTextFields(b, e.Fields)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown pattern %%%s%%", name)
+	}
+}
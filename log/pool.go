@@ -0,0 +1,44 @@
+package log
+
+import (
+	"sync"
+)
+
+const maxPooledBufSize = 64 << 10
+
+var (
+	entryPool = sync.Pool{
+		New: func() interface{} {
+			return new(Entry)
+		},
+	}
+	bufPool = sync.Pool{
+		New: func() interface{} {
+			b := make([]byte, 0, 512)
+			return &b
+		},
+	}
+)
+
+func getEntry() *Entry {
+	return entryPool.Get().(*Entry)
+}
+
+// putEntry 写完之后回收, writer 和 hook 不能保留 entry 的指针
+func putEntry(e *Entry) {
+	*e = Entry{}
+	entryPool.Put(e)
+}
+
+func getBuf() *[]byte {
+	return bufPool.Get().(*[]byte)
+}
+
+// putBuf 过大的 buf 不回收, 避免偶尔的大日志一直占着内存
+func putBuf(b *[]byte) {
+	if cap(*b) > maxPooledBufSize {
+		return
+	}
+	*b = (*b)[:0]
+	bufPool.Put(b)
+}
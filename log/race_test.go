@@ -0,0 +1,6 @@
+//go:build race
+
+package log
+
+// raceEnabled -race 时 sync.Pool 会随机丢弃对象, 分配次数的测试不准确
+const raceEnabled = true
@@ -0,0 +1,244 @@
+package log
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const redactMask = "******"
+
+var (
+	// 身份证号要在手机号之前匹配, 18 位数字里不会单独匹配出手机号
+	idCardRe = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	phoneRe  = regexp.MustCompile(`\b1[3-9]\d{9}\b`)
+	emailRe  = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+)
+
+// MaskMiddle 保留前 head 个和后 tail 个字符, 中间替换为 *
+func MaskMiddle(s string, head, tail int) string {
+	n := utf8.RuneCountInString(s)
+	if n <= head+tail {
+		return strings.Repeat("*", n)
+	}
+	r := []rune(s)
+	return string(r[:head]) + strings.Repeat("*", n-head-tail) + string(r[n-tail:])
+}
+
+// MaskPhone 13812345678 -> 138****5678
+func MaskPhone(s string) string {
+	return MaskMiddle(s, 3, 4)
+}
+
+// MaskIdCard 110101199003071234 -> 110***********1234
+func MaskIdCard(s string) string {
+	return MaskMiddle(s, 3, 4)
+}
+
+// MaskEmail lb@example.com -> l*@example.com
+func MaskEmail(s string) string {
+	i := strings.LastIndex(s, "@")
+	if i <= 0 {
+		return MaskMiddle(s, 1, 0)
+	}
+	return MaskMiddle(s[:i], 1, 0) + s[i:]
+}
+
+type redactRule struct {
+	re   *regexp.Regexp
+	mask func(string) string
+}
+
+type RedactOption func(*Redactor)
+
+// WithRedactRule 增加一条规则, 匹配到的内容交给 mask 处理
+func WithRedactRule(re *regexp.Regexp, mask func(string) string) RedactOption {
+	return func(r *Redactor) {
+		r.rules = append(r.rules, redactRule{re: re, mask: mask})
+	}
+}
+
+// WithRedactFields 这些字段的值整体替换为 ******, 不区分大小写
+func WithRedactFields(names ...string) RedactOption {
+	return func(r *Redactor) {
+		for _, name := range names {
+			r.fields[strings.ToLower(name)] = struct{}{}
+		}
+	}
+}
+
+// Redactor 日志脱敏, 在 hook 之后、编码之前执行, 所有输出看到的都是脱敏后的内容
+// 处理 Msg、Hint、Stack 和字段, 字段里的整数、slice、map、结构体也会逐层处理
+type Redactor struct {
+	rules  []redactRule
+	fields map[string]struct{}
+}
+
+// NewRedactor 默认处理手机号、身份证号、邮箱, 以及 password / token / secret 字段
+func NewRedactor(opts ...RedactOption) *Redactor {
+	r := &Redactor{
+		rules: []redactRule{
+			{re: idCardRe, mask: MaskIdCard},
+			{re: phoneRe, mask: MaskPhone},
+			{re: emailRe, mask: MaskEmail},
+		},
+		fields: map[string]struct{}{
+			"password": {},
+			"passwd":   {},
+			"pwd":      {},
+			"token":    {},
+			"secret":   {},
+		},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// SetRedactor 开启脱敏, 传 nil 关闭
+func SetRedactor(r *Redactor) {
+	log.SetRedactor(r)
+}
+
+func WithRedactor(r *Redactor) Option {
+	return func(l *Logger) {
+		l.SetRedactor(r)
+	}
+}
+
+func (l *Logger) SetRedactor(r *Redactor) {
+	l.redactor.Store(r)
+}
+
+// Redact 按规则处理一段文本
+func (r *Redactor) Redact(s string) string {
+	for _, rule := range r.rules {
+		s = rule.re.ReplaceAllStringFunc(s, rule.mask)
+	}
+	return s
+}
+
+// redact 字段可能来自共享的 FieldLogger, 有改动时复制一份
+func (r *Redactor) redact(e *Entry) {
+	e.Msg = r.Redact(e.Msg)
+	e.Hint = r.Redact(e.Hint)
+	e.Stack = r.Redact(e.Stack)
+	if len(e.Fields) == 0 {
+		return
+	}
+	var fields Fields
+	for k, v := range e.Fields {
+		masked, ok := r.redactField(k, v, 0)
+		if !ok {
+			continue
+		}
+		if fields == nil {
+			fields = make(Fields, len(e.Fields))
+			for k, v := range e.Fields {
+				fields[k] = v
+			}
+		}
+		fields[k] = masked
+	}
+	if fields != nil {
+		e.Fields = fields
+	}
+}
+
+// maxRedactDepth 嵌套层数的上限, 避免循环引用
+const maxRedactDepth = 8
+
+// redactField 敏感字段整体替换, 其他的按值处理
+func (r *Redactor) redactField(key string, v interface{}, depth int) (interface{}, bool) {
+	if _, ok := r.fields[strings.ToLower(key)]; ok {
+		return redactMask, true
+	}
+	return r.redactValue(v, depth)
+}
+
+// redactValue 字符串和整数按文本匹配规则, map / slice / 结构体逐个处理, 没有改动时返回原值和 false
+// 有改动的 map 和结构体转成 map[string]interface{}, slice 转成 []interface{}, 原值不变
+func (r *Redactor) redactValue(v interface{}, depth int) (interface{}, bool) {
+	if masked, ok := r.walkValue(v, depth); ok {
+		return masked, true
+	}
+	return v, false
+}
+
+func (r *Redactor) walkValue(v interface{}, depth int) (interface{}, bool) {
+	if v == nil || depth > maxRedactDepth {
+		return v, false
+	}
+	switch x := v.(type) {
+	case string:
+		return r.redactText(x)
+	case []byte:
+		return r.redactText(string(x))
+	case error:
+		return r.redactText(x.Error())
+	case fmt.Stringer:
+		return r.redactText(x.String())
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return r.redactText(fmt.Sprint(v))
+	case reflect.String:
+		return r.redactText(rv.String())
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return v, false
+		}
+		return r.redactValue(rv.Elem().Interface(), depth+1)
+	case reflect.Slice, reflect.Array:
+		var out []interface{}
+		for i := 0; i < rv.Len(); i++ {
+			masked, ok := r.redactValue(rv.Index(i).Interface(), depth+1)
+			if ok && out == nil {
+				out = make([]interface{}, rv.Len())
+				for j := 0; j < i; j++ {
+					out[j] = rv.Index(j).Interface()
+				}
+			}
+			if out != nil {
+				out[i] = masked
+			}
+		}
+		return out, out != nil
+	case reflect.Map:
+		changed := false
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			masked, ok := r.redactField(k, iter.Value().Interface(), depth+1)
+			changed = changed || ok
+			out[k] = masked
+		}
+		return out, changed
+	case reflect.Struct:
+		changed := false
+		t := rv.Type()
+		out := make(map[string]interface{}, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			masked, ok := r.redactField(t.Field(i).Name, rv.Field(i).Interface(), depth+1)
+			changed = changed || ok
+			out[t.Field(i).Name] = masked
+		}
+		return out, changed
+	}
+	return v, false
+}
+
+func (r *Redactor) redactText(s string) (interface{}, bool) {
+	masked := r.Redact(s)
+	return masked, masked != s
+}
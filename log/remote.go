@@ -0,0 +1,452 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/log/iface"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transport 远程日志的发送方式, 每条日志以换行结尾, 可以自己实现 kafka 等
+type Transport interface {
+	Send(ctx context.Context, batch [][]byte) error
+	Close() error
+}
+
+var _ Transport = (*HTTPTransport)(nil)
+
+// HTTPTransport 以 ndjson 格式 POST 到 url
+type HTTPTransport struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+func NewHTTPTransport(url string, header http.Header) *HTTPTransport {
+	return &HTTPTransport{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+		header: header,
+	}
+}
+
+func (h *HTTPTransport) Send(ctx context.Context, batch [][]byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(bytes.Join(batch, nil)))
+	if err != nil {
+		return err
+	}
+	for k, v := range h.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote log http status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (h *HTTPTransport) Close() error {
+	h.client.CloseIdleConnections()
+	return nil
+}
+
+var _ Transport = (*TCPTransport)(nil)
+
+// TCPTransport 长连接发送, 出错后下次发送时重连
+type TCPTransport struct {
+	addr    string
+	timeout time.Duration
+	conn    net.Conn
+	mu      sync.Mutex
+}
+
+func NewTCPTransport(addr string, timeout time.Duration) *TCPTransport {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &TCPTransport{addr: addr, timeout: timeout}
+}
+
+func (t *TCPTransport) Send(ctx context.Context, batch [][]byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		d := net.Dialer{Timeout: t.timeout}
+		conn, err := d.DialContext(ctx, "tcp", t.addr)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	}
+	_ = t.conn.SetWriteDeadline(time.Now().Add(t.timeout))
+	if _, err := t.conn.Write(bytes.Join(batch, nil)); err != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (t *TCPTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
+
+const (
+	DefaultRemoteBatchSize = 100
+	DefaultRemoteQueueSize = 10000
+	DefaultSpillMaxSize    = 100 * 1024 * 1024
+)
+
+var ErrRemoteWriterClosed = errors.New("remote log writer closed")
+
+type RemoteOption func(*RemoteWriter)
+
+func WithBatchSize(n int) RemoteOption {
+	return func(r *RemoteWriter) {
+		r.batchSize = n
+	}
+}
+
+// WithFlushInterval 不满一批时的发送间隔
+func WithFlushInterval(d time.Duration) RemoteOption {
+	return func(r *RemoteWriter) {
+		r.interval = d
+	}
+}
+
+func WithQueueSize(n int) RemoteOption {
+	return func(r *RemoteWriter) {
+		r.queueSize = n
+	}
+}
+
+// WithRetry 发送失败的重试次数和首次退避时间, 每次翻倍
+func WithRetry(times int, backoff time.Duration) RemoteOption {
+	return func(r *RemoteWriter) {
+		r.retry = times
+		r.backoff = backoff
+	}
+}
+
+// WithSpillFile 远端不可用时写入本地文件, 恢复后补发; maxSize 为文件上限, 超过后丢弃
+// 文件中每条日志带长度前缀, 不是纯文本, 不要和普通日志文件共用
+func WithSpillFile(path string, maxSize int64) RemoteOption {
+	return func(r *RemoteWriter) {
+		r.spillPath = path
+		r.spillMax = maxSize
+	}
+}
+
+var _ iface.LogWriter = (*RemoteWriter)(nil)
+var _ iface.EntryWriter = (*RemoteWriter)(nil)
+
+// RemoteWriter 批量发送到远端, 失败重试, 仍然失败时落到本地文件
+type RemoteWriter struct {
+	t         Transport
+	batchSize int
+	interval  time.Duration
+	queueSize int
+	retry     int
+	backoff   time.Duration
+	spillPath string
+	spillMax  int64
+	spillMu   sync.Mutex
+
+	ch      chan []byte
+	flushCh chan chan error
+	done    chan struct{}
+	wg      sync.WaitGroup
+	dropped uint64
+	// closeMu 保证 Close 之后没有正在入队的 Write, loop 退出前能取到所有日志
+	closed  bool
+	closeMu sync.RWMutex
+}
+
+func NewRemoteWriter(t Transport, opts ...RemoteOption) *RemoteWriter {
+	r := &RemoteWriter{
+		t:         t,
+		batchSize: DefaultRemoteBatchSize,
+		interval:  time.Second,
+		queueSize: DefaultRemoteQueueSize,
+		retry:     3,
+		backoff:   100 * time.Millisecond,
+		spillMax:  DefaultSpillMaxSize,
+		flushCh:   make(chan chan error),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.ch = make(chan []byte, r.queueSize)
+	r.wg.Add(1)
+	go r.loop()
+	return r
+}
+
+// Write 不阻塞, 队列满时直接落到本地文件
+func (r *RemoteWriter) Write(p []byte) (int, error) {
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+	if r.closed {
+		return 0, ErrRemoteWriterClosed
+	}
+	buf := append([]byte(nil), p...)
+	select {
+	case r.ch <- buf:
+	default:
+		if err := r.spill([][]byte{buf}); err != nil {
+			atomic.AddUint64(&r.dropped, 1)
+		}
+	}
+	return len(p), nil
+}
+
+// WriteEntry Write 已经复制了 p, 不需要再复制一次
+func (r *RemoteWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return r.Write(p)
+}
+
+// Flush 发送队列中已有的日志
+func (r *RemoteWriter) Flush() error {
+	r.closeMu.RLock()
+	closed := r.closed
+	r.closeMu.RUnlock()
+	if closed {
+		return ErrRemoteWriterClosed
+	}
+	done := make(chan error, 1)
+	select {
+	case r.flushCh <- done:
+		return <-done
+	case <-r.done:
+		return ErrRemoteWriterClosed
+	}
+}
+
+// Close 发送剩余的日志后关闭
+func (r *RemoteWriter) Close() error {
+	r.closeMu.Lock()
+	if r.closed {
+		r.closeMu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.closeMu.Unlock()
+	close(r.done)
+	r.wg.Wait()
+	return r.t.Close()
+}
+
+// Dropped 远端和本地文件都失败时丢弃的条数
+func (r *RemoteWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+func (r *RemoteWriter) loop() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, r.batchSize)
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := r.send(batch)
+		batch = make([][]byte, 0, r.batchSize)
+		return err
+	}
+	drain := func() {
+		for {
+			select {
+			case p := <-r.ch:
+				batch = append(batch, p)
+				if len(batch) >= r.batchSize {
+					_ = send()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case p := <-r.ch:
+			batch = append(batch, p)
+			if len(batch) >= r.batchSize {
+				_ = send()
+			}
+		case <-ticker.C:
+			if send() == nil {
+				r.replaySpill()
+			}
+		case done := <-r.flushCh:
+			drain()
+			done <- send()
+		case <-r.done:
+			drain()
+			_ = send()
+			return
+		}
+	}
+}
+
+// send 按退避重试, 仍然失败时落到本地文件
+func (r *RemoteWriter) send(batch [][]byte) error {
+	err := r.sendWithRetry(batch)
+	if err == nil {
+		return nil
+	}
+	handleError(fmt.Errorf("remote log send err:%v", err))
+	if spillErr := r.spill(batch); spillErr != nil {
+		atomic.AddUint64(&r.dropped, uint64(len(batch)))
+		handleError(fmt.Errorf("remote log drop %d entries, spill err:%v", len(batch), spillErr))
+	}
+	return err
+}
+
+func (r *RemoteWriter) sendWithRetry(batch [][]byte) error {
+	backoff := r.backoff
+	var err error
+	for i := 0; i <= r.retry; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-r.done:
+				// 关闭时不再等待, 直接落盘
+				return err
+			}
+			backoff *= 2
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = r.t.Send(ctx, batch)
+		cancel()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (r *RemoteWriter) spill(batch [][]byte) error {
+	if r.spillPath == "" {
+		return errors.New("spill file not set")
+	}
+	r.spillMu.Lock()
+	defer r.spillMu.Unlock()
+	if fi, err := os.Stat(r.spillPath); err == nil && r.spillMax > 0 && fi.Size() >= r.spillMax {
+		return errors.New("spill file is full")
+	}
+	f, err := os.OpenFile(r.spillPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(encodeSpill(batch))
+	return err
+}
+
+// encodeSpill 每条日志前写 4 字节长度, 日志内容可能包含换行(调用栈等), 不能按行切分
+func encodeSpill(batch [][]byte) []byte {
+	size := 0
+	for _, p := range batch {
+		size += 4 + len(p)
+	}
+	buf := make([]byte, 0, size)
+	for _, p := range batch {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(p)))
+		buf = append(buf, p...)
+	}
+	return buf
+}
+
+// decodeSpill 末尾写了一半的日志(进程在写文件时退出)丢弃
+func decodeSpill(buf []byte) (entries [][]byte, broken int) {
+	for len(buf) >= 4 {
+		n := binary.BigEndian.Uint32(buf)
+		if uint64(len(buf)-4) < uint64(n) {
+			break
+		}
+		entries = append(entries, buf[4:4+n])
+		buf = buf[4+n:]
+	}
+	return entries, len(buf)
+}
+
+// replaySpill 远端恢复后补发本地文件, 中途失败时保留未发送的部分
+// 只在读取和写回文件时加锁, 发送期间 Write 仍然可以落盘
+func (r *RemoteWriter) replaySpill() {
+	if r.spillPath == "" {
+		return
+	}
+	entries, err := r.takeSpill()
+	if err != nil || len(entries) == 0 {
+		return
+	}
+
+	for i := 0; i < len(entries); i += r.batchSize {
+		end := i + r.batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = r.t.Send(ctx, entries[i:end])
+		cancel()
+		if err != nil {
+			r.restoreSpill(entries[i:])
+			return
+		}
+	}
+}
+
+// takeSpill 读出本地文件的所有日志并删除文件
+func (r *RemoteWriter) takeSpill() ([][]byte, error) {
+	r.spillMu.Lock()
+	defer r.spillMu.Unlock()
+	buf, err := os.ReadFile(r.spillPath)
+	if err != nil {
+		return nil, err
+	}
+	entries, broken := decodeSpill(buf)
+	if broken > 0 {
+		atomic.AddUint64(&r.dropped, 1)
+		handleError(fmt.Errorf("remote log drop %d broken bytes at the end of spill file", broken))
+	}
+	if err = os.Remove(r.spillPath); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// restoreSpill 未发送的日志写回文件, 放在补发期间新落盘的日志前面, 保持顺序
+func (r *RemoteWriter) restoreSpill(entries [][]byte) {
+	r.spillMu.Lock()
+	defer r.spillMu.Unlock()
+	newer, _ := os.ReadFile(r.spillPath)
+	buf := append(encodeSpill(entries), newer...)
+	if err := os.WriteFile(r.spillPath, buf, 0644); err != nil {
+		atomic.AddUint64(&r.dropped, uint64(len(entries)))
+		handleError(fmt.Errorf("remote log drop %d entries, restore spill err:%v", len(entries), err))
+	}
+}
@@ -0,0 +1,152 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeTransport struct {
+	mu   sync.Mutex
+	fail bool
+	got  []string
+}
+
+func (f *fakeTransport) Send(ctx context.Context, batch [][]byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail {
+		return errors.New("remote down")
+	}
+	for _, b := range batch {
+		f.got = append(f.got, string(b))
+	}
+	return nil
+}
+
+func (f *fakeTransport) Close() error {
+	return nil
+}
+
+func (f *fakeTransport) setFail(fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail = fail
+}
+
+func (f *fakeTransport) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.got)
+}
+
+func TestRemoteWriter(t *testing.T) {
+	SetErrorHandler(func(err error) {})
+	defer SetErrorHandler(nil)
+
+	spill := filepath.Join(t.TempDir(), "spill.log")
+	tr := &fakeTransport{}
+	w := NewRemoteWriter(tr, WithBatchSize(2), WithFlushInterval(20*time.Millisecond),
+		WithRetry(1, time.Millisecond), WithSpillFile(spill, 0))
+	defer w.Close()
+
+	_, _ = w.Write([]byte("a\n"))
+	_, _ = w.Write([]byte("b\n"))
+	_, _ = w.Write([]byte("c\n"))
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if tr.count() != 3 {
+		t.Fatalf("got %d", tr.count())
+	}
+
+	tr.setFail(true)
+	_, _ = w.Write([]byte("d\n"))
+	if err := w.Flush(); err == nil {
+		t.Fatal("should fail")
+	}
+	if buf, _ := os.ReadFile(spill); !bytes.Equal(buf, encodeSpill([][]byte{[]byte("d\n")})) {
+		t.Fatalf("spill got %q", buf)
+	}
+
+	tr.setFail(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for tr.count() < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tr.count() != 4 {
+		t.Fatalf("spill should be replayed, got %d", tr.count())
+	}
+	if _, err := os.Stat(spill); !os.IsNotExist(err) {
+		t.Errorf("spill file should be removed")
+	}
+}
+
+func TestReplayLargeSpill(t *testing.T) {
+	spill := filepath.Join(t.TempDir(), "spill.log")
+	var want []string
+	var batch [][]byte
+	for i := 0; i < 2000; i++ {
+		// 带调用栈的日志包含多行, 补发时需要保持为一条
+		line := fmt.Sprintf("%04d %s\n\tat main.go:%d\n", i, strings.Repeat("x", 100), i)
+		want = append(want, line)
+		batch = append(batch, []byte(line))
+	}
+	// 进程在写文件时退出, 末尾只写了一半
+	content := append(encodeSpill(batch), 0, 0, 1)
+	if err := os.WriteFile(spill, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &fakeTransport{}
+	w := NewRemoteWriter(tr, WithBatchSize(100), WithFlushInterval(time.Hour), WithSpillFile(spill, 0))
+	defer w.Close()
+	w.replaySpill()
+	if tr.count() != len(want) {
+		t.Fatalf("got %d", tr.count())
+	}
+	for i, line := range tr.got {
+		if line != want[i] {
+			t.Fatalf("line %d got %q, want %q", i, line, want[i])
+		}
+	}
+}
+
+func TestRemoteWriterCloseRace(t *testing.T) {
+	tr := &fakeTransport{}
+	w := NewRemoteWriter(tr, WithFlushInterval(time.Hour))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	written := 0
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if _, err := w.Write([]byte("a\n")); err != nil {
+					return
+				}
+				mu.Lock()
+				written++
+				mu.Unlock()
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	// Write 成功的日志都要发送出去, 不能在 Close 时丢失
+	if tr.count() != written {
+		t.Fatalf("written %d, sent %d", written, tr.count())
+	}
+}
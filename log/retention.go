@@ -0,0 +1,170 @@
+package log
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Retention 历史日志文件的保留策略
+type Retention struct {
+	MaxAge     time.Duration // 超过时间的删除, 0 不限制
+	MaxBackups int           // 最多保留的历史文件数, 0 不限制
+	Compress   bool          // 历史文件 gzip 压缩
+}
+
+const cleanupInterval = 10 * time.Minute
+
+// SetRetention 默认的文件输出按小时切换和按大小备份, 历史文件由后台协程压缩和清理
+//
+//	log.SetRetention(log.Retention{MaxAge: 14 * 24 * time.Hour, MaxBackups: 30, Compress: true})
+func SetRetention(r Retention) {
+	w, ok := log.logWriter.(*logWriterImpl)
+	if !ok {
+		if a, isAsync := log.logWriter.(*asyncWriter); isAsync {
+			w, ok = a.w.(*logWriterImpl)
+		}
+	}
+	if ok {
+		w.SetRetention(r)
+	}
+}
+
+func (s *logWriterImpl) SetRetention(r Retention) {
+	s.retention.Store(&r)
+	s.cleanupOnce.Do(func() {
+		go s.cleanupLoop()
+	})
+	s.notifyCleanup()
+}
+
+func (s *logWriterImpl) notifyCleanup() {
+	if s.cleanupSignChan == nil {
+		return
+	}
+	select {
+	case s.cleanupSignChan <- struct{}{}:
+	default:
+	}
+}
+
+func (s *logWriterImpl) cleanupLoop() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.cleanupSignChan:
+		}
+		if err := s.cleanup(); err != nil {
+			handleError(err)
+		}
+	}
+}
+
+// backupPattern 当前模块的日志文件: [module_]2006010215.log[.20060102-150405][.gz]
+func backupPattern() *regexp.Regexp {
+	prefix := ""
+	if moduleName != "UNKNOWN" && moduleName != "" {
+		prefix = regexp.QuoteMeta(moduleName) + "_"
+	}
+	return regexp.MustCompile(`^` + prefix + `\d{10}\.log(\.\d{8}-\d{6})?(\.gz)?$`)
+}
+
+type backupFile struct {
+	name    string
+	modTime time.Time
+}
+
+// cleanup 压缩历史文件, 再按数量和时间删除
+func (s *logWriterImpl) cleanup() error {
+	r, _ := s.retention.Load().(*Retention)
+	if r == nil {
+		return nil
+	}
+	pattern := backupPattern()
+
+	entries, err := os.ReadDir(s.baseDir)
+	if err != nil {
+		return err
+	}
+	var files []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !pattern.MatchString(name) || s.isOpenFile(name) {
+			continue
+		}
+		if r.Compress && !strings.HasSuffix(name, ".gz") {
+			if err = gzipFile(filepath.Join(s.baseDir, name)); err != nil {
+				handleError(err)
+			} else {
+				name += ".gz"
+			}
+		}
+		info, err := os.Stat(filepath.Join(s.baseDir, name))
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{name: name, modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+	for i, f := range files {
+		expired := r.MaxAge > 0 && time.Since(f.modTime) > r.MaxAge
+		overflow := r.MaxBackups > 0 && i >= r.MaxBackups
+		if expired || overflow {
+			if err = os.Remove(filepath.Join(s.baseDir, f.name)); err != nil {
+				handleError(err)
+			}
+		}
+	}
+	return nil
+}
+
+// isOpenFile 正在写的文件不能压缩和删除, 每个文件处理前重新读取, 清理期间可能切换了文件
+// 当前小时的文件即使还没记录为当前文件也跳过
+func (s *logWriterImpl) isOpenFile(name string) bool {
+	current, _ := s.currentName.Load().(string)
+	return name == current || name == hourFileName(time.Now())
+}
+
+// gzipFile 压缩成 .gz 并删除原文件, 保留原文件的修改时间用于按时间清理
+func gzipFile(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	_ = os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
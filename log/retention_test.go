@@ -0,0 +1,76 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRetentionCleanup(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := map[string]time.Duration{
+		"2024010210.log":                 0, // 当前文件
+		"2024010209.log":                 time.Hour,
+		"2024010208.log.20240102-085959": 2 * time.Hour,
+		"2024010207.log.gz":              3 * time.Hour,
+		"2023120101.log.gz":              30 * 24 * time.Hour,
+		"other.txt":                      40 * 24 * time.Hour,
+	}
+	for name, age := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("log line\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.Chtimes(path, now.Add(-age), now.Add(-age))
+	}
+
+	w := &logWriterImpl{baseDir: dir}
+	w.currentName.Store("2024010210.log")
+	w.retention.Store(&Retention{MaxAge: 14 * 24 * time.Hour, MaxBackups: 2, Compress: true})
+	if err := w.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	sort.Strings(got)
+	want := []string{"2024010208.log.20240102-085959.gz", "2024010209.log.gz", "2024010210.log", "other.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+// 切换小时文件时新文件名可能还没记录, 当前小时的文件也不能被压缩
+func TestRetentionSkipOpenFile(t *testing.T) {
+	dir := t.TempDir()
+	active := hourFileName(time.Now())
+	old := hourFileName(time.Now().Add(-time.Hour))
+	for _, name := range []string{active, old} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("log line\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w := &logWriterImpl{baseDir: dir}
+	w.currentName.Store(old)
+	w.retention.Store(&Retention{Compress: true})
+	if err := w.cleanup(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{active, old} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s should not be compressed, err:%v", name, err)
+		}
+	}
+}
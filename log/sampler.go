@@ -0,0 +1,90 @@
+package log
+
+import (
+	"github.com/oldbai555/lbtool/utils"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampling 某个等级的采样规则
+// 每个周期内同一条日志(按格式化字符串区分)前 First 条全部输出, 之后每 Thereafter 条输出一条, Thereafter 为 0 时全部丢弃
+type Sampling struct {
+	First      int
+	Thereafter int
+}
+
+type sampleKey struct {
+	level  utils.Level
+	format string
+}
+
+type sampleCounter struct {
+	resetAt int64
+	n       uint64
+}
+
+func (c *sampleCounter) incr(now int64, tick time.Duration) uint64 {
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if now >= resetAt && atomic.CompareAndSwapInt64(&c.resetAt, resetAt, now+int64(tick)) {
+		atomic.StoreUint64(&c.n, 0)
+	}
+	return atomic.AddUint64(&c.n, 1)
+}
+
+type sampler struct {
+	tick     time.Duration
+	rules    map[utils.Level]Sampling
+	counters sync.Map
+	dropped  uint64
+}
+
+func (s *sampler) check(level utils.Level, format string) bool {
+	rule, ok := s.rules[level]
+	if !ok {
+		return true
+	}
+	v, ok := s.counters.Load(sampleKey{level, format})
+	if !ok {
+		v, _ = s.counters.LoadOrStore(sampleKey{level, format}, &sampleCounter{})
+	}
+	n := v.(*sampleCounter).incr(time.Now().UnixNano(), s.tick)
+	if n <= uint64(rule.First) {
+		return true
+	}
+	if rule.Thereafter > 0 && (n-uint64(rule.First))%uint64(rule.Thereafter) == 0 {
+		return true
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	return false
+}
+
+// SetSampling 开启采样, 避免死循环里的错误日志打满磁盘, rules 为空时关闭
+//
+//	log.SetSampling(time.Second, map[utils.Level]log.Sampling{utils.LevelError: {First: 100, Thereafter: 100}})
+func SetSampling(tick time.Duration, rules map[utils.Level]Sampling) {
+	log.SetSampling(tick, rules)
+}
+
+// Sampled 因为采样被丢弃的条数
+func Sampled() uint64 {
+	return log.Sampled()
+}
+
+func (l *Logger) SetSampling(tick time.Duration, rules map[utils.Level]Sampling) {
+	if len(rules) == 0 {
+		l.sampler.Store(nil)
+		return
+	}
+	if tick <= 0 {
+		tick = time.Second
+	}
+	l.sampler.Store(&sampler{tick: tick, rules: rules})
+}
+
+func (l *Logger) Sampled() uint64 {
+	if s := l.sampler.Load(); s != nil {
+		return atomic.LoadUint64(&s.dropped)
+	}
+	return 0
+}
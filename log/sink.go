@@ -0,0 +1,132 @@
+package log
+
+import (
+	"errors"
+	"github.com/oldbai555/lbtool/log/iface"
+	"github.com/oldbai555/lbtool/utils"
+	"io"
+	"os"
+	"sync"
+)
+
+// sink 额外的输出, 只接收不低于 level 的日志
+type sink struct {
+	w     iface.LogWriter
+	level utils.Level
+}
+
+// AddWriter 增加一个输出, 例如控制台收 DEBUG, 文件收 INFO, 远程收 ERROR
+// 默认的文件输出仍然保留, 由 SetLogLevel 控制
+func AddWriter(w iface.LogWriter, level utils.Level) {
+	log.AddWriter(w, level)
+}
+
+// ResetWriters 移除所有通过 AddWriter 增加的输出
+func ResetWriters() {
+	log.ResetWriters()
+}
+
+// SetWriter 替换默认的输出, 需要在打日志之前调用
+func SetWriter(w iface.LogWriter) {
+	log.SetWriter(w)
+}
+
+// sinks 写时复制, 写日志时不加锁
+func (l *Logger) AddWriter(w iface.LogWriter, level utils.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var sinks []*sink
+	if old := l.sinks.Load(); old != nil {
+		sinks = append(sinks, *old...)
+	}
+	sinks = append(sinks, &sink{w: w, level: level})
+	l.sinks.Store(&sinks)
+}
+
+func (l *Logger) ResetWriters() {
+	l.sinks.Store(nil)
+}
+
+func (l *Logger) SetWriter(w iface.LogWriter) {
+	l.logWriter = w
+}
+
+// writeTo 实现了 EntryWriter 的优先使用原始日志内容
+// writeTo p 来自缓冲池, 只有 EntryWriter 约定不保留 p, 普通的 LogWriter 交给它一份复制
+func writeTo(w iface.LogWriter, e *Entry, p []byte) (int, error) {
+	if ew, ok := w.(iface.EntryWriter); ok {
+		return ew.WriteEntry(e, p)
+	}
+	return w.Write(append([]byte(nil), p...))
+}
+
+func (l *Logger) writeSinks(e *Entry, p []byte) error {
+	sinks := l.sinks.Load()
+	if sinks == nil {
+		return nil
+	}
+	var errs []error
+	for _, s := range *sinks {
+		if e.Level < s.level {
+			continue
+		}
+		if _, err := writeTo(s.w, e, p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stdout 测试时替换
+var stdout io.Writer = os.Stdout
+
+var _ iface.LogWriter = (*stdWriter)(nil)
+var _ iface.EntryWriter = (*stdWriter)(nil)
+
+type stdWriter struct {
+	io.Writer
+}
+
+// NewStdWriter 把 io.Writer 包装成 LogWriter
+func NewStdWriter(w io.Writer) iface.LogWriter {
+	return &stdWriter{Writer: w}
+}
+
+var _ iface.EntryWriter = (*consoleWriter)(nil)
+
+// consoleWriter 使用 ConsoleEncoder 输出到标准输出
+type consoleWriter struct {
+	mu sync.Mutex
+}
+
+// NewConsoleWriter 输出到标准输出, 使用带颜色的对齐格式
+// 非 release 环境默认的文件输出已经会打印到控制台
+func NewConsoleWriter() iface.LogWriter {
+	return &consoleWriter{}
+}
+
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return stdout.Write(p)
+}
+
+func (c *consoleWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return c.Write([]byte(ConsoleEncoder(e)))
+}
+
+func (c *consoleWriter) Flush() error {
+	return nil
+}
+
+// WriteEntry io.Writer 约定不保留 p, 直接使用缓冲池里的内容
+func (s *stdWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return s.Writer.Write(p)
+}
+
+func (s *stdWriter) Flush() error {
+	if f, ok := s.Writer.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+		return f.Sync()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package log
+
+import (
+	"context"
+	"github.com/oldbai555/lbtool/log/iface"
+	"github.com/oldbai555/lbtool/utils"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// SlogHandler 把 slog 的日志写入 Logger, 和本包的日志使用相同的输出和格式
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(nil)))
+type SlogHandler struct {
+	l      *Logger
+	fields Fields
+	group  string
+}
+
+// NewSlogHandler l 为 nil 时使用默认实例
+func NewSlogHandler(l *Logger) *SlogHandler {
+	if l == nil {
+		l = log
+	}
+	return &SlogHandler{l: l}
+}
+
+// Enabled 这里拿不到调用位置, 设置了包等级时按最低的等级放行, Handle 中再按调用方所在的包判断
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return fromSlogLevel(level) >= h.l.minLevel()
+}
+
+// Handle 和本包的日志一样经过包等级、采样、去重
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := fromSlogLevel(r.Level)
+	if !h.l.enabledAt(level, r.PC) {
+		return nil
+	}
+	if !h.l.sampled(level, r.Message) {
+		return nil
+	}
+
+	fields := make(Fields, len(h.fields)+r.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(fields, h.group, a)
+		return true
+	})
+	if len(fields) == 0 {
+		fields = nil
+	}
+
+	e := getEntry()
+	defer putEntry(e)
+	h.l.fillEntry(e, ctx, level, r.Message, fields)
+	e.Time = r.Time
+	if r.PC != 0 && !h.l.noCaller.Load() {
+		if c := callerForPC(r.PC); c != nil {
+			e.File, e.Line, e.Func, e.Caller = c.file, c.line, c.fn, c.caller
+		}
+	}
+	return h.l.dedupOutput(e)
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	for _, a := range attrs {
+		addSlogAttr(fields, h.group, a)
+	}
+	return &SlogHandler{l: h.l, fields: fields, group: h.group}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	group := name
+	if h.group != "" {
+		group = h.group + "." + name
+	}
+	return &SlogHandler{l: h.l, fields: h.fields, group: group}
+}
+
+// addSlogAttr 分组展开为 group.key
+func addSlogAttr(fields Fields, group string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if group != "" && key != "" {
+		key = group + "." + key
+	} else if key == "" {
+		key = group
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(fields, key, ga)
+		}
+		return
+	}
+	fields[key] = a.Value.Any()
+}
+
+func fromSlogLevel(level slog.Level) utils.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return utils.LevelDebug
+	case level < slog.LevelWarn:
+		return utils.LevelInfo
+	case level < slog.LevelError:
+		return utils.LevelWarn
+	default:
+		return utils.LevelError
+	}
+}
+
+func toSlogLevel(level utils.Level) slog.Level {
+	switch level {
+	case utils.LevelDebug:
+		return slog.LevelDebug
+	case utils.LevelInfo:
+		return slog.LevelInfo
+	case utils.LevelWarn:
+		return slog.LevelWarn
+	case utils.LevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelError + 4
+	}
+}
+
+var _ iface.EntryWriter = (*slogWriter)(nil)
+
+// slogWriter 把本包的日志转发给已有的 slog.Logger
+type slogWriter struct {
+	h slog.Handler
+}
+
+// NewSlogWriter 作为输出使用, log.AddWriter(log.NewSlogWriter(slog.Default()), utils.LevelInfo)
+// 不要同时把 slog 的默认 handler 设置为 SlogHandler, 否则会循环
+func NewSlogWriter(l *slog.Logger) iface.LogWriter {
+	return &slogWriter{h: l.Handler()}
+}
+
+func (s *slogWriter) Write(p []byte) (int, error) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, strings.TrimRight(string(p), "\n"), 0)
+	return len(p), s.h.Handle(context.Background(), r)
+}
+
+func (s *slogWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	level := toSlogLevel(e.Level)
+	if !s.h.Enabled(context.Background(), level) {
+		return len(p), nil
+	}
+	r := slog.NewRecord(e.Time, level, e.Msg, 0)
+	if e.Module != "" {
+		r.AddAttrs(slog.String("module", e.Module))
+	}
+	if e.Hint != "" {
+		r.AddAttrs(slog.String("hint", e.Hint))
+	}
+	if e.TraceId != "" {
+		r.AddAttrs(slog.String("trace_id", e.TraceId))
+	}
+	if e.Caller != "" {
+		r.AddAttrs(slog.String("caller", e.Caller))
+	}
+	for k, v := range e.Fields {
+		r.AddAttrs(slog.Any(k, v))
+	}
+	if e.Stack != "" {
+		r.AddAttrs(slog.String("stack", e.Stack))
+	}
+	return len(p), s.h.Handle(context.Background(), r)
+}
+
+func (s *slogWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,102 @@
+package log
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/oldbai555/lbtool/utils"
+	"time"
+)
+
+const DefaultSlowThreshold = 200 * time.Millisecond
+
+// SQLLogger 给 orm 使用, 每条 sql 执行完调用 Trace
+// 普通语句为 DEBUG, 超过慢查询阈值为 WARN, 出错为 ERROR
+type SQLLogger struct {
+	l             *Logger
+	slowThreshold time.Duration
+	ignoreNoRows  bool
+	logLevel      utils.Level
+	maxSQLLen     int
+}
+
+type SQLOption func(*SQLLogger)
+
+// WithSQLLogger 默认使用包级别的实例
+func WithSQLLogger(l *Logger) SQLOption {
+	return func(s *SQLLogger) {
+		s.l = l
+	}
+}
+
+// WithSlowThreshold 为 0 时不区分慢查询
+func WithSlowThreshold(d time.Duration) SQLOption {
+	return func(s *SQLLogger) {
+		s.slowThreshold = d
+	}
+}
+
+// WithIgnoreNoRows sql.ErrNoRows 不当作错误
+func WithIgnoreNoRows() SQLOption {
+	return func(s *SQLLogger) {
+		s.ignoreNoRows = true
+	}
+}
+
+// WithSQLLevel 普通语句的等级, 默认 DEBUG
+func WithSQLLevel(level utils.Level) SQLOption {
+	return func(s *SQLLogger) {
+		s.logLevel = level
+	}
+}
+
+// WithMaxSQLLen 超过长度的 sql 截断, 避免批量插入刷屏
+func WithMaxSQLLen(n int) SQLOption {
+	return func(s *SQLLogger) {
+		s.maxSQLLen = n
+	}
+}
+
+func NewSQLLogger(opts ...SQLOption) *SQLLogger {
+	s := &SQLLogger{
+		l:             log,
+		slowThreshold: DefaultSlowThreshold,
+		logLevel:      utils.LevelDebug,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Trace rows 未知时传 -1
+func (s *SQLLogger) Trace(ctx context.Context, begin time.Time, query string, args []interface{}, rows int64, err error) {
+	cost := time.Since(begin)
+	if s.maxSQLLen > 0 && len(query) > s.maxSQLLen {
+		query = query[:s.maxSQLLen] + "..."
+	}
+	fields := Fields{
+		"sql":     query,
+		"cost_ms": float64(cost.Microseconds()) / 1000,
+	}
+	if len(args) > 0 {
+		fields["args"] = args
+	}
+	if rows >= 0 {
+		fields["rows"] = rows
+	}
+
+	level, msg := s.logLevel, "sql"
+	switch {
+	case err != nil && !(s.ignoreNoRows && errors.Is(err, sql.ErrNoRows)):
+		level, msg = utils.LevelError, "sql error"
+		fields["err"] = err.Error()
+	case s.slowThreshold > 0 && cost >= s.slowThreshold:
+		level, msg = utils.LevelWarn, "slow sql"
+		fields["slow_threshold_ms"] = s.slowThreshold.Milliseconds()
+	}
+
+	if writeErr := s.l.write(ctx, fields, level, msg, nil); writeErr != nil {
+		handleError(writeErr)
+	}
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"github.com/pkg/errors"
+	"runtime"
+	"strings"
+)
+
+// EnableStack 不低于 level 的日志附带调用栈, 例如 EnableStack(utils.LevelError)
+// 参数中的 error 自带调用栈时(lberr, pkg/errors)优先输出错误创建时的调用栈
+func EnableStack(level utils.Level) {
+	log.EnableStack(level)
+}
+
+func DisableStack() {
+	log.DisableStack()
+}
+
+func (l *Logger) EnableStack(level utils.Level) {
+	l.stackLv.Store(int32(level))
+	l.stackOn.Store(true)
+}
+
+func (l *Logger) DisableStack() {
+	l.stackOn.Store(false)
+}
+
+type lbStackErr interface {
+	Stack() string
+}
+
+type pkgStackErr interface {
+	StackTrace() errors.StackTrace
+}
+
+// stack 需要直接在 write 中调用, 保证层数一致
+func (l *Logger) stack(args []interface{}) string {
+	for _, arg := range args {
+		switch err := arg.(type) {
+		case lbStackErr:
+			if s := err.Stack(); s != "" {
+				return s
+			}
+		case pkgStackErr:
+			return strings.TrimPrefix(fmt.Sprintf("%+v", err.StackTrace()), "\n") + "\n"
+		}
+	}
+
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(l.getSkipCall()+1, pcs)
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		_, _ = fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package log
+
+import (
+	"context"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"sync/atomic"
+)
+
+// traceKeys 没有 otel span 时依次从 ctx 中查找的 key, 值需要是 string
+var traceKeys atomic.Pointer[[]interface{}]
+
+// SetTraceKeys 设置从 ctx 中读取 trace id / request id 的 key, 例如网关透传的 request id
+// otel span 优先, 传空时只使用 otel span
+//
+//	log.SetTraceKeys(requestIdKey{})
+func SetTraceKeys(keys ...interface{}) {
+	if len(keys) == 0 {
+		traceKeys.Store(nil)
+		return
+	}
+	traceKeys.Store(&keys)
+}
+
+// GetTraceIdFromCtx 获取 ctx 中的 trace id, 规则同日志中的 trace id
+func GetTraceIdFromCtx(ctx context.Context) string {
+	traceId, _ := traceFromCtx(ctx)
+	return traceId
+}
+
+// traceFromCtx 优先取 ctx 中 otel span 的 trace id 和 span id, 没有时按 SetTraceKeys 的 key 查找
+func traceFromCtx(ctx context.Context) (traceId, spanId string) {
+	if ctx == nil {
+		return "", ""
+	}
+	if sc := oteltrace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc.TraceID().String(), sc.SpanID().String()
+	}
+	if keys := traceKeys.Load(); keys != nil {
+		for _, k := range *keys {
+			if v, _ := ctx.Value(k).(string); v != "" {
+				return v, ""
+			}
+		}
+	}
+	return "", ""
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -41,14 +42,15 @@ func newLogWriterImpl() *logWriterImpl {
 		baseDir:                  defaultBaseDir,
 		maxFileSize:              DefaultMaxFileSize,
 		checkFileFullIntervalSec: utils.Seconds * 5,
-		bufCh:                    make(chan []byte, DefaultChannelNumber),
+		bufCh:                    make(chan *[]byte, DefaultChannelNumber),
 		flushSignChan:            make(chan struct{}, DefaultChannelNumber),
 		flushDoneSignChan:        make(chan error, DefaultChannelNumber),
+		cleanupSignChan:          make(chan struct{}, 1),
 	}
 
 	go func() {
 		err := writer.LoopDoLogic()
-		panic(any(err))
+		handleError(err)
 	}()
 
 	writer.isFlushing.Store(false)
@@ -65,31 +67,53 @@ type logWriterImpl struct {
 	isFileFull               bool          // 文件是否已经满了
 	currentFileName          string        // 当前文件名
 	openCurrentFileTime      *time.Time    // 打开文件时间
-	bufCh                    chan []byte   // 缓冲区
+	bufCh                    chan *[]byte  // 缓冲区
 	isFlushing               atomic.Value  // 刷盘标识
 	flushSignChan            chan struct{} // 结束 flush 信号
 	flushDoneSignChan        chan error    // 接收 flush 错误
+	cleanupSignChan          chan struct{} // 通知清理历史文件
+	retention                atomic.Value  // *Retention
+	currentName              atomic.Value  // 当前文件名, 清理协程读取
+	cleanupOnce              sync.Once
 }
 
 // Write 写日志
 func (s *logWriterImpl) Write(p []byte) (n int, err error) {
-
-	s.bufCh <- p
+	s.push(p)
 	if !env.IsRelease() {
 		fmt.Printf(string(p))
 	}
 	return len(p), nil
 }
 
+// WriteEntry dev 环境控制台使用 ConsoleEncoder 输出, 文件内容不变
+func (s *logWriterImpl) WriteEntry(e *Entry, p []byte) (n int, err error) {
+	s.push(p)
+	if env.IsDev() {
+		fmt.Print(ConsoleEncoder(e))
+	} else if !env.IsRelease() {
+		fmt.Printf(string(p))
+	}
+	return len(p), nil
+}
+
+// push p 返回后会被复用, 复制一份交给写文件的协程, 写完后回收
+func (s *logWriterImpl) push(p []byte) {
+	buf := getBuf()
+	*buf = append(*buf, p...)
+	s.bufCh <- buf
+}
+
 // LoopDoLogic 循环执行写日志逻辑
 func (s *logWriterImpl) LoopDoLogic() error {
 	// 看看需不需要追加继续写文件
 	doWriteMoreAsPossible := func(buf []byte) error {
 		for {
-			var moreBuf []byte
+			var moreBuf *[]byte
 			select {
 			case moreBuf = <-s.bufCh:
-				buf = append(buf, moreBuf...)
+				buf = append(buf, *moreBuf...)
+				putBuf(moreBuf)
 			default:
 			}
 
@@ -129,14 +153,21 @@ func (s *logWriterImpl) LoopDoLogic() error {
 	for {
 		select {
 		case buf := <-s.bufCh:
-			if err := doWriteMoreAsPossible(buf); err != nil {
-				return err
+			// 写失败时丢弃这批日志, 不退出循环, 否则 Write 会一直阻塞
+			err := doWriteMoreAsPossible(*buf)
+			putBuf(buf)
+			if err != nil {
+				handleError(err)
 			}
 		case _ = <-s.flushSignChan:
 			if err := doWriteMoreAsPossible([]byte{}); err != nil {
 				s.finishFlush(err)
 				break
 			}
+			if s.fp == nil {
+				s.finishFlush(nil)
+				break
+			}
 			if err := s.fp.Sync(); err != nil {
 				s.finishFlush(err)
 				break
@@ -168,9 +199,9 @@ func (s *logWriterImpl) checkFileIsFull() (bool, error) {
 // tryOpenNewFile 尝试开启新文件
 func (s *logWriterImpl) tryOpenNewFile() error {
 	var err error
-	fileName := fmt.Sprintf("%s.log", time.Now().Format("2006010215"))
-	if moduleName != "UNKNOWN" && moduleName != "" {
-		fileName = fmt.Sprintf("%s_%s", moduleName, fileName)
+	fileName := hourFileName(time.Now())
+	if s.fp != nil && fileName == s.currentFileName {
+		return nil
 	}
 	if s.fp == nil {
 		if _, err = os.Stat(s.baseDir); err != nil {
@@ -183,9 +214,14 @@ func (s *logWriterImpl) tryOpenNewFile() error {
 		}
 	}
 
-	if s.fp, err = os.OpenFile(s.baseDir+"/"+fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0755); err != nil {
+	fp, err := os.OpenFile(s.baseDir+"/"+fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0755)
+	if err != nil {
 		return err
 	}
+	// 先记下新文件名再通知清理, 否则清理协程会把刚打开的文件当成历史文件压缩掉
+	s.currentName.Store(fileName)
+	old := s.fp
+	s.fp = fp
 
 	openFileTime := time.Now()
 	s.openCurrentFileTime = &openFileTime
@@ -193,9 +229,23 @@ func (s *logWriterImpl) tryOpenNewFile() error {
 	s.lastCheckIsFullAt = 0
 	s.currentFileName = fileName
 
+	// 切换到新的小时文件, 旧文件关闭后可以压缩
+	if old != nil {
+		_ = old.Close()
+		s.notifyCleanup()
+	}
 	return nil
 }
 
+// hourFileName 按小时切换的文件名 [module_]2006010215.log
+func hourFileName(t time.Time) string {
+	fileName := fmt.Sprintf("%s.log", t.Format("2006010215"))
+	if moduleName != "UNKNOWN" && moduleName != "" {
+		fileName = fmt.Sprintf("%s_%s", moduleName, fileName)
+	}
+	return fileName
+}
+
 // isFlushingNow 是否正在刷缓冲区
 func (s *logWriterImpl) isFlushingNow() bool {
 	return s.isFlushing.Load().(bool)
@@ -217,9 +267,11 @@ func (s *logWriterImpl) finishFlush(err error) {
 // checkAndRotateFile 检查文件大小并决定是否需要备份和创建新文件
 func (s *logWriterImpl) checkAndRotateFile() error {
 	// 检查时间间隔
-	if s.lastCheckIsFullAt+s.checkFileFullIntervalSec < time.Now().Unix() {
+	now := time.Now().Unix()
+	if s.lastCheckIsFullAt+s.checkFileFullIntervalSec > now {
 		return nil
 	}
+	s.lastCheckIsFullAt = now
 
 	// 获取当前文件的大小
 	fileInfo, err := s.fp.Stat()
@@ -250,18 +302,21 @@ func (s *logWriterImpl) rotateFile() error {
 	backupFilePath := filepath.Join(s.baseDir, backupFileName)
 
 	// 将当前文件重命名为备份文件
-	err = os.Rename(s.currentFileName, backupFilePath)
+	currentFilePath := filepath.Join(s.baseDir, s.currentFileName)
+	err = os.Rename(currentFilePath, backupFilePath)
 	if err != nil {
 		return fmt.Errorf("重命名文件失败: %w", err)
 	}
 
 	// 创建新的日志文件
-	newFile, err := os.Create(s.currentFileName)
+	newFile, err := os.Create(currentFilePath)
 	if err != nil {
 		return fmt.Errorf("创建新日志文件失败: %w", err)
 	}
 	s.fp = newFile
+	s.notifyCleanup()
 	return nil
 }
 
 var _ iface.LogWriter = (*logWriterImpl)(nil)
+var _ iface.EntryWriter = (*logWriterImpl)(nil)
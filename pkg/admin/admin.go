@@ -0,0 +1,220 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/metrics"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultCheckTimeout = 3 * time.Second
+
+	PathHealthz   = "/healthz"
+	PathReadyz    = "/readyz"
+	PathMetrics   = "/metrics"
+	PathBuildInfo = "/buildinfo"
+	PathPprof     = "/debug/pprof/"
+)
+
+// Admin 运维接口, 可以挂到已有的 ServeMux 上, 也可以单独监听一个端口
+type Admin struct {
+	prefix       string
+	checkTimeout time.Duration
+	disablePprof bool
+
+	checkers   []Checker
+	checkersMu sync.RWMutex
+	notReady   int32
+
+	server *http.Server
+}
+
+type Option func(*Admin)
+
+// WithPrefix 挂载路径前缀, 例如 /admin
+func WithPrefix(prefix string) Option {
+	return func(a *Admin) {
+		a.prefix = strings.TrimRight(prefix, "/")
+	}
+}
+
+// WithCheckTimeout 单次就绪检查的超时时间
+func WithCheckTimeout(timeout time.Duration) Option {
+	return func(a *Admin) {
+		a.checkTimeout = timeout
+	}
+}
+
+// WithoutPprof 不暴露 pprof, 对外网开放的端口建议关闭
+func WithoutPprof() Option {
+	return func(a *Admin) {
+		a.disablePprof = true
+	}
+}
+
+func New(ops ...Option) *Admin {
+	a := &Admin{
+		checkTimeout: DefaultCheckTimeout,
+	}
+	for i := range ops {
+		ops[i](a)
+	}
+	return a
+}
+
+// AddChecker 注册就绪检查项
+func (a *Admin) AddChecker(checkers ...Checker) {
+	a.checkersMu.Lock()
+	defer a.checkersMu.Unlock()
+	a.checkers = append(a.checkers, checkers...)
+}
+
+// SetReady 服务退出前置为 false, 让负载均衡摘掉流量
+func (a *Admin) SetReady(ready bool) {
+	if ready {
+		atomic.StoreInt32(&a.notReady, 0)
+		return
+	}
+	atomic.StoreInt32(&a.notReady, 1)
+}
+
+// Mount 把运维接口注册到 mux 上
+func (a *Admin) Mount(mux *http.ServeMux) {
+	mux.HandleFunc(a.prefix+PathHealthz, a.healthz)
+	mux.HandleFunc(a.prefix+PathReadyz, a.readyz)
+	mux.HandleFunc(a.prefix+PathBuildInfo, a.buildInfo)
+	mux.Handle(a.prefix+PathMetrics, metrics.Handler())
+	if a.disablePprof {
+		return
+	}
+	// pprof.Index 依赖 /debug/pprof/ 前缀解析 profile 名, 挂在前缀下时需要去掉前缀
+	pprofIndex := http.StripPrefix(a.prefix, http.HandlerFunc(pprof.Index))
+	mux.Handle(a.prefix+PathPprof, pprofIndex)
+	mux.HandleFunc(a.prefix+PathPprof+"cmdline", pprof.Cmdline)
+	mux.HandleFunc(a.prefix+PathPprof+"profile", pprof.Profile)
+	mux.HandleFunc(a.prefix+PathPprof+"symbol", pprof.Symbol)
+	mux.HandleFunc(a.prefix+PathPprof+"trace", pprof.Trace)
+}
+
+// Handler 只包含运维接口的 handler
+func (a *Admin) Handler() http.Handler {
+	mux := http.NewServeMux()
+	a.Mount(mux)
+	return mux
+}
+
+// Start 单独监听一个端口, 不阻塞
+func (a *Admin) Start(addr string) error {
+	a.server = &http.Server{
+		Addr:    addr,
+		Handler: a.Handler(),
+	}
+	errCh := make(chan error, 1)
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("admin server err:%v", err)
+			errCh <- err
+		}
+	}()
+	// 端口被占用等错误会马上返回
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(100 * time.Millisecond):
+		log.Infof("admin server listen on %s", addr)
+		return nil
+	}
+}
+
+// Stop 关闭单独监听的端口
+func (a *Admin) Stop(ctx context.Context) error {
+	if a.server == nil {
+		return nil
+	}
+	return a.server.Shutdown(ctx)
+}
+
+// CheckResult 单个检查项的结果
+type CheckResult struct {
+	Name   string `json:"name"`
+	Ok     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	CostMs int64  `json:"cost_ms"`
+}
+
+// Check 并发执行所有检查项
+func (a *Admin) Check(ctx context.Context) (ok bool, results []*CheckResult) {
+	a.checkersMu.RLock()
+	checkers := make([]Checker, len(a.checkers))
+	copy(checkers, a.checkers)
+	a.checkersMu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, a.checkTimeout)
+	defer cancel()
+
+	results = make([]*CheckResult, len(checkers))
+	var wg sync.WaitGroup
+	for i, c := range checkers {
+		wg.Add(1)
+		go func(i int, c Checker) {
+			defer wg.Done()
+			start := time.Now()
+			res := &CheckResult{Name: c.Name(), Ok: true}
+			if err := c.Check(ctx); err != nil {
+				res.Ok = false
+				res.Error = err.Error()
+			}
+			res.CostMs = time.Since(start).Milliseconds()
+			results[i] = res
+		}(i, c)
+	}
+	wg.Wait()
+
+	ok = true
+	for _, res := range results {
+		if !res.Ok {
+			ok = false
+			log.Warnf("ready check %s failed, err:%s", res.Name, res.Error)
+		}
+	}
+	return ok, results
+}
+
+func (a *Admin) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+func (a *Admin) readyz(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&a.notReady) == 1 {
+		writeJson(w, http.StatusServiceUnavailable, map[string]interface{}{"status": "shutting down"})
+		return
+	}
+	ok, results := a.Check(r.Context())
+	status := http.StatusOK
+	statusStr := "ok"
+	if !ok {
+		status = http.StatusServiceUnavailable
+		statusStr = "unavailable"
+	}
+	writeJson(w, status, map[string]interface{}{"status": statusStr, "checks": results})
+}
+
+func (a *Admin) buildInfo(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, http.StatusOK, GetBuildInfo())
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("err:%v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdmin_Mount(t *testing.T) {
+	a := New(WithPrefix("/admin"))
+	a.AddChecker(NewFuncChecker("ok", func(ctx context.Context) error {
+		return nil
+	}))
+
+	mux := http.NewServeMux()
+	a.Mount(mux)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/admin/healthz", http.StatusOK, `"ok"`},
+		{"/admin/readyz", http.StatusOK, `"name":"ok"`},
+		{"/admin/buildinfo", http.StatusOK, `"go_version"`},
+		{"/admin/metrics", http.StatusOK, "go_goroutines"},
+		{"/admin/debug/pprof/", http.StatusOK, "goroutine"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s: status %d body %s", tt.path, w.Code, w.Body.String())
+		}
+	}
+
+	a.AddChecker(NewFuncChecker("redis", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz status %d", w.Code)
+	}
+
+	a.SetReady(false)
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz status %d", w.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package admin
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// 构建信息, 编译时通过 -ldflags 注入, 例如:
+// go build -ldflags "-X github.com/oldbai555/lbtool/pkg/admin.Version=v1.0.0 -X github.com/oldbai555/lbtool/pkg/admin.GitCommit=$(git rev-parse HEAD)"
+var (
+	Version   = "unknown"
+	GitCommit = "unknown"
+	BuildTime = "unknown"
+)
+
+// BuildInfo 构建信息
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module"`
+}
+
+func GetBuildInfo() *BuildInfo {
+	info := &BuildInfo{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.Module = bi.Main.Path
+		if info.Version == "unknown" && bi.Main.Version != "" {
+			info.Version = bi.Main.Version
+		}
+		if info.GitCommit == "unknown" {
+			for _, s := range bi.Settings {
+				if s.Key == "vcs.revision" {
+					info.GitCommit = s.Value
+				}
+			}
+		}
+	}
+	return info
+}
@@ -0,0 +1,43 @@
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"github.com/go-redis/redis/v8"
+)
+
+// Checker 就绪检查项
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+type funcChecker struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c *funcChecker) Name() string {
+	return c.name
+}
+
+func (c *funcChecker) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// NewFuncChecker 用函数构造检查项, 例如 nsq 生产者的 Ping
+func NewFuncChecker(name string, fn func(ctx context.Context) error) Checker {
+	return &funcChecker{name: name, fn: fn}
+}
+
+// NewDBChecker 数据库连通性检查
+func NewDBChecker(name string, db *sql.DB) Checker {
+	return NewFuncChecker(name, db.PingContext)
+}
+
+// NewRedisChecker redis 连通性检查
+func NewRedisChecker(name string, client redis.UniversalClient) Checker {
+	return NewFuncChecker(name, func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	})
+}
@@ -0,0 +1,323 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/env"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/admin"
+	"github.com/oldbai555/lbtool/pkg/signal"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAppRunning = errors.New("app is already running")
+)
+
+// App 服务入口, 从一份配置初始化日志和运维接口, 按顺序启动组件, 收到信号后逆序关闭
+type App struct {
+	name       string
+	configFile string
+	envPrefix  string
+	source     bconf.DataSource
+
+	viper *viper.Viper
+	conf  *Config
+	admin *admin.Admin
+
+	initOnce sync.Once
+	initErr  error
+
+	components []Component
+	mu         sync.Mutex
+	running    bool
+
+	failCh chan error
+}
+
+type Option func(*App)
+
+// WithConfigFile 配置文件, 支持 yaml, json, toml 等 viper 支持的格式
+func WithConfigFile(path string) Option {
+	return func(a *App) {
+		a.configFile = path
+	}
+}
+
+// WithDataSource 从配置数据源加载配置, 与配置文件同时存在时数据源优先
+func WithDataSource(source bconf.DataSource) Option {
+	return func(a *App) {
+		a.source = source
+	}
+}
+
+// WithEnvPrefix 环境变量前缀, 例如前缀 ORDER 时 ORDER_HTTP_ADDR 覆盖 http.addr
+func WithEnvPrefix(prefix string) Option {
+	return func(a *App) {
+		a.envPrefix = prefix
+	}
+}
+
+// WithComponent 追加组件, 按添加顺序启动
+func WithComponent(cs ...Component) Option {
+	return func(a *App) {
+		a.components = append(a.components, cs...)
+	}
+}
+
+func New(name string, ops ...Option) *App {
+	a := &App{
+		name:   name,
+		viper:  viper.New(),
+		failCh: make(chan error, 1),
+	}
+	for i := range ops {
+		ops[i](a)
+	}
+	return a
+}
+
+// Init 加载配置, 初始化日志和运维接口; 可以提前调用以便读取业务配置, Run 时会自动调用
+func (a *App) Init() error {
+	a.initOnce.Do(func() {
+		a.initErr = a.init()
+	})
+	return a.initErr
+}
+
+func (a *App) init() error {
+	a.viper.SetDefault("name", a.name)
+	a.viper.SetDefault("shutdown_timeout", DefaultShutdownTimeout)
+	if a.envPrefix != "" {
+		a.viper.SetEnvPrefix(a.envPrefix)
+		a.viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+		a.viper.AutomaticEnv()
+	}
+
+	if a.configFile != "" {
+		a.viper.SetConfigFile(a.configFile)
+		if err := a.viper.ReadInConfig(); err != nil {
+			return fmt.Errorf("read config %s: %w", a.configFile, err)
+		}
+	}
+	if a.source != nil {
+		list, err := a.source.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		for _, d := range list {
+			a.viper.Set(d.Key, d.Val)
+		}
+	}
+
+	// AutomaticEnv 只对 Get 生效, Unmarshal 前需要显式绑定
+	if a.envPrefix != "" {
+		for _, key := range []string{"name", "mode", "log.level", "log.hint", "http.addr", "grpc.addr", "admin.addr", "shutdown_delay", "shutdown_timeout"} {
+			if err := a.viper.BindEnv(key); err != nil {
+				return err
+			}
+		}
+	}
+
+	conf := &Config{}
+	if err := a.viper.Unmarshal(conf); err != nil {
+		return fmt.Errorf("unmarshal config: %w", err)
+	}
+	a.conf = conf
+
+	level, err := conf.logLevel()
+	if err != nil {
+		return err
+	}
+	log.SetLogLevel(level)
+	if conf.Log.Hint != "" {
+		log.SetDefaultHint(conf.Log.Hint)
+	}
+	log.SetModuleName(conf.Name)
+	if conf.Mode != "" {
+		env.SetMode(conf.Mode)
+	}
+
+	if conf.Admin.Addr != "" {
+		a.admin = admin.New()
+		// 运维接口最先启动, 启动过程中也能访问 healthz
+		a.components = append([]Component{NewAdminServer(conf.Admin.Addr, a.admin)}, a.components...)
+	}
+	return nil
+}
+
+// Config 公共配置, Init 之后可用
+func (a *App) Config() *Config {
+	return a.conf
+}
+
+// Viper 合并后的全部配置
+func (a *App) Viper() *viper.Viper {
+	return a.viper
+}
+
+// UnmarshalKey 读取业务自己的配置段
+func (a *App) UnmarshalKey(key string, out interface{}) error {
+	if err := a.Init(); err != nil {
+		return err
+	}
+	return a.viper.UnmarshalKey(key, out)
+}
+
+// Admin 运维接口, 未配置 admin.addr 时为 nil; 可以通过 AddChecker 注册就绪检查
+func (a *App) Admin() *admin.Admin {
+	return a.admin
+}
+
+// Add 追加组件, 需要在 Run 之前调用
+func (a *App) Add(cs ...Component) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.components = append(a.components, cs...)
+}
+
+// Http 按 http.addr 创建 http 服务组件
+func (a *App) Http(handler http.Handler) (*HttpServer, error) {
+	if err := a.Init(); err != nil {
+		return nil, err
+	}
+	if a.conf.Http.Addr == "" {
+		return nil, fmt.Errorf("http.addr is not configured")
+	}
+	s := NewHttpServer(a.conf.Http.Addr, handler)
+	a.Add(s)
+	return s, nil
+}
+
+// Grpc 按 grpc.addr 创建 grpc 服务组件
+func (a *App) Grpc(server *grpc.Server) (*GrpcServer, error) {
+	if err := a.Init(); err != nil {
+		return nil, err
+	}
+	if a.conf.Grpc.Addr == "" {
+		return nil, fmt.Errorf("grpc.addr is not configured")
+	}
+	s := NewGrpcServer(a.conf.Grpc.Addr, server)
+	a.Add(s)
+	return s, nil
+}
+
+// Fail 组件运行中出现不可恢复的错误时调用, App 会开始退出, Run 返回该错误
+func (a *App) Fail(err error) {
+	select {
+	case a.failCh <- err:
+	default:
+	}
+}
+
+// Run 启动所有组件并阻塞, 直到 ctx 结束, 收到退出信号或者组件调用 Fail
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Init(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	if a.running {
+		a.mu.Unlock()
+		return ErrAppRunning
+	}
+	a.running = true
+	components := make([]Component, len(a.components))
+	copy(components, a.components)
+	a.mu.Unlock()
+
+	defer func() {
+		_ = log.GetLogger().Flush()
+	}()
+
+	started, err := a.start(ctx, components)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		if stopErr := a.stop(started); stopErr != nil {
+			log.Errorf("err:%v", stopErr)
+		}
+		return err
+	}
+	log.Infof("%s started", a.conf.Name)
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Infof("context done, shutting down")
+	case sig := <-signal.GetSignalChan():
+		log.Infof("receive signal %s, shutting down", sig)
+	case runErr = <-a.failCh:
+		log.Errorf("component failed, shutting down, err:%v", runErr)
+	}
+
+	if a.admin != nil {
+		a.admin.SetReady(false)
+		if a.conf.ShutdownDelay > 0 {
+			time.Sleep(a.conf.ShutdownDelay)
+		}
+	}
+
+	if err := a.stop(started); err != nil {
+		log.Errorf("err:%v", err)
+		if runErr == nil {
+			runErr = err
+		}
+	}
+	log.Infof("%s stopped", a.conf.Name)
+	return runErr
+}
+
+// Main 作为 main 函数的入口, 出错时以非 0 状态码退出
+func (a *App) Main() {
+	if err := a.Run(context.Background()); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func (a *App) start(ctx context.Context, components []Component) ([]Component, error) {
+	var started []Component
+	for _, c := range components {
+		switch s := c.(type) {
+		case *HttpServer:
+			s.fail = a.Fail
+		case *GrpcServer:
+			s.fail = a.Fail
+		}
+		if err := c.Start(ctx); err != nil {
+			return started, fmt.Errorf("start %s: %w", c.Name(), err)
+		}
+		log.Infof("component %s started", c.Name())
+		started = append(started, c)
+	}
+	return started, nil
+}
+
+// stop 逆序关闭已启动的组件, 所有组件共享一个超时时间
+func (a *App) stop(started []Component) error {
+	timeout := a.conf.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", c.Name(), err))
+			continue
+		}
+		log.Infof("component %s stopped", c.Name())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+name: order
+mode: dev
+log:
+  level: debug
+http:
+  addr: 127.0.0.1:0
+admin:
+  addr: 127.0.0.1:0
+shutdown_timeout: 3s
+order:
+  max_items: 10
+`
+
+func writeConfig(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "conf.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+type recorder struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (r *recorder) add(s string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.list = append(r.list, s)
+}
+
+func (r *recorder) component(name string, startErr error) Component {
+	return NewFuncComponent(name, func(ctx context.Context) error {
+		r.add("start " + name)
+		return startErr
+	}, func(ctx context.Context) error {
+		r.add("stop " + name)
+		return nil
+	})
+}
+
+func TestApp_Run(t *testing.T) {
+	rec := &recorder{}
+	a := New("order", WithConfigFile(writeConfig(t)), WithComponent(rec.component("db", nil)))
+	if err := a.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Config().ShutdownTimeout != 3*time.Second {
+		t.Fatalf("unexpected shutdown timeout %v", a.Config().ShutdownTimeout)
+	}
+	var orderConf struct {
+		MaxItems int `mapstructure:"max_items"`
+	}
+	if err := a.UnmarshalKey("order", &orderConf); err != nil || orderConf.MaxItems != 10 {
+		t.Fatalf("unexpected order conf %+v err %v", orderConf, err)
+	}
+	if a.Admin() == nil {
+		t.Fatal("admin should be created")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	})
+	hs, err := a.Http(mux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Add(rec.component("consumer", nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	var body []byte
+	for i := 0; i < 50; i++ {
+		resp, err := http.Get(fmt.Sprintf("http://%s/ping", hs.Addr()))
+		if err == nil {
+			body, _ = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if string(body) != "pong" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start db", "start consumer", "stop consumer", "stop db"}
+	if !reflect.DeepEqual(rec.list, want) {
+		t.Fatalf("got %v, want %v", rec.list, want)
+	}
+	if err := a.Run(context.Background()); !errors.Is(err, ErrAppRunning) {
+		t.Fatalf("expect ErrAppRunning, got %v", err)
+	}
+}
+
+func TestApp_StartFailed(t *testing.T) {
+	rec := &recorder{}
+	startErr := errors.New("connect refused")
+	a := New("order", WithComponent(
+		rec.component("db", nil),
+		rec.component("cache", startErr),
+		rec.component("consumer", nil),
+	))
+	err := a.Run(context.Background())
+	if !errors.Is(err, startErr) {
+		t.Fatalf("expect start err, got %v", err)
+	}
+	want := []string{"start db", "start cache", "stop db"}
+	if !reflect.DeepEqual(rec.list, want) {
+		t.Fatalf("got %v, want %v", rec.list, want)
+	}
+}
+
+func TestApp_Fail(t *testing.T) {
+	rec := &recorder{}
+	a := New("order", WithComponent(rec.component("db", nil)))
+	failErr := errors.New("consumer disconnected")
+	a.Add(NewFuncComponent("consumer", func(ctx context.Context) error {
+		go a.Fail(failErr)
+		return nil
+	}, nil))
+	if err := a.Run(context.Background()); !errors.Is(err, failErr) {
+		t.Fatalf("expect fail err, got %v", err)
+	}
+	if !reflect.DeepEqual(rec.list, []string{"start db", "stop db"}) {
+		t.Fatalf("unexpected %v", rec.list)
+	}
+}
+
+func TestApp_Env(t *testing.T) {
+	t.Setenv("ORDER_HTTP_ADDR", "127.0.0.1:18080")
+	t.Setenv("ORDER_LOG_LEVEL", "warn")
+	a := New("order", WithConfigFile(writeConfig(t)), WithEnvPrefix("ORDER"))
+	if err := a.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if a.Config().Http.Addr != "127.0.0.1:18080" || a.Config().Log.Level != "warn" {
+		t.Fatalf("env not applied %+v", a.Config())
+	}
+
+	t.Setenv("ORDER_LOG_LEVEL", "verbose")
+	a = New("order", WithEnvPrefix("ORDER"))
+	if err := a.Init(); err == nil {
+		t.Fatal("expect invalid log level err")
+	}
+}
@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/admin"
+	"google.golang.org/grpc"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Component 由 App 统一管理启停的组件
+// Start 不能阻塞, 需要常驻的逻辑自己起协程; 运行中出错通过 App.Fail 通知退出
+type Component interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+var (
+	_ Component = (*funcComponent)(nil)
+	_ Component = (*HttpServer)(nil)
+	_ Component = (*GrpcServer)(nil)
+	_ Component = (*AdminServer)(nil)
+)
+
+type funcComponent struct {
+	name  string
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+// NewFuncComponent 用函数构造组件, start 和 stop 都可以为 nil
+// 例如 mq 消费者: NewFuncComponent("consumer", func(ctx) error { return c.Start() }, func(ctx) error { c.Stop(); return nil })
+func NewFuncComponent(name string, start, stop func(ctx context.Context) error) Component {
+	return &funcComponent{name: name, start: start, stop: stop}
+}
+
+func (c *funcComponent) Name() string {
+	return c.name
+}
+
+func (c *funcComponent) Start(ctx context.Context) error {
+	if c.start == nil {
+		return nil
+	}
+	return c.start(ctx)
+}
+
+func (c *funcComponent) Stop(ctx context.Context) error {
+	if c.stop == nil {
+		return nil
+	}
+	return c.stop(ctx)
+}
+
+// HttpServer http 服务组件, 先监听端口再返回, 端口被占用时 Start 直接报错
+type HttpServer struct {
+	addr   string
+	server *http.Server
+	fail   func(error)
+
+	mu  sync.Mutex
+	lis net.Listener
+}
+
+func NewHttpServer(addr string, handler http.Handler) *HttpServer {
+	return &HttpServer{
+		addr:   addr,
+		server: &http.Server{Handler: handler},
+	}
+}
+
+func (s *HttpServer) Name() string {
+	return "http"
+}
+
+// Server 底层的 http.Server, 可以在 Start 之前调整超时等参数
+func (s *HttpServer) Server() *http.Server {
+	return s.server
+}
+
+// Addr 实际监听的地址, 监听 :0 时用于获取端口
+func (s *HttpServer) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lis == nil {
+		return s.addr
+	}
+	return s.lis.Addr().String()
+}
+
+func (s *HttpServer) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.lis = lis
+	s.mu.Unlock()
+	go func() {
+		if err := s.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("http server err:%v", err)
+			if s.fail != nil {
+				s.fail(err)
+			}
+		}
+	}()
+	log.Infof("http server listen on %s", lis.Addr())
+	return nil
+}
+
+func (s *HttpServer) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// GrpcServer grpc 服务组件, 退出时先 GracefulStop, 超时后强制 Stop
+type GrpcServer struct {
+	addr   string
+	server *grpc.Server
+	fail   func(error)
+
+	mu  sync.Mutex
+	lis net.Listener
+}
+
+func NewGrpcServer(addr string, server *grpc.Server) *GrpcServer {
+	return &GrpcServer{addr: addr, server: server}
+}
+
+func (s *GrpcServer) Name() string {
+	return "grpc"
+}
+
+func (s *GrpcServer) Server() *grpc.Server {
+	return s.server
+}
+
+func (s *GrpcServer) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lis == nil {
+		return s.addr
+	}
+	return s.lis.Addr().String()
+}
+
+func (s *GrpcServer) Start(ctx context.Context) error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.lis = lis
+	s.mu.Unlock()
+	go func() {
+		if err := s.server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Errorf("grpc server err:%v", err)
+			if s.fail != nil {
+				s.fail(err)
+			}
+		}
+	}()
+	log.Infof("grpc server listen on %s", lis.Addr())
+	return nil
+}
+
+func (s *GrpcServer) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// AdminServer 运维接口组件, 最先启动最后关闭, 退出开始时 readyz 立即返回 503
+type AdminServer struct {
+	addr  string
+	admin *admin.Admin
+}
+
+func NewAdminServer(addr string, a *admin.Admin) *AdminServer {
+	return &AdminServer{addr: addr, admin: a}
+}
+
+func (s *AdminServer) Name() string {
+	return "admin"
+}
+
+func (s *AdminServer) Admin() *admin.Admin {
+	return s.admin
+}
+
+func (s *AdminServer) Start(ctx context.Context) error {
+	return s.admin.Start(s.addr)
+}
+
+func (s *AdminServer) Stop(ctx context.Context) error {
+	return s.admin.Stop(ctx)
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultShutdownTimeout = 15 * time.Second
+)
+
+var levelMap = map[string]utils.Level{
+	"debug": utils.LevelDebug,
+	"info":  utils.LevelInfo,
+	"warn":  utils.LevelWarn,
+	"error": utils.LevelError,
+}
+
+// Config 服务的公共配置, 业务自己的配置段通过 App.UnmarshalKey 读取
+//
+//	name: order
+//	mode: release
+//	log:
+//	  level: info
+//	http:
+//	  addr: :8080
+//	grpc:
+//	  addr: :9090
+//	admin:
+//	  addr: :6060
+//	shutdown_timeout: 15s
+type Config struct {
+	Name  string       `mapstructure:"name"`
+	Mode  string       `mapstructure:"mode"`
+	Log   LogConfig    `mapstructure:"log"`
+	Http  ServerConfig `mapstructure:"http"`
+	Grpc  ServerConfig `mapstructure:"grpc"`
+	Admin ServerConfig `mapstructure:"admin"`
+
+	// ShutdownDelay 退出时 readyz 先返回 503, 等待负载均衡摘流量后再关闭服务
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+	// ShutdownTimeout 关闭所有组件的总超时时间
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+}
+
+type LogConfig struct {
+	Level string `mapstructure:"level"`
+	Hint  string `mapstructure:"hint"` // 所有协程共用的 hint
+}
+
+type ServerConfig struct {
+	Addr string `mapstructure:"addr"`
+}
+
+func (c *Config) logLevel() (utils.Level, error) {
+	if c.Log.Level == "" {
+		return utils.LevelInfo, nil
+	}
+	level, ok := levelMap[strings.ToLower(c.Log.Level)]
+	if !ok {
+		return 0, fmt.Errorf("invalid log level %s", c.Log.Level)
+	}
+	return level, nil
+}
@@ -2,12 +2,12 @@ package utils
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-redis/redis/v8"
 	"github.com/oldbai555/lbtool/extpkg/pie/pie"
 	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/codec"
 	"github.com/oldbai555/lbtool/pkg/lberr"
 	"github.com/oldbai555/lbtool/pkg/routine"
 	"reflect"
@@ -30,6 +30,7 @@ var ErrJsonUnmarshal = lberr.NewErr(6001, "json unmarshal error")
 type CacheHelper struct {
 	redisClient *redis.Client
 	exp         time.Duration
+	codec       codec.Codec // 缓存值的序列化方式
 
 	prefix string // 前缀
 	corpId uint32 // 额外参数 corpId
@@ -53,6 +54,8 @@ type NewCacheHelperReq struct {
 	IsOnlyUseCombination bool // 只使用组合字段
 
 	IsCacheHaveCorpIdKey bool // 是否需要缓存所有Key - 指含有corpId 与不含CorpId的 key
+
+	Codec string // 缓存值的序列化方式, 为空时使用 codec.Default(), 修改后旧的缓存无法读取, 需要换 Prefix
 }
 
 func NewCacheHelper(req *NewCacheHelperReq) *CacheHelper {
@@ -112,9 +115,18 @@ func NewCacheHelper(req *NewCacheHelperReq) *CacheHelper {
 		}
 	}
 
+	valCodec := codec.Default()
+	if req.Codec != "" {
+		var err error
+		if valCodec, err = codec.Get(req.Codec); err != nil {
+			panic(err)
+		}
+	}
+
 	return &CacheHelper{
 		redisClient: req.RedisClient,
 		exp:         DefaultCacheExp,
+		codec:       valCodec,
 
 		mType:      req.MType,
 		fieldNames: req.FieldNames,
@@ -155,7 +167,7 @@ func (c *CacheHelper) SetJson(ctx context.Context, model interface{}, exp time.D
 
 	// 根据所需的字段遍历存缓存
 	for _, cacheKey := range c.getAllCacheKey(modelValue) {
-		err = c.setJson(ctx, cacheKey, model, exp)
+		err = c.setVal(ctx, cacheKey, model, exp)
 		if err != nil {
 			log.Errorf("err:%v", err)
 			continue
@@ -180,7 +192,7 @@ func (c *CacheHelper) AsyncSetJson(ctx context.Context, model interface{}, exp t
 // GetJson 获取缓存
 // params fieldValue 字段值
 func (c *CacheHelper) GetJson(ctx context.Context, fieldValue interface{}, opt interface{}) error {
-	err := c.getJson(ctx, c.genCacheKey(fieldValue), opt)
+	err := c.getVal(ctx, c.genCacheKey(fieldValue), opt)
 	if err != nil {
 		log.Errorf("err:%v", err)
 	}
@@ -189,7 +201,7 @@ func (c *CacheHelper) GetJson(ctx context.Context, fieldValue interface{}, opt i
 
 // GetJsonByCustomizeKey 获取缓存 通过组合自定义的valueList 按顺序拼接
 func (c *CacheHelper) GetJsonByCustomizeKey(ctx context.Context, opt interface{}, valueList ...string) error {
-	err := c.getJson(ctx, c.genCacheKey(strings.Join(valueList, "_")), opt)
+	err := c.getVal(ctx, c.genCacheKey(strings.Join(valueList, "_")), opt)
 	if err != nil {
 		log.Errorf("err:%v", err)
 	}
@@ -332,8 +344,8 @@ func (c *CacheHelper) checkValType(model interface{}) bool {
 	return strings.TrimPrefix("*", reflect.TypeOf(c.mType).String()) == strings.TrimPrefix("*", reflect.TypeOf(model).String())
 }
 
-func (c *CacheHelper) setJson(ctx context.Context, key string, j interface{}, exp time.Duration) error {
-	val, err := json.Marshal(j)
+func (c *CacheHelper) setVal(ctx context.Context, key string, j interface{}, exp time.Duration) error {
+	val, err := c.codec.Marshal(j)
 	if err != nil {
 		log.Errorf("err:%s", err)
 		return err
@@ -345,7 +357,7 @@ func (c *CacheHelper) setJson(ctx context.Context, key string, j interface{}, ex
 	return c.redisClient.Set(ctx, key, val, exp).Err()
 }
 
-func (c *CacheHelper) getJson(ctx context.Context, key string, j interface{}) error {
+func (c *CacheHelper) getVal(ctx context.Context, key string, j interface{}) error {
 	val, err := c.redisClient.Get(ctx, key).Bytes()
 	if err != nil {
 		if err == redis.Nil {
@@ -354,7 +366,7 @@ func (c *CacheHelper) getJson(ctx context.Context, key string, j interface{}) er
 		log.Errorf("err:%s", err)
 		return ErrRedisException
 	}
-	err = json.Unmarshal(val, j)
+	err = c.codec.Unmarshal(val, j)
 	if err != nil {
 		log.Errorf("err:%s", err)
 		return ErrJsonUnmarshal
@@ -0,0 +1,121 @@
+package captcha
+
+import (
+	"bytes"
+	"context"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/utils"
+	"image/png"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTTL    = 5 * time.Minute
+	DefaultWidth  = 120
+	DefaultHeight = 40
+
+	// HeaderCaptchaId 图片接口通过该响应头返回验证码 id
+	HeaderCaptchaId = "X-Captcha-Id"
+)
+
+// Captcha 图片验证码, 答案保存在 Store 中, 校验一次后失效
+type Captcha struct {
+	store  Store
+	driver Driver
+	ttl    time.Duration
+	width  int
+	height int
+}
+
+type Option func(*Captcha)
+
+// WithStore 默认存在内存
+func WithStore(store Store) Option {
+	return func(c *Captcha) {
+		c.store = store
+	}
+}
+
+// WithDriver 默认 4 位数字
+func WithDriver(driver Driver) Option {
+	return func(c *Captcha) {
+		c.driver = driver
+	}
+}
+
+// WithTTL 验证码有效期
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Captcha) {
+		c.ttl = ttl
+	}
+}
+
+// WithSize 图片宽高
+func WithSize(width, height int) Option {
+	return func(c *Captcha) {
+		c.width = width
+		c.height = height
+	}
+}
+
+func New(ops ...Option) *Captcha {
+	c := &Captcha{
+		driver: DigitDriver{Length: 4},
+		ttl:    DefaultTTL,
+		width:  DefaultWidth,
+		height: DefaultHeight,
+	}
+	for i := range ops {
+		ops[i](c)
+	}
+	if c.store == nil {
+		c.store = NewMemoryStore()
+	}
+	return c
+}
+
+// Generate 生成验证码, 返回 id 和 png 图片
+func (c *Captcha) Generate(ctx context.Context) (id string, img []byte, err error) {
+	content, answer := c.driver.Generate()
+	id = utils.GenUUID()
+	if err = c.store.Set(ctx, id, strings.ToLower(answer), c.ttl); err != nil {
+		return "", nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, render(content, c.width, c.height)); err != nil {
+		return "", nil, err
+	}
+	return id, buf.Bytes(), nil
+}
+
+// Verify 校验验证码, 无论对错验证码都会失效, 防止暴力尝试
+func (c *Captcha) Verify(ctx context.Context, id, answer string) (bool, error) {
+	if id == "" || answer == "" {
+		return false, nil
+	}
+	expect, ok, err := c.store.Take(ctx, id)
+	if err != nil || !ok {
+		return false, err
+	}
+	return expect == strings.ToLower(strings.TrimSpace(answer)), nil
+}
+
+// Handler 输出验证码图片, id 通过 X-Captcha-Id 响应头返回
+func (c *Captcha) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, img, err := c.Generate(r.Context())
+		if err != nil {
+			log.Errorf("err:%v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(HeaderCaptchaId, id)
+		w.Header().Set("Access-Control-Expose-Headers", HeaderCaptchaId)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(img)
+	})
+}
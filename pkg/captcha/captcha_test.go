@@ -0,0 +1,95 @@
+package captcha
+
+import (
+	"context"
+	"image/png"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedDriver 固定答案, 方便测试
+type fixedDriver struct{}
+
+func (fixedDriver) Generate() (string, string) {
+	return "Ab3d", "Ab3d"
+}
+
+func TestCaptcha_Verify(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithDriver(fixedDriver{}))
+
+	id, img, err := c.Generate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = png.Decode(strings.NewReader(string(img))); err != nil {
+		t.Fatal(err)
+	}
+
+	// 忽略大小写, 只能校验一次
+	if ok, _ := c.Verify(ctx, id, "aB3D"); !ok {
+		t.Fatal("expect ok")
+	}
+	if ok, _ := c.Verify(ctx, id, "ab3d"); ok {
+		t.Fatal("captcha should be used only once")
+	}
+
+	// 答错也会失效
+	id, _, _ = c.Generate(ctx)
+	if ok, _ := c.Verify(ctx, id, "xxxx"); ok {
+		t.Fatal("expect wrong")
+	}
+	if ok, _ := c.Verify(ctx, id, "ab3d"); ok {
+		t.Fatal("captcha should be invalid after wrong answer")
+	}
+}
+
+func TestCaptcha_TTL(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithDriver(fixedDriver{}), WithTTL(time.Millisecond))
+	id, _, err := c.Generate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if ok, _ := c.Verify(ctx, id, "ab3d"); ok {
+		t.Fatal("captcha should be expired")
+	}
+}
+
+func TestMathDriver(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		content, answer := MathDriver{}.Generate()
+		expr := strings.TrimSuffix(content, "=?")
+		var a, b, want int
+		for _, op := range []string{"+", "-", "x"} {
+			if parts := strings.Split(expr, op); len(parts) == 2 {
+				a, _ = strconv.Atoi(parts[0])
+				b, _ = strconv.Atoi(parts[1])
+				switch op {
+				case "+":
+					want = a + b
+				case "-":
+					want = a - b
+				case "x":
+					want = a * b
+				}
+			}
+		}
+		if strconv.Itoa(want) != answer || want < 0 {
+			t.Fatalf("unexpected %s %s", content, answer)
+		}
+	}
+}
+
+func TestCaptcha_Handler(t *testing.T) {
+	c := New(WithDriver(LetterDriver{Length: 5}))
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/captcha", nil))
+	if rec.Code != 200 || rec.Header().Get(HeaderCaptchaId) == "" || rec.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,78 @@
+package captcha
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+// Driver 生成验证码的展示内容和答案
+type Driver interface {
+	Generate() (content, answer string)
+}
+
+const (
+	digits = "0123456789"
+	// letters 去掉了容易混淆的 0 o 1 l i
+	letters = "23456789abcdefghjkmnpqrstuvwxyzABCDEFGHJKMNPQRSTUVWXYZ"
+)
+
+func randInt(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(v.Int64())
+}
+
+func randString(source string, length int) string {
+	buf := make([]byte, length)
+	for i := range buf {
+		buf[i] = source[randInt(len(source))]
+	}
+	return string(buf)
+}
+
+// DigitDriver 纯数字验证码
+type DigitDriver struct {
+	Length int
+}
+
+func (d DigitDriver) Generate() (string, string) {
+	s := randString(digits, d.Length)
+	return s, s
+}
+
+// LetterDriver 数字字母混合验证码, 校验时忽略大小写
+type LetterDriver struct {
+	Length int
+}
+
+func (d LetterDriver) Generate() (string, string) {
+	s := randString(letters, d.Length)
+	return s, s
+}
+
+// MathDriver 算术验证码, 例如 3 + 5 = ?, 结果不会是负数
+type MathDriver struct {
+	// Max 操作数的最大值, 为 0 时使用 10
+	Max int
+}
+
+func (d MathDriver) Generate() (string, string) {
+	max := d.Max
+	if max <= 0 {
+		max = 10
+	}
+	a, b := randInt(max)+1, randInt(max)+1
+	switch randInt(3) {
+	case 0:
+		return fmt.Sprintf("%d+%d=?", a, b), fmt.Sprint(a + b)
+	case 1:
+		if a < b {
+			a, b = b, a
+		}
+		return fmt.Sprintf("%d-%d=?", a, b), fmt.Sprint(a - b)
+	}
+	return fmt.Sprintf("%dx%d=?", a, b), fmt.Sprint(a * b)
+}
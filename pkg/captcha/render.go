@@ -0,0 +1,80 @@
+package captcha
+
+import (
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+)
+
+// render 把内容画成图片, 每个字符随机颜色和上下偏移, 再加干扰线和噪点
+func render(content string, width, height int) image.Image {
+	face := basicfont.Face7x13
+	// 先按点阵字体原始大小画, 再整体放大, 免去依赖外部字体
+	charW := 9
+	smallW, smallH := charW*len(content)+4, 17
+	small := image.NewNRGBA(image.Rect(0, 0, smallW, smallH))
+	bg := color.NRGBA{R: 240, G: 240, B: 240, A: 255}
+	for i := 0; i < len(small.Pix); i += 4 {
+		small.Pix[i], small.Pix[i+1], small.Pix[i+2], small.Pix[i+3] = bg.R, bg.G, bg.B, bg.A
+	}
+	for i, ch := range content {
+		d := &font.Drawer{
+			Dst:  small,
+			Src:  image.NewUniform(randColor()),
+			Face: face,
+			Dot:  fixed.P(2+i*charW, 12+randInt(3)-1),
+		}
+		d.DrawString(string(ch))
+	}
+
+	m := imaging.Resize(small, width, height, imaging.Linear)
+	for i := 0; i < 4; i++ {
+		drawLine(m, randInt(width), randInt(height), randInt(width), randInt(height), randColor())
+	}
+	for i := 0; i < width*height/30; i++ {
+		m.Set(randInt(width), randInt(height), randColor())
+	}
+	return m
+}
+
+func randColor() color.NRGBA {
+	return color.NRGBA{R: uint8(randInt(150)), G: uint8(randInt(150)), B: uint8(randInt(150)), A: 255}
+}
+
+// drawLine Bresenham 画线
+func drawLine(m *image.NRGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		m.Set(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
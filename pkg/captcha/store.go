@@ -0,0 +1,85 @@
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/oldbai555/lbtool/pkg/internal/ttlmap"
+	"sync"
+	"time"
+)
+
+// Store 保存验证码答案, Take 取出后立即删除, 保证验证码只能用一次
+type Store interface {
+	Set(ctx context.Context, id, answer string, ttl time.Duration) error
+	Take(ctx context.Context, id string) (answer string, ok bool, err error)
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore 单机存储, 多实例部署时生成和校验可能落在不同实例, 需要使用 RedisStore
+type MemoryStore struct {
+	answers *ttlmap.Map[string]
+	mu      sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		answers: ttlmap.New[string](),
+	}
+}
+
+func (m *MemoryStore) Set(ctx context.Context, id, answer string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.answers.Set(id, answer, ttl)
+	return nil
+}
+
+func (m *MemoryStore) Take(ctx context.Context, id string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	answer, ok := m.answers.Get(id)
+	m.answers.Delete(id)
+	return answer, ok, nil
+}
+
+var _ Store = (*RedisStore)(nil)
+
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "captcha"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *RedisStore) key(id string) string {
+	return fmt.Sprintf("%s_%s", r.prefix, id)
+}
+
+func (r *RedisStore) Set(ctx context.Context, id, answer string, ttl time.Duration) error {
+	return r.client.Set(ctx, r.key(id), answer, ttl).Err()
+}
+
+func (r *RedisStore) Take(ctx context.Context, id string) (string, bool, error) {
+	// get 和 del 放在一个事务里, 并发校验时只有一个请求能拿到答案
+	pipe := r.client.TxPipeline()
+	get := pipe.Get(ctx, r.key(id))
+	pipe.Del(ctx, r.key(id))
+	_, err := pipe.Exec(ctx)
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return get.Val(), true, nil
+}
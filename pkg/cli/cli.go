@@ -0,0 +1,187 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const (
+	FlagLogLevel = "log-level"
+	FlagLogHint  = "log-hint"
+)
+
+var levelMap = map[string]utils.Level{
+	"debug": utils.LevelDebug,
+	"info":  utils.LevelInfo,
+	"warn":  utils.LevelWarn,
+	"error": utils.LevelError,
+}
+
+// App 命令行程序, 基于 cobra, 自带 help, --version 和 completion 子命令
+// 参数取值优先级: 命令行 > 环境变量 > 配置数据源 > 默认值
+type App struct {
+	root   *cobra.Command
+	viper  *viper.Viper
+	before []func(ctx context.Context) error
+	source bconf.DataSource
+}
+
+type Option func(*App)
+
+// WithVersion 版本号, 一般通过 ldflags 注入
+func WithVersion(version string) Option {
+	return func(a *App) {
+		a.root.Version = version
+	}
+}
+
+// WithEnvPrefix 环境变量前缀, 例如前缀 LB 时 --db-host 对应 LB_DB_HOST
+func WithEnvPrefix(prefix string) Option {
+	return func(a *App) {
+		a.viper.SetEnvPrefix(prefix)
+	}
+}
+
+// WithDataSource 从配置数据源加载参数默认值, 配置的 key 与参数名一致
+func WithDataSource(source bconf.DataSource) Option {
+	return func(a *App) {
+		a.source = source
+	}
+}
+
+// WithBefore 执行命令前的初始化, 例如连接数据库; 日志和配置已经在此之前初始化好
+func WithBefore(fn func(ctx context.Context) error) Option {
+	return func(a *App) {
+		a.before = append(a.before, fn)
+	}
+}
+
+func New(name, short string, ops ...Option) *App {
+	a := &App{
+		root: &cobra.Command{
+			Use:           name,
+			Short:         short,
+			SilenceUsage:  true,
+			SilenceErrors: true,
+		},
+		viper: viper.New(),
+	}
+	a.viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_", ".", "_"))
+	a.viper.AutomaticEnv()
+
+	a.root.PersistentFlags().String(FlagLogLevel, "info", "log level: debug, info, warn, error")
+	a.root.PersistentFlags().String(FlagLogHint, "", "log hint prefix for all goroutines")
+
+	for i := range ops {
+		ops[i](a)
+	}
+
+	a.root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return a.init(cmd)
+	}
+	return a
+}
+
+// Root 根命令, 可以直接设置 Run 作为默认行为
+func (a *App) Root() *cobra.Command {
+	return a.root
+}
+
+// AddCommand 添加子命令, 子命令可以继续 AddCommand 形成多级命令
+func (a *App) AddCommand(cmds ...*cobra.Command) {
+	a.root.AddCommand(cmds...)
+}
+
+// init 绑定参数, 加载配置, 初始化日志, 执行 before 钩子
+func (a *App) init(cmd *cobra.Command) error {
+	if a.source != nil {
+		list, err := a.source.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		for _, d := range list {
+			a.viper.SetDefault(d.Key, d.Val)
+		}
+	}
+
+	var bindErr error
+	bind := func(f *pflag.Flag) {
+		if err := a.viper.BindPFlag(f.Name, f); err != nil && bindErr == nil {
+			bindErr = err
+		}
+	}
+	cmd.Flags().VisitAll(bind)
+	cmd.InheritedFlags().VisitAll(bind)
+	if bindErr != nil {
+		return bindErr
+	}
+
+	// 未在命令行指定的参数使用环境变量和配置的值, 命令里直接读 flag 变量也能拿到
+	var setErr error
+	apply := func(f *pflag.Flag) {
+		if f.Changed || !a.viper.IsSet(f.Name) {
+			return
+		}
+		if err := f.Value.Set(a.viper.GetString(f.Name)); err != nil && setErr == nil {
+			setErr = fmt.Errorf("invalid value of --%s: %w", f.Name, err)
+		}
+	}
+	cmd.Flags().VisitAll(apply)
+	cmd.InheritedFlags().VisitAll(apply)
+	if setErr != nil {
+		return setErr
+	}
+
+	level, ok := levelMap[strings.ToLower(a.viper.GetString(FlagLogLevel))]
+	if !ok {
+		return fmt.Errorf("invalid log level %s", a.viper.GetString(FlagLogLevel))
+	}
+	log.SetLogLevel(level)
+	if hint := a.viper.GetString(FlagLogHint); hint != "" {
+		log.SetDefaultHint(hint)
+	}
+	log.SetModuleName(a.root.Name())
+
+	for _, fn := range a.before {
+		if err := fn(cmd.Context()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Viper 合并了命令行, 环境变量和配置的取值
+func (a *App) Viper() *viper.Viper {
+	return a.viper
+}
+
+// Run 解析参数并执行命令, 收到 SIGINT/SIGTERM 时取消 ctx
+func (a *App) Run(args ...string) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	defer func() {
+		_ = log.GetLogger().Flush()
+	}()
+	if len(args) > 0 {
+		a.root.SetArgs(args)
+	}
+	return a.root.ExecuteContext(ctx)
+}
+
+// Main 作为 main 函数的入口, 出错时打印错误并以非 0 状态码退出
+func (a *App) Main() {
+	if err := a.Run(); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/spf13/cobra"
+	"strings"
+	"testing"
+)
+
+type testSource struct {
+	list []*bconf.Data
+}
+
+func (s *testSource) Load() ([]*bconf.Data, error) {
+	return s.list, nil
+}
+
+func (s *testSource) Watch() (bconf.DataWatcher, error) {
+	return nil, nil
+}
+
+func newTestApp(t *testing.T, got *[]string) *App {
+	var inited bool
+	a := New("lbctl", "lb admin tool",
+		WithVersion("v1.0.0"),
+		WithEnvPrefix("LBCTL"),
+		WithDataSource(&testSource{list: []*bconf.Data{{Key: "dsn", Val: "from-config"}, {Key: "batch", Val: 50}}}),
+		WithBefore(func(ctx context.Context) error {
+			inited = true
+			return nil
+		}),
+	)
+
+	migrate := &cobra.Command{Use: "migrate", Short: "database migration"}
+	var dsn string
+	var batch int
+	up := &cobra.Command{
+		Use: "up",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !inited {
+				t.Fatal("before hook not called")
+			}
+			*got = append(*got, dsn, a.Viper().GetString("batch"), cmd.Context().Value(ctxKey{}).(string))
+			return nil
+		},
+	}
+	up.Flags().StringVar(&dsn, "dsn", "", "database dsn")
+	up.Flags().IntVar(&batch, "batch", 100, "batch size")
+	migrate.AddCommand(up)
+	a.AddCommand(migrate)
+	return a
+}
+
+type ctxKey struct{}
+
+func TestApp_Run(t *testing.T) {
+	var got []string
+	a := newTestApp(t, &got)
+
+	// 配置数据源的值作为默认值
+	a.root.SetArgs([]string{"migrate", "up"})
+	if err := a.root.ExecuteContext(context.WithValue(context.Background(), ctxKey{}, "ctx")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "from-config,50,ctx" {
+		t.Fatalf("unexpected %v", got)
+	}
+
+	// 环境变量优先于配置, 命令行优先于环境变量
+	got = nil
+	a = newTestApp(t, &got)
+	t.Setenv("LBCTL_DSN", "from-env")
+	t.Setenv("LBCTL_BATCH", "10")
+	a.root.SetArgs([]string{"migrate", "up", "--batch", "20"})
+	if err := a.root.ExecuteContext(context.WithValue(context.Background(), ctxKey{}, "ctx")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "from-env,20,ctx" {
+		t.Fatalf("unexpected %v", got)
+	}
+}
+
+func TestApp_VersionAndInvalidLevel(t *testing.T) {
+	var got []string
+	a := newTestApp(t, &got)
+	var out bytes.Buffer
+	a.root.SetOut(&out)
+	if err := a.Run("--version"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "v1.0.0") {
+		t.Fatalf("unexpected version output %s", out.String())
+	}
+
+	if err := a.Run("migrate", "up", "--log-level", "bad"); err == nil {
+		t.Fatal("expect invalid log level error")
+	}
+}
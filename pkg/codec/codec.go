@@ -0,0 +1,127 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	NameJSON    = "json"
+	NameProto   = "proto"
+	NameMsgpack = "msgpack"
+)
+
+var (
+	ErrNotFound        = errors.New("codec not found")
+	ErrNotProtoMessage = errors.New("value is not a proto message")
+)
+
+// Codec 序列化方式, web 绑定, mq, 缓存, rpc 统一从这里取, 在一处配置
+type Codec interface {
+	Name() string
+	// ContentTypes 第一个为默认的 Content-Type, 其余为别名
+	ContentTypes() []string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	mu            sync.RWMutex
+	byName        = make(map[string]Codec)
+	byContentType = make(map[string]Codec)
+	defaultName   = NameJSON
+)
+
+func init() {
+	Register(JSON{})
+	Register(Proto{})
+	Register(Msgpack{})
+}
+
+// Register 注册序列化方式, 同名或者同 Content-Type 的会被覆盖
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	byName[c.Name()] = c
+	for _, ct := range c.ContentTypes() {
+		byContentType[strings.ToLower(ct)] = c
+	}
+}
+
+// Get 按名字获取
+func Get(name string) (Codec, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := byName[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return c, nil
+}
+
+// GetByContentType 按 Content-Type 获取, 忽略 charset 等参数
+func GetByContentType(contentType string) (Codec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(strings.Split(contentType, ";")[0])
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := byContentType[strings.ToLower(mediaType)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, contentType)
+	}
+	return c, nil
+}
+
+// Names 已注册的名字
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetDefault 设置默认的序列化方式, 名字需要已注册
+func SetDefault(name string) error {
+	if _, err := Get(name); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	defaultName = name
+	return nil
+}
+
+// Default 默认的序列化方式, 未设置时为 json
+func Default() Codec {
+	mu.RLock()
+	defer mu.RUnlock()
+	return byName[defaultName]
+}
+
+// Marshal 按名字序列化
+func Marshal(name string, v interface{}) ([]byte, error) {
+	c, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Marshal(v)
+}
+
+// Unmarshal 按名字反序列化
+func Unmarshal(name string, data []byte, v interface{}) error {
+	c, err := Get(name)
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(data, v)
+}
@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"errors"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"testing"
+)
+
+type user struct {
+	Id   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestGetByContentType(t *testing.T) {
+	cases := map[string]string{
+		"application/json; charset=utf-8": NameJSON,
+		"application/x-protobuf":          NameProto,
+		"Application/MsgPack":             NameMsgpack,
+	}
+	for ct, name := range cases {
+		c, err := GetByContentType(ct)
+		if err != nil {
+			t.Fatalf("%s err:%v", ct, err)
+		}
+		if c.Name() != name {
+			t.Errorf("%s got %s, want %s", ct, c.Name(), name)
+		}
+	}
+	if _, err := GetByContentType("text/xml"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{NameJSON, NameMsgpack} {
+		in := &user{Id: 1, Name: "lb"}
+		buf, err := Marshal(name, in)
+		if err != nil {
+			t.Fatalf("%s marshal err:%v", name, err)
+		}
+		var out user
+		if err = Unmarshal(name, buf, &out); err != nil {
+			t.Fatalf("%s unmarshal err:%v", name, err)
+		}
+		if out != *in {
+			t.Errorf("%s got %+v, want %+v", name, out, *in)
+		}
+	}
+
+	for _, name := range []string{NameJSON, NameProto} {
+		buf, err := Marshal(name, wrapperspb.String("lb"))
+		if err != nil {
+			t.Fatalf("%s marshal err:%v", name, err)
+		}
+		out := &wrapperspb.StringValue{}
+		if err = Unmarshal(name, buf, out); err != nil {
+			t.Fatalf("%s unmarshal err:%v", name, err)
+		}
+		if out.Value != "lb" {
+			t.Errorf("%s got %q", name, out.Value)
+		}
+	}
+
+	if _, err := Marshal(NameProto, &user{}); !errors.Is(err, ErrNotProtoMessage) {
+		t.Errorf("got %v, want ErrNotProtoMessage", err)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if Default().Name() != NameJSON {
+		t.Fatalf("default should be json")
+	}
+	if err := SetDefault("xml"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+	if err := SetDefault(NameMsgpack); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = SetDefault(NameJSON) }()
+	if Default().Name() != NameMsgpack {
+		t.Errorf("default should be msgpack")
+	}
+}
@@ -0,0 +1,34 @@
+package codec
+
+import (
+	"github.com/oldbai555/lbtool/pkg/json"
+	"github.com/oldbai555/lbtool/pkg/jsonpb"
+	"google.golang.org/protobuf/proto"
+)
+
+var _ Codec = JSON{}
+
+// JSON proto 消息走 protojson, 其余走 jsoniter
+type JSON struct{}
+
+func (JSON) Name() string {
+	return NameJSON
+}
+
+func (JSON) ContentTypes() []string {
+	return []string{"application/json", "text/json"}
+}
+
+func (JSON) Marshal(v interface{}) ([]byte, error) {
+	if msg, ok := v.(proto.Message); ok {
+		return jsonpb.Marshal(msg)
+	}
+	return json.Marshal(v)
+}
+
+func (JSON) Unmarshal(data []byte, v interface{}) error {
+	if msg, ok := v.(proto.Message); ok {
+		return jsonpb.Unmarshal(data, msg)
+	}
+	return json.Unmarshal(data, v)
+}
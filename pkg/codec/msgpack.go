@@ -0,0 +1,39 @@
+package codec
+
+import (
+	"github.com/ugorji/go/codec"
+	"reflect"
+)
+
+var _ Codec = Msgpack{}
+
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.WriteExt = true
+	h.RawToString = true
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	// 与 json 保持一致, 优先使用 json tag
+	h.TypeInfos = codec.NewTypeInfos([]string{"json", "codec"})
+	return h
+}()
+
+// Msgpack 比 json 更省空间, 适合缓存和内部消息
+type Msgpack struct{}
+
+func (Msgpack) Name() string {
+	return NameMsgpack
+}
+
+func (Msgpack) ContentTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack"}
+}
+
+func (Msgpack) Marshal(v interface{}) ([]byte, error) {
+	var buf []byte
+	err := codec.NewEncoderBytes(&buf, msgpackHandle).Encode(v)
+	return buf, err
+}
+
+func (Msgpack) Unmarshal(data []byte, v interface{}) error {
+	return codec.NewDecoderBytes(data, msgpackHandle).Decode(v)
+}
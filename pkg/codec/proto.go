@@ -0,0 +1,34 @@
+package codec
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+var _ Codec = Proto{}
+
+// Proto protobuf 二进制, 只支持 proto 消息
+type Proto struct{}
+
+func (Proto) Name() string {
+	return NameProto
+}
+
+func (Proto) ContentTypes() []string {
+	return []string{"application/x-protobuf", "application/protobuf", "application/grpc+proto"}
+}
+
+func (Proto) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(msg)
+}
+
+func (Proto) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, msg)
+}
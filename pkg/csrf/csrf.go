@@ -0,0 +1,260 @@
+package csrf
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/session"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+const (
+	DefaultCookieName = "lb_csrf"
+	DefaultHeaderName = "X-CSRF-Token"
+	DefaultFieldName  = "csrf_token"
+
+	// sessionKey 同步令牌模式下 token 在会话中的 key
+	sessionKey = "_csrf"
+
+	tokenLen = 32
+)
+
+var (
+	ErrTokenMissing  = errors.New("csrf token missing")
+	ErrTokenMismatch = errors.New("csrf token mismatch")
+	ErrNoSession     = errors.New("csrf session not found")
+)
+
+// Protector csrf 防护
+// 默认使用双重提交 cookie: token 放在 cookie 中, 请求时需要在 header 或表单中带上相同的 token
+// WithSession 开启同步令牌模式: token 存在会话中, 需要先挂 session.Manager 的中间件
+type Protector struct {
+	cookieName  string
+	headerName  string
+	fieldName   string
+	path        string
+	domain      string
+	secure      bool
+	sameSite    http.SameSite
+	useSession  bool
+	exemptPaths []string
+	exemptFuncs []func(r *http.Request) bool
+	errHandler  func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type Option func(*Protector)
+
+func WithCookieName(name string) Option {
+	return func(p *Protector) {
+		p.cookieName = name
+	}
+}
+
+func WithHeaderName(name string) Option {
+	return func(p *Protector) {
+		p.headerName = name
+	}
+}
+
+func WithFieldName(name string) Option {
+	return func(p *Protector) {
+		p.fieldName = name
+	}
+}
+
+func WithDomain(domain string) Option {
+	return func(p *Protector) {
+		p.domain = domain
+	}
+}
+
+func WithPath(path string) Option {
+	return func(p *Protector) {
+		p.path = path
+	}
+}
+
+// WithSecure cookie 只通过 https 发送
+func WithSecure() Option {
+	return func(p *Protector) {
+		p.secure = true
+	}
+}
+
+// WithSameSite 默认 Lax, 前后端跨站部署时需要设置为 None 并开启 Secure
+func WithSameSite(sameSite http.SameSite) Option {
+	return func(p *Protector) {
+		p.sameSite = sameSite
+	}
+}
+
+// WithSession 使用同步令牌模式, token 保存在会话中
+func WithSession() Option {
+	return func(p *Protector) {
+		p.useSession = true
+	}
+}
+
+// WithExemptPaths 按路径前缀豁免, 例如回调接口
+func WithExemptPaths(prefixes ...string) Option {
+	return func(p *Protector) {
+		p.exemptPaths = append(p.exemptPaths, prefixes...)
+	}
+}
+
+// WithExemptFunc 自定义豁免规则
+func WithExemptFunc(fn func(r *http.Request) bool) Option {
+	return func(p *Protector) {
+		p.exemptFuncs = append(p.exemptFuncs, fn)
+	}
+}
+
+// WithErrorHandler 校验失败时的处理, 默认返回 403
+func WithErrorHandler(fn func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(p *Protector) {
+		p.errHandler = fn
+	}
+}
+
+// ExemptBearer 带 Authorization: Bearer 的请求不依赖 cookie 鉴权, 不存在 csrf 问题
+func ExemptBearer(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+func New(ops ...Option) *Protector {
+	p := &Protector{
+		cookieName: DefaultCookieName,
+		headerName: DefaultHeaderName,
+		fieldName:  DefaultFieldName,
+		path:       "/",
+		sameSite:   http.SameSiteLaxMode,
+		errHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		},
+	}
+	for i := range ops {
+		ops[i](p)
+	}
+	return p
+}
+
+type tokenKey struct{}
+
+// Token 当前请求可用的 token, 用于渲染表单或返回给前端
+func Token(r *http.Request) string {
+	token, _ := r.Context().Value(tokenKey{}).(string)
+	return token
+}
+
+// TemplateField 表单中的隐藏字段, 在模板中使用 {{ .csrfField }}
+func (p *Protector) TemplateField(r *http.Request) template.HTML {
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(p.fieldName), template.HTMLEscapeString(Token(r))))
+}
+
+func newToken() string {
+	buf := make([]byte, tokenLen)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func (p *Protector) exempt(r *http.Request) bool {
+	for _, prefix := range p.exemptPaths {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+	for _, fn := range p.exemptFuncs {
+		if fn(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// HttpMiddleware 安全方法下发 token, 其他方法校验 token
+func (p *Protector) HttpMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		expect, err := p.loadToken(w, r)
+		if err != nil {
+			p.errHandler(w, r, err)
+			return
+		}
+
+		if !isSafeMethod(r.Method) {
+			got := r.Header.Get(p.headerName)
+			if got == "" {
+				got = r.PostFormValue(p.fieldName)
+			}
+			if got == "" {
+				p.errHandler(w, r, ErrTokenMissing)
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(got), []byte(expect)) != 1 {
+				p.errHandler(w, r, ErrTokenMismatch)
+				return
+			}
+		}
+
+		// token 放在响应头里, 方便前端在 ajax 请求中带上
+		w.Header().Set(p.headerName, expect)
+		w.Header().Add("Vary", "Cookie")
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, expect)))
+	})
+}
+
+// loadToken 取出当前的 token, 没有时生成新的
+func (p *Protector) loadToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if p.useSession {
+		s := session.FromContext(r.Context())
+		if s == nil {
+			log.Errorf("csrf session mode requires session middleware")
+			return "", ErrNoSession
+		}
+		token, ok := session.Get[string](s, sessionKey)
+		if ok && token != "" {
+			return token, nil
+		}
+		token = newToken()
+		if err := s.Set(sessionKey, token); err != nil {
+			return "", err
+		}
+		return token, nil
+	}
+
+	if c, err := r.Cookie(p.cookieName); err == nil && c.Value != "" {
+		return c.Value, nil
+	}
+	token := newToken()
+	// 前端需要读取 cookie 放到 header 中, 所以不能设置 HttpOnly
+	http.SetCookie(w, &http.Cookie{
+		Name:     p.cookieName,
+		Value:    token,
+		Path:     p.path,
+		Domain:   p.domain,
+		Secure:   p.secure,
+		SameSite: p.sameSite,
+	})
+	return token, nil
+}
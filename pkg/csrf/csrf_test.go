@@ -0,0 +1,108 @@
+package csrf
+
+import (
+	"github.com/oldbai555/lbtool/pkg/session"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDoubleSubmit(t *testing.T) {
+	p := New(WithExemptPaths("/callback"), WithExemptFunc(ExemptBearer))
+	h := p.HttpMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(p.TemplateField(r)))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/form", nil))
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != DefaultCookieName {
+		t.Fatalf("expect csrf cookie, got %v", cookies)
+	}
+	token := cookies[0].Value
+	if !strings.Contains(rec.Body.String(), token) || rec.Header().Get(DefaultHeaderName) != token {
+		t.Fatalf("unexpected body %s", rec.Body.String())
+	}
+
+	post := func(path string, header, field string) int {
+		form := url.Values{}
+		if field != "" {
+			form.Set(DefaultFieldName, field)
+		}
+		r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(cookies[0])
+		if header != "" {
+			r.Header.Set(DefaultHeaderName, header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	if code := post("/submit", "", ""); code != http.StatusForbidden {
+		t.Fatalf("expect 403 without token, got %d", code)
+	}
+	if code := post("/submit", "bad", ""); code != http.StatusForbidden {
+		t.Fatalf("expect 403 with bad token, got %d", code)
+	}
+	if code := post("/submit", token, ""); code != http.StatusOK {
+		t.Fatalf("expect 200 with header token, got %d", code)
+	}
+	if code := post("/submit", "", token); code != http.StatusOK {
+		t.Fatalf("expect 200 with form token, got %d", code)
+	}
+	if code := post("/callback/pay", "", ""); code != http.StatusOK {
+		t.Fatalf("expect exempt path, got %d", code)
+	}
+
+	r := httptest.NewRequest("POST", "/api", nil)
+	r.Header.Set("Authorization", "Bearer xxx")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expect bearer exempt, got %d", rec.Code)
+	}
+}
+
+func TestSessionToken(t *testing.T) {
+	m := session.NewManager()
+	p := New(WithSession())
+	h := m.HttpMiddleware(p.HttpMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	token := rec.Header().Get(DefaultHeaderName)
+	var sid *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == DefaultCookieName {
+			t.Fatal("session mode should not set csrf cookie")
+		}
+		if c.Name == session.DefaultCookieName {
+			sid = c
+		}
+	}
+	if token == "" || sid == nil {
+		t.Fatalf("unexpected token %s sid %v", token, sid)
+	}
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.AddCookie(sid)
+	r.Header.Set(DefaultHeaderName, token)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expect 200, got %d", rec.Code)
+	}
+
+	// 没有会话时 token 对不上
+	r = httptest.NewRequest("POST", "/", nil)
+	r.Header.Set(DefaultHeaderName, token)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expect 403, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,70 @@
+package csv
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testOrder struct {
+	Id        uint64    `csv:"id"`
+	Title     string    `csv:"title"`
+	Price     float64   `csv:"price"`
+	Paid      bool      `csv:"paid"`
+	CreatedAt time.Time `csv:"created_at,format=2006-01-02"`
+	Note      *string   `csv:"note"`
+	Tmp       string    `csv:"-"`
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	note := "含,逗号"
+	created := time.Date(2023, 5, 1, 0, 0, 0, 0, time.Local)
+	list := []*testOrder{
+		{Id: 1, Title: "订单一", Price: 9.9, Paid: true, CreatedAt: created, Note: &note, Tmp: "x"},
+		{Id: 2, Title: "订单二"},
+	}
+
+	var buf bytes.Buffer
+	if err := Marshal(&buf, list, WithBOM()); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), BOM) {
+		t.Fatal("missing bom")
+	}
+	if !strings.Contains(buf.String(), "id,title,price,paid,created_at,note\n") {
+		t.Fatalf("unexpected header %q", buf.String())
+	}
+
+	got, err := Unmarshal[testOrder](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Title != "订单一" || got[0].Price != 9.9 || !got[0].Paid ||
+		!got[0].CreatedAt.Equal(created) || *got[0].Note != note || got[0].Tmp != "" || got[1].Note != nil {
+		t.Fatalf("unexpected %+v", got)
+	}
+}
+
+func TestHttpWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w, err := NewHttpWriter[testOrder](rec, "订单.csv", WithFlushRows(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = w.Write(&testOrder{Id: uint64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Flushed || !strings.Contains(rec.Header().Get("Content-Disposition"), "%E8%AE%A2%E5%8D%95.csv") {
+		t.Fatalf("unexpected response %v", rec.Header())
+	}
+	if n := strings.Count(rec.Body.String(), "\n"); n != 4 {
+		t.Fatalf("expect 4 lines, got %d", n)
+	}
+}
@@ -0,0 +1,100 @@
+package csv
+
+import (
+	"bufio"
+	"bytes"
+	stdcsv "encoding/csv"
+	"fmt"
+	"github.com/oldbai555/lbtool/pkg/internal/tabular"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Reader 按表头流式读取结构体, 自动去掉 utf-8 BOM
+type Reader[T any] struct {
+	r      *stdcsv.Reader
+	colIdx map[int]*tabular.Field
+	line   int
+}
+
+// NewReader 读取表头并按名称匹配字段, 没有匹配的列会被忽略
+func NewReader[T any](in io.Reader, ops ...Option) (*Reader[T], error) {
+	opts := &options{}
+	for i := range ops {
+		ops[i](opts)
+	}
+
+	var zero T
+	fields, err := parseFields(reflect.TypeOf(zero))
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(in)
+	if prefix, err := br.Peek(len(BOM)); err == nil && bytes.Equal(prefix, BOM) {
+		_, _ = br.Discard(len(BOM))
+	}
+	r := stdcsv.NewReader(br)
+	if opts.comma != 0 {
+		r.Comma = opts.comma
+	}
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*tabular.Field, len(fields))
+	for _, f := range fields {
+		byName[f.Name] = f
+	}
+	colIdx := make(map[int]*tabular.Field, len(fields))
+	for idx, name := range header {
+		if f, ok := byName[strings.TrimSpace(name)]; ok {
+			colIdx[idx] = f
+		}
+	}
+
+	return &Reader[T]{r: r, colIdx: colIdx, line: 1}, nil
+}
+
+// Read 读取下一行, 读完返回 io.EOF
+func (r *Reader[T]) Read() (*T, error) {
+	record, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	r.line++
+
+	item := new(T)
+	v := reflect.ValueOf(item).Elem()
+	for idx, f := range r.colIdx {
+		if idx >= len(record) {
+			continue
+		}
+		if err = f.FromString(v.FieldByIndex(f.Index), record[idx]); err != nil {
+			return nil, fmt.Errorf("csv: line %d column %s: %v", r.line, f.Name, err)
+		}
+	}
+	return item, nil
+}
+
+// Unmarshal 一次性读取所有行
+func Unmarshal[T any](in io.Reader, ops ...Option) ([]*T, error) {
+	r, err := NewReader[T](in, ops...)
+	if err != nil {
+		return nil, err
+	}
+	var list []*T
+	for {
+		item, err := r.Read()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+}
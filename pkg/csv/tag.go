@@ -0,0 +1,21 @@
+package csv
+
+import (
+	"github.com/oldbai555/lbtool/pkg/internal/tabular"
+	"reflect"
+)
+
+const (
+	TagName = "csv"
+
+	DefaultTimeLayout = tabular.DefaultTimeLayout
+)
+
+// parseFields 解析结构体的列定义
+//
+//	Name string    `csv:"name"`
+//	At   time.Time `csv:"created_at,format=2006-01-02"`
+//	Tmp  string    `csv:"-"`
+func parseFields(t reflect.Type) ([]*tabular.Field, error) {
+	return tabular.ParseFields(TagName, t)
+}
@@ -0,0 +1,166 @@
+package csv
+
+import (
+	stdcsv "encoding/csv"
+	"fmt"
+	"github.com/oldbai555/lbtool/pkg/internal/tabular"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// BOM utf-8 BOM, 带上之后 Excel 打开中文不会乱码
+var BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// DefaultFlushRows 流式写入时每写多少行刷一次
+const DefaultFlushRows = 1000
+
+// Writer 流式写入结构体, 每写 flushRows 行刷一次, 内存占用与总行数无关
+type Writer[T any] struct {
+	w         *stdcsv.Writer
+	fields    []*tabular.Field
+	flushRows int
+	pending   int
+}
+
+type Option func(*options)
+
+type options struct {
+	bom       bool
+	comma     rune
+	flushRows int
+}
+
+// WithBOM 输出 utf-8 BOM, 方便 Excel 直接打开
+func WithBOM() Option {
+	return func(o *options) {
+		o.bom = true
+	}
+}
+
+// WithComma 自定义分隔符, 默认逗号
+func WithComma(comma rune) Option {
+	return func(o *options) {
+		o.comma = comma
+	}
+}
+
+// WithFlushRows 每写多少行刷一次
+func WithFlushRows(n int) Option {
+	return func(o *options) {
+		o.flushRows = n
+	}
+}
+
+// NewWriter 创建写入器并写入表头
+func NewWriter[T any](out io.Writer, ops ...Option) (*Writer[T], error) {
+	opts := &options{flushRows: DefaultFlushRows}
+	for i := range ops {
+		ops[i](opts)
+	}
+
+	var zero T
+	fields, err := parseFields(reflect.TypeOf(zero))
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.bom {
+		if _, err = out.Write(BOM); err != nil {
+			return nil, err
+		}
+	}
+	w := stdcsv.NewWriter(out)
+	if opts.comma != 0 {
+		w.Comma = opts.comma
+	}
+	header := make([]string, 0, len(fields))
+	for _, f := range fields {
+		header = append(header, f.Name)
+	}
+	if err = w.Write(header); err != nil {
+		return nil, err
+	}
+
+	if opts.flushRows <= 0 {
+		opts.flushRows = DefaultFlushRows
+	}
+	return &Writer[T]{
+		w:         w,
+		fields:    fields,
+		flushRows: opts.flushRows,
+	}, nil
+}
+
+// Write 追加数据行
+func (w *Writer[T]) Write(rows ...*T) error {
+	record := make([]string, len(w.fields))
+	for _, row := range rows {
+		if row == nil {
+			continue
+		}
+		v := reflect.ValueOf(row).Elem()
+		for i, f := range w.fields {
+			s, err := f.ToString(v.FieldByIndex(f.Index))
+			if err != nil {
+				return fmt.Errorf("csv: column %s: %v", f.Name, err)
+			}
+			record[i] = s
+		}
+		if err := w.w.Write(record); err != nil {
+			return err
+		}
+		w.pending++
+		if w.pending >= w.flushRows {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Flush 把缓冲的数据写出, 写完之后必须调用
+func (w *Writer[T]) Flush() error {
+	w.pending = 0
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Marshal 一次性写入结构体列表
+func Marshal[T any](out io.Writer, list []*T, ops ...Option) error {
+	w, err := NewWriter[T](out, ops...)
+	if err != nil {
+		return err
+	}
+	if err = w.Write(list...); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// NewHttpWriter 设置下载相关的响应头并返回写入器, 默认带 BOM; 写入过程中每 flushRows 行推送一次给客户端
+func NewHttpWriter[T any](rw http.ResponseWriter, fileName string, ops ...Option) (*Writer[T], error) {
+	rw.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(fileName)))
+	out := io.Writer(rw)
+	if f, ok := rw.(http.Flusher); ok {
+		out = &flushWriter{w: rw, f: f}
+	}
+	return NewWriter[T](out, append([]Option{WithBOM()}, ops...)...)
+}
+
+// flushWriter csv 每次 Flush 时同时推送 http 响应, 避免大文件全部堆在缓冲里
+type flushWriter struct {
+	w io.Writer
+	f http.Flusher
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if err == nil {
+		fw.f.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,290 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/log"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNotProvided        = errors.New("type not provided")
+	ErrCycle              = errors.New("dependency cycle")
+	ErrDuplicate          = errors.New("type already provided")
+	ErrInvalidConstructor = errors.New("invalid constructor")
+	ErrStarted            = errors.New("container already started")
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Starter 实例实现该接口时, 容器 Start 时按依赖顺序调用
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper 实例实现该接口时, 容器 Stop 时按依赖逆序调用
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// provider 一个构造函数, 单例, 第一次被依赖时才调用
+type provider struct {
+	ctor     reflect.Value
+	out      reflect.Type
+	in       []reflect.Type
+	hasErr   bool
+	instance reflect.Value
+	built    bool
+}
+
+// Container 依赖注入容器, 按参数类型自动查找构造函数, 所有实例都是单例
+type Container struct {
+	mu        sync.Mutex
+	providers map[reflect.Type]*provider
+	resolving []reflect.Type
+	// instances 按创建顺序记录, 依赖总是排在被依赖者之前
+	instances []reflect.Value
+	started   []reflect.Value
+	running   bool
+}
+
+func New() *Container {
+	return &Container{
+		providers: make(map[reflect.Type]*provider),
+	}
+}
+
+type ProvideOption func(*provideOptions)
+
+type provideOptions struct {
+	as []reflect.Type
+}
+
+// As 同时注册为接口类型, 参数为接口指针, 例如 di.As(new(Store))
+func As(ifacePtr interface{}) ProvideOption {
+	return func(o *provideOptions) {
+		o.as = append(o.as, reflect.TypeOf(ifacePtr))
+	}
+}
+
+// Provide 注册构造函数, 形如 func(deps...) T 或 func(deps...) (T, error)
+func (c *Container) Provide(ctor interface{}, ops ...ProvideOption) error {
+	v := reflect.ValueOf(ctor)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("%w: %s is not a func", ErrInvalidConstructor, t)
+	}
+	p := &provider{ctor: v}
+	switch {
+	case t.NumOut() == 1 && t.Out(0) != errorType:
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+		p.hasErr = true
+	default:
+		return fmt.Errorf("%w: %s must return T or (T, error)", ErrInvalidConstructor, t)
+	}
+	p.out = t.Out(0)
+	for i := 0; i < t.NumIn(); i++ {
+		p.in = append(p.in, t.In(i))
+	}
+	return c.register(p, ops...)
+}
+
+// Supply 注册已经创建好的值
+func (c *Container) Supply(value interface{}, ops ...ProvideOption) error {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return fmt.Errorf("%w: nil value", ErrInvalidConstructor)
+	}
+	p := &provider{out: v.Type(), instance: v, built: true}
+	return c.register(p, ops...)
+}
+
+func (c *Container) register(p *provider, ops ...ProvideOption) error {
+	o := &provideOptions{}
+	for i := range ops {
+		ops[i](o)
+	}
+	types := []reflect.Type{p.out}
+	for _, ptr := range o.as {
+		if ptr == nil || ptr.Kind() != reflect.Ptr || ptr.Elem().Kind() != reflect.Interface {
+			return fmt.Errorf("%w: As expects a pointer to interface, got %v", ErrInvalidConstructor, ptr)
+		}
+		iface := ptr.Elem()
+		if !p.out.Implements(iface) {
+			return fmt.Errorf("%w: %s does not implement %s", ErrInvalidConstructor, p.out, iface)
+		}
+		types = append(types, iface)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, t := range types {
+		if _, ok := c.providers[t]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicate, t)
+		}
+	}
+	for _, t := range types {
+		c.providers[t] = p
+	}
+	if p.built {
+		c.instances = append(c.instances, p.instance)
+	}
+	return nil
+}
+
+// Invoke 解析 fn 的参数并调用, fn 最后一个返回值为 error 时返回该错误
+func (c *Container) Invoke(fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("%w: %s is not a func", ErrInvalidConstructor, t)
+	}
+
+	c.mu.Lock()
+	args := make([]reflect.Value, t.NumIn())
+	for i := range args {
+		arg, err := c.resolve(t.In(i))
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		args[i] = arg
+	}
+	c.mu.Unlock()
+
+	out := v.Call(args)
+	if n := len(out); n > 0 && t.Out(n-1) == errorType && !out[n-1].IsNil() {
+		return out[n-1].Interface().(error)
+	}
+	return nil
+}
+
+// Resolve 获取类型 T 的实例, 需要时创建依赖
+func Resolve[T any](c *Container) (T, error) {
+	var zero T
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, err := c.resolve(t)
+	if err != nil {
+		return zero, err
+	}
+	// T 为接口且构造函数返回 nil 时断言会失败, 返回零值
+	out, _ := v.Interface().(T)
+	return out, nil
+}
+
+// MustResolve 获取失败时 panic, 用于 main 函数里组装
+func MustResolve[T any](c *Container) T {
+	v, err := Resolve[T](c)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// resolve 调用前需要持有锁
+func (c *Container) resolve(t reflect.Type) (reflect.Value, error) {
+	p, ok := c.providers[t]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("%w: %s", ErrNotProvided, t)
+	}
+	if p.built {
+		return p.instance, nil
+	}
+
+	for i, r := range c.resolving {
+		if r == p.out {
+			path := make([]string, 0, len(c.resolving)-i+1)
+			for _, rt := range c.resolving[i:] {
+				path = append(path, rt.String())
+			}
+			path = append(path, p.out.String())
+			return reflect.Value{}, fmt.Errorf("%w: %s", ErrCycle, strings.Join(path, " -> "))
+		}
+	}
+	c.resolving = append(c.resolving, p.out)
+	defer func() {
+		c.resolving = c.resolving[:len(c.resolving)-1]
+	}()
+
+	args := make([]reflect.Value, len(p.in))
+	for i, in := range p.in {
+		arg, err := c.resolve(in)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("resolve %s: %w", p.out, err)
+		}
+		args[i] = arg
+	}
+	out := p.ctor.Call(args)
+	if p.hasErr && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("construct %s: %w", p.out, out[1].Interface().(error))
+	}
+	p.instance = out[0]
+	p.built = true
+	c.instances = append(c.instances, p.instance)
+	return p.instance, nil
+}
+
+// Start 按创建顺序启动已经创建的实例, 只会启动被解析过的实例
+// 某个实例启动失败时, 逆序关闭已经启动的实例
+func (c *Container) Start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return ErrStarted
+	}
+	c.running = true
+	instances := make([]reflect.Value, len(c.instances))
+	copy(instances, c.instances)
+	c.mu.Unlock()
+
+	var started []reflect.Value
+	for _, v := range instances {
+		s, ok := v.Interface().(Starter)
+		if !ok {
+			started = append(started, v)
+			continue
+		}
+		if err := s.Start(ctx); err != nil {
+			c.setStarted(started)
+			if stopErr := c.Stop(ctx); stopErr != nil {
+				log.Errorf("err:%v", stopErr)
+			}
+			return fmt.Errorf("start %s: %w", v.Type(), err)
+		}
+		started = append(started, v)
+	}
+	c.setStarted(started)
+	return nil
+}
+
+func (c *Container) setStarted(started []reflect.Value) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = started
+}
+
+// Stop 按启动的逆序关闭实例
+func (c *Container) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	started := c.started
+	c.started = nil
+	c.running = false
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		s, ok := started[i].Interface().(Stopper)
+		if !ok {
+			continue
+		}
+		if err := s.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", started[i].Type(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
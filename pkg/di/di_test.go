@@ -0,0 +1,162 @@
+package di
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Config struct {
+	Dsn string
+}
+
+type Store interface {
+	Get(key string) string
+}
+
+type DB struct {
+	conf *Config
+	rec  *[]string
+}
+
+func (d *DB) Get(key string) string {
+	return d.conf.Dsn + "/" + key
+}
+
+func (d *DB) Start(ctx context.Context) error {
+	*d.rec = append(*d.rec, "start db")
+	return nil
+}
+
+func (d *DB) Stop(ctx context.Context) error {
+	*d.rec = append(*d.rec, "stop db")
+	return nil
+}
+
+type Service struct {
+	store Store
+	rec   *[]string
+}
+
+func (s *Service) Start(ctx context.Context) error {
+	*s.rec = append(*s.rec, "start service")
+	return nil
+}
+
+func (s *Service) Stop(ctx context.Context) error {
+	*s.rec = append(*s.rec, "stop service")
+	return nil
+}
+
+func TestContainer(t *testing.T) {
+	var rec []string
+	ctorCalls := 0
+	c := New()
+	if err := c.Supply(&Config{Dsn: "mysql"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Provide(func(conf *Config) (*DB, error) {
+		ctorCalls++
+		return &DB{conf: conf, rec: &rec}, nil
+	}, As(new(Store))); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Provide(func(store Store) *Service {
+		return &Service{store: store, rec: &rec}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if ctorCalls != 0 {
+		t.Fatal("constructor should be lazy")
+	}
+
+	svc := MustResolve[*Service](c)
+	if svc.store.Get("k") != "mysql/k" {
+		t.Fatalf("unexpected %s", svc.store.Get("k"))
+	}
+	db := MustResolve[*DB](c)
+	if Store(db) != svc.store || ctorCalls != 1 {
+		t.Fatal("instance should be singleton")
+	}
+
+	if err := c.Invoke(func(s Store, conf *Config) error {
+		if s.Get("x") != "mysql/x" {
+			return errors.New("bad store")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(ctx); !errors.Is(err, ErrStarted) {
+		t.Fatalf("expect ErrStarted, got %v", err)
+	}
+	if err := c.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start db", "start service", "stop service", "stop db"}
+	if !reflect.DeepEqual(rec, want) {
+		t.Fatalf("got %v, want %v", rec, want)
+	}
+}
+
+type A struct{}
+type B struct{}
+
+func TestResolveNilInterface(t *testing.T) {
+	c := New()
+	if err := c.Provide(func() Store { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Provide(func(s Store) *Service { return &Service{store: s} }); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Resolve[Store](c)
+	if err != nil || s != nil {
+		t.Fatalf("got %v, err %v", s, err)
+	}
+	svc, err := Resolve[*Service](c)
+	if err != nil || svc.store != nil {
+		t.Fatalf("got %v, err %v", svc, err)
+	}
+}
+
+func TestContainer_Errors(t *testing.T) {
+	c := New()
+	if err := c.Provide(func(b *B) *A { return &A{} }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Resolve[*A](c); !errors.Is(err, ErrNotProvided) {
+		t.Fatalf("expect ErrNotProvided, got %v", err)
+	}
+	if err := c.Provide(func(a *A) *B { return &B{} }); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Resolve[*A](c)
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("expect ErrCycle, got %v", err)
+	}
+	t.Log(err)
+
+	if err := c.Provide(func() *A { return &A{} }); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("expect ErrDuplicate, got %v", err)
+	}
+	if err := c.Provide(func() error { return nil }); !errors.Is(err, ErrInvalidConstructor) {
+		t.Fatalf("expect ErrInvalidConstructor, got %v", err)
+	}
+	if err := c.Provide(func() *Config { return nil }, As(new(Store))); !errors.Is(err, ErrInvalidConstructor) {
+		t.Fatalf("expect ErrInvalidConstructor, got %v", err)
+	}
+
+	ctorErr := errors.New("connect refused")
+	c = New()
+	_ = c.Provide(func() (*Config, error) { return nil, ctorErr })
+	if _, err := Resolve[*Config](c); !errors.Is(err, ctorErr) {
+		t.Fatalf("expect ctor err, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/utils"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTTL = 10 * time.Second
+)
+
+var (
+	ErrNoLeader = errors.New("no leader")
+)
+
+// Elector 选主, 同一时刻最多只有一个实例是 leader
+type Elector interface {
+	// Campaign 阻塞直到成为 leader 或 ctx 结束
+	Campaign(ctx context.Context) error
+	// Resign 主动退位, 其他实例可以马上当选
+	Resign(ctx context.Context) error
+	IsLeader() bool
+	// Leader 当前 leader 的 id
+	Leader(ctx context.Context) (string, error)
+	// Done 当前任期结束 (退位或者续约失败) 时关闭
+	Done() <-chan struct{}
+}
+
+type options struct {
+	id       string
+	ttl      time.Duration
+	onChange func(leader bool)
+}
+
+type Option func(*options)
+
+// WithId 实例 id, 默认随机生成, 建议使用 主机名+端口 便于排查
+func WithId(id string) Option {
+	return func(o *options) {
+		o.id = id
+	}
+}
+
+// WithTTL leader 失联后多久可以被其他实例取代
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithOnChange 当选和失去 leader 时回调
+func WithOnChange(fn func(leader bool)) Option {
+	return func(o *options) {
+		o.onChange = fn
+	}
+}
+
+func newOptions(ops ...Option) *options {
+	o := &options{
+		id:  utils.GenUUID(),
+		ttl: DefaultTTL,
+	}
+	for i := range ops {
+		ops[i](o)
+	}
+	return o
+}
+
+// term 维护 leader 状态和任期, 各个实现共用
+type term struct {
+	mu       sync.Mutex
+	leader   bool
+	done     chan struct{}
+	onChange func(leader bool)
+}
+
+func newTerm(onChange func(leader bool)) *term {
+	done := make(chan struct{})
+	close(done)
+	return &term{done: done, onChange: onChange}
+}
+
+func (t *term) elected() {
+	t.mu.Lock()
+	t.leader = true
+	t.done = make(chan struct{})
+	t.mu.Unlock()
+	if t.onChange != nil {
+		t.onChange(true)
+	}
+}
+
+func (t *term) revoked() {
+	t.mu.Lock()
+	if !t.leader {
+		t.mu.Unlock()
+		return
+	}
+	t.leader = false
+	close(t.done)
+	t.mu.Unlock()
+	if t.onChange != nil {
+		t.onChange(false)
+	}
+}
+
+func (t *term) IsLeader() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.leader
+}
+
+func (t *term) Done() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.done
+}
+
+// Run 循环竞选, 当选后执行 fn, 失去 leader 时取消 fn 的 ctx; ctx 结束时主动退位并返回
+// fn 返回后如果仍是 leader 会先退位, 然后重新参与竞选
+func Run(ctx context.Context, e Elector, fn func(ctx context.Context) error) error {
+	for {
+		if err := e.Campaign(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Errorf("campaign err:%v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		termCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-e.Done():
+				cancel()
+			case <-termCtx.Done():
+			}
+		}()
+		if err := fn(termCtx); err != nil && termCtx.Err() == nil {
+			log.Errorf("leader task err:%v", err)
+		}
+		cancel()
+
+		if e.IsLeader() {
+			if err := e.Resign(context.WithoutCancel(ctx)); err != nil {
+				log.Errorf("resign err:%v", err)
+			}
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package election
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memElector 进程内的选主, 多个实例共享同一个 holder
+type memElector struct {
+	*term
+	id     string
+	mu     *sync.Mutex
+	holder *string
+}
+
+func newMemElector(id string, mu *sync.Mutex, holder *string, onChange func(bool)) *memElector {
+	return &memElector{term: newTerm(onChange), id: id, mu: mu, holder: holder}
+}
+
+func (m *memElector) Campaign(ctx context.Context) error {
+	for {
+		m.mu.Lock()
+		if *m.holder == "" {
+			*m.holder = m.id
+			m.mu.Unlock()
+			m.elected()
+			return nil
+		}
+		m.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func (m *memElector) Resign(ctx context.Context) error {
+	m.mu.Lock()
+	if *m.holder == m.id {
+		*m.holder = ""
+	}
+	m.mu.Unlock()
+	m.revoked()
+	return nil
+}
+
+func (m *memElector) Leader(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if *m.holder == "" {
+		return "", ErrNoLeader
+	}
+	return *m.holder, nil
+}
+
+// lose 模拟续约失败
+func (m *memElector) lose() {
+	m.mu.Lock()
+	*m.holder = ""
+	m.mu.Unlock()
+	m.revoked()
+}
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	var holder string
+	var changes []bool
+	var changesMu sync.Mutex
+	a := newMemElector("a", &mu, &holder, func(leader bool) {
+		changesMu.Lock()
+		changes = append(changes, leader)
+		changesMu.Unlock()
+	})
+	b := newMemElector("b", &mu, &holder, nil)
+
+	var runningA, runningB int32
+	task := func(running *int32) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			atomic.AddInt32(running, 1)
+			<-ctx.Done()
+			atomic.AddInt32(running, -1)
+			return nil
+		}
+	}
+
+	ctxA, cancelA := context.WithCancel(context.Background())
+	doneA := make(chan struct{})
+	go func() {
+		_ = Run(ctxA, a, task(&runningA))
+		close(doneA)
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&runningA) == 1 })
+
+	ctxB, cancelB := context.WithCancel(context.Background())
+	doneB := make(chan struct{})
+	go func() {
+		_ = Run(ctxB, b, task(&runningB))
+		close(doneB)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if b.IsLeader() || atomic.LoadInt32(&runningB) != 0 {
+		t.Fatal("b should not be leader")
+	}
+
+	// a 失去 leader 后任务被取消, 然后重新竞选
+	a.lose()
+	waitFor(t, func() bool { return a.IsLeader() || b.IsLeader() })
+
+	cancelA()
+	<-doneA
+	waitFor(t, func() bool { return atomic.LoadInt32(&runningA) == 0 && atomic.LoadInt32(&runningB) == 1 })
+	if leader, _ := b.Leader(context.Background()); leader != "b" {
+		t.Fatalf("unexpected leader %s", leader)
+	}
+
+	cancelB()
+	<-doneB
+	if b.IsLeader() || holder != "" || atomic.LoadInt32(&runningB) != 0 {
+		t.Fatal("b should resign when ctx done")
+	}
+	changesMu.Lock()
+	defer changesMu.Unlock()
+	if len(changes) < 2 || !changes[0] || changes[1] {
+		t.Fatalf("unexpected changes %v", changes)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// redis 不可用时, 在 key 过期之前放弃 leader
+func TestRedisRenewRevokeBeforeExpire(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	ttl := 300 * time.Millisecond
+	r := NewRedisElector(client, "election_test", WithTTL(ttl))
+	start := time.Now()
+	r.elected()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.renew(ctx, start)
+
+	select {
+	case <-r.Done():
+	case <-time.After(2 * ttl):
+		t.Fatal("should revoke")
+	}
+	if cost := time.Since(start); cost >= ttl {
+		t.Errorf("revoked after %v, key may already be taken by others", cost)
+	}
+}
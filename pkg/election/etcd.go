@@ -0,0 +1,133 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+	"sync"
+)
+
+var _ Elector = (*EtcdElector)(nil)
+
+// EtcdElector 基于 etcd lease 和 concurrency.Election 的选主
+// 租约过期 (网络分区, 进程卡死) 时视为失去 leader
+type EtcdElector struct {
+	*term
+	client *clientv3.Client
+	prefix string
+	opts   *options
+
+	mu       sync.Mutex
+	session  *concurrency.Session
+	election *concurrency.Election
+}
+
+func NewEtcdElector(client *clientv3.Client, prefix string, ops ...Option) *EtcdElector {
+	o := newOptions(ops...)
+	return &EtcdElector{
+		term:   newTerm(o.onChange),
+		client: client,
+		prefix: prefix,
+		opts:   o,
+	}
+}
+
+func (e *EtcdElector) Id() string {
+	return e.opts.id
+}
+
+// getElection 租约过期后 session 不可用, 需要重新创建
+func (e *EtcdElector) getElection() (*concurrency.Session, *concurrency.Election, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session != nil {
+		select {
+		case <-e.session.Done():
+			e.session = nil
+		default:
+			return e.session, e.election, nil
+		}
+	}
+	ttl := int(e.opts.ttl.Seconds())
+	if ttl < 1 {
+		ttl = 1
+	}
+	session, err := concurrency.NewSession(e.client, concurrency.WithTTL(ttl))
+	if err != nil {
+		return nil, nil, err
+	}
+	e.session = session
+	e.election = concurrency.NewElection(session, e.prefix)
+	return e.session, e.election, nil
+}
+
+func (e *EtcdElector) Campaign(ctx context.Context) error {
+	if e.IsLeader() {
+		return nil
+	}
+	session, election, err := e.getElection()
+	if err != nil {
+		return err
+	}
+	if err = election.Campaign(ctx, e.opts.id); err != nil {
+		return err
+	}
+	e.elected()
+	log.Infof("%s elected as leader of %s", e.opts.id, e.prefix)
+
+	done := e.Done()
+	go func() {
+		select {
+		case <-session.Done():
+			log.Warnf("%s lost leadership of %s, session expired", e.opts.id, e.prefix)
+			e.revoked()
+		case <-done:
+		}
+	}()
+	return nil
+}
+
+func (e *EtcdElector) Resign(ctx context.Context) error {
+	if !e.IsLeader() {
+		return nil
+	}
+	e.revoked()
+	e.mu.Lock()
+	election := e.election
+	e.mu.Unlock()
+	if election == nil {
+		return nil
+	}
+	return election.Resign(ctx)
+}
+
+func (e *EtcdElector) Leader(ctx context.Context) (string, error) {
+	_, election, err := e.getElection()
+	if err != nil {
+		return "", err
+	}
+	resp, err := election.Leader(ctx)
+	if errors.Is(err, concurrency.ErrElectionNoLeader) {
+		return "", ErrNoLeader
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(resp.Kvs[0].Value), nil
+}
+
+// Close 退位并释放租约
+func (e *EtcdElector) Close(ctx context.Context) error {
+	err := e.Resign(ctx)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.session != nil {
+		if closeErr := e.session.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		e.session = nil
+	}
+	return err
+}
@@ -0,0 +1,136 @@
+package election
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/oldbai555/lbtool/log"
+	"time"
+)
+
+var (
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	resignScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+var _ Elector = (*RedisElector)(nil)
+
+// RedisElector 基于 SET NX PX 的选主, leader 每 ttl/3 续约一次
+// 续约失败到下一次续约之前 key 可能过期, 或者 key 被其他实例占用时视为失去 leader
+type RedisElector struct {
+	*term
+	client redis.UniversalClient
+	key    string
+	opts   *options
+
+	stopRenew context.CancelFunc
+}
+
+func NewRedisElector(client redis.UniversalClient, key string, ops ...Option) *RedisElector {
+	o := newOptions(ops...)
+	return &RedisElector{
+		term:   newTerm(o.onChange),
+		client: client,
+		key:    key,
+		opts:   o,
+	}
+}
+
+func (r *RedisElector) Id() string {
+	return r.opts.id
+}
+
+func (r *RedisElector) Campaign(ctx context.Context) error {
+	if r.IsLeader() {
+		return nil
+	}
+	interval := r.opts.ttl / 3
+	for {
+		// key 的过期时间从 redis 执行 SET 时算起, 不会早于发出请求的时间
+		sentAt := time.Now()
+		ok, err := r.client.SetNX(ctx, r.key, r.opts.id, r.opts.ttl).Result()
+		if err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			log.Warnf("campaign %s err:%v", r.key, err)
+		}
+		if ok {
+			r.elected()
+			renewCtx, cancel := context.WithCancel(context.Background())
+			r.stopRenew = cancel
+			go r.renew(renewCtx, sentAt)
+			log.Infof("%s elected as leader of %s", r.opts.id, r.key)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// renew 每 ttl/3 续约一次, lastOk 为上次续约成功时发出请求的时间, key 不会在 lastOk+ttl 之前过期
+// 续约失败后如果等不到下一次续约 key 就可能过期, 立即放弃 leader, 保证同一时刻最多一个 leader
+func (r *RedisElector) renew(ctx context.Context, lastOk time.Time) {
+	interval := r.opts.ttl / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sentAt := time.Now()
+		// 请求阻塞时也要在 key 过期之前返回, 留出 interval/2 的余量
+		callCtx, cancel := context.WithDeadline(ctx, lastOk.Add(r.opts.ttl-interval/2))
+		res, err := renewScript.Run(callCtx, r.client, []string{r.key}, r.opts.id, r.opts.ttl.Milliseconds()).Int()
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err == nil && res == 1:
+			lastOk = sentAt
+			continue
+		case err == nil:
+			log.Warnf("%s lost leadership of %s", r.opts.id, r.key)
+		case time.Since(lastOk) < r.opts.ttl-interval:
+			log.Warnf("renew %s err:%v", r.key, err)
+			continue
+		default:
+			log.Errorf("renew %s failed before lease expires, err:%v", r.key, err)
+		}
+		r.revoked()
+		return
+	}
+}
+
+func (r *RedisElector) Resign(ctx context.Context) error {
+	if !r.IsLeader() {
+		return nil
+	}
+	if r.stopRenew != nil {
+		r.stopRenew()
+	}
+	r.revoked()
+	return resignScript.Run(ctx, r.client, []string{r.key}, r.opts.id).Err()
+}
+
+func (r *RedisElector) Leader(ctx context.Context) (string, error) {
+	id, err := r.client.Get(ctx, r.key).Result()
+	if err == redis.Nil {
+		return "", ErrNoLeader
+	}
+	return id, err
+}
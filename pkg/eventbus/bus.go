@@ -0,0 +1,241 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/routine"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	DefaultQueueSize = 1024
+	DefaultWorkerNum = 4
+)
+
+var (
+	ErrBusClosed = errors.New("event bus closed")
+)
+
+var _ IBus = (*Bus)(nil)
+
+// Bus 进程内的发布订阅总线
+type Bus struct {
+	queueSize int
+	workerNum int
+
+	subId  uint64
+	subs   map[uint64]*Subscription
+	subsMu sync.RWMutex
+
+	queue    chan *asyncJob
+	closed   bool
+	closeMu  sync.RWMutex
+	done     chan struct{}  // Close 时关闭, 阻塞在队列上的 Publish 直接返回
+	pubWg    sync.WaitGroup // 进行中的 Publish, 全部返回后才能关闭队列
+	workerWg sync.WaitGroup
+}
+
+// asyncJob 异步投递的任务
+type asyncJob struct {
+	ctx context.Context
+	sub *Subscription
+	evt *Event
+}
+
+type Option func(*Bus)
+
+// WithQueueSize 异步队列长度,队列满时 Publish 会阻塞
+func WithQueueSize(size int) Option {
+	return func(b *Bus) {
+		b.queueSize = size
+	}
+}
+
+// WithWorkerNum 处理异步事件的协程数
+func WithWorkerNum(num int) Option {
+	return func(b *Bus) {
+		b.workerNum = num
+	}
+}
+
+func NewBus(ops ...Option) *Bus {
+	b := &Bus{
+		queueSize: DefaultQueueSize,
+		workerNum: DefaultWorkerNum,
+		subs:      make(map[uint64]*Subscription),
+		done:      make(chan struct{}),
+	}
+	for i := range ops {
+		ops[i](b)
+	}
+	if b.queueSize <= 0 {
+		b.queueSize = DefaultQueueSize
+	}
+	if b.workerNum <= 0 {
+		b.workerNum = DefaultWorkerNum
+	}
+	b.queue = make(chan *asyncJob, b.queueSize)
+	for i := 0; i < b.workerNum; i++ {
+		b.workerWg.Add(1)
+		go b.loopDoAsync()
+	}
+	return b
+}
+
+// Subscription 订阅关系
+type Subscription struct {
+	id      uint64
+	pattern string
+	handler Handler
+	async   bool
+	bus     *Bus
+}
+
+type SubOption func(*Subscription)
+
+// WithAsync 订阅者异步处理事件,不阻塞发布者
+func WithAsync() SubOption {
+	return func(s *Subscription) {
+		s.async = true
+	}
+}
+
+func (s *Subscription) Pattern() string {
+	return s.pattern
+}
+
+// Unsubscribe 取消订阅,已经投递到队列的事件仍会被处理
+func (s *Subscription) Unsubscribe() {
+	s.bus.subsMu.Lock()
+	defer s.bus.subsMu.Unlock()
+	delete(s.bus.subs, s.id)
+}
+
+func (b *Bus) Subscribe(pattern string, handler Handler, opts ...SubOption) (*Subscription, error) {
+	if handler == nil {
+		return nil, fmt.Errorf("nil handler")
+	}
+	if err := checkPattern(pattern); err != nil {
+		return nil, err
+	}
+	s := &Subscription{
+		id:      atomic.AddUint64(&b.subId, 1),
+		pattern: pattern,
+		handler: handler,
+		bus:     b,
+	}
+	for i := range opts {
+		opts[i](s)
+	}
+
+	b.subsMu.Lock()
+	defer b.subsMu.Unlock()
+	b.subs[s.id] = s
+	return s, nil
+}
+
+// Publish 发布事件,返回同步订阅者的错误,异步订阅者的错误只记录日志
+// 队列满时阻塞, ctx 结束或者总线关闭时返回; 阻塞期间不持有锁, 订阅者内可以再次发布
+func (b *Bus) Publish(ctx context.Context, topic string, payload interface{}) error {
+	if err := checkTopic(topic); err != nil {
+		return err
+	}
+
+	b.closeMu.RLock()
+	if b.closed {
+		b.closeMu.RUnlock()
+		return ErrBusClosed
+	}
+	b.pubWg.Add(1)
+	b.closeMu.RUnlock()
+	defer b.pubWg.Done()
+
+	evt := &Event{Topic: topic, Payload: payload}
+	var errs []error
+	for _, s := range b.matchSubs(topic) {
+		if s.async {
+			// 异步处理时发布者的 ctx 可能已经结束,只保留 ctx 里的值
+			job := &asyncJob{ctx: context.WithoutCancel(ctx), sub: s, evt: evt}
+			select {
+			case b.queue <- job:
+			case <-b.done:
+				errs = append(errs, ErrBusClosed)
+				return errors.Join(errs...)
+			case <-ctx.Done():
+				errs = append(errs, ctx.Err())
+				return errors.Join(errs...)
+			}
+			continue
+		}
+		if err := s.call(ctx, evt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 停止接收新的事件,等待队列中的异步事件处理完,ctx 结束时不再等待
+func (b *Bus) Close(ctx context.Context) error {
+	b.closeMu.Lock()
+	if b.closed {
+		b.closeMu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.done)
+	b.closeMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		// 进行中的 Publish 都返回后没有发送方了, 再关闭队列让 worker 处理完退出
+		b.pubWg.Wait()
+		close(b.queue)
+		b.workerWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		log.Warnf("event bus drain interrupted, %d events left", len(b.queue))
+		return ctx.Err()
+	}
+}
+
+// matchSubs 按订阅顺序找出命中的订阅者
+func (b *Bus) matchSubs(topic string) []*Subscription {
+	b.subsMu.RLock()
+	defer b.subsMu.RUnlock()
+	var list []*Subscription
+	for _, s := range b.subs {
+		if matchTopic(s.pattern, topic) {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].id < list[j].id
+	})
+	return list
+}
+
+func (b *Bus) loopDoAsync() {
+	defer b.workerWg.Done()
+	for job := range b.queue {
+		if err := job.sub.call(job.ctx, job.evt); err != nil {
+			log.Errorf("async handle topic %s err:%v", job.evt.Topic, err)
+		}
+	}
+}
+
+// call 执行订阅者,订阅者 panic 不影响其他订阅者及发布者
+func (s *Subscription) call(ctx context.Context, e *Event) (err error) {
+	defer routine.CatchPanic(func(p interface{}) {
+		err = fmt.Errorf("subscriber %s panic: %v", s.pattern, p)
+	})
+	return s.handler(ctx, e)
+}
@@ -0,0 +1,141 @@
+package eventbus
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"order.created", "order.created", true},
+		{"order.*", "order.created", true},
+		{"order.*", "order.created.v2", false},
+		{"order.**", "order.created.v2", true},
+		{"order.**", "order", false},
+		{"*.created", "user.created", true},
+		{"*.created", "user.deleted", false},
+	}
+	for _, tt := range tests {
+		if got := matchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("matchTopic(%s, %s) = %v, want %v", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestBus_Publish(t *testing.T) {
+	b := NewBus()
+	var syncCnt, asyncCnt int32
+	_, err := b.Subscribe("order.*", func(ctx context.Context, e *Event) error {
+		atomic.AddInt32(&syncCnt, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.Subscribe("order.**", func(ctx context.Context, e *Event) error {
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&asyncCnt, 1)
+		return nil
+	}, WithAsync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.Subscribe("order.paid", func(ctx context.Context, e *Event) error {
+		panic("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = b.Publish(context.Background(), "order.created", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Publish(context.Background(), "order.paid", 2); err == nil {
+		t.Fatal("want panic err")
+	}
+
+	if err = b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if syncCnt != 2 || asyncCnt != 2 {
+		t.Fatalf("sync %d async %d", syncCnt, asyncCnt)
+	}
+	if err = b.Publish(context.Background(), "order.created", 3); err != ErrBusClosed {
+		t.Fatalf("want ErrBusClosed, got %v", err)
+	}
+}
+
+func TestSubscribeTyped(t *testing.T) {
+	type orderCreated struct {
+		Id uint64
+	}
+	b := NewBus()
+	var got uint64
+	_, err := SubscribeTyped(b, "order.created", func(ctx context.Context, topic string, payload *orderCreated) error {
+		got = payload.Id
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = b.Publish(context.Background(), "order.created", "not match")
+	_ = b.Publish(context.Background(), "order.created", &orderCreated{Id: 10})
+	if got != 10 {
+		t.Fatalf("got %d", got)
+	}
+}
+
+// 异步订阅者在队列满时再次发布, 同时 Close 等待队列处理完, 不能死锁
+func TestBus_PublishInHandler(t *testing.T) {
+	b := NewBus(WithQueueSize(1), WithWorkerNum(1))
+	var cnt int32
+	_, err := b.Subscribe("loop", func(ctx context.Context, e *Event) error {
+		if atomic.AddInt32(&cnt, 1) < 100 {
+			_ = b.Publish(ctx, "loop", nil)
+			_ = b.Publish(ctx, "loop", nil)
+		}
+		return nil
+	}, WithAsync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = b.Publish(context.Background(), "loop", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err = b.Close(ctx); err != nil {
+		t.Fatalf("close err:%v", err)
+	}
+}
+
+func TestBus_PublishCtxDone(t *testing.T) {
+	b := NewBus(WithQueueSize(1), WithWorkerNum(1))
+	block := make(chan struct{})
+	_, err := b.Subscribe("slow", func(ctx context.Context, e *Event) error {
+		<-block
+		return nil
+	}, WithAsync())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 一个在处理, 一个在队列里, 第三个阻塞到 ctx 超时
+	_ = b.Publish(context.Background(), "slow", 1)
+	_ = b.Publish(context.Background(), "slow", 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_ = b.Publish(ctx, "slow", 3)
+	if err = b.Publish(ctx, "slow", 4); err == nil {
+		t.Fatal("want ctx err")
+	}
+	close(block)
+	_ = b.Close(context.Background())
+}
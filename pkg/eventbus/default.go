@@ -0,0 +1,29 @@
+package eventbus
+
+import (
+	"context"
+	"sync"
+)
+
+var defaultBus *Bus
+var once sync.Once
+
+func getDefaultBus() *Bus {
+	once.Do(func() {
+		defaultBus = NewBus()
+	})
+	return defaultBus
+}
+
+func Subscribe(pattern string, handler Handler, opts ...SubOption) (*Subscription, error) {
+	return getDefaultBus().Subscribe(pattern, handler, opts...)
+}
+
+func Publish(ctx context.Context, topic string, payload interface{}) error {
+	return getDefaultBus().Publish(ctx, topic, payload)
+}
+
+// Close 服务退出时调用,等待默认总线上的异步事件处理完
+func Close(ctx context.Context) error {
+	return getDefaultBus().Close(ctx)
+}
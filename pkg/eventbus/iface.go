@@ -0,0 +1,22 @@
+package eventbus
+
+import "context"
+
+// Event 总线上流转的事件
+type Event struct {
+	Topic   string      // 发布时的具体主题,不含通配符
+	Payload interface{} // 事件内容
+}
+
+// Handler 订阅者处理函数
+type Handler func(ctx context.Context, e *Event) error
+
+// IBus 进程内事件总线
+type IBus interface {
+	// Subscribe 订阅主题,主题支持通配符 * 匹配一段, ** 匹配剩余所有段
+	Subscribe(pattern string, handler Handler, opts ...SubOption) (*Subscription, error)
+	// Publish 发布事件,同步订阅者在当前协程执行,异步订阅者投递到队列
+	Publish(ctx context.Context, topic string, payload interface{}) error
+	// Close 停止接收事件,并等待队列中的异步事件处理完成
+	Close(ctx context.Context) error
+}
@@ -0,0 +1,63 @@
+package eventbus
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	topicSep          = "."
+	wildcardOne       = "*"  // 匹配一段
+	wildcardRemaining = "**" // 匹配剩余所有段,只能出现在末尾
+)
+
+// checkPattern 校验订阅主题
+func checkPattern(pattern string) error {
+	if pattern == "" {
+		return fmt.Errorf("empty pattern")
+	}
+	parts := strings.Split(pattern, topicSep)
+	for i, part := range parts {
+		if part == "" {
+			return fmt.Errorf("invalid pattern %s", pattern)
+		}
+		if part == wildcardRemaining && i != len(parts)-1 {
+			return fmt.Errorf("%s must be the last part of pattern %s", wildcardRemaining, pattern)
+		}
+	}
+	return nil
+}
+
+// checkTopic 校验发布主题,发布时不允许带通配符
+func checkTopic(topic string) error {
+	if topic == "" {
+		return fmt.Errorf("empty topic")
+	}
+	for _, part := range strings.Split(topic, topicSep) {
+		if part == "" || part == wildcardOne || part == wildcardRemaining {
+			return fmt.Errorf("invalid topic %s", topic)
+		}
+	}
+	return nil
+}
+
+// matchTopic 判断主题是否命中订阅
+func matchTopic(pattern, topic string) bool {
+	if pattern == topic {
+		return true
+	}
+	patternParts := strings.Split(pattern, topicSep)
+	topicParts := strings.Split(topic, topicSep)
+	for i, part := range patternParts {
+		if part == wildcardRemaining {
+			return len(topicParts) > i
+		}
+		if i >= len(topicParts) {
+			return false
+		}
+		if part != wildcardOne && part != topicParts[i] {
+			return false
+		}
+	}
+	return len(patternParts) == len(topicParts)
+}
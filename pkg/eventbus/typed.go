@@ -0,0 +1,20 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+)
+
+// SubscribeTyped 按类型订阅,Payload 类型不匹配的事件会被忽略
+func SubscribeTyped[T any](b IBus, pattern string, fn func(ctx context.Context, topic string, payload T) error, opts ...SubOption) (*Subscription, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("nil handler")
+	}
+	return b.Subscribe(pattern, func(ctx context.Context, e *Event) error {
+		payload, ok := e.Payload.(T)
+		if !ok {
+			return nil
+		}
+		return fn(ctx, e.Topic, payload)
+	}, opts...)
+}
@@ -0,0 +1,139 @@
+package excel
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// RowValidator 结构体实现该接口时, 导入每行后都会调用 Validate 做业务校验
+type RowValidator interface {
+	Validate() error
+}
+
+// RowError 某一行的校验错误, Row 为表格中的行号, 从 1 开始
+type RowError struct {
+	Row  int      `json:"row"`
+	Errs []string `json:"errs"`
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, strings.Join(e.Errs, "; "))
+}
+
+// ImportStructs 按表头名称把 sheet 中的数据解析为结构体, 第一行为表头
+// 校验失败的行不会出现在 list 中, 而是记录在 rowErrs 里, 方便一次性反馈给用户
+func ImportStructs[T any](r io.Reader, sheet string) (list []*T, rowErrs []*RowError, err error) {
+	var zero T
+	cols, err := parseColumns(reflect.TypeOf(zero))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	if sheet == "" {
+		sheet = f.GetSheetName(0)
+	}
+	rows, err := f.Rows(sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	// colIdx 表头列下标 -> 字段定义
+	var colIdx map[int]*column
+	var rowNum int
+	for rows.Next() {
+		rowNum++
+		record, err := rows.Columns()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if colIdx == nil {
+			colIdx, err = matchHeader(cols, record)
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		if isEmptyRow(record) {
+			continue
+		}
+
+		item := new(T)
+		v := reflect.ValueOf(item).Elem()
+		var errs []string
+		for idx, c := range colIdx {
+			var s string
+			if idx < len(record) {
+				s = strings.TrimSpace(record[idx])
+			}
+			if s == "" && c.required {
+				errs = append(errs, fmt.Sprintf("%s 不能为空", c.Name))
+				continue
+			}
+			if err := c.setValue(v.FieldByIndex(c.Index), s); err != nil {
+				errs = append(errs, fmt.Sprintf("%s 格式错误: %v", c.Name, err))
+			}
+		}
+		if len(errs) == 0 {
+			if validator, ok := interface{}(item).(RowValidator); ok {
+				if err := validator.Validate(); err != nil {
+					errs = append(errs, err.Error())
+				}
+			}
+		}
+		if len(errs) > 0 {
+			rowErrs = append(rowErrs, &RowError{Row: rowNum, Errs: errs})
+			continue
+		}
+		list = append(list, item)
+	}
+	if err = rows.Error(); err != nil {
+		return nil, nil, err
+	}
+	if colIdx == nil {
+		return nil, nil, fmt.Errorf("invalid file format fail")
+	}
+	return list, rowErrs, nil
+}
+
+// matchHeader 按表头名称定位每个字段所在的列, 表头可以带 * 表示必填
+func matchHeader(cols []*column, header []string) (map[int]*column, error) {
+	byTitle := make(map[string]*column, len(cols))
+	for _, c := range cols {
+		byTitle[c.Name] = c
+	}
+	colIdx := make(map[int]*column, len(cols))
+	found := make(map[*column]bool, len(cols))
+	for idx, title := range header {
+		title = strings.TrimSpace(strings.ReplaceAll(title, "*", ""))
+		if c, ok := byTitle[title]; ok {
+			colIdx[idx] = c
+			found[c] = true
+		}
+	}
+	for _, c := range cols {
+		if c.required && !found[c] {
+			return nil, fmt.Errorf("missing column %s", c.Name)
+		}
+	}
+	return colIdx, nil
+}
+
+func isEmptyRow(record []string) bool {
+	for _, s := range record {
+		if strings.TrimSpace(s) != "" {
+			return false
+		}
+	}
+	return true
+}
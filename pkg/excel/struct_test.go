@@ -0,0 +1,73 @@
+package excel
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	Name     string    `excel:"姓名,width=20,required"`
+	Age      int       `excel:"年龄"`
+	Birthday time.Time `excel:"生日,format=2006-01-02"`
+	Score    *float64  `excel:"分数"`
+	Ignore   string    `excel:"-"`
+}
+
+func (u *testUser) Validate() error {
+	if u.Age < 0 {
+		return fmt.Errorf("年龄不能小于 0")
+	}
+	return nil
+}
+
+func TestStructExportImport(t *testing.T) {
+	score := 99.5
+	birthday := time.Date(2000, 1, 2, 0, 0, 0, 0, time.Local)
+	list := []*testUser{
+		{Name: "张三", Age: 18, Birthday: birthday, Score: &score, Ignore: "x"},
+		{Name: "", Age: 20},
+		{Name: "李四", Age: -1},
+	}
+
+	var buf bytes.Buffer
+	if err := ExportStructs(&buf, "用户", list); err != nil {
+		t.Fatal(err)
+	}
+
+	got, rowErrs, err := ImportStructs[testUser](&buf, "用户")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "张三" || got[0].Age != 18 || !got[0].Birthday.Equal(birthday) || *got[0].Score != score || got[0].Ignore != "" {
+		t.Fatalf("unexpected import %+v", got)
+	}
+	if len(rowErrs) != 2 || rowErrs[0].Row != 3 || rowErrs[1].Row != 4 {
+		t.Fatalf("unexpected row errs %v", rowErrs)
+	}
+}
+
+func TestSetValueOverflow(t *testing.T) {
+	var v struct {
+		I8  int8
+		U16 uint16
+		F32 float32
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	c := &column{}
+	for i, s := range []string{"128", "65536", "1e39"} {
+		if err := c.setValue(rv.Field(i), s); err == nil {
+			t.Errorf("%s: expect overflow error, got %+v", s, v)
+		}
+	}
+	for i, s := range []string{"-128", "65535", "1.5"} {
+		if err := c.setValue(rv.Field(i), s); err != nil {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+	if v.I8 != -128 || v.U16 != 65535 || v.F32 != 1.5 {
+		t.Errorf("got %+v", v)
+	}
+}
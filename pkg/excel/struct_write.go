@@ -0,0 +1,170 @@
+package excel
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"reflect"
+)
+
+// HeaderStyle 默认的表头样式
+var HeaderStyle = &excelize.Style{
+	Font: &excelize.Font{Bold: true},
+	Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#D9E1F2"}},
+	Alignment: &excelize.Alignment{
+		Horizontal: "center",
+		Vertical:   "center",
+	},
+}
+
+// StructWriter 按 excel tag 流式写入结构体, 数据不会全部留在内存, 适合大批量导出
+type StructWriter[T any] struct {
+	file    *excelize.File
+	sw      *excelize.StreamWriter
+	cols    []*column
+	rowNum  int
+	flushed bool
+}
+
+type WriterOption func(*writerOptions)
+
+type writerOptions struct {
+	headerStyle *excelize.Style
+}
+
+// WithHeaderStyle 自定义表头样式, 传 nil 不设置样式
+func WithHeaderStyle(style *excelize.Style) WriterOption {
+	return func(o *writerOptions) {
+		o.headerStyle = style
+	}
+}
+
+// NewStructWriter 创建写入器并写入表头, sheet 为空时使用 DefaultSheet
+func NewStructWriter[T any](sheet string, ops ...WriterOption) (*StructWriter[T], error) {
+	opts := &writerOptions{headerStyle: HeaderStyle}
+	for i := range ops {
+		ops[i](opts)
+	}
+
+	var zero T
+	cols, err := parseColumns(reflect.TypeOf(zero))
+	if err != nil {
+		return nil, err
+	}
+
+	f := excelize.NewFile()
+	if sheet == "" {
+		sheet = DefaultSheet
+	}
+	if sheet != DefaultSheet {
+		f.SetSheetName(DefaultSheet, sheet)
+	}
+	sw, err := f.NewStreamWriter(sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	// 流式写入时列宽必须在写第一行之前设置
+	for i, c := range cols {
+		if c.width > 0 {
+			if err = sw.SetColWidth(i+1, i+1, c.width); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var styleId int
+	if opts.headerStyle != nil {
+		styleId, err = f.NewStyle(opts.headerStyle)
+		if err != nil {
+			return nil, err
+		}
+	}
+	header := make([]interface{}, 0, len(cols))
+	for _, c := range cols {
+		header = append(header, excelize.Cell{StyleID: styleId, Value: c.Name})
+	}
+	if err = sw.SetRow("A1", header); err != nil {
+		return nil, err
+	}
+
+	return &StructWriter[T]{
+		file:   f,
+		sw:     sw,
+		cols:   cols,
+		rowNum: 1,
+	}, nil
+}
+
+// Write 追加数据行
+func (w *StructWriter[T]) Write(rows ...*T) error {
+	if w.flushed {
+		return fmt.Errorf("excel: writer already flushed")
+	}
+	for _, row := range rows {
+		if row == nil {
+			continue
+		}
+		v := reflect.ValueOf(row).Elem()
+		values := make([]interface{}, 0, len(w.cols))
+		for _, c := range w.cols {
+			val, err := c.cellValue(v.FieldByIndex(c.Index))
+			if err != nil {
+				return fmt.Errorf("excel: column %s: %v", c.Name, err)
+			}
+			values = append(values, val)
+		}
+		w.rowNum++
+		cell, err := excelize.CoordinatesToCellName(1, w.rowNum)
+		if err != nil {
+			return err
+		}
+		if err = w.sw.SetRow(cell, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteTo 结束写入并输出 xlsx
+func (w *StructWriter[T]) WriteTo(out io.Writer) (int64, error) {
+	if err := w.flush(); err != nil {
+		return 0, err
+	}
+	return w.file.WriteTo(out)
+}
+
+// SaveAs 结束写入并保存到文件
+func (w *StructWriter[T]) SaveAs(name string) error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.file.SaveAs(name)
+}
+
+// Close 释放写入过程中的临时文件
+func (w *StructWriter[T]) Close() error {
+	return w.file.Close()
+}
+
+func (w *StructWriter[T]) flush() error {
+	if w.flushed {
+		return nil
+	}
+	w.flushed = true
+	return w.sw.Flush()
+}
+
+// ExportStructs 一次性导出结构体列表
+func ExportStructs[T any](out io.Writer, sheet string, list []*T, ops ...WriterOption) error {
+	w, err := NewStructWriter[T](sheet, ops...)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err = w.Write(list...); err != nil {
+		return err
+	}
+	_, err = w.WriteTo(out)
+	return err
+}
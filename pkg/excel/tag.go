@@ -0,0 +1,68 @@
+package excel
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/pkg/internal/tabular"
+	"reflect"
+	"strconv"
+)
+
+const (
+	TagName = "excel"
+
+	DefaultTimeLayout = tabular.DefaultTimeLayout
+)
+
+// column 结构体字段与表格列的映射, 由 tag 解析得到
+//
+//	Name string    `excel:"姓名,width=20,required"`
+//	At   time.Time `excel:"创建时间,format=2006-01-02"`
+//	Tmp  string    `excel:"-"`
+type column struct {
+	*tabular.Field
+	width    float64
+	required bool
+}
+
+// parseColumns 解析结构体的列定义, 未打 tag 的导出字段使用字段名作为表头
+func parseColumns(t reflect.Type) ([]*column, error) {
+	fields, err := tabular.ParseFields(TagName, t)
+	if err != nil {
+		return nil, err
+	}
+	cols := make([]*column, 0, len(fields))
+	for _, f := range fields {
+		col := &column{Field: f}
+		if w, ok := f.Opts["width"]; ok {
+			if col.width, err = strconv.ParseFloat(w, 64); err != nil {
+				return nil, fmt.Errorf("excel: invalid width of column %s", f.Name)
+			}
+		}
+		_, col.required = f.Opts["required"]
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// cellValue 把字段值转换为写入单元格的值, 时间和 encoding.TextMarshaler 转成文本, 其他类型原样写入
+func (c *column) cellValue(v reflect.Value) (interface{}, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !tabular.IsText(v.Type()) {
+		return v.Interface(), nil
+	}
+	s, err := c.ToString(v)
+	if err != nil || s == "" {
+		return nil, err
+	}
+	return s, nil
+}
+
+// setValue 把单元格的文本解析到字段
+func (c *column) setValue(v reflect.Value, s string) error {
+	return c.FromString(v, s)
+}
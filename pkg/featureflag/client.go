@@ -0,0 +1,341 @@
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/oldbai555/lbtool/log"
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	ReasonNotFound = "not_found"
+	ReasonDisabled = "disabled"
+	ReasonRule     = "rule"
+	ReasonDefault  = "default"
+)
+
+// Result 评估结果, RuleIndex 为命中规则的下标, 未命中规则时为 -1
+type Result struct {
+	Key       string
+	Enabled   bool
+	Reason    string
+	RuleIndex int
+}
+
+// Exposure 曝光记录, 用于实验分析
+type Exposure struct {
+	Key       string
+	UserId    string
+	Enabled   bool
+	Reason    string
+	RuleIndex int
+}
+
+// ExposureLogger 记录曝光, 在评估的协程里同步调用, 耗时操作需要自己异步处理
+type ExposureLogger func(ctx context.Context, e *Exposure)
+
+// LogExposure 把曝光打到日志里
+func LogExposure(ctx context.Context, e *Exposure) {
+	log.Infof("flag exposure key:%s user:%s enabled:%v reason:%s rule:%d", e.Key, e.UserId, e.Enabled, e.Reason, e.RuleIndex)
+}
+
+// Client 本地评估开关, 配置来自 lbconf 数据源并支持热更新
+type Client struct {
+	keyPrefix string
+	exposure  ExposureLogger
+
+	mu    sync.RWMutex
+	flags map[string]*Flag
+
+	// leaves 文件、环境变量等数据源只下发叶子节点, 按开关名保存已收到的字段, 变更时合并后重新解析
+	leafMu sync.Mutex
+	leaves map[string]map[string]interface{}
+}
+
+type Option func(*Client)
+
+// WithKeyPrefix 只读取带前缀的配置, 例如前缀 flag. 时 flag.new_checkout 对应开关 new_checkout
+func WithKeyPrefix(prefix string) Option {
+	return func(c *Client) {
+		c.keyPrefix = prefix
+	}
+}
+
+// WithExposureLogger 每次评估已存在的开关时记录曝光
+func WithExposureLogger(logger ExposureLogger) Option {
+	return func(c *Client) {
+		c.exposure = logger
+	}
+}
+
+func New(ops ...Option) *Client {
+	c := &Client{
+		flags:  make(map[string]*Flag),
+		leaves: make(map[string]map[string]interface{}),
+	}
+	for i := range ops {
+		ops[i](c)
+	}
+	return c
+}
+
+// Set 直接设置开关, 覆盖同名开关
+func (c *Client) Set(flags ...*Flag) error {
+	for _, f := range flags {
+		if err := f.validate(); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range flags {
+		c.flags[f.Key] = f
+	}
+	return nil
+}
+
+// Delete 删除开关, 删除后评估结果为关闭
+func (c *Client) Delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, k := range keys {
+		delete(c.flags, k)
+	}
+}
+
+// Evaluate 评估开关, 不存在的开关视为关闭
+func (c *Client) Evaluate(ctx context.Context, key string, user *User) *Result {
+	c.mu.RLock()
+	f, ok := c.flags[key]
+	c.mu.RUnlock()
+
+	res := &Result{Key: key, RuleIndex: -1}
+	if !ok {
+		res.Reason = ReasonNotFound
+		return res
+	}
+
+	switch {
+	case !f.Enabled:
+		res.Reason = ReasonDisabled
+	default:
+		res.Reason = ReasonDefault
+		rollout := f.Rollout
+		for i, r := range f.Rules {
+			if !r.match(user) {
+				continue
+			}
+			res.Reason = ReasonRule
+			res.RuleIndex = i
+			rollout = 100
+			if r.Rollout != nil {
+				rollout = *r.Rollout
+			}
+			break
+		}
+		res.Enabled = inRollout(key, user, rollout)
+	}
+
+	if c.exposure != nil {
+		e := &Exposure{Key: key, Enabled: res.Enabled, Reason: res.Reason, RuleIndex: res.RuleIndex}
+		if user != nil {
+			e.UserId = user.Id
+		}
+		c.exposure(ctx, e)
+	}
+	return res
+}
+
+// IsEnabled Evaluate 的简写
+func (c *Client) IsEnabled(ctx context.Context, key string, user *User) bool {
+	return c.Evaluate(ctx, key, user).Enabled
+}
+
+// LoadDataSource 从配置中心加载开关
+// 配置值可以是 bool, json/yaml 字符串或者 map, 值为 nil 时删除开关
+// 也可以按字段拆开配置, 例如 flag.new_checkout.enabled, flag.new_checkout.rollout, 因此开关名不能包含 .
+func (c *Client) LoadDataSource(ds bconf.DataSource) error {
+	list, err := ds.Load()
+	if err != nil {
+		return err
+	}
+	return c.applyData(list)
+}
+
+// WatchDataSource 监听配置中心的变更并热更新开关
+func (c *Client) WatchDataSource(ds bconf.DataSource) (bconf.DataWatcher, error) {
+	w, err := ds.Watch()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			list, err := w.Change()
+			if err != nil {
+				log.Warnf("feature flag watch stopped, err:%v", err)
+				return
+			}
+			if err = c.applyData(list); err != nil {
+				log.Errorf("err:%v", err)
+			}
+		}
+	}()
+	return w, nil
+}
+
+// applyData 单个开关解析失败不影响其他开关, 返回第一个错误
+func (c *Client) applyData(list []*bconf.Data) error {
+	var firstErr error
+	var flags []*Flag
+	var deleted []string
+	add := func(key string, val interface{}) {
+		f, err := parseFlag(key, val)
+		if err == nil {
+			err = f.validate()
+		}
+		if err != nil {
+			log.Errorf("parse flag %s%s err:%v", c.keyPrefix, key, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		flags = append(flags, f)
+	}
+
+	c.leafMu.Lock()
+	changed := make(map[string]struct{})
+	for _, d := range list {
+		if !strings.HasPrefix(d.Key, c.keyPrefix) {
+			continue
+		}
+		key := strings.TrimPrefix(d.Key, c.keyPrefix)
+		if name, field, ok := strings.Cut(key, "."); ok {
+			c.setLeaf(name, field, d.Val)
+			changed[name] = struct{}{}
+			continue
+		}
+		if d.Val == nil {
+			deleted = append(deleted, key)
+			continue
+		}
+		add(key, d.Val)
+	}
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fields, ok := c.leaves[name]
+		if !ok {
+			deleted = append(deleted, name)
+			continue
+		}
+		add(name, nest(fields))
+	}
+	c.leafMu.Unlock()
+
+	c.Delete(deleted...)
+	c.mu.Lock()
+	for _, f := range flags {
+		c.flags[f.Key] = f
+	}
+	c.mu.Unlock()
+	return firstErr
+}
+
+// setLeaf 值为 nil 时删除字段, 字段都删除后开关也删除
+func (c *Client) setLeaf(name, field string, val interface{}) {
+	fields, ok := c.leaves[name]
+	if val == nil {
+		delete(fields, field)
+		if ok && len(fields) == 0 {
+			delete(c.leaves, name)
+		}
+		return
+	}
+	if !ok {
+		fields = make(map[string]interface{})
+		c.leaves[name] = fields
+	}
+	fields[field] = val
+}
+
+// nest 把 a.b 这样的字段还原成嵌套的 map
+func nest(fields map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		parts := strings.Split(k, ".")
+		cur := m
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := cur[p].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				cur[p] = next
+			}
+			cur = next
+		}
+		cur[parts[len(parts)-1]] = v
+	}
+	return m
+}
+
+func parseFlag(key string, val interface{}) (*Flag, error) {
+	f := &Flag{}
+	switch v := val.(type) {
+	case bool:
+		f.Enabled = v
+		f.Rollout = 100
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return parseFlag(key, b)
+		}
+		// yaml 是 json 的超集, 两种格式都可以解析
+		if err := yaml.Unmarshal([]byte(v), f); err != nil {
+			return nil, err
+		}
+	default:
+		// 数据源直接下发的 map 或按字段合并出的 map, 转一次 json 统一处理
+		buf, err := json.Marshal(normalize(v))
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(buf, f); err != nil {
+			return nil, fmt.Errorf("invalid flag value: %w", err)
+		}
+	}
+	f.Key = key
+	return f, nil
+}
+
+// normalize yaml 解析出的 map[interface{}]interface{} 不能直接转 json
+func normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[fmt.Sprint(k)] = normalize(item)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			m[k] = normalize(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			list[i] = normalize(item)
+		}
+		return list
+	}
+	return v
+}
@@ -0,0 +1,197 @@
+package featureflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/file"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type memSource struct {
+	list []*bconf.Data
+	ch   chan []*bconf.Data
+}
+
+func (s *memSource) Load() ([]*bconf.Data, error) {
+	return s.list, nil
+}
+
+func (s *memSource) Watch() (bconf.DataWatcher, error) {
+	return s, nil
+}
+
+func (s *memSource) Change() ([]*bconf.Data, error) {
+	list, ok := <-s.ch
+	if !ok {
+		return nil, errors.New("closed")
+	}
+	return list, nil
+}
+
+func (s *memSource) Close() error {
+	close(s.ch)
+	return nil
+}
+
+func TestClient_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	var exposures []*Exposure
+	c := New(WithExposureLogger(func(ctx context.Context, e *Exposure) {
+		exposures = append(exposures, e)
+	}))
+	fifty := float64(50)
+	err := c.Set(
+		&Flag{Key: "on", Enabled: true, Rollout: 100},
+		&Flag{Key: "off", Enabled: false, Rollout: 100},
+		&Flag{Key: "half", Enabled: true, Rollout: 50},
+		&Flag{Key: "beta", Enabled: true, Rules: []*Rule{
+			{Attr: "city", Op: OpIn, Values: []string{"sz", "gz"}},
+			{Attr: AttrId, Op: OpPrefix, Values: []string{"staff_"}, Rollout: &fifty},
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := &User{Id: "u1", Attrs: map[string]string{"city": "sz"}}
+	if !c.IsEnabled(ctx, "on", u) || c.IsEnabled(ctx, "off", u) || c.IsEnabled(ctx, "missing", u) {
+		t.Fatal("unexpected boolean flag result")
+	}
+	if res := c.Evaluate(ctx, "beta", u); !res.Enabled || res.Reason != ReasonRule || res.RuleIndex != 0 {
+		t.Fatalf("unexpected %+v", res)
+	}
+	if res := c.Evaluate(ctx, "beta", &User{Id: "u2", Attrs: map[string]string{"city": "bj"}}); res.Enabled || res.Reason != ReasonDefault {
+		t.Fatalf("unexpected %+v", res)
+	}
+	if len(exposures) != 4 {
+		t.Fatalf("expect 4 exposures, got %d", len(exposures))
+	}
+
+	// 百分比放量: 结果稳定且比例接近配置值
+	enabled := 0
+	for i := 0; i < 10000; i++ {
+		user := &User{Id: fmt.Sprintf("user_%d", i)}
+		res := c.IsEnabled(ctx, "half", user)
+		if res != c.IsEnabled(ctx, "half", user) {
+			t.Fatal("rollout should be sticky")
+		}
+		if res {
+			enabled++
+		}
+	}
+	if enabled < 4500 || enabled > 5500 {
+		t.Fatalf("unexpected rollout count %d", enabled)
+	}
+	if c.IsEnabled(ctx, "half", &User{}) {
+		t.Fatal("anonymous user should not be in partial rollout")
+	}
+
+	if err := c.Set(&Flag{Key: "bad", Rollout: 120}); err == nil {
+		t.Fatal("expect invalid rollout err")
+	}
+}
+
+func TestClient_DataSource(t *testing.T) {
+	ctx := context.Background()
+	ds := &memSource{
+		list: []*bconf.Data{
+			{Key: "flag.new_checkout", Val: `{"enabled": true, "rollout": 100}`},
+			{Key: "flag.dark_mode", Val: "true"},
+			{Key: "flag.vip", Val: map[string]interface{}{
+				"enabled": true,
+				"rules":   []interface{}{map[interface{}]interface{}{"attr": "level", "op": "in", "values": []interface{}{"gold"}}},
+			}},
+			{Key: "other.key", Val: "ignored"},
+		},
+		ch: make(chan []*bconf.Data),
+	}
+	c := New(WithKeyPrefix("flag."))
+	if err := c.LoadDataSource(ds); err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsEnabled(ctx, "new_checkout", nil) || !c.IsEnabled(ctx, "dark_mode", nil) {
+		t.Fatal("flags should be enabled")
+	}
+	if !c.IsEnabled(ctx, "vip", &User{Id: "1", Attrs: map[string]string{"level": "gold"}}) {
+		t.Fatal("vip should be enabled for gold")
+	}
+
+	w, err := c.WatchDataSource(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	ds.ch <- []*bconf.Data{
+		{Key: "flag.new_checkout", Val: "enabled: false"},
+		{Key: "flag.dark_mode", Val: nil},
+	}
+	deadline := time.Now().Add(time.Second)
+	for c.IsEnabled(ctx, "new_checkout", nil) || c.IsEnabled(ctx, "dark_mode", nil) {
+		if time.Now().After(deadline) {
+			t.Fatal("watch not applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestClient_FileSource(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	content := `
+flag:
+  new_checkout:
+    enabled: true
+    rollout: 100
+  vip:
+    enabled: true
+    rules:
+      - attr: level
+        op: in
+        values: [gold]
+db:
+  host: 127.0.0.1
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ds, err := file.NewFileSource(path, file.WithDebounce(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := New(WithKeyPrefix("flag."))
+	if err = c.LoadDataSource(ds); err != nil {
+		t.Fatal(err)
+	}
+	if !c.IsEnabled(ctx, "new_checkout", nil) {
+		t.Fatal("new_checkout should be enabled")
+	}
+	if !c.IsEnabled(ctx, "vip", &User{Id: "1", Attrs: map[string]string{"level": "gold"}}) || c.IsEnabled(ctx, "vip", &User{Id: "1"}) {
+		t.Fatal("vip should be enabled only for gold")
+	}
+
+	w, err := c.WatchDataSource(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	// 只修改一个字段, 删除整个开关
+	content = "flag:\n  new_checkout:\n    enabled: false\n    rollout: 100\n"
+	if err = os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for c.IsEnabled(ctx, "new_checkout", nil) || c.Evaluate(ctx, "vip", nil).Reason != ReasonNotFound {
+		if time.Now().After(deadline) {
+			t.Fatal("watch not applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if r := c.Evaluate(ctx, "new_checkout", nil); r.Reason != ReasonDisabled {
+		t.Errorf("got %+v", r)
+	}
+}
@@ -0,0 +1,128 @@
+package featureflag
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	OpIn     = "in"
+	OpNotIn  = "not_in"
+	OpPrefix = "prefix"
+	OpSuffix = "suffix"
+
+	// AttrId 规则里用 id 表示用户 id
+	AttrId = "id"
+
+	// bucketNum 百分比按万分之一划分桶
+	bucketNum = 10000
+)
+
+// Flag 开关定义
+//
+//	{"enabled": true, "rollout": 10, "rules": [{"attr": "city", "op": "in", "values": ["sz"], "rollout": 100}]}
+//
+// 规则按顺序匹配, 命中的规则决定放量比例; 都没命中时使用 Rollout
+// 布尔开关只需要 {"enabled": true, "rollout": 100}
+type Flag struct {
+	Key     string  `json:"key" yaml:"key"`
+	Enabled bool    `json:"enabled" yaml:"enabled"`
+	Rollout float64 `json:"rollout" yaml:"rollout"`
+	Rules   []*Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule 按用户属性定向
+type Rule struct {
+	Attr   string   `json:"attr" yaml:"attr"`
+	Op     string   `json:"op" yaml:"op"`
+	Values []string `json:"values" yaml:"values"`
+	// Rollout 命中规则的用户中放量的比例, 0-100, 不填时为 100
+	Rollout *float64 `json:"rollout" yaml:"rollout"`
+}
+
+// User 被评估的用户, Id 用于百分比放量的粘性, 同一个用户多次评估结果一致
+type User struct {
+	Id    string
+	Attrs map[string]string
+}
+
+func (u *User) attr(name string) (string, bool) {
+	if u == nil {
+		return "", false
+	}
+	if name == AttrId {
+		return u.Id, u.Id != ""
+	}
+	v, ok := u.Attrs[name]
+	return v, ok
+}
+
+func (f *Flag) validate() error {
+	if f.Rollout < 0 || f.Rollout > 100 {
+		return fmt.Errorf("flag %s: rollout must be in [0, 100]", f.Key)
+	}
+	for _, r := range f.Rules {
+		switch r.Op {
+		case OpIn, OpNotIn, OpPrefix, OpSuffix:
+		default:
+			return fmt.Errorf("flag %s: unknown op %s", f.Key, r.Op)
+		}
+		if r.Rollout != nil && (*r.Rollout < 0 || *r.Rollout > 100) {
+			return fmt.Errorf("flag %s: rule rollout must be in [0, 100]", f.Key)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) match(u *User) bool {
+	v, ok := u.attr(r.Attr)
+	switch r.Op {
+	case OpIn:
+		return ok && contains(r.Values, v)
+	case OpNotIn:
+		return !ok || !contains(r.Values, v)
+	case OpPrefix:
+		if !ok {
+			return false
+		}
+		for _, p := range r.Values {
+			if strings.HasPrefix(v, p) {
+				return true
+			}
+		}
+	case OpSuffix:
+		if !ok {
+			return false
+		}
+		for _, s := range r.Values {
+			if strings.HasSuffix(v, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// inRollout 用户是否落在放量范围内, 按 flag key 和用户 id 哈希分桶
+// 没有用户 id 时无法保证粘性, 只有全量才放行
+func inRollout(key string, u *User, rollout float64) bool {
+	if rollout >= 100 {
+		return true
+	}
+	if rollout <= 0 || u == nil || u.Id == "" {
+		return false
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key + ":" + u.Id))
+	return float64(h.Sum32()%bucketNum) < rollout*bucketNum/100
+}
@@ -0,0 +1,241 @@
+package fieldcrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// TagName 字段标签, 例如 `lb:"encrypted"`, 可以和其他选项用逗号分隔
+	TagName      = "lb"
+	TagEncrypted = "encrypted"
+
+	// prefix 密文格式为 lbenc:v<版本>:<base64(nonce+密文)>
+	prefix = "lbenc:v"
+)
+
+var (
+	ErrUnknownVersion = errors.New("unknown key version")
+	ErrInvalidKey     = errors.New("key length must be 16, 24 or 32")
+	ErrInvalidCipher  = errors.New("invalid ciphertext")
+)
+
+// Keyring 带版本的密钥, 用当前版本加密, 按密文里的版本解密, 用于密钥轮换
+type Keyring struct {
+	current uint32
+	keys    map[uint32][]byte
+}
+
+// NewKeyring current 为加密使用的版本, keys 需要包含所有历史版本以便解密旧数据
+func NewKeyring(current uint32, keys map[uint32][]byte) (*Keyring, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, current)
+	}
+	k := &Keyring{current: current, keys: make(map[uint32][]byte, len(keys))}
+	for v, key := range keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("%w: version %d", ErrInvalidKey, v)
+		}
+		k.keys[v] = key
+	}
+	return k, nil
+}
+
+// Encrypt 加密, 结果带上密钥版本
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	data, err := utils.AesGCMEncrypt([]byte(plain), k.keys[k.current], nil)
+	if err != nil {
+		return "", err
+	}
+	return prefix + strconv.FormatUint(uint64(k.current), 10) + ":" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Decrypt 解密, 不是密文格式的值原样返回, 兼容加密上线前的旧数据
+func (k *Keyring) Decrypt(val string) (string, error) {
+	version, body, ok := parse(val)
+	if !ok {
+		return val, nil
+	}
+	key, ok := k.keys[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	data, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCipher, err)
+	}
+	plain, err := utils.AesGCMDecrypt(data, key, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCipher, err)
+	}
+	return string(plain), nil
+}
+
+// Version 密文使用的密钥版本, 不是密文时返回 false
+func Version(val string) (uint32, bool) {
+	version, _, ok := parse(val)
+	return version, ok
+}
+
+// IsEncrypted 是否为密文格式, 只检查格式, 不代表能够解密
+func IsEncrypted(val string) bool {
+	_, _, ok := parse(val)
+	return ok
+}
+
+// NeedRotate 明文或者不是当前版本加密的值需要重新加密
+func (k *Keyring) NeedRotate(val string) bool {
+	version, ok := Version(val)
+	return !ok || version != k.current
+}
+
+func parse(val string) (version uint32, body string, ok bool) {
+	if !strings.HasPrefix(val, prefix) {
+		return 0, "", false
+	}
+	verStr, body, ok := strings.Cut(val[len(prefix):], ":")
+	if !ok {
+		return 0, "", false
+	}
+	v, err := strconv.ParseUint(verStr, 10, 32)
+	if err != nil {
+		return 0, "", false
+	}
+	return uint32(v), body, true
+}
+
+// EncryptStruct 加密结构体中带 `lb:"encrypted"` 标签的字段, 写库前调用
+// 支持 string, *string, []byte 字段以及嵌套的结构体, 能用密钥环解密的字段不会重复加密
+func (k *Keyring) EncryptStruct(ptr interface{}) error {
+	return k.walk(ptr, func(val string) (string, error) {
+		if k.isCipher(val) {
+			return val, nil
+		}
+		return k.Encrypt(val)
+	})
+}
+
+// isCipher 只看前缀的话, 用户输入 lbenc:v 开头的内容就能绕过加密, 需要确认能解密
+func (k *Keyring) isCipher(val string) bool {
+	if !IsEncrypted(val) {
+		return false
+	}
+	_, err := k.Decrypt(val)
+	return err == nil
+}
+
+// DecryptStruct 解密结构体中带 `lb:"encrypted"` 标签的字段, 读库后调用
+func (k *Keyring) DecryptStruct(ptr interface{}) error {
+	return k.walk(ptr, k.Decrypt)
+}
+
+// RotateStruct 用当前版本重新加密字段, 返回是否有字段发生变化, 用于后台轮换任务
+func (k *Keyring) RotateStruct(ptr interface{}) (bool, error) {
+	changed := false
+	err := k.walk(ptr, func(val string) (string, error) {
+		if !k.NeedRotate(val) {
+			return val, nil
+		}
+		plain, err := k.Decrypt(val)
+		if err != nil {
+			return "", err
+		}
+		changed = true
+		return k.Encrypt(plain)
+	})
+	return changed, err
+}
+
+func (k *Keyring) walk(ptr interface{}, fn func(string) (string, error)) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expect pointer to struct, got %T", ptr)
+	}
+	return walkStruct(v.Elem(), fn)
+}
+
+func walkStruct(v reflect.Value, fn func(string) (string, error)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if !hasEncryptedTag(field.Tag.Get(TagName)) {
+			if err := walkNested(fv, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := apply(fv, fn); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// walkNested 未标记的结构体字段继续向下查找
+func walkNested(fv reflect.Value, fn func(string) (string, error)) error {
+	switch fv.Kind() {
+	case reflect.Struct:
+		return walkStruct(fv, fn)
+	case reflect.Ptr:
+		if !fv.IsNil() && fv.Elem().Kind() == reflect.Struct {
+			return walkStruct(fv.Elem(), fn)
+		}
+	}
+	return nil
+}
+
+func apply(fv reflect.Value, fn func(string) (string, error)) error {
+	switch {
+	case fv.Kind() == reflect.String:
+		if fv.String() == "" {
+			return nil
+		}
+		s, err := fn(fv.String())
+		if err != nil {
+			return err
+		}
+		fv.SetString(s)
+	case fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.String:
+		if fv.IsNil() || fv.Elem().String() == "" {
+			return nil
+		}
+		s, err := fn(fv.Elem().String())
+		if err != nil {
+			return err
+		}
+		// 不修改调用方共享的字符串
+		fv.Set(reflect.ValueOf(&s))
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Uint8:
+		if fv.Len() == 0 {
+			return nil
+		}
+		s, err := fn(string(fv.Bytes()))
+		if err != nil {
+			return err
+		}
+		fv.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func hasEncryptedTag(tag string) bool {
+	for _, item := range strings.Split(tag, ",") {
+		if strings.TrimSpace(item) == TagEncrypted {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package fieldcrypt
+
+import (
+	"errors"
+	"testing"
+)
+
+type Profile struct {
+	Address string `lb:"encrypted"`
+}
+
+type User struct {
+	Id      uint64
+	Name    string
+	Phone   string  `json:"phone" lb:"encrypted"`
+	IdCard  *string `lb:"encrypted"`
+	Secret  []byte  `lb:"column:secret,encrypted"`
+	Profile *Profile
+}
+
+var (
+	keyV1 = []byte("0123456789abcdef")
+	keyV2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestKeyring_Struct(t *testing.T) {
+	k1, err := NewKeyring(1, map[uint32][]byte{1: keyV1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	idCard := "440300199001011234"
+	u := &User{Id: 1, Name: "bai", Phone: "13800000000", IdCard: &idCard, Secret: []byte("s"), Profile: &Profile{Address: "sz"}}
+	if err = k1.EncryptStruct(u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Name != "bai" || !IsEncrypted(u.Phone) || !IsEncrypted(*u.IdCard) || !IsEncrypted(string(u.Secret)) || !IsEncrypted(u.Profile.Address) {
+		t.Fatalf("unexpected %+v", u)
+	}
+	if idCard != "440300199001011234" {
+		t.Fatal("caller's string should not be modified")
+	}
+	phone := u.Phone
+	if err = k1.EncryptStruct(u); err != nil || u.Phone != phone {
+		t.Fatal("encrypted field should not be encrypted again")
+	}
+	if v, _ := Version(u.Phone); v != 1 {
+		t.Fatalf("unexpected version %d", v)
+	}
+
+	// 轮换到 v2, 旧数据仍然可以解密
+	k2, err := NewKeyring(2, map[uint32][]byte{1: keyV1, 2: keyV2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := k2.RotateStruct(u)
+	if err != nil || !changed {
+		t.Fatalf("expect rotated, err %v", err)
+	}
+	if v, _ := Version(u.Phone); v != 2 {
+		t.Fatalf("unexpected version %d", v)
+	}
+	if changed, _ = k2.RotateStruct(u); changed {
+		t.Fatal("already rotated")
+	}
+	if _, err = k1.Decrypt(u.Phone); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("expect ErrUnknownVersion, got %v", err)
+	}
+
+	if err = k2.DecryptStruct(u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Phone != "13800000000" || *u.IdCard != idCard || string(u.Secret) != "s" || u.Profile.Address != "sz" {
+		t.Fatalf("unexpected %+v", u)
+	}
+
+	// 加密上线前的明文原样返回
+	if plain, err := k2.Decrypt("plain"); err != nil || plain != "plain" {
+		t.Fatalf("unexpected %s %v", plain, err)
+	}
+}
+
+func TestNewKeyring(t *testing.T) {
+	if _, err := NewKeyring(2, map[uint32][]byte{1: keyV1}); !errors.Is(err, ErrUnknownVersion) {
+		t.Fatalf("expect ErrUnknownVersion, got %v", err)
+	}
+	if _, err := NewKeyring(1, map[uint32][]byte{1: []byte("short")}); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expect ErrInvalidKey, got %v", err)
+	}
+	k, _ := NewKeyring(1, map[uint32][]byte{1: keyV1})
+	if err := k.EncryptStruct(User{}); err == nil {
+		t.Fatal("expect pointer err")
+	}
+	if _, err := k.Decrypt("lbenc:v1:!!!"); !errors.Is(err, ErrInvalidCipher) {
+		t.Fatalf("expect ErrInvalidCipher, got %v", err)
+	}
+}
+
+func TestEncryptStruct_FakeCipher(t *testing.T) {
+	k, err := NewKeyring(1, map[uint32][]byte{1: keyV1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 用户输入伪造的密文格式, 仍然需要加密
+	fake := "lbenc:v1:13800000000"
+	u := &User{Phone: fake}
+	if err = k.EncryptStruct(u); err != nil {
+		t.Fatal(err)
+	}
+	if u.Phone == fake {
+		t.Fatal("fake cipher should be encrypted")
+	}
+	if err = k.DecryptStruct(u); err != nil || u.Phone != fake {
+		t.Fatalf("got %s, err:%v", u.Phone, err)
+	}
+}
@@ -0,0 +1,217 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type State string
+
+var (
+	ErrInvalidEvent = errors.New("invalid event")
+	ErrConflict     = errors.New("state changed by others")
+)
+
+// Event 一次状态变更的上下文
+type Event struct {
+	Id   string // 状态机实例 id, 例如订单号
+	Name string
+	From State
+	To   State
+	Args []interface{}
+}
+
+// Callback 回调, 返回错误时终止状态变更(enter 回调除外)
+type Callback func(ctx context.Context, e *Event) error
+
+// Transition 状态转移, Guard 返回错误时不允许转移
+type Transition struct {
+	Event string
+	From  []State
+	To    State
+	Guard Callback
+}
+
+// Definition 状态机定义, 定义完成后可以被多个实例共享
+type Definition struct {
+	initial     State
+	transitions map[string]map[State]*Transition
+	enter       map[State][]Callback
+	leave       map[State][]Callback
+	after       []Callback
+}
+
+func NewDefinition(initial State) *Definition {
+	return &Definition{
+		initial:     initial,
+		transitions: make(map[string]map[State]*Transition),
+		enter:       make(map[State][]Callback),
+		leave:       make(map[State][]Callback),
+	}
+}
+
+// AddTransition 添加状态转移, 同一个事件在同一个状态下只能有一个目标状态
+func (d *Definition) AddTransition(t Transition) *Definition {
+	if d.transitions[t.Event] == nil {
+		d.transitions[t.Event] = make(map[State]*Transition)
+	}
+	tr := t
+	for _, from := range t.From {
+		if _, ok := d.transitions[t.Event][from]; ok {
+			panic(fmt.Sprintf("fsm: duplicate transition %s from %s", t.Event, from))
+		}
+		d.transitions[t.Event][from] = &tr
+	}
+	return d
+}
+
+// OnEnter 进入状态后的回调, 此时状态已经变更, 返回的错误只会透传给调用方
+func (d *Definition) OnEnter(state State, cb Callback) *Definition {
+	d.enter[state] = append(d.enter[state], cb)
+	return d
+}
+
+// OnLeave 离开状态前的回调, 返回错误时终止状态变更
+func (d *Definition) OnLeave(state State, cb Callback) *Definition {
+	d.leave[state] = append(d.leave[state], cb)
+	return d
+}
+
+// OnTransition 任意状态变更完成后的回调, 例如记录日志
+func (d *Definition) OnTransition(cb Callback) *Definition {
+	d.after = append(d.after, cb)
+	return d
+}
+
+// Initial 初始状态
+func (d *Definition) Initial() State {
+	return d.initial
+}
+
+// Machine 状态机实例, 并发安全
+type Machine struct {
+	def       *Definition
+	id        string
+	state     State
+	version   int64
+	persister Persister
+	mu        sync.Mutex
+}
+
+type Option func(*Machine)
+
+// WithPersister 状态变更时先持久化, 持久化失败时内存状态不变
+func WithPersister(p Persister) Option {
+	return func(m *Machine) {
+		m.persister = p
+	}
+}
+
+// NewMachine 创建实例, state 为空时使用初始状态
+func (d *Definition) NewMachine(id string, state State, ops ...Option) *Machine {
+	if state == "" {
+		state = d.initial
+	}
+	m := &Machine{def: d, id: id, state: state}
+	for i := range ops {
+		ops[i](m)
+	}
+	return m
+}
+
+// Restore 从持久化存储中恢复实例, 不存在时使用初始状态
+func (d *Definition) Restore(ctx context.Context, id string, p Persister) (*Machine, error) {
+	state, version, err := p.Load(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return d.NewMachine(id, "", WithPersister(p)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := d.NewMachine(id, state, WithPersister(p))
+	m.version = version
+	return m, nil
+}
+
+func (m *Machine) Id() string {
+	return m.id
+}
+
+func (m *Machine) Current() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// Is 是否处于某个状态
+func (m *Machine) Is(state State) bool {
+	return m.Current() == state
+}
+
+// Can 当前状态下是否能触发事件, 不执行 Guard
+func (m *Machine) Can(event string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.def.transitions[event][m.state]
+	return ok
+}
+
+// AvailableEvents 当前状态下可以触发的事件
+func (m *Machine) AvailableEvents() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []string
+	for event, froms := range m.def.transitions {
+		if _, ok := froms[m.state]; ok {
+			events = append(events, event)
+		}
+	}
+	sort.Strings(events)
+	return events
+}
+
+// Fire 触发事件, 执行顺序: Guard -> OnLeave -> 持久化 -> 变更状态 -> OnEnter -> OnTransition
+func (m *Machine) Fire(ctx context.Context, event string, args ...interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, ok := m.def.transitions[event][m.state]
+	if !ok {
+		return fmt.Errorf("%w: %s in state %s", ErrInvalidEvent, event, m.state)
+	}
+	e := &Event{Id: m.id, Name: event, From: m.state, To: t.To, Args: args}
+
+	if t.Guard != nil {
+		if err := t.Guard(ctx, e); err != nil {
+			return err
+		}
+	}
+	for _, cb := range m.def.leave[e.From] {
+		if err := cb(ctx, e); err != nil {
+			return err
+		}
+	}
+	if m.persister != nil {
+		if err := m.persister.Save(ctx, m.id, e.To, m.version); err != nil {
+			return err
+		}
+		m.version++
+	}
+	m.state = e.To
+
+	var errs []error
+	for _, cb := range m.def.enter[e.To] {
+		if err := cb(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, cb := range m.def.after {
+		if err := cb(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,118 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+const (
+	StateCreated  State = "created"
+	StatePaid     State = "paid"
+	StateShipped  State = "shipped"
+	StateCanceled State = "canceled"
+)
+
+func newOrderDef(log *[]string) *Definition {
+	return NewDefinition(StateCreated).
+		AddTransition(Transition{Event: "pay", From: []State{StateCreated}, To: StatePaid, Guard: func(ctx context.Context, e *Event) error {
+			if len(e.Args) == 0 || e.Args[0].(int) <= 0 {
+				return fmt.Errorf("invalid amount")
+			}
+			return nil
+		}}).
+		AddTransition(Transition{Event: "ship", From: []State{StatePaid}, To: StateShipped}).
+		AddTransition(Transition{Event: "cancel", From: []State{StateCreated, StatePaid}, To: StateCanceled}).
+		OnLeave(StateCreated, func(ctx context.Context, e *Event) error {
+			*log = append(*log, "leave "+string(e.From))
+			return nil
+		}).
+		OnEnter(StatePaid, func(ctx context.Context, e *Event) error {
+			*log = append(*log, "enter "+string(e.To))
+			return nil
+		}).
+		OnTransition(func(ctx context.Context, e *Event) error {
+			*log = append(*log, e.Name)
+			return nil
+		})
+}
+
+func TestMachine(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	m := newOrderDef(&log).NewMachine("o1", "")
+
+	if fmt.Sprint(m.AvailableEvents()) != "[cancel pay]" {
+		t.Fatalf("unexpected events %v", m.AvailableEvents())
+	}
+	if err := m.Fire(ctx, "ship"); !errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("expect invalid event, got %v", err)
+	}
+	if err := m.Fire(ctx, "pay", 0); err == nil || !m.Is(StateCreated) {
+		t.Fatalf("guard should reject, got %v %s", err, m.Current())
+	}
+	if err := m.Fire(ctx, "pay", 100); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Is(StatePaid) || fmt.Sprint(log) != "[leave created enter paid pay]" {
+		t.Fatalf("unexpected %s %v", m.Current(), log)
+	}
+	if !m.Can("ship") || m.Can("pay") {
+		t.Fatal("unexpected can")
+	}
+}
+
+func TestPersister(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	def := newOrderDef(&log)
+	p := NewMemoryPersister()
+
+	m1, err := def.Restore(ctx, "o1", p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2, _ := def.Restore(ctx, "o1", p)
+	if err = m1.Fire(ctx, "pay", 1); err != nil {
+		t.Fatal(err)
+	}
+	// m2 持有旧版本, 并发修改会冲突
+	if err = m2.Fire(ctx, "cancel"); !errors.Is(err, ErrConflict) || !m2.Is(StateCreated) {
+		t.Fatalf("expect conflict, got %v", err)
+	}
+
+	m3, _ := def.Restore(ctx, "o1", p)
+	if !m3.Is(StatePaid) {
+		t.Fatalf("unexpected restored state %s", m3.Current())
+	}
+	if err = m3.Fire(ctx, "ship"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentFire(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	m := newOrderDef(&log).NewMachine("o1", StatePaid)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var success int
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Fire(ctx, "ship"); err == nil {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if success != 1 {
+		t.Fatalf("expect only one success, got %d", success)
+	}
+}
@@ -0,0 +1,115 @@
+package fsm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrNotFound = errors.New("state not found")
+)
+
+// Persister 状态持久化, 使用版本号做乐观锁, 版本不一致时返回 ErrConflict
+type Persister interface {
+	Load(ctx context.Context, id string) (state State, version int64, err error)
+	// Save version 为读取时的版本, 保存成功后版本加一; version 为 0 表示首次保存
+	Save(ctx context.Context, id string, state State, version int64) error
+}
+
+var _ Persister = (*MemoryPersister)(nil)
+
+type memoryState struct {
+	state   State
+	version int64
+}
+
+type MemoryPersister struct {
+	states map[string]*memoryState
+	mu     sync.Mutex
+}
+
+func NewMemoryPersister() *MemoryPersister {
+	return &MemoryPersister{
+		states: make(map[string]*memoryState),
+	}
+}
+
+func (p *MemoryPersister) Load(ctx context.Context, id string) (State, int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.states[id]
+	if !ok {
+		return "", 0, ErrNotFound
+	}
+	return s.state, s.version, nil
+}
+
+func (p *MemoryPersister) Save(ctx context.Context, id string, state State, version int64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.states[id]
+	if (!ok && version != 0) || (ok && s.version != version) {
+		return ErrConflict
+	}
+	p.states[id] = &memoryState{state: state, version: version + 1}
+	return nil
+}
+
+// MysqlSchema SQLPersister 使用的表结构
+const MysqlSchema = `
+CREATE TABLE IF NOT EXISTS fsm_state (
+	machine VARCHAR(64) NOT NULL,
+	object_id VARCHAR(64) NOT NULL,
+	state VARCHAR(64) NOT NULL,
+	version BIGINT NOT NULL,
+	PRIMARY KEY (machine, object_id)
+);`
+
+var _ Persister = (*SQLPersister)(nil)
+
+// SQLPersister 基于 database/sql 的持久化, machine 区分不同的状态机, 使用 ? 占位符
+type SQLPersister struct {
+	db      *sql.DB
+	machine string
+}
+
+func NewSQLPersister(db *sql.DB, machine string) *SQLPersister {
+	return &SQLPersister{db: db, machine: machine}
+}
+
+func (p *SQLPersister) Load(ctx context.Context, id string) (State, int64, error) {
+	var state string
+	var version int64
+	err := p.db.QueryRowContext(ctx, "SELECT state, version FROM fsm_state WHERE machine = ? AND object_id = ?", p.machine, id).Scan(&state, &version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, ErrNotFound
+	}
+	return State(state), version, err
+}
+
+func (p *SQLPersister) Save(ctx context.Context, id string, state State, version int64) error {
+	var res sql.Result
+	var err error
+	if version == 0 {
+		// 主键冲突说明已经被其他实例初始化
+		res, err = p.db.ExecContext(ctx, "INSERT INTO fsm_state (machine, object_id, state, version) VALUES (?, ?, ?, 1)", p.machine, id, string(state))
+		if err != nil {
+			return errors.Join(ErrConflict, err)
+		}
+	} else {
+		res, err = p.db.ExecContext(ctx, "UPDATE fsm_state SET state = ?, version = version + 1 WHERE machine = ? AND object_id = ? AND version = ?", string(state), p.machine, id, version)
+		if err != nil {
+			return err
+		}
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package i18n
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/oldbai555/lbtool/log"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v2"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// message 一条文案, 没有复数变化时只有 other
+type message struct {
+	forms map[Plural]*template.Template
+}
+
+// Bundle 多语言文案集合
+type Bundle struct {
+	defaultLang language.Tag
+
+	messages map[language.Tag]map[string]*message
+	tags     []language.Tag
+	matcher  language.Matcher
+	mu       sync.RWMutex
+}
+
+func NewBundle(defaultLang language.Tag) *Bundle {
+	b := &Bundle{
+		defaultLang: defaultLang,
+		messages:    make(map[language.Tag]map[string]*message),
+	}
+	b.tags = []language.Tag{defaultLang}
+	b.matcher = language.NewMatcher(b.tags)
+	return b
+}
+
+// DefaultLang 默认语言
+func (b *Bundle) DefaultLang() language.Tag {
+	return b.defaultLang
+}
+
+// AddMessages 添加文案, 支持嵌套, 嵌套的 key 用 . 连接
+//
+//	{"user": {"hello": "你好 {{.Name}}"}, "apples": {"one": "{{.Count}} apple", "other": "{{.Count}} apples"}}
+func (b *Bundle) AddMessages(lang language.Tag, kv map[string]interface{}) error {
+	parsed := make(map[string]*message)
+	if err := flatten("", kv, parsed); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.messages[lang]; !ok {
+		b.messages[lang] = make(map[string]*message)
+		if lang != b.defaultLang {
+			b.tags = append(b.tags, lang)
+			b.matcher = language.NewMatcher(b.tags)
+		}
+	}
+	for k, m := range parsed {
+		b.messages[lang][k] = m
+	}
+	return nil
+}
+
+// LoadFS 加载目录下的文案文件, 文件名为语言, 例如 zh-CN.json, en.yaml
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		ext := path.Ext(e.Name())
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+		lang, err := language.Parse(strings.TrimSuffix(e.Name(), ext))
+		if err != nil {
+			return fmt.Errorf("i18n: invalid language file %s: %v", e.Name(), err)
+		}
+		buf, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		kv, err := unmarshal(ext, buf)
+		if err != nil {
+			return fmt.Errorf("i18n: parse %s: %v", e.Name(), err)
+		}
+		if err = b.AddMessages(lang, kv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadDataSource 从配置中心加载文案, 配置的 key 格式为 语言.文案key, 例如 zh-CN.user.hello
+func (b *Bundle) LoadDataSource(ds bconf.DataSource) error {
+	list, err := ds.Load()
+	if err != nil {
+		return err
+	}
+	return b.addData(list)
+}
+
+// WatchDataSource 监听配置中心的变更并热更新文案
+func (b *Bundle) WatchDataSource(ds bconf.DataSource) (bconf.DataWatcher, error) {
+	w, err := ds.Watch()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for {
+			list, err := w.Change()
+			if err != nil {
+				log.Warnf("i18n watch stopped, err:%v", err)
+				return
+			}
+			if err = b.addData(list); err != nil {
+				log.Errorf("err:%v", err)
+			}
+		}
+	}()
+	return w, nil
+}
+
+func (b *Bundle) addData(list []*bconf.Data) error {
+	byLang := make(map[language.Tag]map[string]interface{})
+	for _, d := range list {
+		langStr, key, ok := strings.Cut(d.Key, ".")
+		if !ok {
+			continue
+		}
+		lang, err := language.Parse(langStr)
+		if err != nil {
+			log.Warnf("i18n skip key %s, err:%v", d.Key, err)
+			continue
+		}
+		if byLang[lang] == nil {
+			byLang[lang] = make(map[string]interface{})
+		}
+		byLang[lang][key] = d.Val
+	}
+	for lang, kv := range byLang {
+		if err := b.AddMessages(lang, kv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unmarshal(ext string, buf []byte) (map[string]interface{}, error) {
+	kv := make(map[string]interface{})
+	if ext == ".json" {
+		d := json.NewDecoder(bytes.NewReader(buf))
+		return kv, d.Decode(&kv)
+	}
+	if err := yaml.Unmarshal(buf, &kv); err != nil {
+		return nil, err
+	}
+	return kv, nil
+}
+
+// flatten 展开嵌套的文案, 只包含复数分类 key 的 map 作为一条复数文案
+func flatten(prefix string, kv map[string]interface{}, out map[string]*message) error {
+	for k, v := range kv {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		sub, isMap := toStringMap(v)
+		if !isMap {
+			tpl, err := parseTemplate(key, fmt.Sprint(v))
+			if err != nil {
+				return err
+			}
+			out[key] = &message{forms: map[Plural]*template.Template{PluralOther: tpl}}
+			continue
+		}
+		if isPluralMap(sub) {
+			m := &message{forms: make(map[Plural]*template.Template)}
+			for form, text := range sub {
+				tpl, err := parseTemplate(key, fmt.Sprint(text))
+				if err != nil {
+					return err
+				}
+				m.forms[Plural(form)] = tpl
+			}
+			out[key] = m
+			continue
+		}
+		if err := flatten(key, sub, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toStringMap 兼容 json 和 yaml 解析出来的 map
+func toStringMap(v interface{}) (map[string]interface{}, bool) {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m, true
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(m))
+		for k, val := range m {
+			res[fmt.Sprint(k)] = val
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+func isPluralMap(m map[string]interface{}) bool {
+	if len(m) == 0 {
+		return false
+	}
+	for k := range m {
+		if !isPluralKey(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseTemplate(key, text string) (*template.Template, error) {
+	tpl, err := template.New(key).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("i18n: parse message %s: %v", key, err)
+	}
+	return tpl, nil
+}
+
+// match 按优先级找出支持的语言
+func (b *Bundle) match(langs ...string) language.Tag {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var tags []language.Tag
+	for _, l := range langs {
+		if l == "" {
+			continue
+		}
+		// Accept-Language 格式, 例如 zh-CN,zh;q=0.9,en;q=0.8
+		parsed, _, err := language.ParseAcceptLanguage(l)
+		if err != nil {
+			continue
+		}
+		tags = append(tags, parsed...)
+	}
+	if len(tags) == 0 {
+		return b.defaultLang
+	}
+	_, idx, conf := b.matcher.Match(tags...)
+	if conf == language.No {
+		return b.defaultLang
+	}
+	return b.tags[idx]
+}
+
+// lookup 在指定语言中查找文案, 找不到时使用默认语言
+func (b *Bundle) lookup(lang language.Tag, key string) (*message, language.Tag, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if m, ok := b.messages[lang][key]; ok {
+		return m, lang, true
+	}
+	if m, ok := b.messages[b.defaultLang][key]; ok {
+		return m, b.defaultLang, true
+	}
+	return nil, lang, false
+}
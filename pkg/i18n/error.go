@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/oldbai555/lbtool/pkg/lberr"
+	"strings"
+)
+
+const (
+	// ErrKeyPrefix lberr 错误码对应的文案 key 为 err.<code>, 例如 err.1001
+	ErrKeyPrefix = "err."
+	// ValidateKeyPrefix validator 校验错误对应的文案 key 为 validate.<tag>, 例如 validate.required
+	ValidateKeyPrefix = "validate."
+)
+
+// TranslateError 把错误翻译为用户可读的文案
+// lberr 按错误码查找文案, validator 的校验错误按 tag 查找, 模板参数有 Field 和 Param
+// 字段名本身也可以翻译, key 为 field.<字段名>
+func TranslateError(ctx context.Context, err error) string {
+	if err == nil {
+		return ""
+	}
+	l := FromContext(ctx)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		msgs := make([]string, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			msgs = append(msgs, translateFieldError(l, fe))
+		}
+		return strings.Join(msgs, "; ")
+	}
+
+	var lbErr *lberr.Error
+	if errors.As(err, &lbErr) {
+		if l == nil {
+			return lbErr.Message()
+		}
+		key := fmt.Sprintf("%s%d", ErrKeyPrefix, lbErr.Code())
+		if msg := l.T(key); msg != key {
+			return msg
+		}
+		return lbErr.Message()
+	}
+	return err.Error()
+}
+
+func translateFieldError(l *Localizer, fe validator.FieldError) string {
+	if l == nil {
+		return fe.Error()
+	}
+	fieldKey := "field." + fe.Field()
+	field := l.T(fieldKey)
+	if field == fieldKey {
+		field = fe.Field()
+	}
+	key := ValidateKeyPrefix + fe.Tag()
+	msg := l.T(key, map[string]interface{}{"Field": field, "Param": fe.Param()})
+	if msg == key {
+		return fe.Error()
+	}
+	return msg
+}
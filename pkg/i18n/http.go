@@ -0,0 +1,26 @@
+package i18n
+
+import (
+	"net/http"
+)
+
+const (
+	// QueryLang 优先使用 url 参数指定的语言, 其次 cookie, 最后 Accept-Language
+	QueryLang  = "lang"
+	CookieLang = "lang"
+)
+
+// HttpMiddleware 协商语言并把 Localizer 放到请求的 ctx 中
+func HttpMiddleware(b *Bundle) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var cookieLang string
+			if c, err := r.Cookie(CookieLang); err == nil {
+				cookieLang = c.Value
+			}
+			l := b.Localizer(r.URL.Query().Get(QueryLang), cookieLang, r.Header.Get("Accept-Language"))
+			w.Header().Set("Content-Language", l.Lang().String())
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), l)))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"github.com/go-playground/validator/v10"
+	"github.com/oldbai555/lbtool/extpkg/lbconf/bconf"
+	"github.com/oldbai555/lbtool/pkg/lberr"
+	"golang.org/x/text/language"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//go:embed testdata
+var testFS embed.FS
+
+func newTestBundle(t *testing.T) *Bundle {
+	b := NewBundle(language.MustParse("zh-CN"))
+	if err := b.LoadFS(testFS, "testdata"); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLocalizer(t *testing.T) {
+	b := newTestBundle(t)
+
+	en := b.Localizer("en-US,en;q=0.9")
+	if en.Lang() != language.English {
+		t.Fatalf("unexpected lang %s", en.Lang())
+	}
+	if got := en.T("hello", map[string]string{"Name": "lb"}); got != "Hello lb" {
+		t.Fatalf("unexpected %s", got)
+	}
+	if got := en.Plural("user.unread", 1, nil); got != "1 new message" {
+		t.Fatalf("unexpected %s", got)
+	}
+	if got := en.Plural("user.unread", 3, nil); got != "3 new messages" {
+		t.Fatalf("unexpected %s", got)
+	}
+	// en 没有的文案回退到默认语言
+	if got := en.T("err.1001"); got != "参数错误" {
+		t.Fatalf("unexpected %s", got)
+	}
+
+	zh := b.Localizer("fr", "zh-Hans-CN")
+	if got := zh.Plural("user.unread", 0, nil); got != "没有新消息" {
+		t.Fatalf("unexpected %s", got)
+	}
+	if got := zh.Plural("user.unread", 2, nil); got != "2 条新消息" {
+		t.Fatalf("unexpected %s", got)
+	}
+	if got := zh.T("not.exist"); got != "not.exist" {
+		t.Fatalf("unexpected %s", got)
+	}
+
+	// 不支持的语言使用默认语言
+	if got := b.Localizer("ja").Lang(); got != language.MustParse("zh-CN") {
+		t.Fatalf("unexpected lang %s", got)
+	}
+}
+
+type testDataSource struct {
+	list []*bconf.Data
+}
+
+func (d *testDataSource) Load() ([]*bconf.Data, error) {
+	return d.list, nil
+}
+
+func (d *testDataSource) Watch() (bconf.DataWatcher, error) {
+	return nil, nil
+}
+
+func TestLoadDataSource(t *testing.T) {
+	b := newTestBundle(t)
+	err := b.LoadDataSource(&testDataSource{list: []*bconf.Data{
+		{Key: "en.bye", Val: "Bye {{.Name}}"},
+		{Key: "en.hello", Val: "Hi {{.Name}}"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	en := b.Localizer("en")
+	if got := en.T("bye", map[string]string{"Name": "lb"}); got != "Bye lb" {
+		t.Fatalf("unexpected %s", got)
+	}
+	if got := en.T("hello", map[string]string{"Name": "lb"}); got != "Hi lb" {
+		t.Fatalf("unexpected %s", got)
+	}
+}
+
+func TestMiddlewareAndError(t *testing.T) {
+	b := newTestBundle(t)
+	type req struct {
+		Name string `validate:"required"`
+	}
+	validateErr := validator.New().Struct(&req{})
+
+	var got []string
+	h := HttpMiddleware(b)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, TranslateError(r.Context(), validateErr), TranslateError(r.Context(), lberr.NewErr(1001, "invalid arg")))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/?lang=zh-CN", nil))
+	if rec.Header().Get("Content-Language") != "zh-CN" {
+		t.Fatalf("unexpected header %v", rec.Header())
+	}
+	if got[0] != "名称不能为空" || got[1] != "参数错误" {
+		t.Fatalf("unexpected %v", got)
+	}
+
+	if msg := TranslateError(context.Background(), lberr.NewErr(1001, "invalid arg")); msg != "invalid arg" {
+		t.Fatalf("unexpected %s", msg)
+	}
+}
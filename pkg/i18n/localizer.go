@@ -0,0 +1,99 @@
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"github.com/oldbai555/lbtool/log"
+	"golang.org/x/text/language"
+)
+
+// Localizer 绑定了语言的翻译器
+type Localizer struct {
+	bundle *Bundle
+	lang   language.Tag
+}
+
+// Localizer 按优先级选择语言, 参数可以是语言标签或 Accept-Language 头
+func (b *Bundle) Localizer(langs ...string) *Localizer {
+	return &Localizer{
+		bundle: b,
+		lang:   b.match(langs...),
+	}
+}
+
+// Lang 实际使用的语言
+func (l *Localizer) Lang() language.Tag {
+	return l.lang
+}
+
+// T 翻译文案, data 为模板参数, 找不到文案时返回 key
+func (l *Localizer) T(key string, data ...interface{}) string {
+	var d interface{}
+	if len(data) > 0 {
+		d = data[0]
+	}
+	return l.translate(key, PluralOther, d)
+}
+
+// Plural 按数量翻译复数文案, 模板中可以通过 {{.Count}} 取到数量
+func (l *Localizer) Plural(key string, count int, data map[string]interface{}) string {
+	m, lang, ok := l.bundle.lookup(l.lang, key)
+	if !ok {
+		return key
+	}
+	form := pluralRule(lang)(count)
+	// 有 zero 文案时 0 优先使用 zero, 例如 "没有新消息"
+	if count == 0 {
+		if _, ok := m.forms[PluralZero]; ok {
+			form = PluralZero
+		}
+	}
+	d := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		d[k] = v
+	}
+	d["Count"] = count
+	return l.translate(key, form, d)
+}
+
+func (l *Localizer) translate(key string, form Plural, data interface{}) string {
+	m, _, ok := l.bundle.lookup(l.lang, key)
+	if !ok {
+		return key
+	}
+	tpl, ok := m.forms[form]
+	if !ok {
+		tpl, ok = m.forms[PluralOther]
+	}
+	if !ok {
+		return key
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		log.Errorf("i18n execute %s err:%v", key, err)
+		return key
+	}
+	return buf.String()
+}
+
+type localizerKey struct{}
+
+// NewContext 把 Localizer 放到 ctx 中
+func NewContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, localizerKey{}, l)
+}
+
+// FromContext 取出 ctx 中的 Localizer, 没有时返回 nil
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(localizerKey{}).(*Localizer)
+	return l
+}
+
+// T 使用 ctx 中的 Localizer 翻译, 没有时返回 key
+func T(ctx context.Context, key string, data ...interface{}) string {
+	l := FromContext(ctx)
+	if l == nil {
+		return key
+	}
+	return l.T(key, data...)
+}
@@ -0,0 +1,74 @@
+package i18n
+
+import "golang.org/x/text/language"
+
+// Plural 复数形式, 与 CLDR 的分类一致
+type Plural string
+
+const (
+	PluralZero  Plural = "zero"
+	PluralOne   Plural = "one"
+	PluralTwo   Plural = "two"
+	PluralFew   Plural = "few"
+	PluralMany  Plural = "many"
+	PluralOther Plural = "other"
+)
+
+func isPluralKey(k string) bool {
+	switch Plural(k) {
+	case PluralZero, PluralOne, PluralTwo, PluralFew, PluralMany, PluralOther:
+		return true
+	}
+	return false
+}
+
+// PluralRule 根据数量返回复数形式
+type PluralRule func(n int) Plural
+
+var pluralRules = map[string]PluralRule{}
+
+// RegisterPluralRule 注册语言的复数规则, base 为语言的基础部分, 例如 en, zh
+func RegisterPluralRule(rule PluralRule, bases ...string) {
+	for _, base := range bases {
+		pluralRules[base] = rule
+	}
+}
+
+func init() {
+	// 中日韩等没有复数变化
+	RegisterPluralRule(func(n int) Plural {
+		return PluralOther
+	}, "zh", "ja", "ko", "vi", "th", "id", "ms")
+	RegisterPluralRule(func(n int) Plural {
+		if n == 0 || n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}, "fr")
+	RegisterPluralRule(func(n int) Plural {
+		mod10, mod100 := n%10, n%100
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		}
+		return PluralMany
+	}, "ru", "uk", "be")
+}
+
+// defaultPluralRule 英语等大多数语言的规则
+func defaultPluralRule(n int) Plural {
+	if n == 1 {
+		return PluralOne
+	}
+	return PluralOther
+}
+
+func pluralRule(tag language.Tag) PluralRule {
+	base, _ := tag.Base()
+	if rule, ok := pluralRules[base.String()]; ok {
+		return rule
+	}
+	return defaultPluralRule
+}
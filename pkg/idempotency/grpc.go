@@ -0,0 +1,138 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"strings"
+)
+
+// retryableCodes 这些错误不保存, 客户端可以用同一个键重试
+var retryableCodes = map[codes.Code]bool{
+	codes.Unknown:           true,
+	codes.DeadlineExceeded:  true,
+	codes.Canceled:          true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+	codes.Internal:          true,
+	codes.Unavailable:       true,
+}
+
+// UnaryServerInterceptor grpc 幂等处理, 幂等键从 metadata 读取
+// 请求和响应需要是 proto 消息, 重放时按响应的消息名从全局注册表创建实例
+func (i *Idempotency) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	header := strings.ToLower(i.header)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var key string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if list := md.Get(header); len(list) > 0 {
+				key = list[0]
+			}
+		}
+		if key == "" {
+			if i.required {
+				return nil, status.Error(codes.InvalidArgument, ErrKeyMissing.Error())
+			}
+			return handler(ctx, req)
+		}
+		reqMsg, ok := req.(proto.Message)
+		if !ok {
+			log.Warnf("skip idempotency for %s, request is not proto message", info.FullMethod)
+			return handler(ctx, req)
+		}
+		fingerprint, err := grpcFingerprint(info.FullMethod, reqMsg)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, status.Error(codes.Internal, "idempotency fingerprint failed")
+		}
+
+		key = i.storeKey(ctx, key)
+		rec, lock, err := i.begin(ctx, key, fingerprint)
+		switch {
+		case errors.Is(err, ErrInProgress):
+			return nil, status.Error(codes.Aborted, err.Error())
+		case errors.Is(err, ErrMismatch):
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		case err != nil:
+			log.Errorf("err:%v", err)
+			return nil, status.Error(codes.Internal, "idempotency check failed")
+		case rec != nil:
+			return replayGrpc(rec)
+		}
+
+		finished := false
+		defer func() {
+			if finished {
+				return
+			}
+			if err := i.abort(ctx, key, lock); err != nil {
+				log.Errorf("err:%v", err)
+			}
+		}()
+		resp, handleErr := handler(ctx, req)
+
+		rec = &Record{Fingerprint: fingerprint}
+		if handleErr != nil {
+			st := status.Convert(handleErr)
+			if retryableCodes[st.Code()] {
+				return resp, handleErr
+			}
+			rec.Code = uint32(st.Code())
+			rec.ErrMessage = st.Message()
+		} else {
+			respMsg, ok := resp.(proto.Message)
+			if !ok {
+				log.Warnf("skip idempotency for %s, response is not proto message", info.FullMethod)
+				return resp, nil
+			}
+			if rec.Body, err = proto.Marshal(respMsg); err != nil {
+				log.Errorf("err:%v", err)
+				return resp, nil
+			}
+			rec.Message = string(respMsg.ProtoReflect().Descriptor().FullName())
+		}
+		finished = true
+		if err = i.finish(ctx, key, rec); err != nil {
+			log.Errorf("err:%v", err)
+		}
+		return resp, handleErr
+	}
+}
+
+func grpcFingerprint(method string, req proto.Message) (string, error) {
+	buf, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write(buf)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func replayGrpc(rec *Record) (interface{}, error) {
+	if rec.Code != 0 {
+		return nil, status.Error(codes.Code(rec.Code), rec.ErrMessage)
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(rec.Message))
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return nil, status.Error(codes.Internal, "idempotency replay failed")
+	}
+	msg := mt.New().Interface()
+	if err = proto.Unmarshal(rec.Body, msg); err != nil {
+		log.Errorf("err:%v", err)
+		return nil, status.Error(codes.Internal, "idempotency replay failed")
+	}
+	return msg, nil
+}
@@ -0,0 +1,142 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"io"
+	"net/http"
+)
+
+// HttpMiddleware 识别幂等键, 保存第一次的响应并在重试时重放
+// 5xx 响应和 panic 不保存, 客户端可以用同一个键重试
+func (i *Idempotency) HttpMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !i.methods[r.Method] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := r.Header.Get(i.header)
+		if key == "" {
+			if i.required {
+				http.Error(w, ErrKeyMissing.Error(), http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		key = i.storeKey(ctx, key)
+		fingerprint := httpFingerprint(r, body)
+		rec, lock, err := i.begin(ctx, key, fingerprint)
+		switch {
+		case errors.Is(err, ErrInProgress):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, ErrMismatch):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case err != nil:
+			log.Errorf("err:%v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		case rec != nil:
+			replay(w, rec)
+			return
+		}
+
+		rw := &recordWriter{ResponseWriter: w, status: http.StatusOK}
+		finished := false
+		defer func() {
+			if finished {
+				return
+			}
+			if err := i.abort(ctx, key, lock); err != nil {
+				log.Errorf("err:%v", err)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+
+		if rw.status >= http.StatusInternalServerError {
+			return
+		}
+		finished = true
+		err = i.finish(ctx, key, &Record{
+			Fingerprint: fingerprint,
+			Status:      rw.status,
+			Header:      rw.snapshotHeader(),
+			Body:        rw.body.Bytes(),
+		})
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+	})
+}
+
+// httpFingerprint 同一个幂等键只能用于相同的请求
+func httpFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{0})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(w http.ResponseWriter, rec *Record) {
+	for k, v := range rec.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(rec.Status)
+	if _, err := w.Write(rec.Body); err != nil {
+		log.Errorf("err:%v", err)
+	}
+}
+
+// recordWriter 在写出响应的同时记录一份
+type recordWriter struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *recordWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	w.header = w.ResponseWriter.Header().Clone()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// snapshotHeader handler 没有写任何内容时, 响应头以处理结束时为准
+func (w *recordWriter) snapshotHeader() http.Header {
+	if w.wroteHeader {
+		return w.header
+	}
+	return w.ResponseWriter.Header().Clone()
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
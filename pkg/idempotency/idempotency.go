@@ -0,0 +1,173 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/oldbai555/lbtool/utils"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultHeader  = "Idempotency-Key"
+	DefaultTTL     = 24 * time.Hour
+	DefaultLockTTL = time.Minute
+
+	// HeaderReplayed 重放的响应带上该头
+	HeaderReplayed = "Idempotent-Replayed"
+)
+
+var (
+	ErrInProgress = errors.New("request with the same idempotency key is in progress")
+	ErrMismatch   = errors.New("idempotency key reused with a different request")
+	ErrKeyMissing = errors.New("idempotency key is required")
+)
+
+// Record 第一次请求的响应, Done 为 false 表示请求还在处理中
+type Record struct {
+	Done        bool   `json:"done"`
+	Fingerprint string `json:"fingerprint"`
+	// Token 处理中标记的持有者, abort 时只删除自己写入的标记
+	Token string `json:"token,omitempty"`
+
+	// http 响应
+	Status int         `json:"status,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+
+	// grpc 响应, Message 为响应的 proto 全名, Code 非 0 时重放错误
+	Message    string `json:"message,omitempty"`
+	Code       uint32 `json:"code,omitempty"`
+	ErrMessage string `json:"err_message,omitempty"`
+}
+
+// Idempotency 按幂等键保存第一次请求的响应, TTL 内的重试直接重放
+type Idempotency struct {
+	store    Store
+	ttl      time.Duration
+	lockTtl  time.Duration
+	header   string
+	methods  map[string]bool
+	required bool
+	scopeFn  func(ctx context.Context) string
+}
+
+type Option func(*Idempotency)
+
+// WithTTL 响应保存的时长, 超过后同一个键会重新执行
+func WithTTL(ttl time.Duration) Option {
+	return func(i *Idempotency) {
+		i.ttl = ttl
+	}
+}
+
+// WithLockTTL 处理中标记的过期时间, 防止进程崩溃后键一直处于处理中
+func WithLockTTL(ttl time.Duration) Option {
+	return func(i *Idempotency) {
+		i.lockTtl = ttl
+	}
+}
+
+// WithHeader 幂等键所在的请求头, grpc 使用小写后的 metadata
+func WithHeader(header string) Option {
+	return func(i *Idempotency) {
+		i.header = header
+	}
+}
+
+// WithMethods 需要幂等处理的 http 方法, 默认 POST 和 PATCH
+func WithMethods(methods ...string) Option {
+	return func(i *Idempotency) {
+		i.methods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			i.methods[m] = true
+		}
+	}
+}
+
+// WithRequired 缺少幂等键时直接拒绝请求
+func WithRequired() Option {
+	return func(i *Idempotency) {
+		i.required = true
+	}
+}
+
+// WithScope 按用户等维度隔离幂等键, 避免不同用户的键冲突
+func WithScope(fn func(ctx context.Context) string) Option {
+	return func(i *Idempotency) {
+		i.scopeFn = fn
+	}
+}
+
+func New(store Store, ops ...Option) *Idempotency {
+	i := &Idempotency{
+		store:   store,
+		ttl:     DefaultTTL,
+		lockTtl: DefaultLockTTL,
+		header:  DefaultHeader,
+		methods: map[string]bool{http.MethodPost: true, http.MethodPatch: true},
+	}
+	for idx := range ops {
+		ops[idx](i)
+	}
+	return i
+}
+
+func (i *Idempotency) storeKey(ctx context.Context, key string) string {
+	if i.scopeFn == nil {
+		return key
+	}
+	return i.scopeFn(ctx) + ":" + key
+}
+
+// begin 返回已完成的记录用于重放; 记录为 nil 表示本次请求获得执行权, lock 为写入的处理中标记
+func (i *Idempotency) begin(ctx context.Context, key, fingerprint string) (rec *Record, lock []byte, err error) {
+	data, err := i.store.Get(ctx, key)
+	if err == nil {
+		rec = &Record{}
+		if err = json.Unmarshal(data, rec); err != nil {
+			return nil, nil, err
+		}
+		if rec.Fingerprint != fingerprint {
+			return nil, nil, ErrMismatch
+		}
+		if !rec.Done {
+			return nil, nil, ErrInProgress
+		}
+		return rec, nil, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, nil, err
+	}
+
+	lock, err = json.Marshal(&Record{Fingerprint: fingerprint, Token: utils.GenUUID()})
+	if err != nil {
+		return nil, nil, err
+	}
+	ok, err := i.store.SetNX(ctx, key, lock, i.lockTtl)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrInProgress
+	}
+	return nil, lock, nil
+}
+
+// finish 保存响应, 请求的 ctx 可能已经结束, 只保留 ctx 里的值
+func (i *Idempotency) finish(ctx context.Context, key string, rec *Record) error {
+	rec.Done = true
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return i.store.Set(context.WithoutCancel(ctx), key, data, i.ttl)
+}
+
+// abort 处理失败时删除处理中标记, 允许客户端重试
+// 标记过期后可能已被其他请求重新写入, 只有仍是自己写入的标记时才删除
+func (i *Idempotency) abort(ctx context.Context, key string, lock []byte) error {
+	_, err := i.store.CompareAndDelete(context.WithoutCancel(ctx), key, lock)
+	return err
+}
@@ -0,0 +1,194 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func doRequest(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(DefaultHeader, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHttpMiddleware(t *testing.T) {
+	var calls int32
+	var fail int32
+	i := New(NewMemoryStore())
+	h := i.HttpMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("X-Order-Id", "o1")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(strings.Repeat("x", int(n))))
+	}))
+
+	first := doRequest(h, "k1", `{"sku":1}`)
+	if first.Code != http.StatusCreated || first.Body.String() != "x" {
+		t.Fatalf("unexpected first response %d %s", first.Code, first.Body.String())
+	}
+	second := doRequest(h, "k1", `{"sku":1}`)
+	if second.Code != http.StatusCreated || second.Body.String() != "x" ||
+		second.Header().Get("X-Order-Id") != "o1" || second.Header().Get(HeaderReplayed) != "true" {
+		t.Fatalf("unexpected replay %d %s %v", second.Code, second.Body.String(), second.Header())
+	}
+	if calls != 1 {
+		t.Fatalf("handler should be called once, got %d", calls)
+	}
+
+	if w := doRequest(h, "k1", `{"sku":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expect 422, got %d", w.Code)
+	}
+	if doRequest(h, "", `{"sku":1}`); calls != 2 {
+		t.Fatal("request without key should pass through")
+	}
+
+	// 5xx 不保存, 同一个键可以重试
+	atomic.StoreInt32(&fail, 1)
+	if w := doRequest(h, "k2", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expect 500, got %d", w.Code)
+	}
+	atomic.StoreInt32(&fail, 0)
+	if w := doRequest(h, "k2", `{}`); w.Code != http.StatusCreated || w.Header().Get(HeaderReplayed) != "" {
+		t.Fatalf("expect retry executed, got %d %v", w.Code, w.Header())
+	}
+
+	// 处理中的请求返回 409
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := i.HttpMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+	done := make(chan struct{})
+	go func() {
+		doRequest(slow, "k3", `{}`)
+		close(done)
+	}()
+	<-started
+	if w := doRequest(slow, "k3", `{}`); w.Code != http.StatusConflict {
+		t.Fatalf("expect 409, got %d", w.Code)
+	}
+	close(release)
+	<-done
+
+	required := New(NewMemoryStore(), WithRequired()).HttpMiddleware(http.NotFoundHandler())
+	if w := doRequest(required, "", `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expect 400, got %d", w.Code)
+	}
+}
+
+func TestMemoryStore_TTL(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if ok, _ := s.SetNX(ctx, "k", []byte("a"), 20*time.Millisecond); !ok {
+		t.Fatal("first SetNX should succeed")
+	}
+	if ok, _ := s.SetNX(ctx, "k", []byte("b"), time.Minute); ok {
+		t.Fatal("second SetNX should fail")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if ok, _ := s.SetNX(ctx, "k", []byte("c"), time.Minute); !ok {
+		t.Fatal("SetNX after expire should succeed")
+	}
+}
+
+func TestAbortOwnLockOnly(t *testing.T) {
+	ctx := context.Background()
+	i := New(NewMemoryStore(), WithLockTTL(20*time.Millisecond))
+	_, lock, err := i.begin(ctx, "k", "fp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一个请求处理超时, 标记过期后被第二个请求重新写入
+	time.Sleep(30 * time.Millisecond)
+	_, lock2, err := i.begin(ctx, "k", "fp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = i.abort(ctx, "k", lock); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = i.begin(ctx, "k", "fp"); !errors.Is(err, ErrInProgress) {
+		t.Fatalf("second lock should be kept, got %v", err)
+	}
+	if err = i.abort(ctx, "k", lock2); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = i.begin(ctx, "k", "fp"); err != nil {
+		t.Fatalf("lock should be deleted, got %v", err)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := New(NewMemoryStore()).UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/pb.Order/Create"}
+	var calls int
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		in := req.(*wrapperspb.StringValue)
+		if in.Value == "bad" {
+			return nil, status.Error(codes.InvalidArgument, "bad sku")
+		}
+		if in.Value == "retry" {
+			return nil, status.Error(codes.Unavailable, "db down")
+		}
+		return wrapperspb.String("order_" + in.Value), nil
+	}
+	call := func(key, val string) (interface{}, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("idempotency-key", key))
+		return interceptor(ctx, wrapperspb.String(val), info, handler)
+	}
+
+	for n := 0; n < 2; n++ {
+		resp, err := call("k1", "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.(*wrapperspb.StringValue).Value != "order_1" {
+			t.Fatalf("unexpected resp %v", resp)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("handler should be called once, got %d", calls)
+	}
+	if _, err := call("k1", "2"); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expect FailedPrecondition, got %v", err)
+	}
+
+	// 业务错误会重放, 可重试的错误不保存
+	for n := 0; n < 2; n++ {
+		if _, err := call("k2", "bad"); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("expect InvalidArgument, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("business error should be replayed, calls %d", calls)
+	}
+	for n := 0; n < 2; n++ {
+		if _, err := call("k3", "retry"); status.Code(err) != codes.Unavailable {
+			t.Fatalf("expect Unavailable, got %v", err)
+		}
+	}
+	if calls != 4 {
+		t.Fatalf("retryable error should not be saved, calls %d", calls)
+	}
+}
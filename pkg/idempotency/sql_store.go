@@ -0,0 +1,105 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/oldbai555/lbtool/pkg/internal/placeholder"
+	"time"
+)
+
+// MysqlSchema SQLStore 使用的表结构, 过期数据需要定时任务按 expire_at 清理
+const MysqlSchema = `
+CREATE TABLE IF NOT EXISTS idempotency_record (
+	idem_key VARCHAR(191) NOT NULL,
+	data BLOB NOT NULL,
+	expire_at BIGINT NOT NULL,
+	PRIMARY KEY (idem_key),
+	KEY idx_expire_at (expire_at)
+);`
+
+var _ Store = (*SQLStore)(nil)
+
+// SQLStore 没有 redis 时使用, 主键冲突保证同一个幂等键只有一个请求获得执行权
+// 默认使用 ? 占位符 (mysql, sqlite)
+type SQLStore struct {
+	db     *sql.DB
+	dollar bool
+}
+
+type SQLOption func(*SQLStore)
+
+// WithDollarPlaceholder postgres 需要 $n 占位符, data 字段改为 BYTEA
+func WithDollarPlaceholder() SQLOption {
+	return func(s *SQLStore) {
+		s.dollar = true
+	}
+}
+
+func NewSQLStore(db *sql.DB, ops ...SQLOption) *SQLStore {
+	s := &SQLStore{db: db}
+	for i := range ops {
+		ops[i](s)
+	}
+	return s
+}
+
+// rebind 需要时把 ? 替换为 $n
+func (s *SQLStore) rebind(query string) string {
+	if s.dollar {
+		query = placeholder.Dollar(query)
+	}
+	return query
+}
+
+func (s *SQLStore) Get(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, s.rebind("SELECT data FROM idempotency_record WHERE idem_key = ? AND expire_at > ?"),
+		key, time.Now().UnixMilli()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// SetNX 先清理同 key 的过期记录再插入, 主键冲突说明已有未过期的记录
+func (s *SQLStore) SetNX(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	_, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM idempotency_record WHERE idem_key = ? AND expire_at <= ?"), key, now.UnixMilli())
+	if err != nil {
+		return false, err
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind("INSERT INTO idempotency_record (idem_key, data, expire_at) VALUES (?, ?, ?)"),
+		key, data, now.Add(ttl).UnixMilli())
+	if err == nil {
+		return true, nil
+	}
+	// 不同驱动的主键冲突错误不一样, 通过查询确认
+	if _, getErr := s.Get(ctx, key); getErr == nil {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *SQLStore) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	res, err := s.db.ExecContext(ctx, s.rebind("UPDATE idempotency_record SET data = ?, expire_at = ? WHERE idem_key = ?"),
+		data, time.Now().Add(ttl).UnixMilli(), key)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		return nil
+	}
+	_, err = s.db.ExecContext(ctx, s.rebind("INSERT INTO idempotency_record (idem_key, data, expire_at) VALUES (?, ?, ?)"),
+		key, data, time.Now().Add(ttl).UnixMilli())
+	return err
+}
+
+func (s *SQLStore) CompareAndDelete(ctx context.Context, key string, data []byte) (bool, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM idempotency_record WHERE idem_key = ? AND data = ?"), key, data)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
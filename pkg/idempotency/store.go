@@ -0,0 +1,126 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/oldbai555/lbtool/pkg/internal/ttlmap"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("idempotency record not found")
+)
+
+// Store 幂等记录存储, data 为序列化后的记录
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	// SetNX key 不存在时写入, 返回是否写入成功
+	SetNX(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error)
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	// CompareAndDelete 当前值等于 data 时删除, 返回是否删除
+	CompareAndDelete(ctx context.Context, key string, data []byte) (bool, error)
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore 单机存储, 多实例部署时重试可能落到其他实例, 无法保证幂等
+type MemoryStore struct {
+	records *ttlmap.Map[[]byte]
+	mu      sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: ttlmap.New[[]byte](),
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.records.Get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (m *MemoryStore) SetNX(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.records.Get(key); ok {
+		return false, nil
+	}
+	m.records.Set(key, data, ttl)
+	return true, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records.Set(key, data, ttl)
+	return nil
+}
+
+func (m *MemoryStore) CompareAndDelete(ctx context.Context, key string, data []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.records.Get(key)
+	if !ok || !bytes.Equal(cur, data) {
+		return false, nil
+	}
+	m.records.Delete(key)
+	return true, nil
+}
+
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+var _ Store = (*RedisStore)(nil)
+
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "idempotency"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *RedisStore) key(key string) string {
+	return fmt.Sprintf("%s_%s", r.prefix, key)
+}
+
+func (r *RedisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := r.client.Get(ctx, r.key(key)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (r *RedisStore) SetNX(ctx context.Context, key string, data []byte, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, r.key(key), data, ttl).Result()
+}
+
+func (r *RedisStore) Set(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.key(key), data, ttl).Err()
+}
+
+func (r *RedisStore) CompareAndDelete(ctx context.Context, key string, data []byte) (bool, error) {
+	n, err := compareAndDeleteScript.Run(ctx, r.client, []string{r.key(key)}, data).Int()
+	return n == 1, err
+}
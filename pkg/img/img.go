@@ -0,0 +1,106 @@
+package img
+
+import (
+	"errors"
+	"fmt"
+	"github.com/disintegration/imaging"
+	"image"
+	"io"
+	"strings"
+
+	// 注册 webp 解码
+	_ "golang.org/x/image/webp"
+)
+
+type Format string
+
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatGIF  Format = "gif"
+	FormatWEBP Format = "webp"
+
+	DefaultQuality = 85
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	// ErrWebpEncode webp 编码需要 cgo, 这里只支持解码, 输出时请转成 jpeg 或 png
+	ErrWebpEncode = errors.New("webp encoding is not supported")
+)
+
+// ParseFormat 解析格式名或文件后缀, 例如 jpg, .png, image/webp
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "image/")
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	switch s {
+	case "jpg", "jpeg":
+		return FormatJPEG, nil
+	case "png":
+		return FormatPNG, nil
+	case "gif":
+		return FormatGIF, nil
+	case "webp":
+		return FormatWEBP, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Decode 解码图片并按 EXIF 方向信息自动旋转, 手机拍的照片不会歪
+func Decode(r io.Reader) (image.Image, Format, error) {
+	var buf peekReader
+	buf.r = r
+	_, name, err := image.DecodeConfig(&buf)
+	if err != nil {
+		return nil, "", err
+	}
+	format, err := ParseFormat(name)
+	if err != nil {
+		return nil, "", err
+	}
+	m, err := imaging.Decode(buf.replay(), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, "", err
+	}
+	return m, format, nil
+}
+
+// Encode 按格式编码, quality 只对 jpeg 生效, 为 0 时使用 DefaultQuality
+func Encode(w io.Writer, m image.Image, format Format, quality int) error {
+	if quality <= 0 {
+		quality = DefaultQuality
+	}
+	switch format {
+	case FormatJPEG:
+		return imaging.Encode(w, m, imaging.JPEG, imaging.JPEGQuality(quality))
+	case FormatPNG:
+		return imaging.Encode(w, m, imaging.PNG)
+	case FormatGIF:
+		return imaging.Encode(w, m, imaging.GIF)
+	case FormatWEBP:
+		return ErrWebpEncode
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// Convert 格式转换, 例如 webp 转 jpeg
+func Convert(r io.Reader, w io.Writer, format Format, quality int) error {
+	m, _, err := Decode(r)
+	if err != nil {
+		return err
+	}
+	if format == FormatJPEG {
+		// jpeg 没有透明通道, 透明部分铺白底
+		m = flatten(m)
+	}
+	return Encode(w, m, format, quality)
+}
+
+// flatten 把透明图片铺到白底上
+func flatten(m image.Image) image.Image {
+	bg := imaging.New(m.Bounds().Dx(), m.Bounds().Dy(), image.White.C)
+	return imaging.Overlay(bg, m, image.Point{}, 1)
+}
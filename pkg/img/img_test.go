@@ -0,0 +1,103 @@
+package img
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// newTestImage 左半边纯色, 右半边黑白格子
+func newTestImage(w, h int) *image.NRGBA {
+	m := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBA{R: 200, G: 200, B: 200, A: 255}
+			if x >= w/2 && (x/2+y/2)%2 == 0 {
+				c = color.NRGBA{A: 255}
+			}
+			m.Set(x, y, c)
+		}
+	}
+	return m
+}
+
+func TestThumbnailAndSmartCrop(t *testing.T) {
+	m := newTestImage(200, 100)
+
+	thumb := Thumbnail(m, 50, 50)
+	if thumb.Bounds().Dx() != 50 || thumb.Bounds().Dy() != 50 {
+		t.Fatalf("unexpected thumbnail size %v", thumb.Bounds())
+	}
+
+	fit := Fit(m, 100, 100)
+	if fit.Bounds().Dx() != 100 || fit.Bounds().Dy() != 50 {
+		t.Fatalf("unexpected fit size %v", fit.Bounds())
+	}
+
+	// 细节集中在右半边, 裁剪结果应该取右边的格子
+	crop := SmartCrop(m, 100, 100)
+	if crop.Bounds().Dx() != 100 || crop.Bounds().Dy() != 100 {
+		t.Fatalf("unexpected crop size %v", crop.Bounds())
+	}
+	var dark int
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			if r, _, _, _ := crop.At(x, y).RGBA(); r>>8 < 100 {
+				dark++
+			}
+		}
+	}
+	if dark < 4000 {
+		t.Fatalf("smart crop should keep the detailed right half, dark pixels %d", dark)
+	}
+}
+
+func TestConvertAndWatermark(t *testing.T) {
+	var src bytes.Buffer
+	if err := png.Encode(&src, newTestImage(80, 60)); err != nil {
+		t.Fatal(err)
+	}
+
+	var dst bytes.Buffer
+	if err := Convert(&src, &dst, FormatJPEG, 80); err != nil {
+		t.Fatal(err)
+	}
+	m, format, err := Decode(&dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatJPEG || m.Bounds().Dx() != 80 {
+		t.Fatalf("unexpected %s %v", format, m.Bounds())
+	}
+
+	if err = Encode(&dst, m, FormatWEBP, 0); err != ErrWebpEncode {
+		t.Fatalf("expect ErrWebpEncode, got %v", err)
+	}
+
+	marked, err := TextWatermark(m, TextMark{Text: "lb", Color: color.White}, WatermarkOption{Margin: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marked.Bounds() != m.Bounds() {
+		t.Fatalf("unexpected bounds %v", marked.Bounds())
+	}
+
+	logo := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	marked = ImageWatermark(m, logo, WatermarkOption{Position: Center, Opacity: 1})
+	if marked.Bounds() != m.Bounds() {
+		t.Fatalf("unexpected bounds %v", marked.Bounds())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{"a.JPG": FormatJPEG, "image/png": FormatPNG, "webp": FormatWEBP} {
+		if got, err := ParseFormat(s); err != nil || got != want {
+			t.Fatalf("ParseFormat(%s) = %s, %v", s, got, err)
+		}
+	}
+	if _, err := ParseFormat("bmp"); err != ErrUnsupportedFormat {
+		t.Fatalf("expect unsupported, got %v", err)
+	}
+}
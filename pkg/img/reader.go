@@ -0,0 +1,22 @@
+package img
+
+import (
+	"bytes"
+	"io"
+)
+
+// peekReader 记录读取过的数据, 识别格式后可以从头再读一遍, 避免要求调用方传 io.Seeker
+type peekReader struct {
+	r   io.Reader
+	buf bytes.Buffer
+}
+
+func (p *peekReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.buf.Write(b[:n])
+	return n, err
+}
+
+func (p *peekReader) replay() io.Reader {
+	return io.MultiReader(bytes.NewReader(p.buf.Bytes()), p.r)
+}
@@ -0,0 +1,106 @@
+package img
+
+import (
+	"github.com/disintegration/imaging"
+	"image"
+	"math"
+)
+
+// Thumbnail 缩放并居中裁剪为 w*h, 常用于头像, 列表缩略图
+func Thumbnail(m image.Image, w, h int) image.Image {
+	return imaging.Fill(m, w, h, imaging.Center, imaging.Lanczos)
+}
+
+// Fit 等比缩放到 w*h 以内, 不裁剪, 图片本身更小时不放大
+func Fit(m image.Image, w, h int) image.Image {
+	b := m.Bounds()
+	if b.Dx() <= w && b.Dy() <= h {
+		return m
+	}
+	return imaging.Fit(m, w, h, imaging.Lanczos)
+}
+
+// CropCenter 从中心裁剪 w*h, 不缩放
+func CropCenter(m image.Image, w, h int) image.Image {
+	return imaging.CropCenter(m, w, h)
+}
+
+// SmartCrop 缩放后按画面细节最丰富的区域裁剪 w*h, 比居中裁剪更不容易把主体裁掉
+func SmartCrop(m image.Image, w, h int) image.Image {
+	b := m.Bounds()
+	if w <= 0 || h <= 0 || b.Dx() == 0 || b.Dy() == 0 {
+		return m
+	}
+
+	// 先等比缩放到刚好覆盖目标尺寸, 只需要在一个方向上滑动窗口
+	scale := math.Max(float64(w)/float64(b.Dx()), float64(h)/float64(b.Dy()))
+	rw := int(math.Ceil(float64(b.Dx()) * scale))
+	rh := int(math.Ceil(float64(b.Dy()) * scale))
+	resized := imaging.Resize(m, rw, rh, imaging.Lanczos)
+	if rw == w && rh == h {
+		return resized
+	}
+
+	energy := edgeEnergy(resized)
+	if rw > w {
+		// 横向滑动, 按列累计能量
+		cols := make([]float64, rw)
+		for x := 0; x < rw; x++ {
+			for y := 0; y < rh; y++ {
+				cols[x] += energy[y*rw+x]
+			}
+		}
+		best := bestWindow(cols, w)
+		return imaging.Crop(resized, image.Rect(best, 0, best+w, h))
+	}
+	rows := make([]float64, rh)
+	for y := 0; y < rh; y++ {
+		for x := 0; x < rw; x++ {
+			rows[y] += energy[y*rw+x]
+		}
+	}
+	best := bestWindow(rows, h)
+	return imaging.Crop(resized, image.Rect(0, best, w, best+h))
+}
+
+// bestWindow 找出长度为 size 的窗口中能量和最大的起点
+func bestWindow(line []float64, size int) int {
+	var sum float64
+	for i := 0; i < size && i < len(line); i++ {
+		sum += line[i]
+	}
+	best, bestScore := 0, sum
+	for i := size; i < len(line); i++ {
+		sum += line[i] - line[i-size]
+		if sum > bestScore {
+			best, bestScore = i-size+1, sum
+		}
+	}
+	return best
+}
+
+// edgeEnergy 以亮度梯度近似每个像素的细节程度
+func edgeEnergy(m *image.NRGBA) []float64 {
+	w, h := m.Bounds().Dx(), m.Bounds().Dy()
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*m.Stride + x*4
+			lum[y*w+x] = 0.299*float64(m.Pix[i]) + 0.587*float64(m.Pix[i+1]) + 0.114*float64(m.Pix[i+2])
+		}
+	}
+	energy := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy float64
+			if x+1 < w {
+				dx = lum[y*w+x+1] - lum[y*w+x]
+			}
+			if y+1 < h {
+				dy = lum[(y+1)*w+x] - lum[y*w+x]
+			}
+			energy[y*w+x] = math.Abs(dx) + math.Abs(dy)
+		}
+	}
+	return energy
+}
@@ -0,0 +1,114 @@
+package img
+
+import (
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+)
+
+type Position int
+
+const (
+	BottomRight Position = iota
+	BottomLeft
+	TopRight
+	TopLeft
+	Center
+)
+
+// WatermarkOption 水印位置和透明度
+type WatermarkOption struct {
+	Position Position
+	// Margin 距离边缘的像素
+	Margin int
+	// Opacity 0~1, 为 0 时使用 0.5
+	Opacity float64
+}
+
+func (o *WatermarkOption) point(bg, mark image.Rectangle) image.Point {
+	bw, bh := bg.Dx(), bg.Dy()
+	mw, mh := mark.Dx(), mark.Dy()
+	switch o.Position {
+	case BottomLeft:
+		return image.Pt(o.Margin, bh-mh-o.Margin)
+	case TopRight:
+		return image.Pt(bw-mw-o.Margin, o.Margin)
+	case TopLeft:
+		return image.Pt(o.Margin, o.Margin)
+	case Center:
+		return image.Pt((bw-mw)/2, (bh-mh)/2)
+	}
+	return image.Pt(bw-mw-o.Margin, bh-mh-o.Margin)
+}
+
+func (o *WatermarkOption) opacity() float64 {
+	if o.Opacity <= 0 || o.Opacity > 1 {
+		return 0.5
+	}
+	return o.Opacity
+}
+
+// ImageWatermark 图片水印
+func ImageWatermark(m, mark image.Image, opt WatermarkOption) image.Image {
+	return imaging.Overlay(m, mark, opt.point(m.Bounds(), mark.Bounds()), opt.opacity())
+}
+
+// TextMark 文字水印的内容和字体
+type TextMark struct {
+	Text  string
+	Color color.Color
+	// Font ttf/otf 字体内容, 中文需要传入中文字体; 为空时使用内置的 ascii 点阵字体
+	Font []byte
+	// Size 字号, 只对 Font 生效
+	Size float64
+}
+
+// TextWatermark 文字水印
+func TextWatermark(m image.Image, mark TextMark, opt WatermarkOption) (image.Image, error) {
+	face, err := mark.face()
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	// 先把文字画到透明图层上, 再按位置和透明度叠加, 与图片水印逻辑一致
+	bounds, advance := font.BoundString(face, mark.Text)
+	w := advance.Ceil()
+	h := (bounds.Max.Y - bounds.Min.Y).Ceil()
+	if w <= 0 || h <= 0 {
+		return m, nil
+	}
+	layer := image.NewNRGBA(image.Rect(0, 0, w, h))
+	c := mark.Color
+	if c == nil {
+		c = color.White
+	}
+	d := &font.Drawer{
+		Dst:  layer,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.Point26_6{X: 0, Y: -bounds.Min.Y},
+	}
+	d.DrawString(mark.Text)
+
+	return ImageWatermark(m, layer, opt), nil
+}
+
+func (t *TextMark) face() (font.Face, error) {
+	if len(t.Font) == 0 {
+		return basicfont.Face7x13, nil
+	}
+	f, err := opentype.Parse(t.Font)
+	if err != nil {
+		return nil, err
+	}
+	size := t.Size
+	if size <= 0 {
+		size = 24
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
@@ -0,0 +1,24 @@
+package placeholder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dollar 把 ? 占位符按顺序替换为 $1, $2 (postgres)
+// 不解析 sql, 字符串字面量里的 ? 也会被替换, 调用方的语句里不要出现
+func Dollar(query string) string {
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,10 @@
+package placeholder
+
+import "testing"
+
+func TestDollar(t *testing.T) {
+	got := Dollar("UPDATE t SET a = ?, b = ? WHERE id = ?")
+	if got != "UPDATE t SET a = $1, b = $2 WHERE id = $3" {
+		t.Errorf("got %s", got)
+	}
+}
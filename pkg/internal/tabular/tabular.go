@@ -0,0 +1,184 @@
+package tabular
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultTimeLayout = "2006-01-02 15:04:05"
+
+// Field 结构体字段与列的映射, excel 和 csv 共用
+//
+//	Name string    `csv:"name"`
+//	At   time.Time `csv:"created_at,format=2006-01-02"`
+//	Tmp  string    `csv:"-"`
+type Field struct {
+	Name  string
+	Index []int
+	// Format 时间字段的格式, 为空时使用 DefaultTimeLayout
+	Format string
+	// Opts 其他选项, 例如 width=20, 没有值的选项(required)为空字符串
+	Opts map[string]string
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ParseFields 按 tagName 解析结构体字段, 未打 tag 的导出字段使用字段名, 匿名结构体展开
+func ParseFields(tagName string, t reflect.Type) ([]*Field, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%s: %s is not a struct", tagName, t)
+	}
+
+	var fields []*Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && tag == "" && sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			sub, err := ParseFields(tagName, sf.Type)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range sub {
+				f.Index = append([]int{i}, f.Index...)
+			}
+			fields = append(fields, sub...)
+			continue
+		}
+
+		f := &Field{Name: sf.Name, Index: []int{i}, Opts: make(map[string]string)}
+		parts := strings.Split(tag, ",")
+		if parts[0] != "" {
+			f.Name = parts[0]
+		}
+		for _, opt := range parts[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(opt), "=")
+			if k == "format" {
+				f.Format = v
+				continue
+			}
+			f.Opts[k] = v
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// IsText 时间和实现了 encoding.TextMarshaler 的类型需要转成文本输出
+func IsText(t reflect.Type) bool {
+	return t == timeType || t.Implements(textMarshalerType)
+}
+
+// ToString 字段值转成文本, nil 指针和零值时间为空字符串
+func (f *Field) ToString(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", nil
+		}
+		return t.Format(f.timeLayout()), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// FromString 把文本解析到字段, 空字符串保持零值, 数字超出字段的范围时返回错误
+func (f *Field) FromString(v reflect.Value, s string) error {
+	if s == "" {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		t, err := time.ParseInLocation(f.timeLayout(), s, time.Local)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func (f *Field) timeLayout() string {
+	if f.Format != "" {
+		return f.Format
+	}
+	return DefaultTimeLayout
+}
@@ -0,0 +1,58 @@
+package tabular
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testRow struct {
+	I8   int8      `t:"i8"`
+	U16  uint16    `t:"u16,required"`
+	F32  float32   `t:"f32"`
+	IP   net.IP    `t:"ip"`
+	At   time.Time `t:"at,format=2006-01-02"`
+	Skip string    `t:"-"`
+}
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields("t", reflect.TypeOf(&testRow{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 5 || fields[4].Format != "2006-01-02" {
+		t.Fatalf("got %+v", fields)
+	}
+	if _, ok := fields[1].Opts["required"]; !ok {
+		t.Errorf("got %+v", fields[1].Opts)
+	}
+	if _, err = ParseFields("t", reflect.TypeOf(1)); err == nil {
+		t.Error("expect error")
+	}
+}
+
+func TestFromStringToString(t *testing.T) {
+	fields, err := ParseFields("t", reflect.TypeOf(testRow{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var row testRow
+	v := reflect.ValueOf(&row).Elem()
+	for i, s := range []string{"128", "65536", "1e39"} {
+		if err = fields[i].FromString(v.FieldByIndex(fields[i].Index), s); err == nil {
+			t.Errorf("%s: expect overflow error", s)
+		}
+	}
+
+	for i, s := range []string{"-128", "65535", "1.5", "10.0.0.1", "2023-05-01"} {
+		f := fields[i]
+		if err = f.FromString(v.FieldByIndex(f.Index), s); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		got, err := f.ToString(v.FieldByIndex(f.Index))
+		if err != nil || got != s {
+			t.Errorf("got %q, err:%v, want %q", got, err, s)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package ttlmap
+
+import "time"
+
+// gcInterval 两次清理过期数据的最小间隔
+const gcInterval = time.Minute
+
+type item[V any] struct {
+	val      V
+	expireAt time.Time
+}
+
+// Map 带过期时间的 map, 读取时忽略过期的值, 写入时顺带清理, 每分钟最多清理一次
+// 不加锁, 由调用方保证并发安全, 方便和其他判断放在同一把锁里
+type Map[V any] struct {
+	items map[string]item[V]
+	gcAt  time.Time
+}
+
+func New[V any]() *Map[V] {
+	return &Map[V]{items: make(map[string]item[V])}
+}
+
+// Get 不存在或已过期时返回 false
+func (m *Map[V]) Get(key string) (V, bool) {
+	it, ok := m.items[key]
+	if !ok || time.Now().After(it.expireAt) {
+		var zero V
+		return zero, false
+	}
+	return it.val, true
+}
+
+func (m *Map[V]) Set(key string, val V, ttl time.Duration) {
+	now := time.Now()
+	if now.After(m.gcAt) {
+		for k, it := range m.items {
+			if now.After(it.expireAt) {
+				delete(m.items, k)
+			}
+		}
+		m.gcAt = now.Add(gcInterval)
+	}
+	m.items[key] = item[V]{val: val, expireAt: now.Add(ttl)}
+}
+
+func (m *Map[V]) Delete(key string) {
+	delete(m.items, key)
+}
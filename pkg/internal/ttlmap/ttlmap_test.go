@@ -0,0 +1,30 @@
+package ttlmap
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	m := New[string]()
+	m.Set("a", "1", 20*time.Millisecond)
+	m.Set("b", "2", time.Minute)
+	if v, ok := m.Get("a"); !ok || v != "1" {
+		t.Fatalf("got %v %v", v, ok)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := m.Get("a"); ok {
+		t.Fatal("a should expire")
+	}
+
+	// 写入时清理过期数据
+	m.gcAt = time.Time{}
+	m.Set("c", "3", time.Minute)
+	if _, ok := m.items["a"]; ok || len(m.items) != 2 {
+		t.Fatalf("got %+v", m.items)
+	}
+	m.Delete("b")
+	if _, ok := m.Get("b"); ok {
+		t.Fatal("b should be deleted")
+	}
+}
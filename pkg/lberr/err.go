@@ -2,14 +2,20 @@ package lberr
 
 import (
 	"fmt"
+	"io"
+	"runtime"
+	"strings"
 )
 
 var _ error = (*Error)(nil)
 
 type Error struct {
-	code    int32
-	message string
-	errs    []error
+	code     int32
+	message  string
+	errs     []error
+	metadata map[string]string
+	stack    []uintptr
+	byCode   bool // 通过 Register 登记的错误, 一个错误码只对应它一个, errors.Is 只比较错误码
 }
 
 func (e *Error) Code() int32 {
@@ -20,6 +26,11 @@ func (e *Error) Message() string {
 	return e.message
 }
 
+// Metadata 附加信息, 会随 http / grpc 响应返回
+func (e *Error) Metadata() map[string]string {
+	return e.metadata
+}
+
 func (e *Error) Cause() error {
 	return &Error{
 		code:    e.code,
@@ -45,6 +56,73 @@ func (e *Error) Error() string {
 	return fmt.Sprintf("code: %d,msg: %s\t%s", e.code, e.message, appendErrorStr)
 }
 
+// Unwrap 返回被包装的错误, 支持 errors.Is / errors.As
+func (e *Error) Unwrap() []error {
+	return e.errs
+}
+
+// Is target 为 Register 登记的错误(如 lberr.NotFound)时错误码相同即可, 带自定义消息的 WrapErr 也能匹配
+// 其他错误码可能被多个错误共用(如 FAILURE), 需要错误码和消息都相同, 例如 errors.Is(err, lberr.RecordNotFound)
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.byCode {
+		return e.code == t.code
+	}
+	return e.code == t.code && e.message == t.message
+}
+
+// WithMeta 返回带附加信息的副本, kv 为键值对, 不会修改预定义的错误
+func (e *Error) WithMeta(kv ...string) *Error {
+	c := e.clone()
+	c.metadata = make(map[string]string, len(e.metadata)+len(kv)/2)
+	for k, v := range e.metadata {
+		c.metadata[k] = v
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		c.metadata[kv[i]] = kv[i+1]
+	}
+	return c
+}
+
+// Stack 错误创建时的调用栈
+func (e *Error) Stack() string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		_, _ = fmt.Fprintf(&b, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
+
+// Format %+v 时输出调用栈
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		_, _ = io.WriteString(s, e.Error())
+		if s.Flag('+') && len(e.stack) > 0 {
+			_, _ = io.WriteString(s, "\n")
+			_, _ = io.WriteString(s, e.Stack())
+		}
+	case 's':
+		_, _ = io.WriteString(s, e.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+func (e *Error) clone() *Error {
+	c := *e
+	c.errs = append([]error(nil), e.errs...)
+	return &c
+}
+
 func (e *Error) join(errs ...error) {
 	n := 0
 	for _, err := range errs {
@@ -62,7 +140,14 @@ func (e *Error) join(errs ...error) {
 	}
 }
 
-func NewErr(code int32, format string, args ...interface{}) error {
+// callers skip 为 lberr 内部的层数, 调用栈从使用方开始记录
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+2, pcs)
+	return pcs[:n]
+}
+
+func newErr(code int32, format string, args ...interface{}) *Error {
 	var msg = format
 	if len(args) > 0 {
 		msg = fmt.Sprintf(format, args...)
@@ -70,14 +155,63 @@ func NewErr(code int32, format string, args ...interface{}) error {
 	return &Error{
 		code:    code,
 		message: msg,
+		stack:   callers(2),
 	}
 }
 
+func NewErr(code int32, format string, args ...interface{}) error {
+	return newErr(code, format, args...)
+}
+
 func NewInvalidArg(format string, args ...interface{}) error {
-	return NewErr(ErrInvalidArg, format, args...)
+	return newErr(ErrInvalidArg, format, args...)
 }
 
 func NewCustomErr(format string, args ...interface{}) error {
-	return NewErr(ErrCustomError, format, args...)
+	return newErr(ErrCustomError, format, args...)
+
+}
 
+// WrapErr 用错误码包装底层错误, 底层错误可以通过 errors.Is / errors.As 取到
+func WrapErr(cause error, code int32, format string, args ...interface{}) error {
+	if cause == nil {
+		return nil
+	}
+	e := newErr(code, format, args...)
+	e.errs = append(e.errs, cause)
+	return e
+}
+
+// WithStack 记录当前调用栈, 用于直接返回预定义的错误
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		c := e.clone()
+		c.stack = callers(1)
+		return c
+	}
+	return &Error{
+		code:    ErrInternal,
+		message: GetErrMsg(ErrInternal),
+		errs:    []error{err},
+		stack:   callers(1),
+	}
+}
+
+// WithMeta 给错误附加信息, 非 lberr 的错误会包装成 ErrInternal
+func WithMeta(err error, kv ...string) error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e.WithMeta(kv...)
+	}
+	// 被 fmt.Errorf 等包装过的, 沿用内层的错误码
+	w := &Error{code: ErrInternal, message: GetErrMsg(ErrInternal), errs: []error{err}, stack: callers(1)}
+	if e, ok := FromError(err); ok {
+		w.code, w.message, w.metadata = e.code, e.message, e.metadata
+	}
+	return w.WithMeta(kv...)
 }
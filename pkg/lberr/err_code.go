@@ -15,8 +15,41 @@ const (
 	ErrWrapError        = 10010 // 包装错误
 )
 
+// 通用错误码, 后三位与 http 状态码对应
+const (
+	ErrUnauthenticated  = 1401
+	ErrPermissionDenied = 1403
+	ErrAlreadyExists    = 1409
+	ErrTooManyRequests  = 1429
+	ErrCanceled         = 1499
+	ErrInternal         = 1500
+	ErrUnimplemented    = 1501
+	ErrUnavailable      = 1503
+	ErrTimeout          = 1504
+)
+
 var (
 	Success        = NewErr(SUCCESS, "ok")
 	RecordNotFound = NewErr(FAILURE, "record not found")
 	HttpError      = NewErr(ErrHttpError, "http error")
+
+	InvalidArg       = NewErr(ErrInvalidArg, "invalid argument")
+	NotFound         = NewErr(ErrNotFound, "not found")
+	Unauthenticated  = NewErr(ErrUnauthenticated, "unauthenticated")
+	PermissionDenied = NewErr(ErrPermissionDenied, "permission denied")
+	AlreadyExists    = NewErr(ErrAlreadyExists, "already exists")
+	TooManyRequests  = NewErr(ErrTooManyRequests, "too many requests")
+	Canceled         = NewErr(ErrCanceled, "canceled")
+	Internal         = NewErr(ErrInternal, "internal error")
+	Unimplemented    = NewErr(ErrUnimplemented, "unimplemented")
+	Unavailable      = NewErr(ErrUnavailable, "service unavailable")
+	Timeout          = NewErr(ErrTimeout, "timeout")
 )
+
+func init() {
+	Register(
+		InvalidArg.(*Error), NotFound.(*Error), Unauthenticated.(*Error), PermissionDenied.(*Error),
+		AlreadyExists.(*Error), TooManyRequests.(*Error), Canceled.(*Error), Internal.(*Error),
+		Unimplemented.(*Error), Unavailable.(*Error), Timeout.(*Error),
+	)
+}
@@ -6,10 +6,139 @@
 
 package lberr
 
-import "testing"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
 
 func TestWrapByCall(t *testing.T) {
 	err := NewInvalidArg("111")
 	err = Wrap(err)
 	t.Logf("err:%v", err)
 }
+
+func TestIsAs(t *testing.T) {
+	cause := errors.New("dial timeout")
+	err := fmt.Errorf("query user: %w", WrapErr(cause, ErrUnavailable, "db unavailable"))
+	if !errors.Is(err, Unavailable) {
+		t.Errorf("should be Unavailable")
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("should keep cause")
+	}
+	if errors.Is(err, NotFound) {
+		t.Errorf("should not be NotFound")
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Code() != ErrUnavailable {
+		t.Fatalf("As got %v", e)
+	}
+	if GetErrCode(err) != ErrUnavailable {
+		t.Errorf("got code %d", GetErrCode(err))
+	}
+	if !strings.Contains(fmt.Sprintf("%+v", e), "TestIsAs") {
+		t.Errorf("stack should contain caller")
+	}
+}
+
+func TestWithMeta(t *testing.T) {
+	err := NotFound.(*Error).WithMeta("id", "1")
+	if err.Metadata()["id"] != "1" {
+		t.Errorf("got %v", err.Metadata())
+	}
+	if len(NotFound.(*Error).Metadata()) != 0 {
+		t.Errorf("predefined error should not be modified")
+	}
+	wrapped := WithMeta(fmt.Errorf("x: %w", err), "user", "lb")
+	e, _ := FromError(wrapped)
+	if e.Code() != ErrNotFound || e.Metadata()["id"] != "1" || e.Metadata()["user"] != "lb" {
+		t.Errorf("got %d %v", e.Code(), e.Metadata())
+	}
+}
+
+func TestHttp(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{nil, http.StatusOK},
+		{NewInvalidArg("bad id"), http.StatusBadRequest},
+		{PermissionDenied, http.StatusForbidden},
+		{NewErr(20001, "biz"), http.StatusBadRequest},
+		{errors.New("raw"), http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if got := HttpStatus(c.err); got != c.status {
+			t.Errorf("%v got %d, want %d", c.err, got, c.status)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	WriteHttp(w, NotFound.(*Error).WithMeta("id", "1"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("got %d", w.Code)
+	}
+	var body Body
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != ErrNotFound || body.Metadata["id"] != "1" {
+		t.Errorf("got %+v", body)
+	}
+	if Render(errors.New("secret")).Message == "secret" {
+		t.Errorf("should not expose raw error")
+	}
+}
+
+func TestGrpc(t *testing.T) {
+	err := NewErr(20001, "balance not enough").(*Error).WithMeta("need", "10")
+	s := ToGrpcStatus(err)
+	if s.Code() != codes.Unknown || s.Message() != "balance not enough" {
+		t.Errorf("got %v", s)
+	}
+	back := FromGrpcError(s.Err())
+	e, ok := FromError(back)
+	if !ok || e.Code() != 20001 || e.Metadata()["need"] != "10" {
+		t.Fatalf("got %v", back)
+	}
+	if GrpcCode(Timeout) != codes.DeadlineExceeded {
+		t.Errorf("got %v", GrpcCode(Timeout))
+	}
+	if GetErrCode(FromGrpcError(status.Error(codes.NotFound, "x"))) != ErrNotFound {
+		t.Errorf("should map grpc code back")
+	}
+}
+
+func TestIsCodeAndMessage(t *testing.T) {
+	if errors.Is(NewErr(FAILURE, "anything"), RecordNotFound) {
+		t.Errorf("shared code with different message should not match")
+	}
+	if errors.Is(NewErr(FAILURE, "a"), NewErr(FAILURE, "b")) {
+		t.Errorf("different messages should not match")
+	}
+	if !errors.Is(WithStack(RecordNotFound), RecordNotFound) {
+		t.Errorf("same code and message should match")
+	}
+	if !errors.Is(NewErr(ErrNotFound, "user 1 not found"), NotFound) {
+		t.Errorf("registered error matches by code")
+	}
+}
+
+func TestJoinNotModify(t *testing.T) {
+	before := NotFound.Error()
+	err := Wrap(NotFound)
+	_ = WrapByDesc(NotFound, "load user")
+	if NotFound.Error() != before || len(NotFound.(*Error).errs) != 0 {
+		t.Errorf("predefined error modified: %s", NotFound.Error())
+	}
+	if !errors.Is(err, NotFound) || err.Error() == before {
+		t.Errorf("got %s", err.Error())
+	}
+}
@@ -1,11 +1,16 @@
 package lberr
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 var errMap = make(map[int32]*Error)
 
+// Register 登记错误码对应的错误, 登记后 errors.Is 只比较错误码
 func Register(err ...*Error) {
 	for _, lbErr := range err {
+		lbErr.byCode = true
 		errMap[lbErr.code] = lbErr
 	}
 }
@@ -14,7 +19,7 @@ func GetErrCode(err error) int32 {
 	if err == nil {
 		return 0
 	}
-	if p, ok := err.(*Error); ok {
+	if p, ok := FromError(err); ok {
 		return p.code
 	}
 
@@ -36,7 +41,7 @@ func GetErrMsg(errCode int32) string {
 }
 
 func GetErrMsgByErr(err error) string {
-	if x, ok := err.(*Error); ok {
+	if x, ok := FromError(err); ok {
 		return x.message
 	} else {
 		return err.Error()
@@ -50,3 +55,12 @@ func CreateLbErr(code int32) *Error {
 	}
 	return &Error{code: code, message: fmt.Sprintf("unknown code %d", code)}
 }
+
+// FromError 从错误链中取出 lberr, 支持被 fmt.Errorf("%w") 包装过的错误
+func FromError(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
@@ -0,0 +1,59 @@
+package lberr
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Body 接口响应中的错误结构
+type Body struct {
+	Code     int32             `json:"code"`
+	Message  string            `json:"message"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Render 转换为接口响应的结构, 非 lberr 的错误不暴露内部信息
+func Render(err error) *Body {
+	if err == nil {
+		return &Body{Code: SUCCESS, Message: GetErrMsg(SUCCESS)}
+	}
+	e, ok := FromError(err)
+	if !ok {
+		return &Body{Code: ErrInternal, Message: GetErrMsg(ErrInternal)}
+	}
+	return &Body{Code: e.code, Message: e.message, Metadata: e.metadata}
+}
+
+// WriteHttp 按错误码设置 http 状态码并输出 json
+func WriteHttp(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(HttpStatus(err))
+	_ = json.NewEncoder(w).Encode(Render(err))
+}
+
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&Body{Code: e.code, Message: e.message, Metadata: e.metadata})
+}
+
+// Fields 用于结构化日志, 包含底层错误和调用栈
+func Fields(err error) map[string]interface{} {
+	if err == nil {
+		return nil
+	}
+	fields := map[string]interface{}{
+		"error": err.Error(),
+	}
+	e, ok := FromError(err)
+	if !ok {
+		return fields
+	}
+	fields["code"] = e.code
+	fields["message"] = e.message
+	if len(e.metadata) > 0 {
+		fields["metadata"] = e.metadata
+	}
+	if len(e.stack) > 0 {
+		fields["stack"] = e.Stack()
+	}
+	return fields
+}
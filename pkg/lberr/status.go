@@ -0,0 +1,186 @@
+package lberr
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// GrpcDomain grpc 错误详情中的 domain, 用于识别 lberr
+const GrpcDomain = "lb"
+
+var (
+	statusMu sync.RWMutex
+
+	httpStatusMap = map[int32]int{
+		SUCCESS:             http.StatusOK,
+		ErrInvalidArg:       http.StatusBadRequest,
+		ErrNotFound:         http.StatusNotFound,
+		ErrRecordNotFound:   http.StatusNotFound,
+		ErrOrmNotFound:      http.StatusNotFound,
+		ErrUnauthenticated:  http.StatusUnauthorized,
+		ErrPermissionDenied: http.StatusForbidden,
+		ErrAlreadyExists:    http.StatusConflict,
+		ErrTooManyRequests:  http.StatusTooManyRequests,
+		ErrCanceled:         499,
+		ErrInternal:         http.StatusInternalServerError,
+		ErrUnimplemented:    http.StatusNotImplemented,
+		ErrUnavailable:      http.StatusServiceUnavailable,
+		ErrTimeout:          http.StatusGatewayTimeout,
+	}
+
+	grpcCodeMap = map[int32]codes.Code{
+		SUCCESS:             codes.OK,
+		ErrInvalidArg:       codes.InvalidArgument,
+		ErrNotFound:         codes.NotFound,
+		ErrRecordNotFound:   codes.NotFound,
+		ErrOrmNotFound:      codes.NotFound,
+		ErrUnauthenticated:  codes.Unauthenticated,
+		ErrPermissionDenied: codes.PermissionDenied,
+		ErrAlreadyExists:    codes.AlreadyExists,
+		ErrTooManyRequests:  codes.ResourceExhausted,
+		ErrCanceled:         codes.Canceled,
+		ErrInternal:         codes.Internal,
+		ErrUnimplemented:    codes.Unimplemented,
+		ErrUnavailable:      codes.Unavailable,
+		ErrTimeout:          codes.DeadlineExceeded,
+	}
+
+	// fromGrpcCodeMap 对端不是 lberr 时按 grpc 状态码还原
+	fromGrpcCodeMap = map[codes.Code]int32{
+		codes.Canceled:          ErrCanceled,
+		codes.InvalidArgument:   ErrInvalidArg,
+		codes.DeadlineExceeded:  ErrTimeout,
+		codes.NotFound:          ErrNotFound,
+		codes.AlreadyExists:     ErrAlreadyExists,
+		codes.PermissionDenied:  ErrPermissionDenied,
+		codes.ResourceExhausted: ErrTooManyRequests,
+		codes.Unimplemented:     ErrUnimplemented,
+		codes.Unavailable:       ErrUnavailable,
+		codes.Unauthenticated:   ErrUnauthenticated,
+	}
+)
+
+// RegisterHttpStatus 自定义错误码对应的 http 状态码
+func RegisterHttpStatus(code int32, httpStatus int) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	httpStatusMap[code] = httpStatus
+}
+
+// RegisterGrpcCode 自定义错误码对应的 grpc 状态码
+func RegisterGrpcCode(code int32, grpcCode codes.Code) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	grpcCodeMap[code] = grpcCode
+}
+
+// HttpStatus 错误对应的 http 状态码
+// 未登记的业务错误码返回 400, 非 lberr 的错误返回 500
+func HttpStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	e, ok := FromError(err)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	if s, ok := httpStatusMap[e.code]; ok {
+		return s
+	}
+	return http.StatusBadRequest
+}
+
+// GrpcCode 错误对应的 grpc 状态码
+// 未登记的业务错误码返回 Unknown, 非 lberr 的错误返回 Internal
+func GrpcCode(err error) codes.Code {
+	if err == nil {
+		return codes.OK
+	}
+	e, ok := FromError(err)
+	if !ok {
+		return codes.Internal
+	}
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	if c, ok := grpcCodeMap[e.code]; ok {
+		return c
+	}
+	return codes.Unknown
+}
+
+// ToGrpcStatus 转换为 grpc status, 错误码和附加信息放在 ErrorInfo 中
+func ToGrpcStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+	if s, ok := status.FromError(err); ok {
+		return s
+	}
+	e, ok := FromError(err)
+	if !ok {
+		return status.New(codes.Internal, err.Error())
+	}
+	s := status.New(GrpcCode(err), e.message)
+	ds, dErr := s.WithDetails(&errdetails.ErrorInfo{
+		Reason:   strconv.Itoa(int(e.code)),
+		Domain:   GrpcDomain,
+		Metadata: e.metadata,
+	})
+	if dErr != nil {
+		return s
+	}
+	return ds
+}
+
+// FromGrpcError 还原对端返回的错误, 对端是 lberr 时保留错误码和附加信息
+func FromGrpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	for _, d := range s.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != GrpcDomain {
+			continue
+		}
+		code, cErr := strconv.Atoi(info.Reason)
+		if cErr != nil {
+			continue
+		}
+		return &Error{code: int32(code), message: s.Message(), metadata: info.Metadata, errs: []error{err}}
+	}
+	code, ok := fromGrpcCodeMap[s.Code()]
+	if !ok {
+		code = ErrInternal
+	}
+	return &Error{code: code, message: s.Message(), errs: []error{err}}
+}
+
+// UnaryServerInterceptor 把 handler 返回的 lberr 转换为 grpc status
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, ToGrpcStatus(err).Err()
+		}
+		return resp, nil
+	}
+}
+
+// UnaryClientInterceptor 把对端返回的 grpc status 还原为 lberr
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return FromGrpcError(invoker(ctx, method, req, reply, cc, opts...))
+	}
+}
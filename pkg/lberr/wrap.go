@@ -11,10 +11,12 @@ import (
 	"github.com/oldbai555/lbtool/utils"
 )
 
+// Join 返回新的错误, 不修改 oldErr, 预定义的错误可以直接传入
 func Join(oldErr error, errList ...error) error {
 	if e, ok := oldErr.(*Error); ok {
-		e.join(errList...)
-		return e
+		c := e.clone()
+		c.join(errList...)
+		return c
 	}
 	var errs []error
 	errs = append(errs, oldErr)
@@ -0,0 +1,124 @@
+package mail
+
+import (
+	"fmt"
+	"github.com/oldbai555/lbtool/extpkg/gomail"
+	"github.com/oldbai555/lbtool/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultIdleTimeout = 30 * time.Second
+)
+
+// Mailer 复用 smtp 连接发送邮件, 连接空闲一段时间后自动关闭
+type Mailer struct {
+	sender      *Sender
+	idleTimeout time.Duration
+
+	conn      gomail.SendCloser
+	idleTimer *time.Timer
+	mu        sync.Mutex
+}
+
+type MailerOption func(*Mailer)
+
+// WithIdleTimeout 连接空闲多久后关闭
+func WithIdleTimeout(timeout time.Duration) MailerOption {
+	return func(m *Mailer) {
+		m.idleTimeout = timeout
+	}
+}
+
+func NewMailer(sender *Sender, opts ...MailerOption) *Mailer {
+	m := &Mailer{
+		sender:      sender,
+		idleTimeout: DefaultIdleTimeout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Send 发送邮件, 连接失效时重连一次
+func (m *Mailer) Send(detail *Details) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msg := newMessage(detail)
+	err := m.send(msg)
+	if err != nil && m.conn != nil && isConnErr(err) {
+		log.Warnf("smtp conn broken, redial, err:%v", err)
+		m.closeConn()
+		err = m.send(msg)
+	}
+	if err != nil {
+		log.Errorf("send mail err: %v", err)
+		if strings.Contains(err.Error(), "invalid address") {
+			return fmt.Errorf("invalid address")
+		}
+		return err
+	}
+	m.resetIdleTimer()
+	return nil
+}
+
+// Close 关闭连接
+func (m *Mailer) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.idleTimer != nil {
+		m.idleTimer.Stop()
+	}
+	return m.closeConn()
+}
+
+func (m *Mailer) send(msg *gomail.Message) error {
+	if m.conn == nil {
+		conn, err := NewSendClient(m.sender)
+		if err != nil {
+			return err
+		}
+		m.conn = conn
+	}
+	return gomail.Send(m.conn, msg)
+}
+
+func (m *Mailer) closeConn() error {
+	if m.conn == nil {
+		return nil
+	}
+	err := m.conn.Close()
+	m.conn = nil
+	return err
+}
+
+func (m *Mailer) resetIdleTimer() {
+	if m.idleTimeout <= 0 {
+		return
+	}
+	if m.idleTimer != nil {
+		m.idleTimer.Stop()
+	}
+	m.idleTimer = time.AfterFunc(m.idleTimeout, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if err := m.closeConn(); err != nil {
+			log.Warnf("close idle smtp conn err:%v", err)
+		}
+	})
+}
+
+// isConnErr 连接类错误, 重连后可以重试
+func isConnErr(err error) bool {
+	msg := err.Error()
+	for _, s := range []string{"EOF", "broken pipe", "connection reset", "use of closed network connection", "421"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultQueueSize  = 1000
+	DefaultMaxRetry   = 3
+	DefaultRetryDelay = 2 * time.Second
+)
+
+var ErrQueueClosed = errors.New("mail queue closed")
+var ErrQueueFull = errors.New("mail queue full")
+
+// SendFailFunc 重试多次仍然失败时回调
+type SendFailFunc func(detail *Details, err error)
+
+// Queue 异步发送队列, 失败时按 RetryDelay 线性退避重试
+type Queue struct {
+	mailer     *Mailer
+	maxRetry   int
+	retryDelay time.Duration
+	onFail     SendFailFunc
+
+	ch      chan *Details
+	closed  bool
+	closeMu sync.RWMutex
+	done    chan struct{}
+}
+
+type QueueOption func(*Queue)
+
+func WithQueueSize(size int) QueueOption {
+	return func(q *Queue) {
+		q.ch = make(chan *Details, size)
+	}
+}
+
+func WithMaxRetry(maxRetry int) QueueOption {
+	return func(q *Queue) {
+		q.maxRetry = maxRetry
+	}
+}
+
+func WithRetryDelay(delay time.Duration) QueueOption {
+	return func(q *Queue) {
+		q.retryDelay = delay
+	}
+}
+
+func WithSendFail(fn SendFailFunc) QueueOption {
+	return func(q *Queue) {
+		q.onFail = fn
+	}
+}
+
+func NewQueue(mailer *Mailer, opts ...QueueOption) *Queue {
+	q := &Queue{
+		mailer:     mailer,
+		maxRetry:   DefaultMaxRetry,
+		retryDelay: DefaultRetryDelay,
+		ch:         make(chan *Details, DefaultQueueSize),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+	go q.loop()
+	return q
+}
+
+// Push 投递邮件, 队列满时返回 ErrQueueFull 不阻塞调用方
+func (q *Queue) Push(detail *Details) error {
+	q.closeMu.RLock()
+	defer q.closeMu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	select {
+	case q.ch <- detail:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close 停止接收新邮件并等待队列发送完, ctx 结束时不再等待
+func (q *Queue) Close(ctx context.Context) error {
+	q.closeMu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+	q.closeMu.Unlock()
+
+	select {
+	case <-q.done:
+		return q.mailer.Close()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) loop() {
+	defer close(q.done)
+	for detail := range q.ch {
+		q.sendWithRetry(detail)
+	}
+}
+
+func (q *Queue) sendWithRetry(detail *Details) {
+	var err error
+	for i := 0; i <= q.maxRetry; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * q.retryDelay)
+		}
+		if err = q.mailer.Send(detail); err == nil {
+			return
+		}
+		log.Warnf("send mail %s failed %d times, err:%v", detail.Subject, i+1, err)
+	}
+	log.Errorf("send mail %s to %v failed, err:%v", detail.Subject, detail.ToList, err)
+	if q.onFail != nil {
+		q.onFail(detail, err)
+	}
+}
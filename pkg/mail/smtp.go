@@ -11,6 +11,7 @@ import (
 
 const (
 	DefaultContentType = "text/html"
+	ContentTypePlain   = "text/plain"
 
 	HeaderFrom    = "From"     // 发件人
 	HeaderSender  = "Sender"   // 发件人
@@ -27,17 +28,32 @@ const (
 func SendMail(sender *Sender, detail *Details) error {
 
 	s, err := NewSendClient(sender)
+	if err != nil {
+		log.Errorf("err:%v", err)
+		return err
+	}
 	defer func() {
 		closeErr := s.Close()
 		if closeErr != nil {
 			panic(any(fmt.Sprintf("err:%v", closeErr)))
 		}
 	}()
-	if err != nil {
-		log.Errorf("err:%v", err)
+
+	m := newMessage(detail)
+
+	// 开始发送
+	if err = gomail.Send(s, m); err != nil {
+		log.Errorf("send mail err: %v", err)
+		if strings.Contains(err.Error(), "invalid address") {
+			return fmt.Errorf("invalid address")
+		}
 		return err
 	}
+	return nil
+}
 
+// newMessage 根据邮件内容构造 gomail 消息
+func newMessage(detail *Details) *gomail.Message {
 	m := gomail.NewMessage(
 		gomail.SetEncoding(gomail.Base64),
 	)
@@ -67,15 +83,21 @@ func SendMail(sender *Sender, detail *Details) error {
 		m.SetHeader(HeaderBcc, detail.BlindCarbonCopyList...)
 	}
 
-	// 邮件内容
-	if len(detail.Body) > 0 {
-		ct := detail.ContentType
-		if ct == "" {
-			ct = DefaultContentType
-		}
+	// 邮件内容, 同时有纯文本和 html 时, 客户端优先展示 html
+	ct := detail.ContentType
+	if ct == "" {
+		ct = DefaultContentType
+	}
+	switch {
+	case len(detail.PlainBody) > 0 && len(detail.Body) > 0:
+		m.SetBody(ContentTypePlain, string(detail.PlainBody))
+		m.AddAlternative(ct, string(detail.Body))
+	case len(detail.PlainBody) > 0:
+		m.SetBody(ContentTypePlain, string(detail.PlainBody))
+	case len(detail.Body) > 0:
 		m.SetBody(ct, string(detail.Body))
-	} else {
-		m.SetBody(DefaultContentType, "")
+	default:
+		m.SetBody(ct, "")
 	}
 
 	// 添加附件
@@ -87,15 +109,7 @@ func SendMail(sender *Sender, detail *Details) error {
 		}))
 	}
 
-	// 开始发送
-	if err = gomail.Send(s, m); err != nil {
-		log.Errorf("send mail err: %v", err)
-		if strings.Contains(err.Error(), "invalid address") {
-			return fmt.Errorf("invalid address")
-		}
-		return err
-	}
-	return nil
+	return m
 }
 
 // NewSendClient 声明邮件发送Client
@@ -103,13 +117,16 @@ func NewSendClient(sender *Sender) (gomail.SendCloser, error) {
 	// 声明连接邮箱服务器对象
 	d := gomail.NewDialer(sender.SmtpHost, int(sender.SmtpPort), sender.AuthEmail, sender.AuthCode)
 
-	// 关闭TLS认证设置,为true时，关闭TLS认证，否则默认开启，需要配置证书认证
+	// 默认不校验服务端证书, 配置 VerifyCert 后按 SmtpHost 校验
 	d.TLSConfig = &tls.Config{
-		InsecureSkipVerify: true,
+		ServerName:         sender.SmtpHost,
+		InsecureSkipVerify: !sender.VerifyCert,
 	}
 
-	// 配制 SSL
-	// d.SSL = true
+	// 配制 SSL, 465 端口默认开启, 其他端口使用 STARTTLS
+	if sender.SSL {
+		d.SSL = true
+	}
 
 	// 开始建立客户端
 	s, err := d.Dial()
@@ -1,7 +1,10 @@
 package mail
 
 import (
+	"bytes"
 	"github.com/oldbai555/lbtool/log"
+	"html/template"
+	"strings"
 	"testing"
 )
 
@@ -24,3 +27,32 @@ func TestSendMail(t *testing.T) {
 		return
 	}
 }
+
+func TestNewMessage(t *testing.T) {
+	detail := &Details{
+		Form:      "noreply@example.com",
+		Alias:     "lb",
+		Subject:   "欢迎",
+		PlainBody: []byte("hello"),
+		ToList:    []string{"user@example.com"},
+		Attach:    []*Attach{{FileName: "a.txt", Buf: []byte("a")}},
+	}
+	tpl := template.Must(template.New("welcome").Parse(`<p>hi {{.Name}}</p>`))
+	if err := detail.SetHtmlTemplate(tpl, map[string]string{"Name": "<b>"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if _, err := newMessage(detail).WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	raw := b.String()
+	for _, want := range []string{"multipart/mixed", "multipart/alternative", "text/plain", "text/html", "a.txt"} {
+		if !strings.Contains(raw, want) {
+			t.Errorf("message missing %s", want)
+		}
+	}
+	if !strings.Contains(string(detail.Body), "&lt;b&gt;") {
+		t.Errorf("html template not escaped: %s", detail.Body)
+	}
+}
@@ -13,6 +13,12 @@ type Sender struct {
 
 	// SmtpPort smtp端口
 	SmtpPort uint32 `json:"smtp_port"`
+
+	// SSL 直接使用 TLS 连接, 465 端口默认开启, 其他端口通过 STARTTLS 升级
+	SSL bool `json:"ssl"`
+
+	// VerifyCert 校验服务端证书
+	VerifyCert bool `json:"verify_cert"`
 }
 
 // Details 邮件内容
@@ -32,6 +38,9 @@ type Details struct {
 	// Body 发送内容
 	Body []byte `json:"body"`
 
+	// PlainBody 纯文本内容, 与 Body 同时存在时作为不支持 html 的客户端的备选内容
+	PlainBody []byte `json:"plain_body"`
+
 	// Attach 邮件附件
 	Attach []*Attach `json:"attach"`
 
@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// RenderHtml 使用 html/template 渲染邮件正文, 变量会被转义
+func RenderHtml(tpl *htmltemplate.Template, data interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// RenderText 使用 text/template 渲染纯文本正文
+func RenderText(tpl *texttemplate.Template, data interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// SetHtmlTemplate 渲染 html 模板作为邮件正文
+func (d *Details) SetHtmlTemplate(tpl *htmltemplate.Template, data interface{}) error {
+	body, err := RenderHtml(tpl, data)
+	if err != nil {
+		return err
+	}
+	d.Body = body
+	d.ContentType = DefaultContentType
+	return nil
+}
+
+// SetTextTemplate 渲染纯文本模板作为备选正文
+func (d *Details) SetTextTemplate(tpl *texttemplate.Template, data interface{}) error {
+	body, err := RenderText(tpl, data)
+	if err != nil {
+		return err
+	}
+	d.PlainBody = body
+	return nil
+}
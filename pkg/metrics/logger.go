@@ -0,0 +1,10 @@
+package metrics
+
+import "github.com/oldbai555/lbtool/log"
+
+// promLogger 把 promhttp 的错误输出到 log
+type promLogger struct{}
+
+func (promLogger) Println(v ...interface{}) {
+	log.Errorf("prometheus: %v", v)
+}
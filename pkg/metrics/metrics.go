@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultNamespace = "lb"
+)
+
+var (
+	registry  *prometheus.Registry
+	namespace = DefaultNamespace
+	subsystem string
+	once      sync.Once
+)
+
+// GetRegistry 预置了 go runtime 与进程采集器的注册中心
+func GetRegistry() *prometheus.Registry {
+	once.Do(func() {
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+	})
+	return registry
+}
+
+// SetNamespace 设置指标前缀,需要在创建指标之前调用
+func SetNamespace(ns string) {
+	namespace = normalize(ns)
+}
+
+// SetSubsystem 设置指标的子系统,一般为服务名,需要在创建指标之前调用
+func SetSubsystem(sub string) {
+	subsystem = normalize(sub)
+}
+
+// Handler 暴露 /metrics 的 http handler
+func Handler() http.Handler {
+	reg := GetRegistry()
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{
+		ErrorLog: promLogger{},
+		Registry: reg,
+	})
+}
+
+// Register 注册自定义的采集器
+func Register(c prometheus.Collector) error {
+	return GetRegistry().Register(c)
+}
+
+// NewCounter 创建计数器, 同名指标重复创建时返回已注册的指标
+func NewCounter(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      normalize(name),
+		Help:      help,
+	}, labels)
+	return mustRegisterOrGet(c).(*prometheus.CounterVec)
+}
+
+// NewGauge 创建仪表盘指标
+func NewGauge(name, help string, labels ...string) *prometheus.GaugeVec {
+	g := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      normalize(name),
+		Help:      help,
+	}, labels)
+	return mustRegisterOrGet(g).(*prometheus.GaugeVec)
+}
+
+// NewHistogram 创建直方图, buckets 为空时使用 DefaultBuckets
+func NewHistogram(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      normalize(name),
+		Help:      help,
+		Buckets:   buckets,
+	}, labels)
+	return mustRegisterOrGet(h).(*prometheus.HistogramVec)
+}
+
+// DefaultBuckets 耗时类指标默认的分桶, 单位秒
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+func mustRegisterOrGet(c prometheus.Collector) prometheus.Collector {
+	err := GetRegistry().Register(c)
+	if err == nil {
+		return c
+	}
+	if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+		return are.ExistingCollector
+	}
+	panic(any(err))
+}
+
+// normalize 指标名只允许字母数字下划线
+func normalize(name string) string {
+	return strings.NewReplacer("-", "_", ".", "_", " ", "_").Replace(name)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	c := NewCounter("test.requests", "test requests", "code")
+	c.WithLabelValues("200").Inc()
+	// 重复创建拿到的是同一个指标
+	NewCounter("test.requests", "test requests", "code").WithLabelValues("200").Inc()
+
+	h := NewHistogram("test_latency_seconds", "test latency", nil)
+	h.WithLabelValues().Observe(0.01)
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`lb_test_requests{code="200"} 2`,
+		"lb_test_latency_seconds_count 1",
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package netx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	DefaultMaxFrameSize = 1 << 20
+)
+
+var (
+	ErrFrameTooLarge = errors.New("frame too large")
+)
+
+// Codec 拆包和封包, Decode 每次返回一个完整的消息, 返回的切片可以被调用方持有
+type Codec interface {
+	Decode(r *bufio.Reader) ([]byte, error)
+	Encode(w io.Writer, msg []byte) error
+}
+
+var (
+	_ Codec = (*LengthFieldCodec)(nil)
+	_ Codec = (*DelimiterCodec)(nil)
+	_ Codec = RawCodec{}
+)
+
+// RawCodec 不拆包, 读取剩余的全部数据作为一个消息, 只适用于 udp 这类按包传输的场景
+type RawCodec struct{}
+
+func (RawCodec) Decode(r *bufio.Reader) ([]byte, error) {
+	msg, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(msg) == 0 {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func (RawCodec) Encode(w io.Writer, msg []byte) error {
+	_, err := w.Write(msg)
+	return err
+}
+
+// LengthFieldCodec 长度前缀协议, 头部为大端序的消息长度, 不包含头部本身
+type LengthFieldCodec struct {
+	headerSize   int
+	maxFrameSize int
+}
+
+// NewLengthFieldCodec headerSize 为 2 或 4, maxFrameSize 为 0 时使用 DefaultMaxFrameSize
+func NewLengthFieldCodec(headerSize int, maxFrameSize int) *LengthFieldCodec {
+	if headerSize != 2 && headerSize != 4 {
+		panic(fmt.Sprintf("invalid header size %d", headerSize))
+	}
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+	if headerSize == 2 && maxFrameSize > 0xffff {
+		maxFrameSize = 0xffff
+	}
+	return &LengthFieldCodec{headerSize: headerSize, maxFrameSize: maxFrameSize}
+}
+
+func (c *LengthFieldCodec) Decode(r *bufio.Reader) ([]byte, error) {
+	header := make([]byte, c.headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	var size int
+	if c.headerSize == 2 {
+		size = int(binary.BigEndian.Uint16(header))
+	} else {
+		size = int(binary.BigEndian.Uint32(header))
+	}
+	if size > c.maxFrameSize {
+		return nil, fmt.Errorf("%w: %d", ErrFrameTooLarge, size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (c *LengthFieldCodec) Encode(w io.Writer, msg []byte) error {
+	if len(msg) > c.maxFrameSize {
+		return fmt.Errorf("%w: %d", ErrFrameTooLarge, len(msg))
+	}
+	header := make([]byte, c.headerSize)
+	if c.headerSize == 2 {
+		binary.BigEndian.PutUint16(header, uint16(len(msg)))
+	} else {
+		binary.BigEndian.PutUint32(header, uint32(len(msg)))
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// DelimiterCodec 分隔符协议, 例如按行分隔的文本协议, 返回的消息不包含分隔符
+type DelimiterCodec struct {
+	delim        byte
+	maxFrameSize int
+}
+
+func NewDelimiterCodec(delim byte, maxFrameSize int) *DelimiterCodec {
+	if maxFrameSize <= 0 {
+		maxFrameSize = DefaultMaxFrameSize
+	}
+	return &DelimiterCodec{delim: delim, maxFrameSize: maxFrameSize}
+}
+
+// NewLineCodec 按 \n 分隔
+func NewLineCodec() *DelimiterCodec {
+	return NewDelimiterCodec('\n', 0)
+}
+
+func (c *DelimiterCodec) Decode(r *bufio.Reader) ([]byte, error) {
+	var msg []byte
+	for {
+		line, err := r.ReadSlice(c.delim)
+		if len(msg)+len(line) > c.maxFrameSize+1 {
+			return nil, fmt.Errorf("%w: %d", ErrFrameTooLarge, len(msg)+len(line))
+		}
+		msg = append(msg, line...)
+		if err == nil {
+			return msg[:len(msg)-1], nil
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			return nil, err
+		}
+	}
+}
+
+func (c *DelimiterCodec) Encode(w io.Writer, msg []byte) error {
+	if len(msg) > c.maxFrameSize {
+		return fmt.Errorf("%w: %d", ErrFrameTooLarge, len(msg))
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte{c.delim})
+	return err
+}
@@ -0,0 +1,192 @@
+package netx
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrConnClosed    = errors.New("connection closed")
+	ErrSendQueueFull = errors.New("send queue full")
+	ErrIdleTimeout   = errors.New("idle timeout")
+)
+
+var _ Session = (*Conn)(nil)
+
+// Conn tcp 连接, 一个协程读, 一个协程写
+type Conn struct {
+	id   uint64
+	conn net.Conn
+	srv  *Server
+
+	sendCh chan []byte
+	mu     sync.Mutex
+	closed bool
+
+	writeDone chan struct{}
+
+	valuesMu sync.RWMutex
+	values   map[string]interface{}
+}
+
+func newConn(id uint64, conn net.Conn, srv *Server) *Conn {
+	return &Conn{
+		id:        id,
+		conn:      conn,
+		srv:       srv,
+		sendCh:    make(chan []byte, srv.sendQueueSize),
+		writeDone: make(chan struct{}),
+	}
+}
+
+func (c *Conn) Id() uint64 {
+	return c.id
+}
+
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// NetConn 底层连接
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+func (c *Conn) Send(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrConnClosed
+	}
+	select {
+	case c.sendCh <- msg:
+		return nil
+	default:
+		return ErrSendQueueFull
+	}
+}
+
+func (c *Conn) Close() error {
+	c.shutdown(false)
+	return nil
+}
+
+// CloseGraceful 发送完队列中的消息后再关闭
+func (c *Conn) CloseGraceful() {
+	c.shutdown(true)
+}
+
+func (c *Conn) shutdown(graceful bool) {
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.sendCh)
+	}
+	c.mu.Unlock()
+	if !graceful {
+		_ = c.conn.Close()
+	}
+}
+
+func (c *Conn) Set(key string, val interface{}) {
+	c.valuesMu.Lock()
+	defer c.valuesMu.Unlock()
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	c.values[key] = val
+}
+
+func (c *Conn) Get(key string) (interface{}, bool) {
+	c.valuesMu.RLock()
+	defer c.valuesMu.RUnlock()
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// readLoop 读取并分发消息, 返回导致连接关闭的错误, 正常关闭时为 nil
+func (c *Conn) readLoop() error {
+	br := bufio.NewReaderSize(c.conn, c.srv.readBufferSize)
+	for {
+		if c.srv.idleTimeout > 0 {
+			_ = c.conn.SetReadDeadline(time.Now().Add(c.srv.idleTimeout))
+		}
+		msg, err := c.srv.codec.Decode(br)
+		if err != nil {
+			return c.readErr(err)
+		}
+		c.srv.dispatch(c, func() {
+			c.srv.handler.OnMessage(c, msg)
+		})
+	}
+}
+
+func (c *Conn) readErr(err error) error {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	var netErr net.Error
+	switch {
+	case closed, errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed):
+		return nil
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrIdleTimeout
+	}
+	return err
+}
+
+// writeLoop 合并发送, 队列为空时才 flush; 队列关闭后发送完剩余消息并关闭连接
+func (c *Conn) writeLoop() {
+	defer close(c.writeDone)
+	defer c.conn.Close()
+
+	bw := bufio.NewWriterSize(c.conn, c.srv.writeBufferSize)
+	var heartbeat <-chan time.Time
+	if c.srv.heartbeatInterval > 0 {
+		ticker := time.NewTicker(c.srv.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	write := func(msg []byte) bool {
+		if c.srv.writeTimeout > 0 {
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.srv.writeTimeout))
+		}
+		if err := c.srv.codec.Encode(bw, msg); err != nil {
+			c.srv.logErr(c, err)
+			return false
+		}
+		return true
+	}
+	flush := func() bool {
+		if err := bw.Flush(); err != nil {
+			c.srv.logErr(c, err)
+			return false
+		}
+		return true
+	}
+
+	for {
+		select {
+		case msg, ok := <-c.sendCh:
+			if !ok {
+				flush()
+				return
+			}
+			if !write(msg) {
+				return
+			}
+			if len(c.sendCh) == 0 && !flush() {
+				return
+			}
+		case <-heartbeat:
+			if !write(c.srv.heartbeatMsg) || !flush() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package netx
+
+import (
+	"net"
+)
+
+// Session 一个客户端会话, tcp 连接和后续的其他传输方式共用
+type Session interface {
+	Id() uint64
+	RemoteAddr() net.Addr
+	// Send 异步发送, 发送队列满时返回 ErrSendQueueFull
+	Send(msg []byte) error
+	// Close 立即关闭, 未发送的消息会被丢弃
+	Close() error
+	// Set 保存会话级别的数据, 例如登录后的设备 id
+	Set(key string, val interface{})
+	Get(key string) (interface{}, bool)
+}
+
+// Handler 会话事件, 同一个会话的事件按顺序回调
+type Handler interface {
+	OnConnect(s Session)
+	OnMessage(s Session, msg []byte)
+	// OnClose err 为 nil 表示正常关闭
+	OnClose(s Session, err error)
+}
+
+// BaseHandler 空实现, 嵌入后只需要实现关心的方法
+type BaseHandler struct{}
+
+func (BaseHandler) OnConnect(s Session) {}
+
+func (BaseHandler) OnMessage(s Session, msg []byte) {}
+
+func (BaseHandler) OnClose(s Session, err error) {}
+
+// HandlerFunc 只处理消息
+type HandlerFunc func(s Session, msg []byte)
+
+func (f HandlerFunc) OnConnect(s Session) {}
+
+func (f HandlerFunc) OnMessage(s Session, msg []byte) {
+	f(s, msg)
+}
+
+func (f HandlerFunc) OnClose(s Session, err error) {}
@@ -0,0 +1,59 @@
+package netx
+
+import (
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/routine"
+	"sync"
+)
+
+const (
+	DefaultLoopQueueSize = 1024
+)
+
+// loopGroup 固定数量的事件循环, 同一个会话的事件固定在一个协程上按顺序执行
+// 数量为 0 时在调用方协程直接执行
+type loopGroup struct {
+	loops []chan func()
+	wg    sync.WaitGroup
+}
+
+func newLoopGroup(n int) *loopGroup {
+	g := &loopGroup{}
+	for i := 0; i < n; i++ {
+		ch := make(chan func(), DefaultLoopQueueSize)
+		g.loops = append(g.loops, ch)
+		g.wg.Add(1)
+		go g.run(ch)
+	}
+	return g
+}
+
+func (g *loopGroup) run(ch chan func()) {
+	defer g.wg.Done()
+	for fn := range ch {
+		fn()
+	}
+}
+
+// dispatch handler panic 不影响其他会话
+func (g *loopGroup) dispatch(id uint64, fn func()) {
+	call := func() {
+		defer routine.CatchPanic(func(err interface{}) {
+			log.Errorf("session %d handler panic: %v", id, err)
+		})
+		fn()
+	}
+	if len(g.loops) == 0 {
+		call()
+		return
+	}
+	g.loops[id%uint64(len(g.loops))] <- call
+}
+
+// close 等待已投递的事件执行完
+func (g *loopGroup) close() {
+	for _, ch := range g.loops {
+		close(ch)
+	}
+	g.wg.Wait()
+}
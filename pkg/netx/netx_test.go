@@ -0,0 +1,253 @@
+package netx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCodec(t *testing.T) {
+	var buf bytes.Buffer
+	lc := NewLengthFieldCodec(2, 16)
+	for _, msg := range []string{"hello", "", "world"} {
+		if err := lc.Encode(&buf, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := lc.Encode(&buf, make([]byte, 17)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("expect ErrFrameTooLarge, got %v", err)
+	}
+	r := bufio.NewReader(&buf)
+	for _, want := range []string{"hello", "", "world"} {
+		msg, err := lc.Decode(r)
+		if err != nil || string(msg) != want {
+			t.Fatalf("got %q %v, want %q", msg, err, want)
+		}
+	}
+
+	dc := NewDelimiterCodec('\n', 8)
+	r = bufio.NewReaderSize(strings.NewReader("ab\ncd\n0123456789\n"), 16)
+	for _, want := range []string{"ab", "cd"} {
+		msg, err := dc.Decode(r)
+		if err != nil || string(msg) != want {
+			t.Fatalf("got %q %v, want %q", msg, err, want)
+		}
+	}
+	if _, err := dc.Decode(r); !errors.Is(err, ErrFrameTooLarge) {
+		t.Fatalf("expect ErrFrameTooLarge, got %v", err)
+	}
+}
+
+type echoHandler struct {
+	mu      sync.Mutex
+	events  []string
+	closeCh chan error
+}
+
+func (h *echoHandler) OnConnect(s Session) {
+	h.mu.Lock()
+	h.events = append(h.events, "connect")
+	h.mu.Unlock()
+	s.Set("uid", "u1")
+}
+
+func (h *echoHandler) OnMessage(s Session, msg []byte) {
+	uid, _ := s.Get("uid")
+	_ = s.Send([]byte(uid.(string) + ":" + string(msg)))
+}
+
+func (h *echoHandler) OnClose(s Session, err error) {
+	h.mu.Lock()
+	h.events = append(h.events, "close")
+	h.mu.Unlock()
+	h.closeCh <- err
+}
+
+func startServer(t *testing.T, h Handler, ops ...Option) *Server {
+	s := NewServer("127.0.0.1:0", h, append([]Option{WithCodec(NewLineCodec())}, ops...)...)
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestServer_Echo(t *testing.T) {
+	for _, loop := range []int{0, 4} {
+		h := &echoHandler{closeCh: make(chan error, 1)}
+		s := startServer(t, h, WithEventLoop(loop))
+
+		conn, err := net.Dial("tcp", s.Addr())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(conn)
+		for _, msg := range []string{"ping", "hello"} {
+			if _, err = conn.Write([]byte(msg + "\n")); err != nil {
+				t.Fatal(err)
+			}
+			line, err := r.ReadString('\n')
+			if err != nil || line != "u1:"+msg+"\n" {
+				t.Fatalf("unexpected %q %v", line, err)
+			}
+		}
+		if s.ConnNum() != 1 {
+			t.Fatalf("expect 1 conn, got %d", s.ConnNum())
+		}
+		_ = conn.Close()
+		if err = <-h.closeCh; err != nil {
+			t.Fatalf("expect normal close, got %v", err)
+		}
+		if s.ConnNum() != 0 {
+			t.Fatalf("expect 0 conn, got %d", s.ConnNum())
+		}
+		if err = s.Stop(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		h.mu.Lock()
+		if strings.Join(h.events, ",") != "connect,close" {
+			t.Fatalf("unexpected events %v", h.events)
+		}
+		h.mu.Unlock()
+	}
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	h := &echoHandler{closeCh: make(chan error, 1)}
+	s := startServer(t, h, WithIdleTimeout(50*time.Millisecond))
+	defer s.Stop(context.Background())
+
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	select {
+	case err = <-h.closeCh:
+		if !errors.Is(err, ErrIdleTimeout) {
+			t.Fatalf("expect ErrIdleTimeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("idle conn not closed")
+	}
+}
+
+func TestServer_GracefulStop(t *testing.T) {
+	var sent sync.WaitGroup
+	sent.Add(1)
+	h := HandlerFunc(func(s Session, msg []byte) {
+		for i := 0; i < 100; i++ {
+			_ = s.Send([]byte("data"))
+		}
+		sent.Done()
+	})
+	s := startServer(t, h, WithMaxConns(1))
+
+	conn, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// 超过最大连接数的连接被直接关闭
+	extra, err := net.Dial("tcp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = extra.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err = extra.Read(make([]byte, 1)); err == nil {
+		t.Fatal("extra conn should be closed")
+	}
+	_ = extra.Close()
+
+	if _, err = conn.Write([]byte("go\n")); err != nil {
+		t.Fatal(err)
+	}
+	sent.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err = s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 关闭前队列中的消息都已发出
+	r := bufio.NewReader(conn)
+	n := 0
+	for {
+		if _, err = r.ReadString('\n'); err != nil {
+			break
+		}
+		n++
+	}
+	if n != 100 {
+		t.Fatalf("expect 100 messages drained, got %d", n)
+	}
+	if err = s.Start(context.Background()); !errors.Is(err, ErrServerClosed) {
+		t.Fatalf("expect ErrServerClosed, got %v", err)
+	}
+}
+
+func TestUDPServer(t *testing.T) {
+	h := &echoHandler{closeCh: make(chan error, 2)}
+	s := NewUDPServer("127.0.0.1:0", h, WithCodec(NewLineCodec()), WithIdleTimeout(100*time.Millisecond), WithEventLoop(2))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("udp", s.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// 一个数据包里的多个消息分别回调
+	if _, err = conn.Write([]byte("a\nb\n")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1024)
+	var got []string
+	for len(got) < 2 {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(buf[:n]))
+	}
+	if strings.Join(got, "") != "u1:a\nu1:b\n" {
+		t.Fatalf("unexpected %q", got)
+	}
+	if s.SessionNum() != 1 {
+		t.Fatalf("expect 1 session, got %d", s.SessionNum())
+	}
+
+	select {
+	case err = <-h.closeCh:
+		if !errors.Is(err, ErrIdleTimeout) {
+			t.Fatalf("expect ErrIdleTimeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("idle session not closed")
+	}
+
+	if _, err = conn.Write([]byte("c\n")); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for s.SessionNum() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("session not recreated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err = s.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = <-h.closeCh; err != nil {
+		t.Fatalf("expect normal close on stop, got %v", err)
+	}
+}
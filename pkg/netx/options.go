@@ -0,0 +1,89 @@
+package netx
+
+import (
+	"time"
+)
+
+const (
+	DefaultSendQueueSize = 256
+	DefaultBufferSize    = 4096
+)
+
+// options tcp 和 udp 服务共用的参数
+type options struct {
+	codec             Codec
+	idleTimeout       time.Duration
+	writeTimeout      time.Duration
+	heartbeatInterval time.Duration
+	heartbeatMsg      []byte
+	sendQueueSize     int
+	readBufferSize    int
+	writeBufferSize   int
+	maxConns          int
+	loopNum           int
+}
+
+type Option func(*options)
+
+func newOptions(ops ...Option) *options {
+	o := &options{
+		sendQueueSize:   DefaultSendQueueSize,
+		readBufferSize:  DefaultBufferSize,
+		writeBufferSize: DefaultBufferSize,
+	}
+	for i := range ops {
+		ops[i](o)
+	}
+	return o
+}
+
+// WithCodec 拆包协议, tcp 默认 4 字节长度前缀, udp 默认一个数据包就是一个消息
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithIdleTimeout 超过该时间没有收到任何数据时关闭会话, 客户端需要定时发心跳
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.idleTimeout = timeout
+	}
+}
+
+// WithWriteTimeout 单次写超时, 防止客户端不读导致写协程卡住
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.writeTimeout = timeout
+	}
+}
+
+// WithHeartbeat 服务端定时向客户端发送心跳消息, 只对 tcp 生效
+func WithHeartbeat(interval time.Duration, msg []byte) Option {
+	return func(o *options) {
+		o.heartbeatInterval = interval
+		o.heartbeatMsg = msg
+	}
+}
+
+// WithSendQueueSize 每个连接的发送队列长度, 只对 tcp 生效
+func WithSendQueueSize(size int) Option {
+	return func(o *options) {
+		o.sendQueueSize = size
+	}
+}
+
+// WithMaxConns 最大会话数, 超过时新连接直接关闭, udp 丢弃新地址的数据包
+func WithMaxConns(n int) Option {
+	return func(o *options) {
+		o.maxConns = n
+	}
+}
+
+// WithEventLoop 事件由 n 个固定协程处理, 同一个会话的事件固定在一个协程上按顺序执行
+// tcp 默认在每个连接的读协程里直接回调, udp 默认在唯一的读协程里回调
+func WithEventLoop(n int) Option {
+	return func(o *options) {
+		o.loopNum = n
+	}
+}
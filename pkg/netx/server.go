@@ -0,0 +1,211 @@
+package netx
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrServerClosed = errors.New("server closed")
+)
+
+// Server tcp 服务, 负责 accept, 连接管理, 超时和优雅关闭
+type Server struct {
+	*options
+	addr    string
+	handler Handler
+
+	listener net.Listener
+	connId   uint64
+	conns    sync.Map
+	connNum  int64
+	connWg   sync.WaitGroup
+	loops    *loopGroup
+	closed   int32
+	acceptWg sync.WaitGroup
+}
+
+func NewServer(addr string, handler Handler, ops ...Option) *Server {
+	o := newOptions(ops...)
+	if o.codec == nil {
+		o.codec = NewLengthFieldCodec(4, 0)
+	}
+	return &Server{
+		options: o,
+		addr:    addr,
+		handler: handler,
+	}
+}
+
+func (s *Server) Name() string {
+	return "tcp"
+}
+
+// Addr 实际监听的地址, 监听 :0 时用于获取端口
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return s.addr
+	}
+	return s.listener.Addr().String()
+}
+
+// Start 监听端口并在后台 accept, 不阻塞
+func (s *Server) Start(ctx context.Context) error {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return ErrServerClosed
+	}
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// Serve 使用已有的 listener, 不阻塞
+func (s *Server) Serve(lis net.Listener) error {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return ErrServerClosed
+	}
+	s.listener = lis
+	s.loops = newLoopGroup(s.loopNum)
+	s.acceptWg.Add(1)
+	go s.acceptLoop()
+	log.Infof("tcp server listen on %s", lis.Addr())
+	return nil
+}
+
+func (s *Server) acceptLoop() {
+	defer s.acceptWg.Done()
+	var delay time.Duration
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if atomic.LoadInt32(&s.closed) == 1 {
+				return
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) {
+				// 文件句柄不够等临时错误, 退避后重试
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				log.Warnf("tcp accept err:%v, retry in %v", err, delay)
+				time.Sleep(delay)
+				continue
+			}
+			log.Errorf("tcp accept err:%v", err)
+			return
+		}
+		delay = 0
+
+		if s.maxConns > 0 && atomic.LoadInt64(&s.connNum) >= int64(s.maxConns) {
+			log.Warnf("tcp too many connections, reject %s", conn.RemoteAddr())
+			_ = conn.Close()
+			continue
+		}
+		s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(netConn net.Conn) {
+	c := newConn(atomic.AddUint64(&s.connId, 1), netConn, s)
+	s.conns.Store(c.id, c)
+	atomic.AddInt64(&s.connNum, 1)
+	s.connWg.Add(1)
+
+	go c.writeLoop()
+	go func() {
+		defer s.connWg.Done()
+		s.dispatch(c, func() {
+			s.handler.OnConnect(c)
+		})
+		err := c.readLoop()
+		c.shutdown(false)
+		<-c.writeDone
+		s.conns.Delete(c.id)
+		atomic.AddInt64(&s.connNum, -1)
+		s.dispatch(c, func() {
+			s.handler.OnClose(c, err)
+		})
+	}()
+}
+
+// dispatch 开启事件循环时投递到连接对应的协程, 否则直接执行
+func (s *Server) dispatch(c *Conn, fn func()) {
+	s.loops.dispatch(c.id, fn)
+}
+
+func (s *Server) logErr(c *Conn, err error) {
+	if errors.Is(err, net.ErrClosed) {
+		return
+	}
+	log.Warnf("tcp conn %d %s err:%v", c.id, c.RemoteAddr(), err)
+}
+
+// ConnNum 当前连接数
+func (s *Server) ConnNum() int {
+	return int(atomic.LoadInt64(&s.connNum))
+}
+
+// GetConn 按 id 查找连接
+func (s *Server) GetConn(id uint64) (*Conn, bool) {
+	v, ok := s.conns.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return v.(*Conn), true
+}
+
+// Range 遍历连接, fn 返回 false 时停止, 用于广播
+func (s *Server) Range(fn func(c *Conn) bool) {
+	s.conns.Range(func(key, value interface{}) bool {
+		return fn(value.(*Conn))
+	})
+}
+
+// Stop 停止 accept, 所有连接发送完队列中的消息后关闭; ctx 结束时强制关闭剩余连接
+func (s *Server) Stop(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+	s.acceptWg.Wait()
+
+	s.Range(func(c *Conn) bool {
+		c.CloseGraceful()
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.connWg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		log.Warnf("tcp server drain interrupted, %d connections left", s.ConnNum())
+		s.Range(func(c *Conn) bool {
+			_ = c.Close()
+			return true
+		})
+		<-done
+	}
+
+	if s.loops != nil {
+		s.loops.close()
+	}
+	return err
+}
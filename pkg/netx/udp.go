@@ -0,0 +1,300 @@
+package netx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultUDPIdleTimeout = 2 * time.Minute
+	maxDatagramSize       = 64 * 1024
+)
+
+var _ Session = (*UDPSession)(nil)
+
+// UDPSession 按远端地址区分的虚拟会话, 超过空闲时间没有收到数据时关闭
+type UDPSession struct {
+	id   uint64
+	addr *net.UDPAddr
+	srv  *UDPServer
+
+	lastActive int64
+	closed     int32
+
+	valuesMu sync.RWMutex
+	values   map[string]interface{}
+}
+
+func (s *UDPSession) Id() uint64 {
+	return s.id
+}
+
+func (s *UDPSession) RemoteAddr() net.Addr {
+	return s.addr
+}
+
+// Send 编码后直接发送一个数据包
+func (s *UDPSession) Send(msg []byte) error {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return ErrConnClosed
+	}
+	var buf bytes.Buffer
+	if err := s.srv.codec.Encode(&buf, msg); err != nil {
+		return err
+	}
+	if s.srv.writeTimeout > 0 {
+		_ = s.srv.conn.SetWriteDeadline(time.Now().Add(s.srv.writeTimeout))
+	}
+	_, err := s.srv.conn.WriteToUDP(buf.Bytes(), s.addr)
+	return err
+}
+
+func (s *UDPSession) Close() error {
+	s.srv.closeSession(s, nil)
+	return nil
+}
+
+func (s *UDPSession) Set(key string, val interface{}) {
+	s.valuesMu.Lock()
+	defer s.valuesMu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]interface{})
+	}
+	s.values[key] = val
+}
+
+func (s *UDPSession) Get(key string) (interface{}, bool) {
+	s.valuesMu.RLock()
+	defer s.valuesMu.RUnlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// UDPServer udp 服务, 与 tcp 服务共用 Codec, Handler 和 Session
+type UDPServer struct {
+	*options
+	addr    string
+	handler Handler
+
+	conn      *net.UDPConn
+	sessionId uint64
+	sessions  map[string]*UDPSession
+	mu        sync.Mutex
+	loops     *loopGroup
+	closed    int32
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewUDPServer(addr string, handler Handler, ops ...Option) *UDPServer {
+	o := newOptions(ops...)
+	if o.codec == nil {
+		o.codec = RawCodec{}
+	}
+	if o.idleTimeout <= 0 {
+		o.idleTimeout = DefaultUDPIdleTimeout
+	}
+	return &UDPServer{
+		options:  o,
+		addr:     addr,
+		handler:  handler,
+		sessions: make(map[string]*UDPSession),
+	}
+}
+
+func (s *UDPServer) Name() string {
+	return "udp"
+}
+
+func (s *UDPServer) Addr() string {
+	if s.conn == nil {
+		return s.addr
+	}
+	return s.conn.LocalAddr().String()
+}
+
+// Start 监听端口并在后台读取, 不阻塞
+func (s *UDPServer) Start(ctx context.Context) error {
+	if atomic.LoadInt32(&s.closed) == 1 {
+		return ErrServerClosed
+	}
+	addr, err := net.ResolveUDPAddr("udp", s.addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	s.loops = newLoopGroup(s.loopNum)
+
+	var janitorCtx context.Context
+	janitorCtx, s.cancel = context.WithCancel(context.Background())
+	s.wg.Add(2)
+	go s.readLoop()
+	go s.janitor(janitorCtx)
+	log.Infof("udp server listen on %s", conn.LocalAddr())
+	return nil
+}
+
+func (s *UDPServer) readLoop() {
+	defer s.wg.Done()
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			if atomic.LoadInt32(&s.closed) == 1 {
+				return
+			}
+			log.Warnf("udp read err:%v", err)
+			continue
+		}
+		sess := s.getSession(addr)
+		if sess == nil {
+			continue
+		}
+		atomic.StoreInt64(&sess.lastActive, time.Now().UnixNano())
+
+		// 一个数据包中可以有多个消息
+		br := bufio.NewReader(bytes.NewReader(append([]byte(nil), buf[:n]...)))
+		for {
+			msg, err := s.codec.Decode(br)
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					log.Warnf("udp session %d %s decode err:%v", sess.id, addr, err)
+				}
+				break
+			}
+			s.loops.dispatch(sess.id, func() {
+				s.handler.OnMessage(sess, msg)
+			})
+		}
+	}
+}
+
+// getSession 新地址创建会话, 超过最大会话数时返回 nil
+func (s *UDPServer) getSession(addr *net.UDPAddr) *UDPSession {
+	key := addr.String()
+	s.mu.Lock()
+	sess, ok := s.sessions[key]
+	if !ok {
+		if s.maxConns > 0 && len(s.sessions) >= s.maxConns {
+			s.mu.Unlock()
+			log.Warnf("udp too many sessions, drop packet from %s", addr)
+			return nil
+		}
+		sess = &UDPSession{
+			id:         atomic.AddUint64(&s.sessionId, 1),
+			addr:       addr,
+			srv:        s,
+			lastActive: time.Now().UnixNano(),
+		}
+		s.sessions[key] = sess
+	}
+	s.mu.Unlock()
+	if !ok {
+		s.loops.dispatch(sess.id, func() {
+			s.handler.OnConnect(sess)
+		})
+	}
+	return sess
+}
+
+func (s *UDPServer) closeSession(sess *UDPSession, err error) {
+	if !atomic.CompareAndSwapInt32(&sess.closed, 0, 1) {
+		return
+	}
+	s.mu.Lock()
+	delete(s.sessions, sess.addr.String())
+	s.mu.Unlock()
+	s.loops.dispatch(sess.id, func() {
+		s.handler.OnClose(sess, err)
+	})
+}
+
+// janitor 定时清理空闲的会话
+func (s *UDPServer) janitor(ctx context.Context) {
+	defer s.wg.Done()
+	interval := s.idleTimeout / 2
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		deadline := time.Now().Add(-s.idleTimeout).UnixNano()
+		var idle []*UDPSession
+		s.mu.Lock()
+		for _, sess := range s.sessions {
+			if atomic.LoadInt64(&sess.lastActive) < deadline {
+				idle = append(idle, sess)
+			}
+		}
+		s.mu.Unlock()
+		for _, sess := range idle {
+			s.closeSession(sess, ErrIdleTimeout)
+		}
+	}
+}
+
+// SessionNum 当前会话数
+func (s *UDPServer) SessionNum() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Range 遍历会话, fn 返回 false 时停止
+func (s *UDPServer) Range(fn func(sess *UDPSession) bool) {
+	s.mu.Lock()
+	list := make([]*UDPSession, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		list = append(list, sess)
+	}
+	s.mu.Unlock()
+	for _, sess := range list {
+		if !fn(sess) {
+			return
+		}
+	}
+}
+
+// Stop 停止读取, 关闭所有会话并等待已投递的事件处理完
+func (s *UDPServer) Stop(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return nil
+	}
+	if s.conn == nil {
+		return nil
+	}
+	s.cancel()
+	_ = s.conn.Close()
+	s.wg.Wait()
+	s.Range(func(sess *UDPSession) bool {
+		s.closeSession(sess, nil)
+		return true
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.loops.close()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,17 +1,32 @@
 package nsqsdk
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/nsqio/go-nsq"
+	"github.com/oldbai555/lbtool/pkg/codec"
 	"time"
 )
 
 type nsqDProducer struct {
-	np *nsq.Producer
+	np    *nsq.Producer
+	codec codec.Codec // 消息内容的序列化方式
 }
 
-func NewProducer(addr string) (*nsqDProducer, error) {
+type ProducerOption func(*nsqDProducer)
+
+// WithCodec 消息内容的序列化方式, 默认使用 codec.Default(), 名字随消息发送, Process 按名字解码
+func WithCodec(name string) ProducerOption {
+	return func(n *nsqDProducer) {
+		c, err := codec.Get(name)
+		if err != nil {
+			fmt.Printf("err:%v\n", err)
+			return
+		}
+		n.codec = c
+	}
+}
+
+func NewProducer(addr string, opts ...ProducerOption) (*nsqDProducer, error) {
 	cfg := nsq.NewConfig()
 	cfg.LookupdPollInterval = TIMEOUT
 
@@ -22,19 +37,23 @@ func NewProducer(addr string) (*nsqDProducer, error) {
 	}
 
 	np := &nsqDProducer{
-		np: p,
+		np:    p,
+		codec: codec.Default(),
+	}
+	for _, opt := range opts {
+		opt(np)
 	}
 	fmt.Printf("InitProducer SUCCESS addr:%s", addr)
 	return np, nil
 }
 
 func (n *nsqDProducer) Pub(topic string, c interface{}) error {
-	msg, err := json.Marshal(c)
+	msg, err := n.codec.Marshal(c)
 	if err != nil {
 		fmt.Printf("err:%v\n", err)
 		return err
 	}
-	b, err := EncodeMsg("", 0, msg)
+	b, err := encodeMsg(&Msg{Codec: n.codec.Name(), MsgInfo: msg})
 	if err != nil {
 		fmt.Printf("err:%v\n", err)
 		return err
@@ -44,12 +63,12 @@ func (n *nsqDProducer) Pub(topic string, c interface{}) error {
 }
 
 func (n *nsqDProducer) DelayPub(topic string, delay time.Duration, c interface{}) error {
-	msg, err := json.Marshal(c)
+	msg, err := n.codec.Marshal(c)
 	if err != nil {
 		fmt.Printf("err:%v\n", err)
 		return err
 	}
-	b, err := EncodeMsg("", 0, msg)
+	b, err := encodeMsg(&Msg{Codec: n.codec.Name(), MsgInfo: msg})
 	if err != nil {
 		fmt.Printf("err:%v\n", err)
 		return err
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/nsqio/go-nsq"
+	"github.com/oldbai555/lbtool/pkg/codec"
 	"time"
 )
 
@@ -15,14 +16,20 @@ type Msg struct {
 	ReqId   string
 	CorpId  uint32
 	MsgInfo []byte
+	Codec   string `json:",omitempty"` // MsgInfo 的序列化方式, 为空表示 json
 }
 
+// EncodeMsg msg 为 json 编码的消息内容
 func EncodeMsg(reqId string, corpId uint32, msg []byte) ([]byte, error) {
-	info := &Msg{
+	return encodeMsg(&Msg{
 		ReqId:   reqId,
 		CorpId:  corpId,
 		MsgInfo: msg,
-	}
+	})
+}
+
+// encodeMsg 外层固定使用 json, 新旧版本的消费者都能解析, 内容的序列化方式记录在 Codec 中
+func encodeMsg(info *Msg) ([]byte, error) {
 	b, err := json.Marshal(info)
 	if err != nil {
 		return nil, err
@@ -52,8 +59,18 @@ func Process(msg *nsq.Message, doLogic func(data interface{}) error) error {
 		return nil
 	}
 
+	name := info.Codec
+	if name == "" {
+		name = codec.NameJSON
+	}
+	c, err := codec.Get(name)
+	if err != nil {
+		fmt.Printf("err:%v\n", err)
+		return err
+	}
+	// 解码到 interface{}, 只支持 json / msgpack 这类自描述的格式
 	var data interface{}
-	err = json.Unmarshal(info.MsgInfo, &data)
+	err = c.Unmarshal(info.MsgInfo, &data)
 	if err != nil {
 		fmt.Printf("err:%v\n", err)
 		return err
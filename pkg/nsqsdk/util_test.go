@@ -0,0 +1,35 @@
+package nsqsdk
+
+import (
+	"fmt"
+	"github.com/nsqio/go-nsq"
+	"github.com/oldbai555/lbtool/pkg/codec"
+	"testing"
+)
+
+func TestProcessCodec(t *testing.T) {
+	// 旧版本的消息没有 Codec, 按 json 解码
+	old, err := EncodeMsg("", 0, []byte(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := codec.Marshal(codec.NameMsgpack, map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	packed, err := encodeMsg(&Msg{Codec: codec.NameMsgpack, MsgInfo: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range [][]byte{old, packed} {
+		var got string
+		err = Process(nsq.NewMessage(nsq.MessageID{}, body), func(data interface{}) error {
+			got = fmt.Sprint(data)
+			return nil
+		})
+		if err != nil || got != "map[id:1]" {
+			t.Errorf("got %s, err:%v", got, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type memStore struct {
+	mu     sync.Mutex
+	seq    int64
+	rows   map[int64]*row
+	failed map[int64]string
+}
+
+type row struct {
+	msg    *Message
+	status int
+	nextAt time.Time
+	sentAt time.Time
+}
+
+func newMemStore() *memStore {
+	return &memStore{rows: make(map[int64]*row), failed: make(map[int64]string)}
+}
+
+func (s *memStore) stage(topic, payload string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	s.rows[s.seq] = &row{msg: &Message{Id: s.seq, Topic: topic, Payload: []byte(payload)}}
+}
+
+func (s *memStore) Pending(ctx context.Context, limit int) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []*Message
+	for _, r := range s.rows {
+		if r.status == StatusPending && !r.nextAt.After(time.Now()) {
+			m := *r.msg
+			list = append(list, &m)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (s *memStore) MarkSent(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows[id].status = StatusSent
+	s.rows[id].sentAt = time.Now()
+	return nil
+}
+
+func (s *memStore) MarkRetry(ctx context.Context, id int64, nextAt time.Time, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows[id].msg.Attempts++
+	s.rows[id].nextAt = nextAt
+	return nil
+}
+
+func (s *memStore) MarkFailed(ctx context.Context, id int64, lastErr string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rows[id].status = StatusFailed
+	s.failed[id] = lastErr
+	return nil
+}
+
+func (s *memStore) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for id, r := range s.rows {
+		if r.status == StatusSent && r.sentAt.Before(before) {
+			delete(s.rows, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type memPub struct {
+	mu   sync.Mutex
+	fail map[string]bool
+	sent []*Message
+}
+
+func (p *memPub) Pub(topic string, msg interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.fail[topic] {
+		return errors.New("nsq unavailable")
+	}
+	p.sent = append(p.sent, msg.(*Message))
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	ctx := context.Background()
+	store := newMemStore()
+	pub := &memPub{fail: map[string]bool{"bad": true}}
+	relay := NewRelay(store, pub, WithBatchSize(2), WithRetry(time.Millisecond, 3))
+
+	store.stage("order", `{"id":1}`)
+	store.stage("bad", `{}`)
+	store.stage("order", `{"id":2}`)
+
+	for i := 0; i < 10; i++ {
+		if _, err := relay.RelayOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(pub.sent) != 2 || pub.sent[0].Id != 1 || pub.sent[1].Id != 3 {
+		t.Fatalf("unexpected sent %+v", pub.sent)
+	}
+	if store.rows[2].status != StatusFailed || store.rows[2].msg.Attempts != 2 || store.failed[2] == "" {
+		t.Fatalf("bad message should be failed after 3 attempts, %+v", store.rows[2])
+	}
+
+	if n, _ := store.Cleanup(ctx, time.Now().Add(time.Second)); n != 2 {
+		t.Fatalf("expect cleanup 2, got %d", n)
+	}
+}
+
+func TestRelay_StartStop(t *testing.T) {
+	store := newMemStore()
+	pub := &memPub{}
+	relay := NewRelay(store, pub, WithPollInterval(5*time.Millisecond))
+	if err := relay.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store.stage("order", `{}`)
+	deadline := time.Now().Add(time.Second)
+	for {
+		pub.mu.Lock()
+		n := len(pub.sent)
+		pub.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("message not relayed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := relay.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLStore_Rebind(t *testing.T) {
+	s := NewSQLStore(nil, WithTable("order_outbox"), WithDollarPlaceholder())
+	got := s.rebind("UPDATE {table} SET status = ? WHERE id = ?")
+	if got != "UPDATE order_outbox SET status = $1 WHERE id = $2" {
+		t.Fatalf("unexpected %s", got)
+	}
+}
@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"github.com/oldbai555/lbtool/log"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBatchSize       = 100
+	DefaultPollInterval    = time.Second
+	DefaultRetryInterval   = time.Second
+	DefaultMaxRetryDelay   = 10 * time.Minute
+	DefaultMaxAttempts     = 20
+	DefaultRetention       = 7 * 24 * time.Hour
+	DefaultCleanupInterval = time.Hour
+)
+
+// Publisher 消息投递, nsqsdk 的生产者满足该接口
+type Publisher interface {
+	Pub(topic string, msg interface{}) error
+}
+
+// Relay 轮询待投递的消息并发布到消息队列, 投递成功后标记为已发送
+// 发布成功但标记失败时消息会被重复投递, 消费者需要按 Message.Id 去重
+// 多实例部署时需要配合选主只让一个实例运行
+type Relay struct {
+	store Store
+	pub   Publisher
+
+	batchSize       int
+	pollInterval    time.Duration
+	retryInterval   time.Duration
+	maxRetryDelay   time.Duration
+	maxAttempts     int
+	retention       time.Duration
+	cleanupInterval time.Duration
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type RelayOption func(*Relay)
+
+// WithBatchSize 每次轮询取出的消息数
+func WithBatchSize(size int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithPollInterval 没有待投递消息时的轮询间隔
+func WithPollInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.pollInterval = interval
+	}
+}
+
+// WithRetry 投递失败后按 interval 指数退避重试, 超过 maxAttempts 次标记为失败
+func WithRetry(interval time.Duration, maxAttempts int) RelayOption {
+	return func(r *Relay) {
+		r.retryInterval = interval
+		r.maxAttempts = maxAttempts
+	}
+}
+
+// WithRetention 已投递消息的保留时长, 超过后被清理
+func WithRetention(retention time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.retention = retention
+	}
+}
+
+func NewRelay(store Store, pub Publisher, ops ...RelayOption) *Relay {
+	r := &Relay{
+		store:           store,
+		pub:             pub,
+		batchSize:       DefaultBatchSize,
+		pollInterval:    DefaultPollInterval,
+		retryInterval:   DefaultRetryInterval,
+		maxRetryDelay:   DefaultMaxRetryDelay,
+		maxAttempts:     DefaultMaxAttempts,
+		retention:       DefaultRetention,
+		cleanupInterval: DefaultCleanupInterval,
+	}
+	for i := range ops {
+		ops[i](r)
+	}
+	return r
+}
+
+func (r *Relay) Name() string {
+	return "outbox_relay"
+}
+
+// Start 启动后台轮询, 不阻塞
+func (r *Relay) Start(ctx context.Context) error {
+	ctx, r.cancel = context.WithCancel(context.WithoutCancel(ctx))
+	r.wg.Add(1)
+	go r.loop(ctx)
+	return nil
+}
+
+// Stop 停止轮询, 等待正在投递的批次结束
+func (r *Relay) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) loop(ctx context.Context) {
+	defer r.wg.Done()
+	lastCleanup := time.Now()
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			log.Errorf("err:%v", err)
+		}
+		if r.retention > 0 && time.Since(lastCleanup) >= r.cleanupInterval {
+			lastCleanup = time.Now()
+			if deleted, err := r.store.Cleanup(ctx, time.Now().Add(-r.retention)); err != nil {
+				log.Errorf("err:%v", err)
+			} else if deleted > 0 {
+				log.Infof("outbox cleanup %d messages", deleted)
+			}
+		}
+		// 一批取满说明还有积压, 不等待直接继续
+		if err == nil && n >= r.batchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// RelayOnce 投递一批消息, 返回取出的消息数
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	list, err := r.store.Pending(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range list {
+		if ctx.Err() != nil {
+			return len(list), nil
+		}
+		r.publish(ctx, m)
+	}
+	return len(list), nil
+}
+
+func (r *Relay) publish(ctx context.Context, m *Message) {
+	err := r.pub.Pub(m.Topic, m)
+	if err == nil {
+		if err = r.store.MarkSent(ctx, m.Id); err != nil {
+			log.Errorf("outbox mark sent %d err:%v", m.Id, err)
+		}
+		return
+	}
+
+	log.Warnf("outbox publish %d to %s err:%v", m.Id, m.Topic, err)
+	if m.Attempts+1 >= r.maxAttempts {
+		if err = r.store.MarkFailed(ctx, m.Id, err.Error()); err != nil {
+			log.Errorf("outbox mark failed %d err:%v", m.Id, err)
+		}
+		return
+	}
+	if err = r.store.MarkRetry(ctx, m.Id, time.Now().Add(r.backoff(m.Attempts)), err.Error()); err != nil {
+		log.Errorf("outbox mark retry %d err:%v", m.Id, err)
+	}
+}
+
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.retryInterval
+	for i := 0; i < attempts && d < r.maxRetryDelay; i++ {
+		d *= 2
+	}
+	if d > r.maxRetryDelay {
+		d = r.maxRetryDelay
+	}
+	return d
+}
@@ -0,0 +1,176 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/oldbai555/lbtool/pkg/internal/placeholder"
+	"strings"
+	"time"
+)
+
+const (
+	StatusPending = 0
+	StatusSent    = 1
+	StatusFailed  = 2
+
+	DefaultTable = "lb_outbox"
+)
+
+// MysqlSchema SQLStore 使用的表结构, 表名可以通过 WithTable 修改
+const MysqlSchema = `
+CREATE TABLE IF NOT EXISTS lb_outbox (
+	id BIGINT NOT NULL AUTO_INCREMENT,
+	topic VARCHAR(255) NOT NULL,
+	payload BLOB NOT NULL,
+	status TINYINT NOT NULL DEFAULT 0,
+	attempts INT NOT NULL DEFAULT 0,
+	last_err VARCHAR(1024) NOT NULL DEFAULT '',
+	next_at BIGINT NOT NULL,
+	created_at BIGINT NOT NULL,
+	sent_at BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (id),
+	KEY idx_status_next_at (status, next_at)
+);`
+
+// Message 投递到消息队列的内容, 消费者可以用 Id 去重
+type Message struct {
+	Id        int64           `json:"id"`
+	Topic     string          `json:"topic"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt int64           `json:"created_at"`
+
+	Attempts int `json:"-"`
+}
+
+// Execer *sql.Tx 和 *sql.DB 都满足该接口
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Store Relay 依赖的存储操作
+type Store interface {
+	// Pending 按 id 顺序取出到期的待投递消息
+	Pending(ctx context.Context, limit int) ([]*Message, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkRetry(ctx context.Context, id int64, nextAt time.Time, lastErr string) error
+	MarkFailed(ctx context.Context, id int64, lastErr string) error
+	// Cleanup 删除 before 之前已投递的消息
+	Cleanup(ctx context.Context, before time.Time) (int64, error)
+}
+
+var _ Store = (*SQLStore)(nil)
+
+// SQLStore 消息表需要和业务表在同一个库, Stage 才能和业务数据放在一个事务里
+// 默认使用 ? 占位符 (mysql, sqlite)
+type SQLStore struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+}
+
+type SQLOption func(*SQLStore)
+
+// WithTable 表名, 默认 lb_outbox
+func WithTable(table string) SQLOption {
+	return func(s *SQLStore) {
+		s.table = table
+	}
+}
+
+// WithDollarPlaceholder postgres 需要 $n 占位符, 建表时 AUTO_INCREMENT 改为 BIGSERIAL, BLOB 改为 BYTEA
+func WithDollarPlaceholder() SQLOption {
+	return func(s *SQLStore) {
+		s.dollar = true
+	}
+}
+
+func NewSQLStore(db *sql.DB, ops ...SQLOption) *SQLStore {
+	s := &SQLStore{db: db, table: DefaultTable}
+	for i := range ops {
+		ops[i](s)
+	}
+	return s
+}
+
+// rebind 替换表名, 并把 ? 替换为 $n
+func (s *SQLStore) rebind(query string) string {
+	query = strings.ReplaceAll(query, "{table}", s.table)
+	if s.dollar {
+		query = placeholder.Dollar(query)
+	}
+	return query
+}
+
+// Stage 在业务事务中写入待投递的消息, 与业务数据一起提交或回滚
+// payload 为 []byte 或 json.RawMessage 时原样保存, 其他类型序列化为 json
+func (s *SQLStore) Stage(ctx context.Context, tx Execer, topic string, payload interface{}) error {
+	var buf []byte
+	switch v := payload.(type) {
+	case []byte:
+		buf = v
+	case json.RawMessage:
+		buf = v
+	default:
+		var err error
+		if buf, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	now := time.Now().UnixMilli()
+	_, err := tx.ExecContext(ctx, s.rebind("INSERT INTO {table} (topic, payload, status, attempts, last_err, next_at, created_at, sent_at) VALUES (?, ?, ?, 0, '', ?, ?, 0)"),
+		topic, buf, StatusPending, now, now)
+	return err
+}
+
+func (s *SQLStore) Pending(ctx context.Context, limit int) ([]*Message, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT id, topic, payload, attempts, created_at FROM {table} WHERE status = ? AND next_at <= ? ORDER BY id LIMIT ?"),
+		StatusPending, time.Now().UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []*Message
+	for rows.Next() {
+		m := &Message{}
+		var payload []byte
+		if err = rows.Scan(&m.Id, &m.Topic, &payload, &m.Attempts, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.Payload = payload
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+func (s *SQLStore) MarkSent(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, s.rebind("UPDATE {table} SET status = ?, sent_at = ? WHERE id = ?"), StatusSent, time.Now().UnixMilli(), id)
+	return err
+}
+
+func (s *SQLStore) MarkRetry(ctx context.Context, id int64, nextAt time.Time, lastErr string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind("UPDATE {table} SET attempts = attempts + 1, next_at = ?, last_err = ? WHERE id = ?"),
+		nextAt.UnixMilli(), truncate(lastErr, 1024), id)
+	return err
+}
+
+func (s *SQLStore) MarkFailed(ctx context.Context, id int64, lastErr string) error {
+	_, err := s.db.ExecContext(ctx, s.rebind("UPDATE {table} SET status = ?, attempts = attempts + 1, last_err = ? WHERE id = ?"),
+		StatusFailed, truncate(lastErr, 1024), id)
+	return err
+}
+
+func (s *SQLStore) Cleanup(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.rebind("DELETE FROM {table} WHERE status = ? AND sent_at < ?"), StatusSent, before.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
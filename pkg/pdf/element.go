@@ -0,0 +1,178 @@
+package pdf
+
+import (
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"io"
+)
+
+// TextStyle 文本样式, 零值使用默认样式
+type TextStyle struct {
+	Size  float64
+	Bold  bool // 嵌入的字体需要同时提供粗体, 否则忽略
+	Align string
+	Color [3]int
+}
+
+func (d *Document) applyStyle(style *TextStyle) {
+	size := d.fontSize
+	var fontStyle string
+	if style != nil {
+		if style.Size > 0 {
+			size = style.Size
+		}
+		if style.Bold && d.fontFamily == DefaultFont {
+			fontStyle = "B"
+		}
+		d.pdf.SetTextColor(style.Color[0], style.Color[1], style.Color[2])
+	}
+	d.pdf.SetFont(d.fontFamily, fontStyle, size)
+}
+
+func (d *Document) resetStyle() {
+	d.pdf.SetFont(d.fontFamily, "", d.fontSize)
+	d.pdf.SetTextColor(0, 0, 0)
+}
+
+// Text 输出一段文本, 超出宽度自动换行
+func (d *Document) Text(text string, style *TextStyle) {
+	d.applyStyle(style)
+	defer d.resetStyle()
+
+	align := AlignLeft
+	if style != nil && style.Align != "" {
+		align = style.Align
+	}
+	_, size := d.pdf.GetFontSize()
+	d.pdf.MultiCell(0, size*1.5, text, "", align, false)
+}
+
+// Title 居中的标题
+func (d *Document) Title(text string) {
+	d.Text(text, &TextStyle{Size: d.fontSize * 1.6, Bold: true, Align: AlignCenter})
+	d.Ln(0)
+}
+
+// KeyValue 两列对齐的键值对, 例如发票抬头信息
+func (d *Document) KeyValue(pairs [][2]string, keyWidth float64) {
+	if keyWidth <= 0 {
+		keyWidth = d.contentWidth() / 4
+	}
+	for _, pair := range pairs {
+		d.pdf.CellFormat(keyWidth, d.lineHeight*1.4, pair[0], "", 0, AlignLeft, false, 0, "")
+		d.pdf.MultiCell(0, d.lineHeight*1.4, pair[1], "", AlignLeft, false)
+	}
+}
+
+// Table 表格
+type Table struct {
+	Headers []string
+	Rows    [][]string
+	// Widths 各列宽度, 为空时平均分配
+	Widths []float64
+	// Aligns 各列对齐方式, 为空时左对齐
+	Aligns []string
+	// HeaderFill 表头背景色
+	HeaderFill [3]int
+}
+
+// Table 绘制表格, 单元格内容自动换行, 跨页时重复表头
+func (d *Document) Table(t *Table) error {
+	cols := len(t.Headers)
+	if cols == 0 {
+		return fmt.Errorf("pdf: table without headers")
+	}
+	widths := t.Widths
+	if len(widths) != cols {
+		widths = make([]float64, cols)
+		for i := range widths {
+			widths[i] = d.contentWidth() / float64(cols)
+		}
+	}
+	fill := t.HeaderFill
+	if fill == [3]int{} {
+		fill = [3]int{230, 230, 230}
+	}
+
+	drawHeader := func() {
+		d.pdf.SetFillColor(fill[0], fill[1], fill[2])
+		d.drawRow(t.Headers, widths, nil, true)
+	}
+	drawHeader()
+	for _, row := range t.Rows {
+		if len(row) != cols {
+			return fmt.Errorf("pdf: row has %d cells, expect %d", len(row), cols)
+		}
+		if d.needPageBreak(d.rowHeight(row, widths)) {
+			d.pdf.AddPage()
+			drawHeader()
+		}
+		d.drawRow(row, widths, t.Aligns, false)
+	}
+	return d.pdf.Error()
+}
+
+const cellPadding = 1
+
+func (d *Document) rowHeight(row []string, widths []float64) float64 {
+	lines := 1
+	for i, cell := range row {
+		if n := len(d.pdf.SplitText(cell, widths[i]-2*cellPadding)); n > lines {
+			lines = n
+		}
+	}
+	return float64(lines) * d.lineHeight * 1.2
+}
+
+func (d *Document) needPageBreak(h float64) bool {
+	_, pageH := d.pdf.GetPageSize()
+	_, _, _, bottom := d.pdf.GetMargins()
+	return d.pdf.GetY()+h > pageH-bottom
+}
+
+func (d *Document) drawRow(row []string, widths []float64, aligns []string, fill bool) {
+	h := d.rowHeight(row, widths)
+	x, y := d.pdf.GetXY()
+	// 一行内各单元格高度一致, 先画边框再写内容
+	d.pdf.SetAutoPageBreak(false, 0)
+	for i, cell := range row {
+		align := AlignLeft
+		if i < len(aligns) && aligns[i] != "" {
+			align = aligns[i]
+		}
+		style := "D"
+		if fill {
+			style = "FD"
+		}
+		d.pdf.Rect(x, y, widths[i], h, style)
+		d.pdf.SetXY(x+cellPadding, y)
+		d.pdf.MultiCell(widths[i]-2*cellPadding, d.lineHeight*1.2, cell, "", align, false)
+		x += widths[i]
+	}
+	_, _, _, bottom := d.pdf.GetMargins()
+	d.pdf.SetAutoPageBreak(true, bottom)
+	left, _, _, _ := d.pdf.GetMargins()
+	d.pdf.SetXY(left, y+h)
+}
+
+// ImageType 图片格式
+type ImageType string
+
+const (
+	ImagePNG  ImageType = "PNG"
+	ImageJPEG ImageType = "JPG"
+	ImageGIF  ImageType = "GIF"
+)
+
+// Image 插入图片, w/h 为 0 时按比例缩放, 都为 0 时使用原始大小
+func (d *Document) Image(r io.Reader, typ ImageType, w, h float64) error {
+	d.imageSeq++
+	name := fmt.Sprintf("img_%d", d.imageSeq)
+	opts := fpdf.ImageOptions{ImageType: string(typ), ReadDpi: true}
+	info := d.pdf.RegisterImageOptionsReader(name, opts, r)
+	if info == nil {
+		return d.pdf.Error()
+	}
+	d.pdf.ImageOptions(name, -1, -1, w, h, true, opts, 0, "")
+	return d.pdf.Error()
+}
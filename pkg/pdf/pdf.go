@@ -0,0 +1,230 @@
+package pdf
+
+import (
+	"fmt"
+	"github.com/go-pdf/fpdf"
+	"io"
+	"os"
+)
+
+const (
+	PageA4     = "A4"
+	PageLetter = "Letter"
+
+	Portrait  = "P"
+	Landscape = "L"
+
+	// DefaultFont 内置字体不支持中文, 输出中文需要通过 WithFont 嵌入 ttf 字体
+	DefaultFont     = "Helvetica"
+	DefaultFontSize = 11
+	DefaultMargin   = 15
+
+	AlignLeft   = "L"
+	AlignCenter = "C"
+	AlignRight  = "R"
+)
+
+// Document 简单文档, 单位为毫米, 常用于发票, 报表等
+type Document struct {
+	pdf *fpdf.Fpdf
+
+	fontFamily string
+	fontSize   float64
+	lineHeight float64
+	imageSeq   int
+
+	header func(d *Document)
+	footer func(d *Document)
+}
+
+type Option func(*options)
+
+type options struct {
+	pageSize    string
+	orientation string
+	margin      float64
+	fontFamily  string
+	fontBytes   []byte
+	fontPath    string
+	fontSize    float64
+	header      func(d *Document)
+	footer      func(d *Document)
+}
+
+// WithPageSize 纸张大小, 默认 A4
+func WithPageSize(size string) Option {
+	return func(o *options) {
+		o.pageSize = size
+	}
+}
+
+// WithLandscape 横向
+func WithLandscape() Option {
+	return func(o *options) {
+		o.orientation = Landscape
+	}
+}
+
+// WithMargin 页边距
+func WithMargin(margin float64) Option {
+	return func(o *options) {
+		o.margin = margin
+	}
+}
+
+// WithFont 嵌入 ttf 字体, 中文需要使用例如 NotoSansSC, 思源黑体等字体
+func WithFont(family string, ttf []byte) Option {
+	return func(o *options) {
+		o.fontFamily = family
+		o.fontBytes = ttf
+	}
+}
+
+// WithFontFile 从文件嵌入 ttf 字体
+func WithFontFile(family, path string) Option {
+	return func(o *options) {
+		o.fontFamily = family
+		o.fontPath = path
+	}
+}
+
+// WithFontSize 默认字号
+func WithFontSize(size float64) Option {
+	return func(o *options) {
+		o.fontSize = size
+	}
+}
+
+// WithHeader 每页的页眉
+func WithHeader(fn func(d *Document)) Option {
+	return func(o *options) {
+		o.header = fn
+	}
+}
+
+// WithFooter 每页的页脚
+func WithFooter(fn func(d *Document)) Option {
+	return func(o *options) {
+		o.footer = fn
+	}
+}
+
+// WithPageNumberFooter 页脚居中显示 "第 x 页 / 共 y 页", format 为空时使用 "%d / {nb}"
+func WithPageNumberFooter(format string) Option {
+	if format == "" {
+		format = "%d / {nb}"
+	}
+	return WithFooter(func(d *Document) {
+		d.pdf.SetY(-DefaultMargin)
+		d.pdf.CellFormat(0, d.lineHeight, fmt.Sprintf(format, d.pdf.PageNo()), "", 0, AlignCenter, false, 0, "")
+	})
+}
+
+func New(ops ...Option) (*Document, error) {
+	o := &options{
+		pageSize:    PageA4,
+		orientation: Portrait,
+		margin:      DefaultMargin,
+		fontFamily:  DefaultFont,
+		fontSize:    DefaultFontSize,
+	}
+	for i := range ops {
+		ops[i](o)
+	}
+
+	p := fpdf.New(o.orientation, "mm", o.pageSize, "")
+	p.SetMargins(o.margin, o.margin, o.margin)
+	p.SetAutoPageBreak(true, o.margin)
+	p.AliasNbPages("")
+
+	if o.fontPath != "" {
+		buf, err := os.ReadFile(o.fontPath)
+		if err != nil {
+			return nil, err
+		}
+		o.fontBytes = buf
+	}
+	if o.fontFamily != DefaultFont {
+		if len(o.fontBytes) == 0 {
+			return nil, fmt.Errorf("pdf: font %s is empty", o.fontFamily)
+		}
+		p.AddUTF8FontFromBytes(o.fontFamily, "", o.fontBytes)
+	}
+	p.SetFont(o.fontFamily, "", o.fontSize)
+	if err := p.Error(); err != nil {
+		return nil, err
+	}
+
+	d := &Document{
+		pdf:        p,
+		fontFamily: o.fontFamily,
+		fontSize:   o.fontSize,
+		lineHeight: o.fontSize * 0.5,
+		header:     o.header,
+		footer:     o.footer,
+	}
+	if d.header != nil {
+		p.SetHeaderFuncMode(func() {
+			d.restoreFont(func() { d.header(d) })
+		}, true)
+	}
+	if d.footer != nil {
+		p.SetFooterFunc(func() {
+			d.restoreFont(func() { d.footer(d) })
+		})
+	}
+	p.AddPage()
+	return d, nil
+}
+
+// restoreFont 执行 fn 后恢复默认字体, 避免页眉页脚的样式影响正文
+func (d *Document) restoreFont(fn func()) {
+	fn()
+	d.pdf.SetFont(d.fontFamily, "", d.fontSize)
+	d.pdf.SetTextColor(0, 0, 0)
+}
+
+// Fpdf 底层对象, 用于封装之外的定制
+func (d *Document) Fpdf() *fpdf.Fpdf {
+	return d.pdf
+}
+
+// AddPage 新起一页
+func (d *Document) AddPage() {
+	d.pdf.AddPage()
+}
+
+// PageNo 当前页码
+func (d *Document) PageNo() int {
+	return d.pdf.PageNo()
+}
+
+// Ln 换行, h 为 0 时使用默认行高
+func (d *Document) Ln(h float64) {
+	if h <= 0 {
+		h = d.lineHeight
+	}
+	d.pdf.Ln(h)
+}
+
+// contentWidth 正文可用宽度
+func (d *Document) contentWidth() float64 {
+	w, _ := d.pdf.GetPageSize()
+	left, _, right, _ := d.pdf.GetMargins()
+	return w - left - right
+}
+
+// Output 输出到 io.Writer
+func (d *Document) Output(w io.Writer) error {
+	return d.pdf.Output(w)
+}
+
+// SaveAs 保存到文件
+func (d *Document) SaveAs(path string) error {
+	return d.pdf.OutputFileAndClose(path)
+}
+
+// Err 绘制过程中出现的错误
+func (d *Document) Err() error {
+	return d.pdf.Error()
+}
@@ -0,0 +1,68 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	d, err := New(
+		WithHeader(func(d *Document) {
+			d.Text("lb report", &TextStyle{Size: 8, Align: AlignRight, Color: [3]int{128, 128, 128}})
+		}),
+		WithPageNumberFooter("page %d / {nb}"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Title("Invoice")
+	d.KeyValue([][2]string{{"No.", "INV-0001"}, {"Customer", "lb"}}, 30)
+
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	img.Set(1, 1, color.Black)
+	var imgBuf bytes.Buffer
+	if err = png.Encode(&imgBuf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Image(&imgBuf, ImagePNG, 10, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	table := &Table{
+		Headers: []string{"Item", "Qty", "Price"},
+		Widths:  []float64{100, 30, 50},
+		Aligns:  []string{AlignLeft, AlignRight, AlignRight},
+	}
+	for i := 0; i < 100; i++ {
+		table.Rows = append(table.Rows, []string{fmt.Sprintf("item %d with a long long long long long long long long name", i), "1", "9.90"})
+	}
+	if err = d.Table(table); err != nil {
+		t.Fatal(err)
+	}
+	if d.PageNo() < 2 {
+		t.Fatalf("expect multi pages, got %d", d.PageNo())
+	}
+
+	var buf bytes.Buffer
+	if err = d.Output(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatal("invalid pdf output")
+	}
+}
+
+func TestTableRowMismatch(t *testing.T) {
+	d, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Table(&Table{Headers: []string{"a", "b"}, Rows: [][]string{{"1"}}})
+	if err == nil {
+		t.Fatal("expect error")
+	}
+}
@@ -0,0 +1,57 @@
+package rbac
+
+import (
+	"context"
+	"github.com/oldbai555/lbtool/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+)
+
+// ActionCall grpc 接口的操作名
+const ActionCall = "call"
+
+// HttpMiddleware 按路由校验权限, 资源为请求路径, 操作为请求方法
+// userFn 从请求中取出当前用户, 返回空表示未登录
+func HttpMiddleware(e *Enforcer, userFn func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := userFn(r)
+			if user == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			ok, err := e.CheckPermission(r.Context(), user, r.URL.Path, r.Method)
+			if err != nil {
+				log.Errorf("err:%v", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryServerInterceptor grpc 接口权限校验, 资源为完整方法名, 例如 /pb.Order/Create, 操作为 call
+func UnaryServerInterceptor(e *Enforcer, userFn func(ctx context.Context) string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		user := userFn(ctx)
+		if user == "" {
+			return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+		}
+		ok, err := e.CheckPermission(ctx, user, info.FullMethod, ActionCall)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return nil, status.Error(codes.Internal, "check permission failed")
+		}
+		if !ok {
+			return nil, status.Error(codes.PermissionDenied, "permission denied")
+		}
+		return handler(ctx, req)
+	}
+}
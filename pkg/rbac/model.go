@@ -0,0 +1,28 @@
+package rbac
+
+import "strings"
+
+const Wildcard = "*"
+
+// Permission 权限, Resource 以 * 结尾时按前缀匹配, Action 为 * 时匹配所有操作
+//
+//	{Resource: "/api/order/*", Action: "GET"}
+//	{Resource: "order", Action: "*"}
+type Permission struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// Match 判断权限是否覆盖 resource 上的 action
+func (p Permission) Match(resource, action string) bool {
+	if p.Action != Wildcard && !strings.EqualFold(p.Action, action) {
+		return false
+	}
+	if p.Resource == Wildcard || p.Resource == resource {
+		return true
+	}
+	if strings.HasSuffix(p.Resource, Wildcard) {
+		return strings.HasPrefix(resource, strings.TrimSuffix(p.Resource, Wildcard))
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const DefaultCacheTTL = time.Minute
+
+// Enforcer 权限校验, 用户的权限集合会缓存 cacheTTL, 通过 Enforcer 修改授权时自动失效
+type Enforcer struct {
+	store    Store
+	cacheTTL time.Duration
+
+	cache   map[string]*cacheItem
+	cacheMu sync.RWMutex
+}
+
+type cacheItem struct {
+	perms    []Permission
+	expireAt time.Time
+}
+
+type Option func(*Enforcer)
+
+// WithCacheTTL 权限缓存时间, 为 0 时不缓存; 多实例部署时其他实例的修改最多延迟一个 ttl 生效
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(e *Enforcer) {
+		e.cacheTTL = ttl
+	}
+}
+
+func New(store Store, ops ...Option) *Enforcer {
+	e := &Enforcer{
+		store:    store,
+		cacheTTL: DefaultCacheTTL,
+		cache:    make(map[string]*cacheItem),
+	}
+	for i := range ops {
+		ops[i](e)
+	}
+	return e
+}
+
+// CheckPermission 判断用户是否有 resource 上 action 的权限
+func (e *Enforcer) CheckPermission(ctx context.Context, user, resource, action string) (bool, error) {
+	perms, err := e.UserPermissions(ctx, user)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if p.Match(resource, action) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// UserPermissions 用户通过角色获得的所有权限
+func (e *Enforcer) UserPermissions(ctx context.Context, user string) ([]Permission, error) {
+	if e.cacheTTL > 0 {
+		e.cacheMu.RLock()
+		item, ok := e.cache[user]
+		e.cacheMu.RUnlock()
+		if ok && time.Now().Before(item.expireAt) {
+			return item.perms, nil
+		}
+	}
+
+	roles, err := e.store.UserRoles(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	var perms []Permission
+	for _, role := range roles {
+		list, err := e.store.RolePermissions(ctx, role)
+		if err != nil {
+			return nil, err
+		}
+		perms = append(perms, list...)
+	}
+
+	if e.cacheTTL > 0 {
+		e.cacheMu.Lock()
+		e.cache[user] = &cacheItem{perms: perms, expireAt: time.Now().Add(e.cacheTTL)}
+		e.cacheMu.Unlock()
+	}
+	return perms, nil
+}
+
+func (e *Enforcer) AssignRole(ctx context.Context, user, role string) error {
+	defer e.Invalidate(user)
+	return e.store.AssignRole(ctx, user, role)
+}
+
+func (e *Enforcer) UnassignRole(ctx context.Context, user, role string) error {
+	defer e.Invalidate(user)
+	return e.store.UnassignRole(ctx, user, role)
+}
+
+// Grant 给角色授权, 影响所有绑定该角色的用户, 所以清空全部缓存
+func (e *Enforcer) Grant(ctx context.Context, role string, perm Permission) error {
+	defer e.InvalidateAll()
+	return e.store.Grant(ctx, role, perm)
+}
+
+func (e *Enforcer) Revoke(ctx context.Context, role string, perm Permission) error {
+	defer e.InvalidateAll()
+	return e.store.Revoke(ctx, role, perm)
+}
+
+// Invalidate 清除用户的权限缓存
+func (e *Enforcer) Invalidate(user string) {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	delete(e.cache, user)
+}
+
+func (e *Enforcer) InvalidateAll() {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	e.cache = make(map[string]*cacheItem)
+}
@@ -0,0 +1,87 @@
+package rbac
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEnforcer(t *testing.T) {
+	ctx := context.Background()
+	e := New(NewMemoryStore())
+
+	_ = e.Grant(ctx, "admin", Permission{Resource: Wildcard, Action: Wildcard})
+	_ = e.Grant(ctx, "editor", Permission{Resource: "/api/article/*", Action: "POST"})
+	_ = e.Grant(ctx, "editor", Permission{Resource: "/api/article/*", Action: "GET"})
+	_ = e.AssignRole(ctx, "u1", "editor")
+	_ = e.AssignRole(ctx, "root", "admin")
+
+	cases := []struct {
+		user, resource, action string
+		want                   bool
+	}{
+		{"u1", "/api/article/1", "get", true},
+		{"u1", "/api/article/1", "DELETE", false},
+		{"u1", "/api/user/1", "GET", false},
+		{"root", "/api/user/1", "DELETE", true},
+		{"nobody", "/api/article/1", "GET", false},
+	}
+	for _, c := range cases {
+		ok, err := e.CheckPermission(ctx, c.user, c.resource, c.action)
+		if err != nil || ok != c.want {
+			t.Fatalf("%+v got %v %v", c, ok, err)
+		}
+	}
+
+	// 修改授权后缓存失效
+	_ = e.Revoke(ctx, "editor", Permission{Resource: "/api/article/*", Action: "GET"})
+	if ok, _ := e.CheckPermission(ctx, "u1", "/api/article/1", "GET"); ok {
+		t.Fatal("expect revoked")
+	}
+	_ = e.UnassignRole(ctx, "root", "admin")
+	if ok, _ := e.CheckPermission(ctx, "root", "/api/user/1", "GET"); ok {
+		t.Fatal("expect unassigned")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	e := New(NewMemoryStore())
+	_ = e.Grant(ctx, "viewer", Permission{Resource: "/api/*", Action: "GET"})
+	_ = e.Grant(ctx, "viewer", Permission{Resource: "/pb.Order/*", Action: ActionCall})
+	_ = e.AssignRole(ctx, "u1", "viewer")
+
+	h := HttpMiddleware(e, func(r *http.Request) string {
+		return r.Header.Get("X-User")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, c := range []struct {
+		user, method string
+		code         int
+	}{
+		{"", "GET", http.StatusUnauthorized},
+		{"u1", "GET", http.StatusOK},
+		{"u1", "POST", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(c.method, "/api/order", nil)
+		r.Header.Set("X-User", c.user)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != c.code {
+			t.Fatalf("%+v got %d", c, rec.Code)
+		}
+	}
+
+	interceptor := UnaryServerInterceptor(e, func(ctx context.Context) string { return "u1" })
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	if _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/pb.Order/Get"}, handler); err != nil {
+		t.Fatal(err)
+	}
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/pb.User/Get"}, handler)
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expect permission denied, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"github.com/oldbai555/lbtool/pkg/internal/placeholder"
+)
+
+// MysqlSchema SQLStore 使用的表结构
+const MysqlSchema = `
+CREATE TABLE IF NOT EXISTS rbac_user_role (
+	user_id VARCHAR(64) NOT NULL,
+	role VARCHAR(64) NOT NULL,
+	PRIMARY KEY (user_id, role)
+);
+CREATE TABLE IF NOT EXISTS rbac_role_permission (
+	role VARCHAR(64) NOT NULL,
+	resource VARCHAR(255) NOT NULL,
+	action VARCHAR(32) NOT NULL,
+	PRIMARY KEY (role, resource, action)
+);`
+
+var _ Store = (*SQLStore)(nil)
+
+// SQLStore 角色分配和授权保存在两张表中, 每次鉴权都会查库, 高频场景在外层加缓存
+// 默认使用 ? 占位符 (mysql, sqlite)
+type SQLStore struct {
+	db     *sql.DB
+	dollar bool
+}
+
+type SQLOption func(*SQLStore)
+
+// WithDollarPlaceholder postgres 需要 $n 占位符, 建表语句也要按 postgres 的语法调整
+func WithDollarPlaceholder() SQLOption {
+	return func(s *SQLStore) {
+		s.dollar = true
+	}
+}
+
+func NewSQLStore(db *sql.DB, ops ...SQLOption) *SQLStore {
+	s := &SQLStore{db: db}
+	for i := range ops {
+		ops[i](s)
+	}
+	return s
+}
+
+// rebind 需要时把 ? 替换为 $n
+func (s *SQLStore) rebind(query string) string {
+	if s.dollar {
+		query = placeholder.Dollar(query)
+	}
+	return query
+}
+
+func (s *SQLStore) UserRoles(ctx context.Context, user string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT role FROM rbac_user_role WHERE user_id = ?"), user)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err = rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func (s *SQLStore) RolePermissions(ctx context.Context, role string) ([]Permission, error) {
+	rows, err := s.db.QueryContext(ctx, s.rebind("SELECT resource, action FROM rbac_role_permission WHERE role = ?"), role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var perms []Permission
+	for rows.Next() {
+		var p Permission
+		if err = rows.Scan(&p.Resource, &p.Action); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
+
+func (s *SQLStore) AssignRole(ctx context.Context, user, role string) error {
+	// 先删后插, 兼容不同数据库的 upsert 语法
+	return s.exec(ctx,
+		[]string{"DELETE FROM rbac_user_role WHERE user_id = ? AND role = ?", "INSERT INTO rbac_user_role (user_id, role) VALUES (?, ?)"},
+		user, role)
+}
+
+func (s *SQLStore) UnassignRole(ctx context.Context, user, role string) error {
+	return s.exec(ctx, []string{"DELETE FROM rbac_user_role WHERE user_id = ? AND role = ?"}, user, role)
+}
+
+func (s *SQLStore) Grant(ctx context.Context, role string, perm Permission) error {
+	return s.exec(ctx,
+		[]string{"DELETE FROM rbac_role_permission WHERE role = ? AND resource = ? AND action = ?", "INSERT INTO rbac_role_permission (role, resource, action) VALUES (?, ?, ?)"},
+		role, perm.Resource, perm.Action)
+}
+
+func (s *SQLStore) Revoke(ctx context.Context, role string, perm Permission) error {
+	return s.exec(ctx, []string{"DELETE FROM rbac_role_permission WHERE role = ? AND resource = ? AND action = ?"}, role, perm.Resource, perm.Action)
+}
+
+// exec 在一个事务中执行多条语句, 参数相同
+func (s *SQLStore) exec(ctx context.Context, queries []string, args ...interface{}) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, q := range queries {
+		if _, err = tx.ExecContext(ctx, s.rebind(q), args...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+)
+
+// Store 角色权限的存储
+type Store interface {
+	// UserRoles 用户绑定的角色
+	UserRoles(ctx context.Context, user string) ([]string, error)
+	// RolePermissions 角色拥有的权限
+	RolePermissions(ctx context.Context, role string) ([]Permission, error)
+
+	AssignRole(ctx context.Context, user, role string) error
+	UnassignRole(ctx context.Context, user, role string) error
+	Grant(ctx context.Context, role string, perm Permission) error
+	Revoke(ctx context.Context, role string, perm Permission) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore 内存存储, 适合权限固定写在代码里的场景和测试
+type MemoryStore struct {
+	userRoles map[string]map[string]bool
+	rolePerms map[string]map[Permission]bool
+	mu        sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		userRoles: make(map[string]map[string]bool),
+		rolePerms: make(map[string]map[Permission]bool),
+	}
+}
+
+func (m *MemoryStore) UserRoles(ctx context.Context, user string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var roles []string
+	for role := range m.userRoles[user] {
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+func (m *MemoryStore) RolePermissions(ctx context.Context, role string) ([]Permission, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var perms []Permission
+	for perm := range m.rolePerms[role] {
+		perms = append(perms, perm)
+	}
+	return perms, nil
+}
+
+func (m *MemoryStore) AssignRole(ctx context.Context, user, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.userRoles[user] == nil {
+		m.userRoles[user] = make(map[string]bool)
+	}
+	m.userRoles[user][role] = true
+	return nil
+}
+
+func (m *MemoryStore) UnassignRole(ctx context.Context, user, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.userRoles[user], role)
+	return nil
+}
+
+func (m *MemoryStore) Grant(ctx context.Context, role string, perm Permission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rolePerms[role] == nil {
+		m.rolePerms[role] = make(map[Permission]bool)
+	}
+	m.rolePerms[role][perm] = true
+	return nil
+}
+
+func (m *MemoryStore) Revoke(ctx context.Context, role string, perm Permission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rolePerms[role], perm)
+	return nil
+}
@@ -0,0 +1,68 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Publisher 消息投递, nsqsdk 的生产者满足该接口
+type Publisher interface {
+	Pub(topic string, msg interface{}) error
+}
+
+// StepMsg 异步步骤投递的消息, 下游处理完后需要把 StepResult 回传
+type StepMsg struct {
+	SagaId string          `json:"saga_id"`
+	Step   string          `json:"step"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// StepResult 异步步骤的处理结果
+type StepResult struct {
+	SagaId string `json:"saga_id"`
+	Step   string `json:"step"`
+	Err    string `json:"err"`
+}
+
+type sagaIdKey struct{}
+
+// SagaId 步骤执行时 ctx 中带有实例 id
+func SagaId(ctx context.Context) string {
+	id, _ := ctx.Value(sagaIdKey{}).(string)
+	return id
+}
+
+// AsyncStep 构造通过消息队列执行的步骤, build 返回要投递给下游的数据
+func AsyncStep(name, topic string, pub Publisher, build func(ctx context.Context, data *Data) (interface{}, error), compensate StepFunc) Step {
+	return Step{
+		Name:  name,
+		Async: true,
+		Action: func(ctx context.Context, data *Data) error {
+			payload, err := build(ctx, data)
+			if err != nil {
+				return err
+			}
+			buf, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			return pub.Pub(topic, &StepMsg{SagaId: SagaId(ctx), Step: name, Data: buf})
+		},
+		Compensate: compensate,
+	}
+}
+
+// HandleResult 处理下游回传的结果, 在结果队列的消费者中调用
+func (o *Orchestrator) HandleResult(ctx context.Context, body []byte) error {
+	var res StepResult
+	if err := json.Unmarshal(body, &res); err != nil {
+		return err
+	}
+	var stepErr error
+	if res.Err != "" {
+		stepErr = errors.New(res.Err)
+	}
+	_, err := o.Complete(ctx, res.SagaId, res.Step, stepErr)
+	return err
+}
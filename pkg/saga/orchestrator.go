@@ -0,0 +1,244 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/routine"
+	"github.com/oldbai555/lbtool/utils"
+	"sync"
+	"time"
+)
+
+const DefaultRetryInterval = time.Second
+
+var (
+	ErrSagaNotRegistered = errors.New("saga not registered")
+	ErrStepMismatch      = errors.New("step mismatch")
+)
+
+// Orchestrator 编排执行 saga, 每个步骤完成后持久化, 重启后通过 Resume 继续执行
+type Orchestrator struct {
+	store         Store
+	retryInterval time.Duration
+
+	sagas map[string]*Saga
+	// locks 同一个实例同时只能有一个协程推进
+	locks sync.Map
+}
+
+type Option func(*Orchestrator)
+
+// WithStore 默认存在内存, 需要崩溃恢复时使用 RedisStore
+func WithStore(store Store) Option {
+	return func(o *Orchestrator) {
+		o.store = store
+	}
+}
+
+// WithRetryInterval 步骤失败后的重试间隔
+func WithRetryInterval(interval time.Duration) Option {
+	return func(o *Orchestrator) {
+		o.retryInterval = interval
+	}
+}
+
+func NewOrchestrator(ops ...Option) *Orchestrator {
+	o := &Orchestrator{
+		retryInterval: DefaultRetryInterval,
+		sagas:         make(map[string]*Saga),
+	}
+	for i := range ops {
+		ops[i](o)
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore()
+	}
+	return o
+}
+
+// Register 注册流程定义, 需要在 Start 和 Resume 之前调用
+func (o *Orchestrator) Register(sagas ...*Saga) {
+	for _, s := range sagas {
+		o.sagas[s.name] = s
+	}
+}
+
+func (o *Orchestrator) lock(id string) func() {
+	v, _ := o.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// Start 创建实例并执行, 遇到异步步骤时返回, 实例状态为 StatusWaiting
+func (o *Orchestrator) Start(ctx context.Context, sagaName string, init func(data *Data) error) (*Instance, error) {
+	if _, ok := o.sagas[sagaName]; !ok {
+		return nil, ErrSagaNotRegistered
+	}
+	now := time.Now()
+	ins := &Instance{
+		Id:        utils.GenUUID(),
+		Saga:      sagaName,
+		Data:      newData(),
+		Status:    StatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if init != nil {
+		if err := init(ins.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := o.save(ctx, ins); err != nil {
+		return nil, err
+	}
+
+	unlock := o.lock(ins.Id)
+	defer unlock()
+	return ins, o.run(ctx, ins)
+}
+
+// Get 查询实例
+func (o *Orchestrator) Get(ctx context.Context, id string) (*Instance, error) {
+	return o.store.Get(ctx, id)
+}
+
+// Complete 异步步骤的结果回传, 通常在消息队列的消费者中调用; stepErr 不为空时开始补偿
+func (o *Orchestrator) Complete(ctx context.Context, id, step string, stepErr error) (*Instance, error) {
+	unlock := o.lock(id)
+	defer unlock()
+
+	ins, err := o.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := o.sagas[ins.Saga]
+	if !ok {
+		return nil, ErrSagaNotRegistered
+	}
+	// 重复投递的消息直接忽略
+	if ins.Status != StatusWaiting {
+		return ins, nil
+	}
+	if ins.Step >= len(s.steps) || s.steps[ins.Step].Name != step {
+		return nil, fmt.Errorf("%w: expect %d, got %s", ErrStepMismatch, ins.Step, step)
+	}
+
+	if stepErr != nil {
+		ins.Err = stepErr.Error()
+		// 失败的异步步骤可能做了一半, 同样需要补偿
+		ins.Status = StatusCompensating
+	} else {
+		ins.Step++
+		ins.Status = StatusRunning
+	}
+	if err = o.save(ctx, ins); err != nil {
+		return nil, err
+	}
+	return ins, o.run(ctx, ins)
+}
+
+// Resume 恢复所有未结束的实例, 服务启动时调用; 等待异步结果的实例保持等待
+func (o *Orchestrator) Resume(ctx context.Context) error {
+	list, err := o.store.Unfinished(ctx)
+	if err != nil {
+		return err
+	}
+	for _, ins := range list {
+		if ins.Status == StatusWaiting {
+			continue
+		}
+		ins := ins
+		routine.GoV2(func() error {
+			unlock := o.lock(ins.Id)
+			defer unlock()
+			if err := o.run(ctx, ins); err != nil {
+				log.Errorf("resume saga %s err:%v", ins.Id, err)
+			}
+			return nil
+		})
+	}
+	return nil
+}
+
+func (o *Orchestrator) save(ctx context.Context, ins *Instance) error {
+	ins.UpdatedAt = time.Now()
+	return o.store.Save(ctx, ins)
+}
+
+// run 从当前步骤继续推进, 直到结束或等待异步结果; 返回值只代表持久化错误, 流程结果看 ins.Status
+func (o *Orchestrator) run(ctx context.Context, ins *Instance) error {
+	s, ok := o.sagas[ins.Saga]
+	if !ok {
+		return ErrSagaNotRegistered
+	}
+
+	for ins.Status == StatusRunning {
+		if ins.Step >= len(s.steps) {
+			ins.Status = StatusDone
+			return o.save(ctx, ins)
+		}
+		step := s.steps[ins.Step]
+		if err := o.call(ctx, ins.Id, step.Action, ins.Data, step.Retry); err != nil {
+			log.Warnf("saga %s step %s failed, start compensating, err:%v", ins.Id, step.Name, err)
+			ins.Err = err.Error()
+			ins.Status = StatusCompensating
+			// 同步步骤失败时认为没有产生副作用, 从上一步开始补偿
+			ins.Step--
+		} else if step.Async {
+			ins.Status = StatusWaiting
+		} else {
+			ins.Step++
+		}
+		if err := o.save(ctx, ins); err != nil {
+			return err
+		}
+	}
+
+	for ins.Status == StatusCompensating {
+		if ins.Step < 0 {
+			ins.Status = StatusCompensated
+			return o.save(ctx, ins)
+		}
+		step := s.steps[ins.Step]
+		if step.Compensate != nil {
+			if err := o.call(ctx, ins.Id, step.Compensate, ins.Data, step.Retry); err != nil {
+				log.Errorf("saga %s compensate %s failed, err:%v", ins.Id, step.Name, err)
+				ins.Err = fmt.Sprintf("%s; compensate %s: %v", ins.Err, step.Name, err)
+				ins.Status = StatusFailed
+				return o.save(ctx, ins)
+			}
+		}
+		ins.Step--
+		if err := o.save(ctx, ins); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call 执行并按次数重试, panic 视为失败
+func (o *Orchestrator) call(ctx context.Context, id string, fn StepFunc, data *Data, retry int) (err error) {
+	ctx = context.WithValue(ctx, sagaIdKey{}, id)
+	for i := 0; i <= retry; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(o.retryInterval):
+			}
+		}
+		err = func() (err error) {
+			defer routine.CatchPanic(func(p interface{}) {
+				err = fmt.Errorf("panic: %v", p)
+			})
+			return fn(ctx, data)
+		}()
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusRunning      Status = "running"
+	StatusWaiting      Status = "waiting" // 等待异步步骤的结果
+	StatusCompensating Status = "compensating"
+	StatusDone         Status = "done"
+	StatusCompensated  Status = "compensated"
+	// StatusFailed 补偿也失败了, 需要人工介入
+	StatusFailed Status = "failed"
+)
+
+// Finished 是否已经结束
+func (s Status) Finished() bool {
+	return s == StatusDone || s == StatusCompensated || s == StatusFailed
+}
+
+// StepFunc 步骤的执行或补偿逻辑, 崩溃恢复后可能被重复执行, 需要保证幂等
+type StepFunc func(ctx context.Context, data *Data) error
+
+// Step 一个步骤
+type Step struct {
+	Name       string
+	Action     StepFunc
+	Compensate StepFunc
+	// Retry 失败后的重试次数
+	Retry int
+	// Async 为 true 时 Action 只负责投递消息, 结果通过 Orchestrator.Complete 回传
+	Async bool
+}
+
+// Saga 流程定义
+type Saga struct {
+	name  string
+	steps []*Step
+}
+
+func New(name string) *Saga {
+	return &Saga{name: name}
+}
+
+func (s *Saga) Name() string {
+	return s.name
+}
+
+// Step 追加步骤, 按追加顺序执行, 失败时按相反顺序补偿已完成的步骤
+func (s *Saga) Step(step Step) *Saga {
+	st := step
+	s.steps = append(s.steps, &st)
+	return s
+}
+
+// Data 流程中共享的数据, 每个步骤完成后随实例一起持久化
+type Data struct {
+	values map[string]json.RawMessage
+}
+
+func newData() *Data {
+	return &Data{values: make(map[string]json.RawMessage)}
+}
+
+func (d *Data) Set(key string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	d.values[key] = buf
+	return nil
+}
+
+// Get 把值解析到 v 中, 不存在时返回 false
+func (d *Data) Get(key string, v interface{}) (bool, error) {
+	buf, ok := d.values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(buf, v)
+}
+
+func (d *Data) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.values)
+}
+
+func (d *Data) UnmarshalJSON(buf []byte) error {
+	d.values = make(map[string]json.RawMessage)
+	return json.Unmarshal(buf, &d.values)
+}
+
+// Instance 一次流程的执行状态
+type Instance struct {
+	Id   string `json:"id"`
+	Saga string `json:"saga"`
+	Data *Data  `json:"data"`
+	// Step 当前执行到的步骤下标; 补偿时为下一个要补偿的步骤
+	Step      int       `json:"step"`
+	Status    Status    `json:"status"`
+	Err       string    `json:"err"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
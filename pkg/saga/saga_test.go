@@ -0,0 +1,167 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) step(name string, fail bool) Step {
+	return Step{
+		Name: name,
+		Action: func(ctx context.Context, data *Data) error {
+			r.calls = append(r.calls, name)
+			if fail {
+				return fmt.Errorf("%s failed", name)
+			}
+			return data.Set(name, true)
+		},
+		Compensate: func(ctx context.Context, data *Data) error {
+			r.calls = append(r.calls, "undo "+name)
+			return nil
+		},
+	}
+}
+
+func TestOrchestrator_Compensate(t *testing.T) {
+	ctx := context.Background()
+	r := &recorder{}
+	o := NewOrchestrator(WithRetryInterval(time.Millisecond))
+	o.Register(New("order").Step(r.step("reserve", false)).Step(r.step("pay", false)).Step(r.step("ship", true)))
+
+	ins, err := o.Start(ctx, "order", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ins.Status != StatusCompensated || fmt.Sprint(r.calls) != "[reserve pay ship undo pay undo reserve]" {
+		t.Fatalf("unexpected %s %v", ins.Status, r.calls)
+	}
+}
+
+func TestOrchestrator_Retry(t *testing.T) {
+	ctx := context.Background()
+	var n int
+	o := NewOrchestrator(WithRetryInterval(time.Millisecond))
+	o.Register(New("retry").Step(Step{Name: "flaky", Retry: 2, Action: func(ctx context.Context, data *Data) error {
+		n++
+		if n < 3 {
+			return fmt.Errorf("temporary")
+		}
+		return nil
+	}}))
+	ins, err := o.Start(ctx, "retry", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ins.Status != StatusDone || n != 3 {
+		t.Fatalf("unexpected %s %d", ins.Status, n)
+	}
+}
+
+type chanPublisher struct {
+	ch chan []byte
+}
+
+func (p *chanPublisher) Pub(topic string, msg interface{}) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.ch <- buf
+	return nil
+}
+
+func TestOrchestrator_AsyncAndResume(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	pub := &chanPublisher{ch: make(chan []byte, 1)}
+	r := &recorder{}
+	def := New("async").
+		Step(r.step("local", false)).
+		Step(AsyncStep("remote", "remote_topic", pub, func(ctx context.Context, data *Data) (interface{}, error) {
+			return map[string]string{"order": "o1"}, nil
+		}, nil)).
+		Step(r.step("finish", false))
+
+	o := NewOrchestrator(WithStore(store))
+	o.Register(def)
+	ins, err := o.Start(ctx, "async", func(data *Data) error {
+		return data.Set("order", "o1")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ins.Status != StatusWaiting {
+		t.Fatalf("unexpected status %s", ins.Status)
+	}
+
+	var msg StepMsg
+	if err = json.Unmarshal(<-pub.ch, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.SagaId != ins.Id || msg.Step != "remote" {
+		t.Fatalf("unexpected msg %+v", msg)
+	}
+
+	// 模拟重启后由新的 orchestrator 接收结果
+	o2 := NewOrchestrator(WithStore(store))
+	o2.Register(def)
+	if err = o2.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res, _ := json.Marshal(&StepResult{SagaId: msg.SagaId, Step: msg.Step})
+	if err = o2.HandleResult(ctx, res); err != nil {
+		t.Fatal(err)
+	}
+	ins, err = o2.Get(ctx, ins.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order string
+	if _, err = ins.Data.Get("order", &order); err != nil {
+		t.Fatal(err)
+	}
+	if ins.Status != StatusDone || order != "o1" || fmt.Sprint(r.calls) != "[local finish]" {
+		t.Fatalf("unexpected %s %s %v", ins.Status, order, r.calls)
+	}
+
+	// 重复投递的结果被忽略
+	if err = o2.HandleResult(ctx, res); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrchestrator_ResumeRunning(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	r := &recorder{}
+	def := New("resume").Step(r.step("a", false)).Step(r.step("b", false))
+
+	// 模拟执行完第一步后崩溃
+	data := newData()
+	_ = data.Set("a", true)
+	_ = store.Save(ctx, &Instance{Id: "i1", Saga: "resume", Data: data, Step: 1, Status: StatusRunning})
+
+	o := NewOrchestrator(WithStore(store))
+	o.Register(def)
+	if err := o.Resume(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		ins, _ := o.Get(ctx, "i1")
+		if ins.Status == StatusDone {
+			if fmt.Sprint(r.calls) != "[b]" {
+				t.Fatalf("unexpected calls %v", r.calls)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("resume timeout")
+}
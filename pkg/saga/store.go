@@ -0,0 +1,141 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"sync"
+)
+
+var (
+	ErrNotFound = errors.New("saga instance not found")
+)
+
+// Store 实例的持久化
+type Store interface {
+	Save(ctx context.Context, ins *Instance) error
+	Get(ctx context.Context, id string) (*Instance, error)
+	// Unfinished 未结束的实例, 用于重启后恢复
+	Unfinished(ctx context.Context) ([]*Instance, error)
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+type MemoryStore struct {
+	items map[string][]byte
+	mu    sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string][]byte)}
+}
+
+func (m *MemoryStore) Save(ctx context.Context, ins *Instance) error {
+	buf, err := json.Marshal(ins)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[ins.Id] = buf
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Instance, error) {
+	m.mu.Lock()
+	buf, ok := m.items[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var ins Instance
+	return &ins, json.Unmarshal(buf, &ins)
+}
+
+func (m *MemoryStore) Unfinished(ctx context.Context) ([]*Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []*Instance
+	for _, buf := range m.items {
+		var ins Instance
+		if err := json.Unmarshal(buf, &ins); err != nil {
+			return nil, err
+		}
+		if !ins.Status.Finished() {
+			list = append(list, &ins)
+		}
+	}
+	return list, nil
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// RedisStore 实例以 json 存在 string 中, 未结束的实例 id 记录在一个 set 里
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "saga"
+	}
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (r *RedisStore) key(id string) string {
+	return fmt.Sprintf("%s_%s", r.prefix, id)
+}
+
+func (r *RedisStore) unfinishedKey() string {
+	return r.prefix + "_unfinished"
+}
+
+func (r *RedisStore) Save(ctx context.Context, ins *Instance) error {
+	buf, err := json.Marshal(ins)
+	if err != nil {
+		return err
+	}
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, r.key(ins.Id), buf, 0)
+	if ins.Status.Finished() {
+		pipe.SRem(ctx, r.unfinishedKey(), ins.Id)
+	} else {
+		pipe.SAdd(ctx, r.unfinishedKey(), ins.Id)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (r *RedisStore) Get(ctx context.Context, id string) (*Instance, error) {
+	buf, err := r.client.Get(ctx, r.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ins Instance
+	return &ins, json.Unmarshal(buf, &ins)
+}
+
+func (r *RedisStore) Unfinished(ctx context.Context) ([]*Instance, error) {
+	ids, err := r.client.SMembers(ctx, r.unfinishedKey()).Result()
+	if err != nil {
+		return nil, err
+	}
+	var list []*Instance
+	for _, id := range ids {
+		ins, err := r.Get(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, ins)
+	}
+	return list, nil
+}
@@ -0,0 +1,227 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultCookieName = "lb_sid"
+	DefaultTTL        = 2 * time.Hour
+)
+
+// Manager 会话管理, 会话 id 放在 cookie 中, 每次访问都会顺延过期时间
+type Manager struct {
+	store      Store
+	cookieName string
+	ttl        time.Duration
+	path       string
+	domain     string
+	secure     bool
+	sameSite   http.SameSite
+}
+
+type Option func(*Manager)
+
+// WithStore 默认存在内存
+func WithStore(store Store) Option {
+	return func(m *Manager) {
+		m.store = store
+	}
+}
+
+func WithCookieName(name string) Option {
+	return func(m *Manager) {
+		m.cookieName = name
+	}
+}
+
+// WithTTL 空闲多久后会话过期
+func WithTTL(ttl time.Duration) Option {
+	return func(m *Manager) {
+		m.ttl = ttl
+	}
+}
+
+func WithDomain(domain string) Option {
+	return func(m *Manager) {
+		m.domain = domain
+	}
+}
+
+func WithPath(path string) Option {
+	return func(m *Manager) {
+		m.path = path
+	}
+}
+
+// WithSecure cookie 只通过 https 发送
+func WithSecure() Option {
+	return func(m *Manager) {
+		m.secure = true
+	}
+}
+
+func WithSameSite(sameSite http.SameSite) Option {
+	return func(m *Manager) {
+		m.sameSite = sameSite
+	}
+}
+
+func NewManager(ops ...Option) *Manager {
+	m := &Manager{
+		cookieName: DefaultCookieName,
+		ttl:        DefaultTTL,
+		path:       "/",
+		sameSite:   http.SameSiteLaxMode,
+	}
+	for i := range ops {
+		ops[i](m)
+	}
+	if m.store == nil {
+		m.store = NewMemoryStore()
+	}
+	return m
+}
+
+func newId() string {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
+
+// Load 按请求中的 cookie 加载会话, 不存在时创建新会话(保存前不会写存储)
+func (m *Manager) Load(r *http.Request) (*Session, error) {
+	c, err := r.Cookie(m.cookieName)
+	if err != nil || c.Value == "" {
+		return newSession(newId()), nil
+	}
+	data, err := m.store.Get(r.Context(), c.Value)
+	if errors.Is(err, ErrNotFound) {
+		return newSession(newId()), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{id: c.Value}
+	if err = json.Unmarshal(data, &s.values); err != nil {
+		log.Warnf("invalid session data, err:%v", err)
+		return newSession(newId()), nil
+	}
+	return s, nil
+}
+
+// Save 保存会话并设置 cookie, 已存在的会话每次都会保存以顺延过期时间
+func (m *Manager) Save(ctx context.Context, w http.ResponseWriter, s *Session) error {
+	if s.destroyed {
+		if !s.isNew {
+			if err := m.store.Delete(ctx, s.id); err != nil {
+				return err
+			}
+		}
+		if s.oldId != "" {
+			_ = m.store.Delete(ctx, s.oldId)
+		}
+		http.SetCookie(w, m.cookie(s.id, -1))
+		return nil
+	}
+	// 新会话没有写入内容时不保存, 避免爬虫等请求产生大量空会话
+	if s.isNew && !s.dirty {
+		return nil
+	}
+
+	data, err := s.marshal()
+	if err != nil {
+		return err
+	}
+	if err = m.store.Set(ctx, s.Id(), data, m.ttl); err != nil {
+		return err
+	}
+	if s.oldId != "" {
+		if err = m.store.Delete(ctx, s.oldId); err != nil {
+			log.Errorf("err:%v", err)
+		}
+	}
+	http.SetCookie(w, m.cookie(s.Id(), int(m.ttl/time.Second)))
+	return nil
+}
+
+func (m *Manager) cookie(id string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     m.cookieName,
+		Value:    id,
+		Path:     m.path,
+		Domain:   m.domain,
+		MaxAge:   maxAge,
+		Secure:   m.secure,
+		HttpOnly: true,
+		SameSite: m.sameSite,
+	}
+}
+
+type sessionKey struct{}
+
+// FromContext 取出中间件加载的会话, 没有时返回 nil
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// HttpMiddleware 自动加载和保存会话, 会话在响应头写出之前保存
+func (m *Manager) HttpMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := m.Load(r)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		sw := &saveWriter{ResponseWriter: w, save: func() {
+			if err := m.Save(r.Context(), w, s); err != nil {
+				log.Errorf("err:%v", err)
+			}
+		}}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+		sw.saveOnce()
+	})
+}
+
+// saveWriter 第一次写响应前保存会话, 保证 Set-Cookie 能带上
+type saveWriter struct {
+	http.ResponseWriter
+	save  func()
+	saved bool
+}
+
+func (w *saveWriter) saveOnce() {
+	if w.saved {
+		return
+	}
+	w.saved = true
+	w.save()
+}
+
+func (w *saveWriter) WriteHeader(code int) {
+	w.saveOnce()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *saveWriter) Write(b []byte) (int, error) {
+	w.saveOnce()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *saveWriter) Flush() {
+	w.saveOnce()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
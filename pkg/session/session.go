@@ -0,0 +1,114 @@
+package session
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Session 一次会话, 值以 json 保存, 存到 redis 后也能按类型取回
+type Session struct {
+	id     string
+	values map[string]json.RawMessage
+	mu     sync.RWMutex
+
+	isNew     bool
+	dirty     bool
+	destroyed bool
+	// oldId 登录时轮换 id, 保存时删除旧的会话, 防止会话固定攻击
+	oldId string
+}
+
+func newSession(id string) *Session {
+	return &Session{
+		id:     id,
+		values: make(map[string]json.RawMessage),
+		isNew:  true,
+	}
+}
+
+func (s *Session) Id() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.id
+}
+
+// IsNew 本次请求新建的会话
+func (s *Session) IsNew() bool {
+	return s.isNew
+}
+
+// Set 设置值, v 需要能被 json 序列化
+func (s *Session) Set(key string, v interface{}) error {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = buf
+	s.dirty = true
+	return nil
+}
+
+// Get 把值解析到 v 中, 不存在时返回 false
+func (s *Session) Get(key string, v interface{}) (bool, error) {
+	s.mu.RLock()
+	buf, ok := s.values[key]
+	s.mu.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(buf, v)
+}
+
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.dirty = true
+	}
+}
+
+// Clear 清空所有值, 保留会话 id
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]json.RawMessage)
+	s.dirty = true
+}
+
+// Destroy 销毁会话, 响应时删除存储并清除 cookie, 用于退出登录
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]json.RawMessage)
+	s.destroyed = true
+}
+
+// Rotate 更换会话 id 并保留数据, 登录成功或权限变化时调用
+func (s *Session) Rotate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isNew && s.oldId == "" {
+		s.oldId = s.id
+	}
+	s.id = newId()
+	s.dirty = true
+}
+
+func (s *Session) marshal() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return json.Marshal(s.values)
+}
+
+// Get 按类型取值, 不存在或类型不匹配时返回零值和 false
+func Get[T any](s *Session, key string) (T, bool) {
+	var v T
+	ok, err := s.Get(key, &v)
+	if err != nil {
+		return v, false
+	}
+	return v, ok
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type user struct {
+	Id   uint64 `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestHttpMiddleware(t *testing.T) {
+	m := NewManager()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		s := FromContext(r.Context())
+		s.Rotate()
+		_ = s.Set("user", &user{Id: 1, Name: "lb"})
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		u, ok := Get[user](FromContext(r.Context()), "user")
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(u.Name))
+	})
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Destroy()
+	})
+	h := m.HttpMiddleware(mux)
+
+	do := func(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec
+	}
+	sid := func(rec *httptest.ResponseRecorder) *http.Cookie {
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == DefaultCookieName {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// 未登录时不产生会话
+	rec := do("/me", nil)
+	if rec.Code != http.StatusUnauthorized || sid(rec) != nil {
+		t.Fatalf("unexpected %d %v", rec.Code, sid(rec))
+	}
+
+	// 匿名会话登录后 id 会轮换
+	anon := &http.Cookie{Name: DefaultCookieName, Value: "anon"}
+	_ = m.store.Set(context.Background(), "anon", []byte(`{}`), DefaultTTL)
+	rec = do("/login", anon)
+	c := sid(rec)
+	if c == nil || c.Value == "anon" || !c.HttpOnly {
+		t.Fatalf("unexpected cookie %v", c)
+	}
+	if _, err := m.store.Get(context.Background(), "anon"); err != ErrNotFound {
+		t.Fatalf("old session should be deleted, got %v", err)
+	}
+
+	rec = do("/me", c)
+	if rec.Body.String() != "lb" {
+		t.Fatalf("unexpected body %s", rec.Body.String())
+	}
+	// 访问时顺延过期时间
+	if sid(rec) == nil || sid(rec).MaxAge != int(DefaultTTL.Seconds()) {
+		t.Fatalf("expect sliding expiration cookie")
+	}
+
+	rec = do("/logout", c)
+	if sid(rec) == nil || sid(rec).MaxAge >= 0 {
+		t.Fatalf("expect cookie cleared")
+	}
+	if rec = do("/me", c); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expect unauthorized after logout, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,97 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"github.com/oldbai555/lbtool/pkg/internal/ttlmap"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("session not found")
+)
+
+// Store 会话存储, data 为序列化后的会话内容
+type Store interface {
+	Get(ctx context.Context, id string) ([]byte, error)
+	Set(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// MemoryStore 单机存储, 重启后会话全部失效, 多实例部署时使用 RedisStore
+type MemoryStore struct {
+	items *ttlmap.Map[[]byte]
+	mu    sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		items: ttlmap.New[[]byte](),
+	}
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.items.Get(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func (m *MemoryStore) Set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items.Set(id, data, ttl)
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items.Delete(id)
+	return nil
+}
+
+var _ Store = (*RedisStore)(nil)
+
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "session"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *RedisStore) key(id string) string {
+	return fmt.Sprintf("%s_%s", r.prefix, id)
+}
+
+func (r *RedisStore) Get(ctx context.Context, id string) ([]byte, error) {
+	data, err := r.client.Get(ctx, r.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (r *RedisStore) Set(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.key(id), data, ttl).Err()
+}
+
+func (r *RedisStore) Delete(ctx context.Context, id string) error {
+	return r.client.Del(ctx, r.key(id)).Err()
+}
@@ -0,0 +1,140 @@
+package sms
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// https://help.aliyun.com/document_detail/419273.html 阿里云短信 SendSms
+
+const (
+	aliyunEndpoint = "https://dysmsapi.aliyuncs.com/"
+	aliyunVersion  = "2017-05-25"
+)
+
+var _ Provider = (*AliyunProvider)(nil)
+
+// AliyunConfig 阿里云短信配置, 请使用子账户凭据
+type AliyunConfig struct {
+	AccessKeyId     string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	SignName        string `json:"sign_name"`
+	RegionId        string `json:"region_id"`
+	// Endpoint 为空时使用默认地址
+	Endpoint string `json:"endpoint"`
+}
+
+type AliyunProvider struct {
+	conf   AliyunConfig
+	client *http.Client
+}
+
+func NewAliyunProvider(conf AliyunConfig) *AliyunProvider {
+	if conf.RegionId == "" {
+		conf.RegionId = "cn-hangzhou"
+	}
+	if conf.Endpoint == "" {
+		conf.Endpoint = aliyunEndpoint
+	}
+	return &AliyunProvider{
+		conf:   conf,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *AliyunProvider) Name() string {
+	return "aliyun"
+}
+
+type aliyunRsp struct {
+	Code      string `json:"Code"`
+	Message   string `json:"Message"`
+	RequestId string `json:"RequestId"`
+	BizId     string `json:"BizId"`
+}
+
+func (p *AliyunProvider) Send(ctx context.Context, phone, templateId string, params map[string]string) error {
+	templateParam, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	query := map[string]string{
+		"Action":           "SendSms",
+		"Version":          aliyunVersion,
+		"Format":           "JSON",
+		"RegionId":         p.conf.RegionId,
+		"AccessKeyId":      p.conf.AccessKeyId,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureVersion": "1.0",
+		"SignatureNonce":   utils.GenUUID(),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"PhoneNumbers":     phone,
+		"SignName":         p.conf.SignName,
+		"TemplateCode":     templateId,
+		"TemplateParam":    string(templateParam),
+	}
+	canonical := aliyunCanonicalQuery(query)
+	signature := aliyunSign(http.MethodGet, canonical, p.conf.AccessKeySecret)
+	reqUrl := fmt.Sprintf("%s?Signature=%s&%s", p.conf.Endpoint, aliyunPercentEncode(signature), canonical)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqUrl, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rsp aliyunRsp
+	if err = json.NewDecoder(resp.Body).Decode(&rsp); err != nil {
+		return fmt.Errorf("decode aliyun rsp err:%v, status %d", err, resp.StatusCode)
+	}
+	if rsp.Code != "OK" {
+		return fmt.Errorf("aliyun sms failed, code %s, msg %s, request id %s", rsp.Code, rsp.Message, rsp.RequestId)
+	}
+	return nil
+}
+
+// aliyunCanonicalQuery 参数按 key 排序后编码
+func aliyunCanonicalQuery(query map[string]string) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]string, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, aliyunPercentEncode(k)+"="+aliyunPercentEncode(query[k]))
+	}
+	return strings.Join(list, "&")
+}
+
+// aliyunSign 签名算法 v1: base64(hmac-sha1(secret&, METHOD&%2F&encode(query)))
+func aliyunSign(method, canonicalQuery, secret string) string {
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(canonicalQuery)
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunPercentEncode 阿里云要求的 url 编码, 空格编码为 %20, ~ 不编码
+func aliyunPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	s = strings.ReplaceAll(s, "%7E", "~")
+	return s
+}
@@ -0,0 +1,149 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"sync"
+	"time"
+)
+
+// Limiter 按手机号限流
+type Limiter interface {
+	// Allow 允许发送时返回 true, 同时记录一次发送
+	Allow(ctx context.Context, phone string) (bool, error)
+}
+
+// LimitRule 限流规则, 值为 0 表示不限制
+type LimitRule struct {
+	// MinInterval 同一手机号两次发送的最小间隔
+	MinInterval time.Duration `json:"min_interval"`
+	// MaxPerHour 每小时最多发送次数
+	MaxPerHour int `json:"max_per_hour"`
+	// MaxPerDay 每天最多发送次数
+	MaxPerDay int `json:"max_per_day"`
+}
+
+// DefaultLimitRule 常见的验证码限流规则
+var DefaultLimitRule = LimitRule{
+	MinInterval: time.Minute,
+	MaxPerHour:  5,
+	MaxPerDay:   10,
+}
+
+var _ Limiter = (*MemoryLimiter)(nil)
+
+// MemoryLimiter 单机限流, 多实例部署时使用 RedisLimiter
+type MemoryLimiter struct {
+	rule    LimitRule
+	history map[string][]time.Time
+	mu      sync.Mutex
+}
+
+func NewMemoryLimiter(rule LimitRule) *MemoryLimiter {
+	return &MemoryLimiter{
+		rule:    rule,
+		history: make(map[string][]time.Time),
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, phone string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	// 只保留一天内的记录
+	var list []time.Time
+	for _, t := range l.history[phone] {
+		if now.Sub(t) < 24*time.Hour {
+			list = append(list, t)
+		}
+	}
+
+	if l.rule.MinInterval > 0 && len(list) > 0 && now.Sub(list[len(list)-1]) < l.rule.MinInterval {
+		l.history[phone] = list
+		return false, nil
+	}
+	if l.rule.MaxPerDay > 0 && len(list) >= l.rule.MaxPerDay {
+		l.history[phone] = list
+		return false, nil
+	}
+	if l.rule.MaxPerHour > 0 {
+		var hourCnt int
+		for _, t := range list {
+			if now.Sub(t) < time.Hour {
+				hourCnt++
+			}
+		}
+		if hourCnt >= l.rule.MaxPerHour {
+			l.history[phone] = list
+			return false, nil
+		}
+	}
+
+	l.history[phone] = append(list, now)
+	return true, nil
+}
+
+// allowScript 所有规则都通过后才计数, 被拒绝的请求不占用次数
+var allowScript = redis.NewScript(`
+local interval, maxHour, maxDay = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+if interval > 0 and redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+if maxHour > 0 and tonumber(redis.call("GET", KEYS[2]) or "0") >= maxHour then
+	return 0
+end
+if maxDay > 0 and tonumber(redis.call("GET", KEYS[3]) or "0") >= maxDay then
+	return 0
+end
+if interval > 0 then
+	redis.call("SET", KEYS[1], 1, "PX", interval)
+end
+if maxHour > 0 then
+	redis.call("INCR", KEYS[2])
+	redis.call("PEXPIRE", KEYS[2], ARGV[4])
+end
+if maxDay > 0 then
+	redis.call("INCR", KEYS[3])
+	redis.call("PEXPIRE", KEYS[3], ARGV[5])
+end
+return 1`)
+
+var _ Limiter = (*RedisLimiter)(nil)
+
+// RedisLimiter 基于 redis 的分布式限流, 小时和天按自然时间段计数
+type RedisLimiter struct {
+	rule   LimitRule
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisLimiter(client redis.UniversalClient, prefix string, rule LimitRule) *RedisLimiter {
+	if prefix == "" {
+		prefix = "sms_limit"
+	}
+	return &RedisLimiter{
+		rule:   rule,
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, phone string) (bool, error) {
+	now := time.Now()
+	// hash tag 保证同一个手机号的 key 在 redis cluster 的同一个 slot, 脚本才能一起操作
+	keys := []string{
+		fmt.Sprintf("%s_interval_{%s}", l.prefix, phone),
+		fmt.Sprintf("%s_hour_%s_{%s}", l.prefix, now.Format("2006010215"), phone),
+		fmt.Sprintf("%s_day_%s_{%s}", l.prefix, now.Format("20060102"), phone),
+	}
+	res, err := allowScript.Run(ctx, l.client, keys,
+		l.rule.MinInterval.Milliseconds(), l.rule.MaxPerHour, l.rule.MaxPerDay,
+		time.Hour.Milliseconds(), (24 * time.Hour).Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
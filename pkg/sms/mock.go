@@ -0,0 +1,58 @@
+package sms
+
+import (
+	"context"
+	"sync"
+)
+
+var _ Provider = (*MockProvider)(nil)
+
+// MockMsg mock 服务商记录的短信
+type MockMsg struct {
+	Phone      string
+	TemplateId string
+	Params     map[string]string
+}
+
+// MockProvider 测试用, 只记录不发送
+type MockProvider struct {
+	// Err 不为空时 Send 直接返回该错误
+	Err error
+
+	msgs []*MockMsg
+	mu   sync.Mutex
+}
+
+func NewMockProvider() *MockProvider {
+	return &MockProvider{}
+}
+
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+func (p *MockProvider) Send(ctx context.Context, phone, templateId string, params map[string]string) error {
+	if p.Err != nil {
+		return p.Err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, &MockMsg{Phone: phone, TemplateId: templateId, Params: params})
+	return nil
+}
+
+// Sent 已发送的短信
+func (p *MockProvider) Sent() []*MockMsg {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	list := make([]*MockMsg, len(p.msgs))
+	copy(list, p.msgs)
+	return list
+}
+
+// Reset 清空记录
+func (p *MockProvider) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = nil
+}
@@ -0,0 +1,64 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+)
+
+var (
+	ErrRateLimited  = errors.New("sms rate limited")
+	ErrInvalidPhone = errors.New("invalid phone number")
+)
+
+// Provider 短信服务商
+// params 为模板参数, 腾讯云模板参数是有序的, key 按 "1","2"... 的数字顺序填写
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, phone, templateId string, params map[string]string) error
+}
+
+// Client 在服务商之上增加按手机号限流
+type Client struct {
+	provider Provider
+	limiter  Limiter
+}
+
+type Option func(*Client)
+
+// WithLimiter 设置限流器, 不设置时不限流
+func WithLimiter(l Limiter) Option {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+func NewClient(provider Provider, opts ...Option) *Client {
+	c := &Client{provider: provider}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) Send(ctx context.Context, phone, templateId string, params map[string]string) error {
+	if phone == "" {
+		return ErrInvalidPhone
+	}
+	if c.limiter != nil {
+		ok, err := c.limiter.Allow(ctx, phone)
+		if err != nil {
+			log.Errorf("err:%v", err)
+			return err
+		}
+		if !ok {
+			log.Warnf("sms to %s rate limited", phone)
+			return ErrRateLimited
+		}
+	}
+	if err := c.provider.Send(ctx, phone, templateId, params); err != nil {
+		log.Errorf("%s send sms to %s err:%v", c.provider.Name(), phone, err)
+		return err
+	}
+	return nil
+}
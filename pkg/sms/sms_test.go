@@ -0,0 +1,78 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_Send(t *testing.T) {
+	mock := NewMockProvider()
+	c := NewClient(mock, WithLimiter(NewMemoryLimiter(LimitRule{MinInterval: time.Hour})))
+	ctx := context.Background()
+
+	if err := c.Send(ctx, "13800000000", "SMS_1", map[string]string{"code": "1234"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(ctx, "13800000000", "SMS_1", map[string]string{"code": "5678"}); err != ErrRateLimited {
+		t.Fatalf("want ErrRateLimited, got %v", err)
+	}
+	if err := c.Send(ctx, "13800000001", "SMS_1", map[string]string{"code": "5678"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mock.Sent()) != 2 {
+		t.Fatalf("sent %d", len(mock.Sent()))
+	}
+}
+
+func TestMemoryLimiter_MaxPerHour(t *testing.T) {
+	l := NewMemoryLimiter(LimitRule{MaxPerHour: 2})
+	for i, want := range []bool{true, true, false} {
+		ok, _ := l.Allow(context.Background(), "13800000000")
+		if ok != want {
+			t.Fatalf("%d: got %v", i, ok)
+		}
+	}
+}
+
+func TestAliyunProvider_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("Signature") == "" || q.Get("TemplateParam") != `{"code":"1234"}` || q.Get("PhoneNumbers") != "13800000000" {
+			t.Errorf("bad query %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(`{"Code":"OK","Message":"OK","RequestId":"1"}`))
+	}))
+	defer srv.Close()
+
+	p := NewAliyunProvider(AliyunConfig{AccessKeyId: "ak", AccessKeySecret: "sk", SignName: "lb", Endpoint: srv.URL + "/"})
+	if err := p.Send(context.Background(), "13800000000", "SMS_1", map[string]string{"code": "1234"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTencentProvider_Send(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "TC3-HMAC-SHA256 Credential=id/") {
+			t.Errorf("bad authorization %s", r.Header.Get("Authorization"))
+		}
+		_, _ = w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"LimitExceeded.PhoneNumberDailyLimit","Message":"limit"}],"RequestId":"1"}}`))
+	}))
+	defer srv.Close()
+
+	p := NewTencentProvider(TencentConfig{SecretId: "id", SecretKey: "key", SdkAppId: "1400000000", SignName: "lb", Endpoint: srv.URL})
+	err := p.Send(context.Background(), "13800000000", "1", map[string]string{"2": "5", "1": "1234"})
+	if err == nil || !strings.Contains(err.Error(), "PhoneNumberDailyLimit") {
+		t.Fatalf("want limit err, got %v", err)
+	}
+}
+
+func TestTencentParams(t *testing.T) {
+	got := tencentParams(map[string]string{"10": "c", "2": "b", "1": "a"})
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Fatalf("got %v", got)
+	}
+}
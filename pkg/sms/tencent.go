@@ -0,0 +1,191 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/oldbai555/lbtool/utils"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// https://cloud.tencent.com/document/product/382/55981 腾讯云短信 SendSms
+
+const (
+	tencentHost    = "sms.tencentcloudapi.com"
+	tencentService = "sms"
+	tencentVersion = "2021-01-11"
+	tencentAlgo    = "TC3-HMAC-SHA256"
+	tencentCt      = "application/json; charset=utf-8"
+)
+
+var _ Provider = (*TencentProvider)(nil)
+
+// TencentConfig 腾讯云短信配置
+type TencentConfig struct {
+	SecretId  string `json:"secret_id"`
+	SecretKey string `json:"secret_key"`
+	SdkAppId  string `json:"sdk_app_id"`
+	SignName  string `json:"sign_name"`
+	Region    string `json:"region"`
+	// Endpoint 为空时使用 https://sms.tencentcloudapi.com
+	Endpoint string `json:"endpoint"`
+}
+
+type TencentProvider struct {
+	conf   TencentConfig
+	client *http.Client
+}
+
+func NewTencentProvider(conf TencentConfig) *TencentProvider {
+	if conf.Region == "" {
+		conf.Region = "ap-guangzhou"
+	}
+	if conf.Endpoint == "" {
+		conf.Endpoint = "https://" + tencentHost
+	}
+	return &TencentProvider{
+		conf:   conf,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *TencentProvider) Name() string {
+	return "tencent"
+}
+
+type tencentReq struct {
+	PhoneNumberSet   []string `json:"PhoneNumberSet"`
+	SmsSdkAppId      string   `json:"SmsSdkAppId"`
+	SignName         string   `json:"SignName"`
+	TemplateId       string   `json:"TemplateId"`
+	TemplateParamSet []string `json:"TemplateParamSet"`
+}
+
+type tencentRsp struct {
+	Response struct {
+		SendStatusSet []struct {
+			Code        string `json:"Code"`
+			Message     string `json:"Message"`
+			PhoneNumber string `json:"PhoneNumber"`
+		} `json:"SendStatusSet"`
+		Error *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+		RequestId string `json:"RequestId"`
+	} `json:"Response"`
+}
+
+func (p *TencentProvider) Send(ctx context.Context, phone, templateId string, params map[string]string) error {
+	// 国内手机号需要带上 +86
+	if !strings.HasPrefix(phone, "+") {
+		phone = "+86" + phone
+	}
+	payload, err := json.Marshal(&tencentReq{
+		PhoneNumberSet:   []string{phone},
+		SmsSdkAppId:      p.conf.SdkAppId,
+		SignName:         p.conf.SignName,
+		TemplateId:       templateId,
+		TemplateParamSet: tencentParams(params),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.conf.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", tencentCt)
+	req.Header.Set("X-TC-Action", "SendSms")
+	req.Header.Set("X-TC-Version", tencentVersion)
+	req.Header.Set("X-TC-Region", p.conf.Region)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("Authorization", tencentAuthorization(p.conf.SecretId, p.conf.SecretKey, now, payload))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var rsp tencentRsp
+	if err = json.NewDecoder(resp.Body).Decode(&rsp); err != nil {
+		return fmt.Errorf("decode tencent rsp err:%v, status %d", err, resp.StatusCode)
+	}
+	if rsp.Response.Error != nil {
+		return fmt.Errorf("tencent sms failed, code %s, msg %s, request id %s", rsp.Response.Error.Code, rsp.Response.Error.Message, rsp.Response.RequestId)
+	}
+	for _, status := range rsp.Response.SendStatusSet {
+		if status.Code != "Ok" {
+			return fmt.Errorf("tencent sms failed, code %s, msg %s, request id %s", status.Code, status.Message, rsp.Response.RequestId)
+		}
+	}
+	return nil
+}
+
+// tencentParams 模板参数是有序的, key 按数字排序, 非数字的 key 排在最后
+func tencentParams(params map[string]string) []string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, aErr := strconv.Atoi(keys[i])
+		b, bErr := strconv.Atoi(keys[j])
+		if aErr == nil && bErr == nil {
+			return a < b
+		}
+		if aErr == nil || bErr == nil {
+			return aErr == nil
+		}
+		return keys[i] < keys[j]
+	})
+	list := make([]string, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, params[k])
+	}
+	return list
+}
+
+// tencentAuthorization 签名方法 v3
+func tencentAuthorization(secretId, secretKey string, now time.Time, payload []byte) string {
+	date := now.UTC().Format("2006-01-02")
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		"content-type:" + tencentCt + "\nhost:" + tencentHost + "\n",
+		"content-type;host",
+		utils.StrSha256(string(payload)),
+	}, "\n")
+	credentialScope := date + "/" + tencentService + "/tc3_request"
+	stringToSign := strings.Join([]string{
+		tencentAlgo,
+		strconv.FormatInt(now.Unix(), 10),
+		credentialScope,
+		utils.StrSha256(canonicalRequest),
+	}, "\n")
+
+	secretDate := hmacSha256([]byte("TC3"+secretKey), date)
+	secretService := hmacSha256(secretDate, tencentService)
+	secretSigning := hmacSha256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSha256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s", tencentAlgo, secretId, credentialScope, signature)
+}
+
+func hmacSha256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
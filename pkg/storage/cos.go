@@ -164,6 +164,50 @@ func (o COSStorage) PutFromFile(objectKey string, filePath string) (err error) {
 	return
 }
 
+func (o COSStorage) PutMultipart(objectKey string, filePath string, partSize int64) (err error) {
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	// cos 的分片大小单位是 MB
+	partSizeMB := partSize / utils.UnitMB
+	if partSizeMB <= 0 {
+		partSizeMB = 1
+	}
+	_, _, err = o.Client.Object.Upload(context.Background(), objectKey, filePath, &cos.MultiUploadOptions{
+		PartSize:       partSizeMB,
+		ThreadPoolSize: 3,
+		CheckPoint:     true,
+	})
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("Upload failed,err is %v", err))
+		return
+	}
+
+	return
+}
+
+func (o COSStorage) List(prefix string, maxKeys int) (objects []*ObjectInfo, err error) {
+	result, _, err := o.Client.Bucket.Get(context.Background(), &cos.BucketGetOptions{
+		Prefix:  prefix,
+		MaxKeys: maxKeys,
+	})
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("Bucket.Get failed,err is %v", err))
+		return
+	}
+
+	for _, object := range result.Contents {
+		lastModified, _ := time.Parse(time.RFC3339, object.LastModified)
+		objects = append(objects, &ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			ETag:         object.ETag,
+			LastModified: lastModified,
+		})
+	}
+	return
+}
+
 func (o COSStorage) Delete(objectKeys ...string) (deletedObjects []string, err error) {
 	objects := make([]cos.Object, 0)
 	for _, key := range objectKeys {
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"github.com/gogf/gf/crypto/gsha1"
 	"github.com/gogf/gf/os/gfile"
@@ -47,9 +48,11 @@ func (o LocalStorage) CheckSignedURL(signedURL string, method string, expireAt i
 
 	fileURL.RawQuery = fmt.Sprintf("expire_at=%d", expireAt)
 
-	signData := fmt.Sprintf("path=%s;method=%s;query=%s;sign_key=%s", fileURL.Path, method, fileURL.RawQuery, "sing_key")
-	log.Infof("signData %v", signData)
-	if signature != gsha1.Encrypt(signData) {
+	expected, err := o.sign(fileURL, method)
+	if err != nil {
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(expected)) != 1 {
 		err = lberr.NewErr(lberr.ErrStorageOptErr, "invalid_sign_error")
 		return
 	}
@@ -63,9 +66,9 @@ func (o LocalStorage) SignURL(objectKey string, method utils.HTTPMethod, expired
 		return
 	}
 
-	fileURL, err := url.Parse("your file url")
-	if err != nil {
-		err = errors.Wrap(err, "invalid MainApp URL")
+	fileURL, err := url.Parse(o.Config.CdnURL)
+	if err != nil || fileURL.Host == "" {
+		err = errors.Errorf("invalid CdnURL %q", o.Config.CdnURL)
 		return
 	}
 
@@ -73,15 +76,25 @@ func (o LocalStorage) SignURL(objectKey string, method utils.HTTPMethod, expired
 
 	fileURL.RawQuery = fmt.Sprintf("expire_at=%d", time.Now().Unix()+expiredInSec)
 
-	signData := fmt.Sprintf("path=%s;method=%s;query=%s;sign_key=%s", fileURL.Path, method, fileURL.RawQuery, "your sign_key")
-	log.Debugf("signData,%s", signData)
-	signature := gsha1.Encrypt(signData)
+	signature, err := o.sign(fileURL, string(method))
+	if err != nil {
+		return
+	}
 	fileURL.RawQuery += fmt.Sprintf("&signature=%s", signature)
 	signedURL = fileURL.String()
 
 	return
 }
 
+// sign 签名内容包含密钥, 不要打到日志里
+func (o LocalStorage) sign(fileURL *url.URL, method string) (string, error) {
+	if o.Config.SignKey == "" {
+		return "", errors.New("SignKey is empty")
+	}
+	signData := fmt.Sprintf("path=%s;method=%s;query=%s;sign_key=%s", fileURL.Path, method, fileURL.RawQuery, o.Config.SignKey)
+	return gsha1.Encrypt(signData), nil
+}
+
 func (o LocalStorage) Get(objectKey string) (content io.ReadCloser, err error) {
 	filePath, err := o.AbsPath(objectKey)
 	if err != nil {
@@ -221,3 +234,103 @@ func (o LocalStorage) IsFilePathContains(targetPath string, parentPath string) b
 	parentPath = filepath.Clean(parentPath)
 	return strings.HasPrefix(targetPath, parentPath)
 }
+
+// PutMultipart 本地存储没有分片的概念, 直接流式拷贝文件, 避免大文件整个读进内存
+func (o LocalStorage) PutMultipart(objectKey string, filePath string, partSize int64) (err error) {
+	dstPath, err := o.AbsPath(objectKey)
+	if err != nil {
+		err = errors.Wrap(err, "AbsPath failed")
+		return
+	}
+
+	src, err := os.Open(filePath)
+	if err != nil {
+		err = errors.Wrap(err, "os.Open failed")
+		return
+	}
+	defer src.Close()
+
+	err = os.MkdirAll(filepath.Dir(dstPath), os.ModePerm)
+	if err != nil {
+		err = errors.Wrap(err, "os.MkdirAll failed")
+		return
+	}
+
+	// 先写临时文件再重命名, 避免读到写了一半的文件
+	tmpPath := dstPath + ".tmp"
+	dst, err := os.Create(tmpPath)
+	if err != nil {
+		err = errors.Wrap(err, "os.Create failed")
+		return
+	}
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	_, err = io.CopyBuffer(dst, src, make([]byte, partSize))
+	closeErr := dst.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		err = errors.Wrap(err, "copy file failed")
+		return
+	}
+
+	err = os.Rename(tmpPath, dstPath)
+	if err != nil {
+		err = errors.Wrap(err, "os.Rename failed")
+		return
+	}
+
+	return
+}
+
+// errStopWalk 数量达到 maxKeys 后提前结束遍历
+var errStopWalk = errors.New("stop walk")
+
+func (o LocalStorage) List(prefix string, maxKeys int) (objects []*ObjectInfo, err error) {
+	root := o.Config.LocalRootPath
+	err = filepath.Walk(root, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return relErr
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		if maxKeys > 0 && len(objects) >= maxKeys {
+			return errStopWalk
+		}
+		objects = append(objects, &ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err == errStopWalk {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, "filepath.Walk failed")
+		return
+	}
+
+	return
+}
+
+func (o LocalStorage) GetCredentials() (*Credentials, error) {
+	return nil, errors.New("local storage not support credentials")
+}
+
+func (o LocalStorage) GetSignature(httpMethod, name, ak, sk string, expired time.Duration) string {
+	return ""
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/oldbai555/lbtool/utils"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestLocalStorage_PutMultipartAndList(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocalStorage(Config{
+		LocalRootPath:  filepath.Join(dir, "root"),
+		ServerRootPath: "/static",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := filepath.Join(dir, "a.txt")
+	if err = os.WriteFile(src, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"img/a.txt", "img/b.txt", "doc/c.txt"} {
+		if err = s.PutMultipart(key, src, 4); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	objects, err := s.List("img/", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Key != "img/a.txt" || objects[0].Size != 11 {
+		t.Fatalf("unexpected objects %+v", objects)
+	}
+
+	objects, err = s.List("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 {
+		t.Fatalf("expect 1 object, got %d", len(objects))
+	}
+}
+
+func TestLocalStorage_SignURL(t *testing.T) {
+	conf := Config{
+		Type:           "local",
+		CdnURL:         "https://file.example.com",
+		LocalRootPath:  t.TempDir(),
+		ServerRootPath: "/static",
+		SignKey:        "key",
+	}
+	if err := validator.New().Struct(conf); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewLocalStorage(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := s.SignURL("img/a.png", utils.HTTPGet, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "file.example.com" || u.Path != "/static/img/a.png" {
+		t.Fatalf("got %s", signed)
+	}
+	expireAt, _ := strconv.ParseInt(u.Query().Get("expire_at"), 10, 64)
+	sig := u.Query().Get("signature")
+	if err = s.CheckSignedURL(signed, string(utils.HTTPGet), expireAt, sig); err != nil {
+		t.Fatal(err)
+	}
+
+	s.Config.SignKey = "other"
+	if err = s.CheckSignedURL(signed, string(utils.HTTPGet), expireAt, sig); err == nil {
+		t.Fatal("expect invalid sign")
+	}
+
+	conf.SignKey = ""
+	if err = validator.New().Struct(conf); err == nil {
+		t.Fatal("expect SignKey required")
+	}
+	if err = validator.New().Struct(Config{Type: "s3"}); err == nil {
+		t.Fatal("expect s3 fields required")
+	}
+}
@@ -122,6 +122,42 @@ func (o OSSStorage) PutFromFile(objectKey string, filePath string) (err error) {
 	return
 }
 
+func (o OSSStorage) PutMultipart(objectKey string, filePath string, partSize int64) (err error) {
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+	// UploadFile 内部按分片并发上传, 开启断点续传后失败重试时只上传未完成的分片
+	err = o.Bucket.UploadFile(objectKey, filePath, partSize, oss.Routines(3), oss.Checkpoint(true, ""))
+	if err != nil {
+		err = errors.Wrap(err, "UploadFile failed")
+		return
+	}
+
+	return
+}
+
+func (o OSSStorage) List(prefix string, maxKeys int) (objects []*ObjectInfo, err error) {
+	options := []oss.Option{oss.Prefix(prefix)}
+	if maxKeys > 0 {
+		options = append(options, oss.MaxKeys(maxKeys))
+	}
+	result, err := o.Bucket.ListObjectsV2(options...)
+	if err != nil {
+		err = errors.Wrap(err, "ListObjectsV2 failed")
+		return
+	}
+
+	for _, object := range result.Objects {
+		objects = append(objects, &ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			ETag:         object.ETag,
+			LastModified: object.LastModified,
+		})
+	}
+	return
+}
+
 func (o OSSStorage) Delete(objectKeys ...string) (deletedObjects []string, err error) {
 	result, err := o.Bucket.DeleteObjects(objectKeys)
 	if err != nil {
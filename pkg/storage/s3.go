@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/lberr"
+	"github.com/oldbai555/lbtool/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// S3Storage 兼容 S3 协议的存储, aws s3, minio, 以及各云厂商的 s3 兼容接口都可以使用
+type S3Storage struct {
+	Client *minio.Client
+	Config Config
+}
+
+func NewS3(conf Config) (storage S3Storage, err error) {
+	storage.Client, err = minio.New(conf.EndPoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.AccessKeyId, conf.AccessKeySecret, ""),
+		Secure: conf.UseSSL,
+		Region: conf.Region,
+	})
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("minio.New failed,err is %v", err))
+		return
+	}
+
+	storage.Config = conf
+
+	return
+}
+
+func (o S3Storage) SignURL(objectKey string, method utils.HTTPMethod, expiredInSec int64) (signedURL string, err error) {
+	var u *url.URL
+	expires := time.Duration(expiredInSec) * time.Second
+	switch method {
+	case utils.HTTPGet:
+		u, err = o.Client.PresignedGetObject(context.Background(), o.Config.Bucket, objectKey, expires, nil)
+	case utils.HTTPPut:
+		u, err = o.Client.PresignedPutObject(context.Background(), o.Config.Bucket, objectKey, expires)
+	case utils.HTTPHead:
+		u, err = o.Client.PresignedHeadObject(context.Background(), o.Config.Bucket, objectKey, expires, nil)
+	default:
+		u, err = o.Client.Presign(context.Background(), string(method), o.Config.Bucket, objectKey, expires, nil)
+	}
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("Presign failed,err is %v", err))
+		return
+	}
+
+	if o.Config.CdnURL != "" {
+		cdnURL, cdnErr := url.Parse(o.Config.CdnURL)
+		if cdnErr != nil {
+			cdnErr = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("url.ParseLink failed,err is %v", cdnErr))
+			return signedURL, cdnErr
+		}
+
+		u.Host = cdnURL.Host
+		u.Scheme = cdnURL.Scheme
+	}
+
+	signedURL = u.String()
+
+	return
+}
+
+func (o S3Storage) Get(objectKey string) (content io.ReadCloser, err error) {
+	content, err = o.Client.GetObject(context.Background(), o.Config.Bucket, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("GetObject failed,err is %v", err))
+		return
+	}
+
+	return
+}
+
+func (o S3Storage) Put(objectKey string, reader io.Reader) (err error) {
+	contentType, err := GetContentType(objectKey)
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("GetContentType failed,err is %v", err))
+		return
+	}
+
+	// 大小未知时 minio 会按分片上传
+	_, err = o.Client.PutObject(context.Background(), o.Config.Bucket, objectKey, reader, -1, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("PutObject failed,err is %v", err))
+		return
+	}
+
+	return
+}
+
+func (o S3Storage) IsExist(objectKey string) (ok bool, err error) {
+	_, err = o.Client.StatObject(context.Background(), o.Config.Bucket, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("StatObject failed,err is %v", err))
+		return
+	}
+
+	return true, nil
+}
+
+func (o S3Storage) PutFromFile(objectKey string, filePath string) (err error) {
+	return o.PutMultipart(objectKey, filePath, 0)
+}
+
+func (o S3Storage) PutMultipart(objectKey string, filePath string, partSize int64) (err error) {
+	contentType, err := GetContentType(filePath)
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("GetContentType failed,err is %v", err))
+		return
+	}
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+
+	// 文件大于分片大小时 minio 自动使用分片上传
+	_, err = o.Client.FPutObject(context.Background(), o.Config.Bucket, objectKey, filePath, minio.PutObjectOptions{
+		ContentType: contentType,
+		PartSize:    uint64(partSize),
+	})
+	if err != nil {
+		err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("FPutObject failed,err is %v", err))
+		return
+	}
+
+	return
+}
+
+func (o S3Storage) Delete(objectKeys ...string) (deletedObjects []string, err error) {
+	objectsCh := make(chan minio.ObjectInfo, len(objectKeys))
+	for _, key := range objectKeys {
+		objectsCh <- minio.ObjectInfo{Key: key}
+	}
+	close(objectsCh)
+
+	failed := make(map[string]bool)
+	for e := range o.Client.RemoveObjects(context.Background(), o.Config.Bucket, objectsCh, minio.RemoveObjectsOptions{}) {
+		log.Errorf("remove object %s failed, err is %v", e.ObjectName, e.Err)
+		failed[e.ObjectName] = true
+	}
+
+	deletedObjects = make([]string, 0, len(objectKeys))
+	for _, key := range objectKeys {
+		if !failed[key] {
+			deletedObjects = append(deletedObjects, key)
+		}
+	}
+
+	return
+}
+
+func (o S3Storage) List(prefix string, maxKeys int) (objects []*ObjectInfo, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for object := range o.Client.ListObjects(ctx, o.Config.Bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+		MaxKeys:   maxKeys,
+	}) {
+		if object.Err != nil {
+			err = lberr.NewErr(lberr.ErrStorageOptErr, fmt.Sprintf("ListObjects failed,err is %v", object.Err))
+			return
+		}
+		objects = append(objects, &ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			ETag:         object.ETag,
+			LastModified: object.LastModified,
+		})
+		if maxKeys > 0 && len(objects) >= maxKeys {
+			break
+		}
+	}
+
+	return
+}
+
+func (o S3Storage) GetCredentials() (*Credentials, error) {
+	return &Credentials{
+		SecretID:  o.Config.AccessKeyId,
+		SecretKey: o.Config.AccessKeySecret,
+	}, nil
+}
+
+func (o S3Storage) GetSignature(httpMethod, name, ak, sk string, expired time.Duration) string {
+	return ""
+}
@@ -4,7 +4,6 @@ import (
 	"github.com/oldbai555/lbtool/log"
 	"github.com/oldbai555/lbtool/utils"
 	"io"
-	"os"
 	"time"
 )
 
@@ -12,16 +11,20 @@ var FileStorage FileStorageInterface
 
 // Config 存储配制
 type Config struct {
-	// Type 存储类型, 可配置 aliyun, qcloud；分别对应阿里云OSS, 腾讯云COS
-	Type string `validate:"required,oneof=aliyun qcloud"`
-	// CdnURL CDN绑定域名，可选配置，本地存储必填
-	CdnURL string `validate:"omitempty,url"`
+	// Type 存储类型, 可配置 aliyun, qcloud, s3, local；分别对应阿里云OSS, 腾讯云COS, 兼容S3协议的存储, 本地存储
+	Type string `validate:"required,oneof=aliyun qcloud s3 local"`
+	// CdnURL CDN绑定域名，可选配置，本地存储必填, 作为签名链接的域名
+	CdnURL string `validate:"required_if=Type local,omitempty,url"`
 
-	// 阿里云OSS相关配置，请使用子账户凭据，且仅授权oss访问权限
-	AccessKeyId     string `validate:"required_if=Type aliyun"`
-	AccessKeySecret string `validate:"required_if=Type aliyun"`
-	EndPoint        string `validate:"required_if=Type aliyun"`
-	Bucket          string `validate:"required_if=Type aliyun"`
+	// 阿里云OSS相关配置，请使用子账户凭据，且仅授权oss访问权限; s3 同样使用这几个配置
+	AccessKeyId     string `validate:"required_if=Type aliyun,required_if=Type s3"`
+	AccessKeySecret string `validate:"required_if=Type aliyun,required_if=Type s3"`
+	EndPoint        string `validate:"required_if=Type aliyun,required_if=Type s3"`
+	Bucket          string `validate:"required_if=Type aliyun,required_if=Type s3"`
+
+	// S3 相关配置, EndPoint 不带协议, 例如 s3.amazonaws.com, 127.0.0.1:9000
+	Region string
+	UseSSL bool
 
 	// 腾讯云OSS相关配置，请使用子账户凭据，且仅授权cos访问权限
 	SecretID  string `validate:"required_if=Type qcloud"`
@@ -34,6 +37,8 @@ type Config struct {
 	LocalRootPath string `validate:"required_if=Type local"`
 	// ServerRootPath 文件服务的根目录，http服务中的文件根目录，相对路径，用于识别文件服务请求的路径标识
 	ServerRootPath string `validate:"required_if=Type local"`
+	// SignKey 签名链接的密钥, SignURL 和 CheckSignedURL 使用同一个
+	SignKey string `validate:"required_if=Type local"`
 }
 
 type Credentials struct {
@@ -42,13 +47,28 @@ type Credentials struct {
 	SessionToken string `json:"session_token"`
 }
 
+// ObjectInfo 列举对象时返回的信息
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// DefaultPartSize 分片上传默认的分片大小
+const DefaultPartSize = 8 * utils.UnitMB
+
 type FileStorageInterface interface {
 	SignURL(objectKey string, method utils.HTTPMethod, expiredInSec int64) (signedURL string, err error)
 	Get(objectKey string) (content io.ReadCloser, err error)
 	Put(objectKey string, reader io.Reader) (err error)
 	IsExist(objectKey string) (ok bool, err error)
 	PutFromFile(objectKey string, filePath string) (err error)
+	// PutMultipart 分片上传大文件, partSize 小于等于 0 时使用 DefaultPartSize
+	PutMultipart(objectKey string, filePath string, partSize int64) (err error)
 	Delete(objectKeys ...string) (deletedObjects []string, err error)
+	// List 列举前缀下的对象, maxKeys 小于等于 0 时由各存储决定默认数量
+	List(prefix string, maxKeys int) (objects []*ObjectInfo, err error)
 	GetCredentials() (*Credentials, error)
 	GetSignature(httpMethod, name, ak, sk string, expired time.Duration) string
 }
@@ -59,8 +79,7 @@ func Setup(conf Config) {
 	if conf.Type == string(utils.AliyunStorage) {
 		FileStorage, err = NewOSS(conf)
 		if err != nil {
-			log.Errorf("NewOSS failed,err is %v", err)
-			os.Exit(1)
+			log.Fatalf("NewOSS failed,err is %v", err)
 			return
 		}
 	}
@@ -68,8 +87,23 @@ func Setup(conf Config) {
 	if conf.Type == string(utils.QcloudStorage) {
 		FileStorage, err = NewCOS(conf)
 		if err != nil {
-			log.Errorf("NewCOS failed,err is %v", err)
-			os.Exit(1)
+			log.Fatalf("NewCOS failed,err is %v", err)
+			return
+		}
+	}
+
+	if conf.Type == string(utils.S3Storage) {
+		FileStorage, err = NewS3(conf)
+		if err != nil {
+			log.Fatalf("NewS3 failed,err is %v", err)
+			return
+		}
+	}
+
+	if conf.Type == string(utils.LocalStorage) {
+		FileStorage, err = NewLocalStorage(conf)
+		if err != nil {
+			log.Fatalf("NewLocalStorage failed,err is %v", err)
 			return
 		}
 	}
@@ -0,0 +1,111 @@
+package trace
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+const HeaderTraceId = "X-Trace-Id"
+
+type HttpOption func(*httpOptions)
+
+type httpOptions struct {
+	route func(r *http.Request) string
+}
+
+// WithRoute 返回请求匹配的路由模板, 例如 /users/{id}, span 名为 GET /users/{id}
+// 不要直接返回 r.URL.Path, 路径里带 id 时 span 名的数量没有上限
+func WithRoute(route func(r *http.Request) string) HttpOption {
+	return func(o *httpOptions) {
+		o.route = route
+	}
+}
+
+// HttpMiddleware 为每个请求创建 server span, 并从请求头中还原上游的链路
+// span 名默认为 HTTP GET, 设置 WithRoute 后为 方法 + 路由模板, 原始路径记录在 http.target 属性中
+func HttpMiddleware(serverName string, opts ...HttpOption) func(http.Handler) http.Handler {
+	o := &httpOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			name, route := "HTTP "+r.Method, ""
+			if o.route != nil {
+				if route = o.route(r); route != "" {
+					name = r.Method + " " + route
+				}
+			}
+			ctx, span := StartSpan(ctx, name,
+				oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+				oteltrace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(serverName, route, r)...),
+			)
+			defer span.End()
+
+			if traceId := GetTraceId(ctx); traceId != "" {
+				w.Header().Set(HeaderTraceId, traceId)
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(sw.status)...)
+			span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(sw.status))
+		})
+	}
+}
+
+// statusWriter 记录响应的状态码
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// Transport 为每个请求创建 client span, 并把链路信息写入请求头
+type Transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport base 为空时使用 http.DefaultTransport
+func NewTransport(base http.RoundTripper) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base}
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := StartSpan(r.Context(), "HTTP "+r.Method,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(semconv.HTTPClientAttributesFromHTTPRequest(r)...),
+	)
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		EndSpan(span, err)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(resp.StatusCode)...)
+	span.SetStatus(semconv.SpanStatusFromHTTPStatusCode(resp.StatusCode))
+	span.End()
+	return resp, nil
+}
@@ -0,0 +1,68 @@
+package trace
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// nsq 等消息队列没有消息头, 链路信息需要随消息体一起投递
+
+// mapCarrier 当前 otel 版本还没有 propagation.MapCarrier
+type mapCarrier map[string]string
+
+func (c mapCarrier) Get(key string) string {
+	return c[key]
+}
+
+func (c mapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c mapCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// InjectMap 把 ctx 中的链路信息写到 map 中
+func InjectMap(ctx context.Context) map[string]string {
+	carrier := mapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// ExtractMap 从 map 中还原链路信息
+func ExtractMap(ctx context.Context, m map[string]string) context.Context {
+	if len(m) == 0 {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, mapCarrier(m))
+}
+
+// StartProducerSpan 发送消息前调用, 返回需要随消息投递的链路信息
+func StartProducerSpan(ctx context.Context, system, topic string) (context.Context, oteltrace.Span, map[string]string) {
+	ctx, span := StartSpan(ctx, topic+" send",
+		oteltrace.WithSpanKind(oteltrace.SpanKindProducer),
+		oteltrace.WithAttributes(
+			semconv.MessagingSystemKey.String(system),
+			semconv.MessagingDestinationKey.String(topic),
+		),
+	)
+	return ctx, span, InjectMap(ctx)
+}
+
+// StartConsumerSpan 消费消息时调用, carrier 为生产者投递的链路信息
+func StartConsumerSpan(ctx context.Context, system, topic string, carrier map[string]string) (context.Context, oteltrace.Span) {
+	ctx = ExtractMap(ctx, carrier)
+	return StartSpan(ctx, topic+" process",
+		oteltrace.WithSpanKind(oteltrace.SpanKindConsumer),
+		oteltrace.WithAttributes(
+			semconv.MessagingSystemKey.String(system),
+			semconv.MessagingDestinationKey.String(topic),
+		),
+	)
+}
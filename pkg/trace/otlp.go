@@ -0,0 +1,310 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 依赖的 grpc 版本被 replace 到了 v1.29.1, 官方的 otlp exporter 无法编译,
+// 这里按 OTLP/HTTP 的 json 编码自己实现上报, 收集器默认监听 4318 端口
+
+const (
+	otlpTracesPath = "/v1/traces"
+	otlpUserAgent  = "lbtool-otlp-exporter"
+)
+
+var _ sdktrace.SpanExporter = (*OTLPExporter)(nil)
+
+// OTLPExporter 以 OTLP/HTTP json 协议上报 span
+type OTLPExporter struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	stopped bool
+	mu      sync.RWMutex
+}
+
+func NewOTLPExporter(endpoint string, headers map[string]string) *OTLPExporter {
+	return &OTLPExporter{
+		url:     strings.TrimRight(endpoint, "/") + otlpTracesPath,
+		headers: headers,
+		client:  &http.Client{Timeout: DefaultExportTimeout},
+	}
+}
+
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.RLock()
+	stopped := e.stopped
+	e.mu.RUnlock()
+	if stopped || len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(newOTLPTraceRequest(spans))
+	if err != nil {
+		return err
+	}
+	return PostOTLP(ctx, e.client, e.url, e.headers, body)
+}
+
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopped = true
+	return nil
+}
+
+// PostOTLP 以 json 格式上报到 OTLP/HTTP 收集器
+func PostOTLP(ctx context.Context, client *http.Client, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", otlpUserAgent)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("otlp export failed, status %d, body %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// =================================== otlp json ===================================
+
+type otlpTraceRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   OTLPResource      `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type OTLPResource struct {
+	Attributes []OTLPKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope OTLPScope   `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type OTLPScope struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []OTLPKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []OTLPKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type OTLPKeyValue struct {
+	Key   string    `json:"key"`
+	Value OTLPValue `json:"value"`
+}
+
+type OTLPValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []OTLPValue `json:"values"`
+}
+
+// otlp 的状态码与 otel 的定义不同
+var otelCodeToOTLP = map[codes.Code]int{
+	codes.Unset: 0,
+	codes.Ok:    1,
+	codes.Error: 2,
+}
+
+func newOTLPTraceRequest(spans []sdktrace.ReadOnlySpan) *otlpTraceRequest {
+	type scopeKey struct {
+		name    string
+		version string
+	}
+	req := &otlpTraceRequest{}
+	resourceMap := make(map[string]*otlpResourceSpans)
+	scopeMap := make(map[string]map[scopeKey]*otlpScopeSpans)
+	for _, s := range spans {
+		resKey := ""
+		var resAttrs []attribute.KeyValue
+		if res := s.Resource(); res != nil {
+			resKey = res.Encoded(attribute.DefaultEncoder())
+			resAttrs = res.Attributes()
+		}
+		rs, ok := resourceMap[resKey]
+		if !ok {
+			rs = &otlpResourceSpans{Resource: OTLPResource{Attributes: OTLPAttributes(resAttrs)}}
+			resourceMap[resKey] = rs
+			scopeMap[resKey] = make(map[scopeKey]*otlpScopeSpans)
+			req.ResourceSpans = append(req.ResourceSpans, rs)
+		}
+
+		lib := s.InstrumentationLibrary()
+		sk := scopeKey{name: lib.Name, version: lib.Version}
+		ss, ok := scopeMap[resKey][sk]
+		if !ok {
+			ss = &otlpScopeSpans{Scope: OTLPScope{Name: lib.Name, Version: lib.Version}}
+			scopeMap[resKey][sk] = ss
+			rs.ScopeSpans = append(rs.ScopeSpans, ss)
+		}
+		ss.Spans = append(ss.Spans, newOTLPSpan(s))
+	}
+	return req
+}
+
+func newOTLPSpan(s sdktrace.ReadOnlySpan) *otlpSpan {
+	span := &otlpSpan{
+		TraceId:           s.SpanContext().TraceID().String(),
+		SpanId:            s.SpanContext().SpanID().String(),
+		Name:              s.Name(),
+		Kind:              int(s.SpanKind()),
+		StartTimeUnixNano: UnixNanoStr(s.StartTime()),
+		EndTimeUnixNano:   UnixNanoStr(s.EndTime()),
+		Attributes:        OTLPAttributes(s.Attributes()),
+		Status: otlpStatus{
+			Code:    otelCodeToOTLP[s.Status().Code],
+			Message: s.Status().Description,
+		},
+	}
+	if s.Parent().HasSpanID() {
+		span.ParentSpanId = s.Parent().SpanID().String()
+	}
+	for _, e := range s.Events() {
+		span.Events = append(span.Events, otlpEvent{
+			TimeUnixNano: UnixNanoStr(e.Time),
+			Name:         e.Name,
+			Attributes:   OTLPAttributes(e.Attributes),
+		})
+	}
+	return span
+}
+
+// OTLPAttributes 把 otel 的属性转换为 otlp json 格式
+func OTLPAttributes(attrs []attribute.KeyValue) []OTLPKeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	list := make([]OTLPKeyValue, 0, len(attrs))
+	for _, kv := range attrs {
+		list = append(list, OTLPKeyValue{Key: string(kv.Key), Value: OTLPAnyValue(kv.Value.AsInterface())})
+	}
+	return list
+}
+
+// OTLPAnyValue 把任意值转换为 otlp json 的 AnyValue, 不认识的类型按字符串处理
+func OTLPAnyValue(v interface{}) OTLPValue {
+	switch x := v.(type) {
+	case string:
+		return OTLPValue{StringValue: &x}
+	case bool:
+		return OTLPValue{BoolValue: &x}
+	case int:
+		return otlpIntValue(int64(x))
+	case int32:
+		return otlpIntValue(int64(x))
+	case int64:
+		return otlpIntValue(x)
+	case uint32:
+		return otlpIntValue(int64(x))
+	case uint64:
+		return otlpIntValue(int64(x))
+	case float32:
+		f := float64(x)
+		return OTLPValue{DoubleValue: &f}
+	case float64:
+		return OTLPValue{DoubleValue: &x}
+	case []string:
+		arr := &otlpArrayValue{}
+		for _, s := range x {
+			arr.Values = append(arr.Values, OTLPAnyValue(s))
+		}
+		return OTLPValue{ArrayValue: arr}
+	case []int64:
+		arr := &otlpArrayValue{}
+		for _, i := range x {
+			arr.Values = append(arr.Values, OTLPAnyValue(i))
+		}
+		return OTLPValue{ArrayValue: arr}
+	case []bool:
+		arr := &otlpArrayValue{}
+		for _, b := range x {
+			arr.Values = append(arr.Values, OTLPAnyValue(b))
+		}
+		return OTLPValue{ArrayValue: arr}
+	case []float64:
+		arr := &otlpArrayValue{}
+		for _, f := range x {
+			arr.Values = append(arr.Values, OTLPAnyValue(f))
+		}
+		return OTLPValue{ArrayValue: arr}
+	case fmt.Stringer:
+		s := x.String()
+		return OTLPValue{StringValue: &s}
+	case error:
+		s := x.Error()
+		return OTLPValue{StringValue: &s}
+	default:
+		s := fmt.Sprintf("%v", x)
+		return OTLPValue{StringValue: &s}
+	}
+}
+
+func otlpIntValue(i int64) OTLPValue {
+	s := strconv.FormatInt(i, 10)
+	return OTLPValue{IntValue: &s}
+}
+
+// UnixNanoStr otlp json 中 64 位整数以字符串表示
+func UnixNanoStr(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
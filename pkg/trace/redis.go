@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"strings"
+)
+
+var _ redis.Hook = (*RedisHook)(nil)
+
+// RedisHook 为每条 redis 命令创建 span, 使用 client.AddHook(trace.NewRedisHook()) 接入
+type RedisHook struct{}
+
+func NewRedisHook() *RedisHook {
+	return &RedisHook{}
+}
+
+func (h *RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = StartSpan(ctx, "redis "+cmd.Name(),
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBStatementKey.String(cmd.Name()),
+		),
+	)
+	return ctx, nil
+}
+
+func (h *RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (h *RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	names := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		names = append(names, cmd.Name())
+	}
+	ctx, _ = StartSpan(ctx, "redis pipeline",
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(
+			semconv.DBSystemRedis,
+			semconv.DBStatementKey.String(strings.Join(names, " ")),
+		),
+	)
+	return ctx, nil
+}
+
+func (h *RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	endRedisSpan(ctx, err)
+	return nil
+}
+
+func endRedisSpan(ctx context.Context, err error) {
+	// redis.Nil 表示 key 不存在, 不算错误
+	if err == redis.Nil {
+		err = nil
+	}
+	EndSpan(oteltrace.SpanFromContext(ctx), err)
+}
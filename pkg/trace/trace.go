@@ -0,0 +1,133 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdkresource "go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	InstrumentationName = "github.com/oldbai555/lbtool/pkg/trace"
+
+	DefaultEndpoint      = "http://127.0.0.1:4318"
+	DefaultExportTimeout = 10 * time.Second
+)
+
+// Config 链路追踪配置
+type Config struct {
+	// ServiceName 服务名
+	ServiceName string `json:"service_name"`
+	// Endpoint OTLP/HTTP 收集器地址,例如 http://127.0.0.1:4318
+	Endpoint string `json:"endpoint"`
+	// Headers 上报时附带的请求头,一般用于鉴权
+	Headers map[string]string `json:"headers"`
+	// SampleRatio 采样率 0-1, 小于等于 0 时不采样, 大于等于 1 时全采样
+	SampleRatio float64 `json:"sample_ratio"`
+	// Attributes 附加到 resource 上的属性,例如 env, version
+	Attributes map[string]string `json:"attributes"`
+	// Sync 同步上报,只建议在测试时使用
+	Sync bool `json:"sync"`
+}
+
+// Setup 初始化全局的 TracerProvider 和传播器, 返回的方法在服务退出时调用以上报剩余的 span
+func Setup(conf Config) (shutdown func(ctx context.Context) error, err error) {
+	if conf.ServiceName == "" {
+		return nil, errors.New("service name is required")
+	}
+	if conf.Endpoint == "" {
+		conf.Endpoint = DefaultEndpoint
+	}
+
+	exporter := NewOTLPExporter(conf.Endpoint, conf.Headers)
+	provider := NewTracerProvider(conf, exporter)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Warnf("otel err:%v", err)
+	}))
+
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider 按配置创建 TracerProvider, exporter 可以替换为其他实现
+func NewTracerProvider(conf Config, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	attrs := []attribute.KeyValue{semconv.ServiceNameKey.String(conf.ServiceName)}
+	for k, v := range conf.Attributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+
+	spanExporterOpt := sdktrace.WithBatcher(exporter, sdktrace.WithExportTimeout(DefaultExportTimeout))
+	if conf.Sync {
+		spanExporterOpt = sdktrace.WithSyncer(exporter)
+	}
+
+	return sdktrace.NewTracerProvider(
+		spanExporterOpt,
+		sdktrace.WithSampler(newSampler(conf.SampleRatio)),
+		sdktrace.WithResource(sdkresource.NewWithAttributes(semconv.SchemaURL, attrs...)),
+	)
+}
+
+// newSampler 跟随上游的采样决定,没有上游时按比例采样
+func newSampler(ratio float64) sdktrace.Sampler {
+	var root sdktrace.Sampler
+	switch {
+	case ratio >= 1:
+		root = sdktrace.AlwaysSample()
+	case ratio <= 0:
+		root = sdktrace.NeverSample()
+	default:
+		root = sdktrace.TraceIDRatioBased(ratio)
+	}
+	return sdktrace.ParentBased(root)
+}
+
+// Tracer 获取本包使用的 tracer
+func Tracer() oteltrace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// StartSpan 开启一个 span, 使用方需要调用 span.End()
+func StartSpan(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// EndSpan 根据 err 设置 span 状态后结束 span
+func EndSpan(span oteltrace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// GetTraceId 获取 ctx 中的 trace id, 没有时返回空串
+func GetTraceId(ctx context.Context) string {
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// GetSpanId 获取 ctx 中的 span id, 没有时返回空串
+func GetSpanId(ctx context.Context) string {
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.HasSpanID() {
+		return ""
+	}
+	return sc.SpanID().String()
+}
@@ -0,0 +1,104 @@
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		spans []*otlpSpan
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != otlpTracesPath {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req otlpTraceRequest
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("err:%v", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+
+	provider := NewTracerProvider(Config{ServiceName: "trace_test", SampleRatio: 1, Sync: true}, NewOTLPExporter(collector.URL, nil))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	route := WithRoute(func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/users/") {
+			return "/users/{id}"
+		}
+		return ""
+	})
+	server := httptest.NewServer(HttpMiddleware("trace_test", route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := StartSpan(r.Context(), "handler")
+		span.End()
+	})))
+	defer server.Close()
+
+	ctx, root := StartSpan(context.Background(), "root")
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/users/42", nil)
+	resp, err := (&http.Client{Transport: NewTransport(nil)}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.Header.Get(HeaderTraceId) != GetTraceId(ctx) {
+		t.Errorf("trace id not propagated")
+	}
+	root.End()
+
+	if err = provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	names := map[string]bool{}
+	for _, s := range spans {
+		names[s.Name] = true
+		if s.TraceId != GetTraceId(ctx) {
+			t.Errorf("span %s trace id %s", s.Name, s.TraceId)
+		}
+	}
+	for _, want := range []string{"root", "HTTP GET", "GET /users/{id}", "handler"} {
+		if !names[want] {
+			t.Errorf("missing span %s, got %v", want, names)
+		}
+	}
+	if names["GET /users/42"] {
+		t.Errorf("span name should not use the raw path, got %v", names)
+	}
+}
+
+func TestInjectMap(t *testing.T) {
+	provider := NewTracerProvider(Config{ServiceName: "trace_test", SampleRatio: 1}, NewOTLPExporter("http://127.0.0.1:1", nil))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx, span, carrier := StartProducerSpan(context.Background(), "nsq", "order")
+	defer span.End()
+	consumerCtx, consumerSpan := StartConsumerSpan(context.Background(), "nsq", "order", carrier)
+	defer consumerSpan.End()
+	if GetTraceId(consumerCtx) != GetTraceId(ctx) {
+		t.Errorf("trace id not propagated")
+	}
+}
@@ -0,0 +1,147 @@
+package upload
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/oldbai555/lbtool/log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	PathInit     = "/init"
+	PathChunk    = "/chunk"
+	PathComplete = "/complete"
+	PathStatus   = "/status"
+	PathAbort    = "/abort"
+
+	// HeaderChunkSha256 分片的 sha256, 可选
+	HeaderChunkSha256 = "X-Chunk-Sha256"
+)
+
+// SessionResp 接口返回的会话信息
+type SessionResp struct {
+	*Session
+	Missing []int `json:"missing"`
+}
+
+// Mount 把上传接口挂到 mux 上
+//
+//	POST   {prefix}/init                      body: InitReq
+//	PUT    {prefix}/chunk?upload_id=&index=   body: 分片原始内容
+//	GET    {prefix}/status?upload_id=         断线后查询缺失的分片
+//	POST   {prefix}/complete?upload_id=
+//	DELETE {prefix}/abort?upload_id=
+func (m *Manager) Mount(mux *http.ServeMux, prefix string) {
+	prefix = strings.TrimRight(prefix, "/")
+	mux.HandleFunc(prefix+PathInit, m.handleInit)
+	mux.HandleFunc(prefix+PathChunk, m.handleChunk)
+	mux.HandleFunc(prefix+PathStatus, m.handleStatus)
+	mux.HandleFunc(prefix+PathComplete, m.handleComplete)
+	mux.HandleFunc(prefix+PathAbort, m.handleAbort)
+}
+
+func (m *Manager) handleInit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req InitReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErr(w, http.StatusBadRequest, err)
+		return
+	}
+	s, err := m.Init(r.Context(), &req)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err)
+		return
+	}
+	writeSession(w, s)
+}
+
+func (m *Manager) handleChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPatch && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	index, err := strconv.Atoi(r.URL.Query().Get("index"))
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, ErrInvalidChunk)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, m.maxChunkSize)
+	s, err := m.WriteChunk(r.Context(), r.URL.Query().Get("upload_id"), index, r.Body, r.Header.Get(HeaderChunkSha256))
+	if err != nil {
+		writeErr(w, errStatus(err), err)
+		return
+	}
+	writeSession(w, s)
+}
+
+func (m *Manager) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s, err := m.Status(r.Context(), r.URL.Query().Get("upload_id"))
+	if err != nil {
+		writeErr(w, errStatus(err), err)
+		return
+	}
+	writeSession(w, s)
+}
+
+func (m *Manager) handleComplete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	s, err := m.Complete(r.Context(), r.URL.Query().Get("upload_id"))
+	if err != nil {
+		writeErr(w, errStatus(err), err)
+		return
+	}
+	writeSession(w, s)
+}
+
+func (m *Manager) handleAbort(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := m.Abort(r.Context(), r.URL.Query().Get("upload_id")); err != nil {
+		writeErr(w, errStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func errStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInvalidChunk), errors.Is(err, ErrChunkSize), errors.Is(err, ErrChecksumMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrIncomplete), errors.Is(err, ErrCompleted):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeSession(w http.ResponseWriter, s *Session) {
+	writeJson(w, http.StatusOK, &SessionResp{Session: s, Missing: s.Missing()})
+}
+
+func writeErr(w http.ResponseWriter, code int, err error) {
+	if code == http.StatusInternalServerError {
+		log.Errorf("err:%v", err)
+	}
+	writeJson(w, code, map[string]string{"error": err.Error()})
+}
+
+func writeJson(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
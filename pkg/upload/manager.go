@@ -0,0 +1,294 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/oldbai555/lbtool/log"
+	"github.com/oldbai555/lbtool/pkg/storage"
+	"github.com/oldbai555/lbtool/utils"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultChunkSize    = 5 * utils.UnitMB
+	DefaultMaxChunkSize = 32 * utils.UnitMB
+	DefaultExpire       = 24 * time.Hour
+)
+
+var (
+	ErrInvalidChunk     = errors.New("invalid chunk index")
+	ErrChunkSize        = errors.New("chunk size mismatch")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrIncomplete       = errors.New("upload incomplete")
+	ErrCompleted        = errors.New("upload already completed")
+)
+
+// InitReq 初始化上传的参数
+type InitReq struct {
+	FileName  string `json:"file_name"`
+	ObjectKey string `json:"object_key"` // 为空时按日期和 upload_id 生成
+	FileSize  int64  `json:"file_size"`
+	ChunkSize int64  `json:"chunk_size"` // 为空时使用 DefaultChunkSize
+	Sha256    string `json:"sha256"`
+}
+
+// Manager 管理分片上传, 分片先落到本地临时目录, 全部到齐后合并并写入 storage
+type Manager struct {
+	store        SessionStore
+	storage      storage.FileStorageInterface
+	tmpDir       string
+	expire       time.Duration
+	maxChunkSize int64
+
+	// locks 同一个会话的分片可能并发上传, 更新会话时需要串行
+	locks sync.Map
+}
+
+type Option func(*Manager)
+
+// WithStore 会话存储, 默认存在内存
+func WithStore(store SessionStore) Option {
+	return func(m *Manager) {
+		m.store = store
+	}
+}
+
+// WithTmpDir 分片临时目录, 默认 os.TempDir()/lb_upload
+func WithTmpDir(dir string) Option {
+	return func(m *Manager) {
+		m.tmpDir = dir
+	}
+}
+
+// WithExpire 会话过期时间, 过期后需要重新上传
+func WithExpire(expire time.Duration) Option {
+	return func(m *Manager) {
+		m.expire = expire
+	}
+}
+
+// WithMaxChunkSize 允许的最大分片大小
+func WithMaxChunkSize(size int64) Option {
+	return func(m *Manager) {
+		m.maxChunkSize = size
+	}
+}
+
+func NewManager(fs storage.FileStorageInterface, ops ...Option) *Manager {
+	m := &Manager{
+		storage:      fs,
+		tmpDir:       filepath.Join(os.TempDir(), "lb_upload"),
+		expire:       DefaultExpire,
+		maxChunkSize: DefaultMaxChunkSize,
+	}
+	for i := range ops {
+		ops[i](m)
+	}
+	if m.store == nil {
+		m.store = NewMemoryStore()
+	}
+	return m
+}
+
+func (m *Manager) lock(uploadId string) func() {
+	v, _ := m.locks.LoadOrStore(uploadId, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func (m *Manager) sessionDir(uploadId string) string {
+	return filepath.Join(m.tmpDir, uploadId)
+}
+
+func (m *Manager) chunkPath(uploadId string, index int) string {
+	return filepath.Join(m.sessionDir(uploadId), fmt.Sprintf("%d.part", index))
+}
+
+// Init 创建上传会话
+func (m *Manager) Init(ctx context.Context, req *InitReq) (*Session, error) {
+	if req.FileSize <= 0 {
+		return nil, fmt.Errorf("invalid file size %d", req.FileSize)
+	}
+	chunkSize := req.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > m.maxChunkSize {
+		return nil, fmt.Errorf("chunk size %d exceeds %d", chunkSize, m.maxChunkSize)
+	}
+
+	now := time.Now()
+	s := &Session{
+		UploadId:   utils.GenUUID(),
+		FileName:   filepath.Base(req.FileName),
+		ObjectKey:  strings.TrimLeft(req.ObjectKey, "/"),
+		FileSize:   req.FileSize,
+		ChunkSize:  chunkSize,
+		ChunkCount: int((req.FileSize + chunkSize - 1) / chunkSize),
+		Sha256:     strings.ToLower(req.Sha256),
+		Status:     StatusUploading,
+		CreatedAt:  now,
+		ExpireAt:   now.Add(m.expire),
+	}
+	if s.ObjectKey == "" {
+		s.ObjectKey = fmt.Sprintf("upload/%s/%s%s", now.Format("20060102"), s.UploadId, filepath.Ext(s.FileName))
+	}
+
+	if err := os.MkdirAll(m.sessionDir(s.UploadId), os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := m.store.Save(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Status 查询会话, 用于断点续传
+func (m *Manager) Status(ctx context.Context, uploadId string) (*Session, error) {
+	return m.store.Get(ctx, uploadId)
+}
+
+// WriteChunk 写入一个分片, checksum 为分片的 sha256, 为空时不校验; 同一分片可以重复上传
+func (m *Manager) WriteChunk(ctx context.Context, uploadId string, index int, r io.Reader, checksum string) (*Session, error) {
+	s, err := m.store.Get(ctx, uploadId)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status == StatusCompleted {
+		return nil, ErrCompleted
+	}
+	if index < 0 || index >= s.ChunkCount {
+		return nil, ErrInvalidChunk
+	}
+
+	// 先写临时文件, 校验通过后再重命名, 断线时不会留下残缺的分片
+	tmp, err := os.CreateTemp(m.sessionDir(uploadId), fmt.Sprintf("%d.*.tmp", index))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	expectLen := s.chunkLen(index)
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, expectLen+1))
+	closeErr := tmp.Close()
+	if err != nil {
+		return nil, err
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	if n != expectLen {
+		return nil, ErrChunkSize
+	}
+	if checksum != "" && !strings.EqualFold(checksum, hex.EncodeToString(h.Sum(nil))) {
+		return nil, ErrChecksumMismatch
+	}
+	if err = os.Rename(tmp.Name(), m.chunkPath(uploadId, index)); err != nil {
+		return nil, err
+	}
+
+	unlock := m.lock(uploadId)
+	defer unlock()
+	// 加锁后重新读取, 避免并发上传时互相覆盖已上传记录
+	s, err = m.store.Get(ctx, uploadId)
+	if err != nil {
+		return nil, err
+	}
+	s.markUploaded(index)
+	if err = m.store.Save(ctx, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Complete 合并分片并写入 storage, 校验失败时保留分片以便重传
+func (m *Manager) Complete(ctx context.Context, uploadId string) (*Session, error) {
+	unlock := m.lock(uploadId)
+	defer unlock()
+
+	s, err := m.store.Get(ctx, uploadId)
+	if err != nil {
+		return nil, err
+	}
+	if s.Status == StatusCompleted {
+		return s, nil
+	}
+	if len(s.Missing()) > 0 {
+		return nil, ErrIncomplete
+	}
+
+	mergedPath := filepath.Join(m.sessionDir(uploadId), "merged"+filepath.Ext(s.FileName))
+	sum, err := m.merge(s, mergedPath)
+	if err != nil {
+		return nil, err
+	}
+	if s.Sha256 != "" && s.Sha256 != sum {
+		_ = os.Remove(mergedPath)
+		return nil, ErrChecksumMismatch
+	}
+
+	if err = m.storage.PutMultipart(s.ObjectKey, mergedPath, storage.DefaultPartSize); err != nil {
+		log.Errorf("err:%v", err)
+		return nil, err
+	}
+
+	s.Status = StatusCompleted
+	if err = m.store.Save(ctx, s); err != nil {
+		log.Errorf("err:%v", err)
+	}
+	m.cleanup(uploadId)
+	return s, nil
+}
+
+// Abort 取消上传, 删除会话和已上传的分片
+func (m *Manager) Abort(ctx context.Context, uploadId string) error {
+	unlock := m.lock(uploadId)
+	defer unlock()
+	m.cleanup(uploadId)
+	return m.store.Delete(ctx, uploadId)
+}
+
+func (m *Manager) cleanup(uploadId string) {
+	if err := os.RemoveAll(m.sessionDir(uploadId)); err != nil {
+		log.Errorf("err:%v", err)
+	}
+	m.locks.Delete(uploadId)
+}
+
+// merge 按顺序合并分片, 返回整个文件的 sha256
+func (m *Manager) merge(s *Session, dst string) (string, error) {
+	f, err := os.Create(dst)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	for i := 0; i < s.ChunkCount; i++ {
+		if err = appendFile(w, m.chunkPath(s.UploadId, i)); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func appendFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
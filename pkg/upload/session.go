@@ -0,0 +1,173 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v8"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("upload session not found")
+)
+
+type Status int
+
+const (
+	StatusUploading Status = iota + 1
+	StatusCompleted
+)
+
+// Session 一次分片上传的会话, 断线重连后通过 Missing 得到还没上传的分片
+type Session struct {
+	UploadId   string    `json:"upload_id"`
+	FileName   string    `json:"file_name"`
+	ObjectKey  string    `json:"object_key"`
+	FileSize   int64     `json:"file_size"`
+	ChunkSize  int64     `json:"chunk_size"`
+	ChunkCount int       `json:"chunk_count"`
+	Sha256     string    `json:"sha256"` // 整个文件的 sha256, 为空时不校验
+	Uploaded   []int     `json:"uploaded"`
+	Status     Status    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpireAt   time.Time `json:"expire_at"`
+}
+
+// Missing 还没上传的分片序号, 从 0 开始
+func (s *Session) Missing() []int {
+	done := make(map[int]bool, len(s.Uploaded))
+	for _, idx := range s.Uploaded {
+		done[idx] = true
+	}
+	var list []int
+	for i := 0; i < s.ChunkCount; i++ {
+		if !done[i] {
+			list = append(list, i)
+		}
+	}
+	return list
+}
+
+// markUploaded 记录分片已上传, 重复上传同一个分片只记一次
+func (s *Session) markUploaded(index int) {
+	for _, idx := range s.Uploaded {
+		if idx == index {
+			return
+		}
+	}
+	s.Uploaded = append(s.Uploaded, index)
+	sort.Ints(s.Uploaded)
+}
+
+// chunkLen 第 index 个分片的长度, 最后一片可能不足 ChunkSize
+func (s *Session) chunkLen(index int) int64 {
+	if index == s.ChunkCount-1 {
+		return s.FileSize - int64(index)*s.ChunkSize
+	}
+	return s.ChunkSize
+}
+
+// SessionStore 会话存储, 多实例部署时需要使用共享存储并挂载同一个临时目录
+type SessionStore interface {
+	Save(ctx context.Context, s *Session) error
+	Get(ctx context.Context, uploadId string) (*Session, error)
+	Delete(ctx context.Context, uploadId string) error
+}
+
+var _ SessionStore = (*MemoryStore)(nil)
+
+type MemoryStore struct {
+	sessions map[string]*Session
+	mu       sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]*Session),
+	}
+}
+
+func (m *MemoryStore) Save(ctx context.Context, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *s
+	cp.Uploaded = append([]int(nil), s.Uploaded...)
+	m.sessions[s.UploadId] = &cp
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, uploadId string) (*Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[uploadId]
+	if !ok || time.Now().After(s.ExpireAt) {
+		return nil, ErrSessionNotFound
+	}
+	cp := *s
+	cp.Uploaded = append([]int(nil), s.Uploaded...)
+	return &cp, nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, uploadId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, uploadId)
+	return nil
+}
+
+var _ SessionStore = (*RedisStore)(nil)
+
+// RedisStore 会话以 json 存在 redis, 过期时间跟随会话
+type RedisStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewRedisStore(client redis.UniversalClient, prefix string) *RedisStore {
+	if prefix == "" {
+		prefix = "upload_session"
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}
+}
+
+func (r *RedisStore) key(uploadId string) string {
+	return fmt.Sprintf("%s_%s", r.prefix, uploadId)
+}
+
+func (r *RedisStore) Save(ctx context.Context, s *Session) error {
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(s.ExpireAt)
+	if ttl <= 0 {
+		return r.Delete(ctx, s.UploadId)
+	}
+	return r.client.Set(ctx, r.key(s.UploadId), buf, ttl).Err()
+}
+
+func (r *RedisStore) Get(ctx context.Context, uploadId string) (*Session, error) {
+	buf, err := r.client.Get(ctx, r.key(uploadId)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err = json.Unmarshal(buf, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *RedisStore) Delete(ctx context.Context, uploadId string) error {
+	return r.client.Del(ctx, r.key(uploadId)).Err()
+}
@@ -0,0 +1,159 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/oldbai555/lbtool/pkg/storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestManager(t *testing.T) (*Manager, string) {
+	root := t.TempDir()
+	fs, err := storage.NewLocalStorage(storage.Config{
+		LocalRootPath:  root,
+		ServerRootPath: "/static",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewManager(fs, WithTmpDir(t.TempDir())), root
+}
+
+func sum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+func TestManager_Resume(t *testing.T) {
+	m, root := newTestManager(t)
+	ctx := context.Background()
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	s, err := m.Init(ctx, &InitReq{FileName: "a.txt", ObjectKey: "doc/a.txt", FileSize: int64(len(data)), ChunkSize: 30, Sha256: sum(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ChunkCount != 4 {
+		t.Fatalf("expect 4 chunks, got %d", s.ChunkCount)
+	}
+
+	chunk := func(i int) []byte {
+		end := (i + 1) * 30
+		if end > len(data) {
+			end = len(data)
+		}
+		return data[i*30 : end]
+	}
+
+	// 校验失败的分片不算上传成功
+	if _, err = m.WriteChunk(ctx, s.UploadId, 0, bytes.NewReader(chunk(0)), sum(chunk(1))); err != ErrChecksumMismatch {
+		t.Fatalf("expect checksum mismatch, got %v", err)
+	}
+	if _, err = m.WriteChunk(ctx, s.UploadId, 0, bytes.NewReader(chunk(0)), sum(chunk(0))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.WriteChunk(ctx, s.UploadId, 3, bytes.NewReader(chunk(3)), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Complete(ctx, s.UploadId); err != ErrIncomplete {
+		t.Fatalf("expect incomplete, got %v", err)
+	}
+
+	// 模拟断线后查询缺失的分片继续上传
+	s, err = m.Status(ctx, s.UploadId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing := s.Missing()
+	if fmt.Sprint(missing) != "[1 2]" {
+		t.Fatalf("unexpected missing %v", missing)
+	}
+	for _, i := range missing {
+		if _, err = m.WriteChunk(ctx, s.UploadId, i, bytes.NewReader(chunk(i)), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err = m.Complete(ctx, s.UploadId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Status != StatusCompleted {
+		t.Fatalf("unexpected status %v", s.Status)
+	}
+	got, err := os.ReadFile(filepath.Join(root, "doc", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("merged file mismatch")
+	}
+}
+
+func TestManager_Handler(t *testing.T) {
+	m, _ := newTestManager(t)
+	mux := http.NewServeMux()
+	m.Mount(mux, "/upload")
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	data := []byte("hello chunked upload")
+	body, _ := json.Marshal(&InitReq{FileName: "b.txt", FileSize: int64(len(data)), ChunkSize: 8})
+	resp, err := http.Post(srv.URL+"/upload/init", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s SessionResp
+	_ = json.NewDecoder(resp.Body).Decode(&s)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(s.Missing) != 3 {
+		t.Fatalf("unexpected init resp %d %+v", resp.StatusCode, s)
+	}
+
+	for i := 0; i < s.ChunkCount; i++ {
+		end := (i + 1) * 8
+		if end > len(data) {
+			end = len(data)
+		}
+		req, _ := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/upload/chunk?upload_id=%s&index=%d", srv.URL, s.UploadId, i), bytes.NewReader(data[i*8:end]))
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("chunk %d status %d", i, resp.StatusCode)
+		}
+	}
+
+	resp, err = http.Post(srv.URL+"/upload/complete?upload_id="+s.UploadId, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("complete status %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/upload/status?upload_id=unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expect 404, got %d", resp.StatusCode)
+	}
+}
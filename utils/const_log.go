@@ -9,6 +9,7 @@ type Format int
 
 const (
 	FormatText Format = iota
+	FormatJSON
 )
 
 // ================================Level===============================
@@ -21,8 +22,8 @@ const (
 	LevelInfo
 	LevelWarn
 	LevelError
-	levelPanic
-	levelFatal
+	LevelPanic
+	LevelFatal
 )
 
 var LevelToStrMap = map[Level]string{
@@ -30,8 +31,8 @@ var LevelToStrMap = map[Level]string{
 	LevelInfo:  "INFO",
 	LevelWarn:  "WARN",
 	LevelError: "ERR",
-	levelPanic: "FATAL",
-	levelFatal: "PANIC",
+	LevelPanic: "PANIC",
+	LevelFatal: "FATAL",
 }
 
 var LevelToStdoutColorMap = map[Level]Color{
@@ -39,8 +40,8 @@ var LevelToStdoutColorMap = map[Level]Color{
 	LevelInfo:  colorGreen,
 	LevelWarn:  colorYellow,
 	LevelError: colorRed,
-	levelFatal: colorPurple,
-	levelPanic: colorRed,
+	LevelFatal: colorPurple,
+	LevelPanic: colorRed,
 }
 
 // ================================Color===============================
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAesGCM(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	data, err := AesGCMEncrypt([]byte("hello world"), key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := AesGCMDecrypt(data, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain, []byte("hello world")) {
+		t.Fatalf("unexpected %s", plain)
+	}
+	data[len(data)-1] ^= 1
+	if _, err = AesGCMDecrypt(data, key, nil); err == nil {
+		t.Fatal("tampered data should fail")
+	}
+}
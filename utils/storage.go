@@ -6,6 +6,7 @@ const (
 	AliyunStorage StorageType = "aliyun"
 	QcloudStorage StorageType = "qcloud"
 	LocalStorage  StorageType = "local"
+	S3Storage     StorageType = "s3" // 兼容 S3 协议的存储, 例如 aws s3, minio
 )
 
 // HTTPMethod HTTP request method